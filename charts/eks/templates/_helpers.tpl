@@ -126,4 +126,96 @@ Host cluster service mapping
 {{- range $key, $value := .Values.mapServices.fromHost }}
 - '--map-host-service={{ $value.from }}={{ $value.to }}'
 {{- end }}
+{{- range $key, $value := .Values.mapServices.fromVClusters }}
+- '--import-service={{ $value.from }}={{ $value.to }}'
+{{- end }}
+{{- end -}}
+
+{{/*
+  Hostname of the vcluster behind the shared gateway
+*/}}
+{{- define "vcluster.gateway.hostname" -}}
+{{ .Release.Name }}.{{ .Release.Namespace }}.{{ trimPrefix "." .Values.gateway.baseDomain }}
+{{- end -}}
+
+{{/*
+  Config files of the syncer, e.g. policies and rules. Each of them is stored in its own ConfigMap,
+  mounted into the syncer at /manifests/<name> and passed to the syncer with its flag.
+*/}}
+{{- define "vcluster.syncer.configFiles" -}}
+files:
+{{- if .Values.sync.nodes.syncRules }}
+  - name: node-sync-rules
+    file: rules.yaml
+    flag: node-sync-rules
+    config: {{ toJson .Values.sync.nodes.syncRules }}
+{{- end }}
+{{- if .Values.sync.serviceaccounts.identityPolicy }}
+  - name: service-account-identity-policy
+    file: policy.yaml
+    flag: service-account-identity-policy
+    config: {{ toJson .Values.sync.serviceaccounts.identityPolicy }}
+{{- end }}
+{{- if .Values.sync.ingresses.policy }}
+  - name: ingress-policy
+    file: policy.yaml
+    flag: ingress-policy
+    config: {{ toJson .Values.sync.ingresses.policy }}
+{{- end }}
+{{- if .Values.sync.persistentvolumeclaims.storagePolicy }}
+  - name: storage-policy
+    file: policy.yaml
+    flag: storage-policy
+    config: {{ toJson .Values.sync.persistentvolumeclaims.storagePolicy }}
+{{- end }}
+{{- if .Values.coredns.managedConfig }}
+  - name: coredns-config
+    file: config.yaml
+    flag: coredns-config
+    config: {{ toJson .Values.coredns.managedConfig }}
+{{- end }}
+{{- if .Values.syncer.podPlacementRules }}
+  - name: pod-placement
+    file: rules.yaml
+    flag: pod-placement-rules
+    config: {{ toJson .Values.syncer.podPlacementRules }}
+{{- end }}
+{{- if .Values.syncer.podResourcePolicy }}
+  - name: pod-resource-policy
+    file: policy.yaml
+    flag: pod-resource-policy
+    config: {{ toJson .Values.syncer.podResourcePolicy }}
+{{- end }}
+{{- end -}}
+
+{{/*
+  Volumes of the syncer config files
+*/}}
+{{- define "vcluster.syncer.configFiles.volumes" -}}
+{{- $root := . -}}
+{{- range (include "vcluster.syncer.configFiles" . | fromYaml).files }}
+- name: {{ .name }}
+  configMap:
+    name: {{ $root.Release.Name }}-{{ .name }}
+{{- end }}
+{{- end -}}
+
+{{/*
+  Volume mounts of the syncer config files
+*/}}
+{{- define "vcluster.syncer.configFiles.volumeMounts" -}}
+{{- range (include "vcluster.syncer.configFiles" . | fromYaml).files }}
+- name: {{ .name }}
+  mountPath: /manifests/{{ .name }}
+  readOnly: true
+{{- end }}
+{{- end -}}
+
+{{/*
+  Syncer flags of the syncer config files
+*/}}
+{{- define "vcluster.syncer.configFiles.args" -}}
+{{- range (include "vcluster.syncer.configFiles" . | fromYaml).files }}
+- --{{ .flag }}=/manifests/{{ .name }}/{{ .file }}
+{{- end }}
 {{- end -}}
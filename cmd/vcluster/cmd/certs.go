@@ -2,8 +2,8 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -15,7 +15,9 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/klog"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -30,6 +32,17 @@ type CertsCmd struct {
 
 	CertificateDir string
 	EtcdReplicas   int
+
+	CACert      string
+	CAKey       string
+	CARootCert  string
+	CAIssuer    string
+	CASignerURL string
+
+	FrontProxyCACert string
+	FrontProxyCAKey  string
+	EtcdCACert       string
+	EtcdCAKey        string
 }
 
 func NewCertsCommand() *cobra.Command {
@@ -50,49 +63,20 @@ func NewCertsCommand() *cobra.Command {
 	cmd.Flags().StringVar(&options.Namespace, "namespace", "", "Namespace where to deploy the cert secret to")
 	cmd.Flags().StringVar(&options.CertificateDir, "certificate-dir", "certs", "The temporary directory where the certificates will be stored")
 	cmd.Flags().IntVar(&options.EtcdReplicas, "etcd-replicas", 1, "The etcd cluster size")
+	cmd.Flags().StringVar(&options.CACert, "ca-cert", "", "Path to an existing certificate authority (followed by its chain) that should sign the control plane certificates instead of a self-signed one")
+	cmd.Flags().StringVar(&options.CAKey, "ca-key", "", "Path to the private key of the certificate authority specified in --ca-cert")
+	cmd.Flags().StringVar(&options.CARootCert, "ca-root-cert", "", "Path to the root certificate authority the certificate chain is validated against, if the chain does not contain it already. Ignored if the file does not exist")
+	cmd.Flags().StringVar(&options.CAIssuer, "ca-issuer", "", "A cert-manager issuer in the form Issuer/name or ClusterIssuer/name that should issue the certificate authority")
+	cmd.Flags().StringVar(&options.CASignerURL, "ca-signer-url", "", "An external signing endpoint the certificate signing request for the certificate authority is posted to")
+	cmd.Flags().StringVar(&options.FrontProxyCACert, "front-proxy-ca-cert", "", "Path to an existing certificate authority (followed by its chain) for the front proxy. If empty, a separate self-signed one is created")
+	cmd.Flags().StringVar(&options.FrontProxyCAKey, "front-proxy-ca-key", "", "Path to the private key of the certificate authority specified in --front-proxy-ca-cert")
+	cmd.Flags().StringVar(&options.EtcdCACert, "etcd-ca-cert", "", "Path to an existing certificate authority (followed by its chain) for etcd. If empty, a separate self-signed one is created")
+	cmd.Flags().StringVar(&options.EtcdCAKey, "etcd-ca-key", "", "Path to the private key of the certificate authority specified in --etcd-ca-cert")
+
+	cmd.AddCommand(NewCertsRotateCommand())
 	return cmd
 }
 
-// write needed files to secret
-var certMap = map[string]string{
-	certs.AdminKubeConfigFileName:             certs.AdminKubeConfigFileName,
-	certs.ControllerManagerKubeConfigFileName: certs.ControllerManagerKubeConfigFileName,
-	certs.SchedulerKubeConfigFileName:         certs.SchedulerKubeConfigFileName,
-
-	certs.APIServerCertName: certs.APIServerCertName,
-	certs.APIServerKeyName:  certs.APIServerKeyName,
-
-	certs.APIServerEtcdClientCertName: certs.APIServerEtcdClientCertName,
-	certs.APIServerEtcdClientKeyName:  certs.APIServerEtcdClientKeyName,
-
-	certs.APIServerKubeletClientCertName: certs.APIServerKubeletClientCertName,
-	certs.APIServerKubeletClientKeyName:  certs.APIServerKubeletClientKeyName,
-
-	certs.CACertName: certs.CACertName,
-	certs.CAKeyName:  certs.CAKeyName,
-
-	certs.FrontProxyCACertName: certs.FrontProxyCACertName,
-	certs.FrontProxyCAKeyName:  certs.FrontProxyCAKeyName,
-
-	certs.FrontProxyClientCertName: certs.FrontProxyClientCertName,
-	certs.FrontProxyClientKeyName:  certs.FrontProxyClientKeyName,
-
-	certs.ServiceAccountPrivateKeyName: certs.ServiceAccountPrivateKeyName,
-	certs.ServiceAccountPublicKeyName:  certs.ServiceAccountPublicKeyName,
-
-	certs.EtcdCACertName: strings.ReplaceAll(certs.EtcdCACertName, "/", "-"),
-	certs.EtcdCAKeyName:  strings.ReplaceAll(certs.EtcdCAKeyName, "/", "-"),
-
-	certs.EtcdHealthcheckClientCertName: strings.ReplaceAll(certs.EtcdHealthcheckClientCertName, "/", "-"),
-	certs.EtcdHealthcheckClientKeyName:  strings.ReplaceAll(certs.EtcdHealthcheckClientKeyName, "/", "-"),
-
-	certs.EtcdPeerCertName: strings.ReplaceAll(certs.EtcdPeerCertName, "/", "-"),
-	certs.EtcdPeerKeyName:  strings.ReplaceAll(certs.EtcdPeerKeyName, "/", "-"),
-
-	certs.EtcdServerCertName: strings.ReplaceAll(certs.EtcdServerCertName, "/", "-"),
-	certs.EtcdServerKeyName:  strings.ReplaceAll(certs.EtcdServerKeyName, "/", "-"),
-}
-
 func ExecuteCerts(options *CertsCmd) error {
 	inClusterConfig := ctrl.GetConfigOrDie()
 	kubeClient, err := kubernetes.NewForConfig(inClusterConfig)
@@ -117,7 +101,7 @@ func ExecuteCerts(options *CertsCmd) error {
 		}
 	}
 
-	secretName := options.Prefix + "-certs"
+	secretName := certs.SecretName(options.Prefix)
 	_, err = kubeClient.CoreV1().Secrets(options.Namespace).Get(context.Background(), secretName, metav1.GetOptions{})
 	if err == nil {
 		klog.Infof("Certs secret already exists, skip generation")
@@ -149,6 +133,39 @@ func ExecuteCerts(options *CertsCmd) error {
 	cfg.CertificatesDir = options.CertificateDir
 	cfg.LocalAPIEndpoint.AdvertiseAddress = "0.0.0.0"
 	cfg.LocalAPIEndpoint.BindPort = 443
+
+	// use an existing certificate authority if configured
+	externalCA, err := getExternalCA(context.Background(), kubeClient, inClusterConfig, options)
+	if err != nil {
+		return errors.Wrap(err, "get certificate authority")
+	} else if externalCA != nil {
+		err = externalCA.WriteToDir(options.CertificateDir, certs.CACertAndKeyBaseName)
+		if err != nil {
+			return errors.Wrap(err, "write certificate authority")
+		}
+	}
+
+	// the front proxy and etcd are separate trust domains and get their own self-signed
+	// certificate authorities, unless they are configured explicitly
+	for baseName, files := range map[string][2]string{
+		certs.FrontProxyCACertAndKeyBaseName: {options.FrontProxyCACert, options.FrontProxyCAKey},
+		certs.EtcdCACertAndKeyBaseName:       {options.EtcdCACert, options.EtcdCAKey},
+	} {
+		if files[0] == "" {
+			continue
+		}
+
+		ca, err := loadExternalCA(files[0], files[1], nil)
+		if err != nil {
+			return errors.Wrapf(err, "load %s certificate authority", baseName)
+		}
+
+		err = ca.WriteToDir(options.CertificateDir, baseName)
+		if err != nil {
+			return errors.Wrapf(err, "write %s certificate authority", baseName)
+		}
+	}
+
 	err = certs.CreatePKIAssets(cfg)
 	if err != nil {
 		return errors.Wrap(err, "create pki assets")
@@ -166,14 +183,9 @@ func ExecuteCerts(options *CertsCmd) error {
 		},
 		Data: map[string][]byte{},
 	}
-
-	for fromName, toName := range certMap {
-		data, err := os.ReadFile(filepath.Join(options.CertificateDir, fromName))
-		if err != nil {
-			return errors.Wrap(err, "read "+fromName)
-		}
-
-		secret.Data[toName] = data
+	err = certs.ReadDirToSecret(options.CertificateDir, secret)
+	if err != nil {
+		return err
 	}
 
 	// finally create the secret
@@ -185,3 +197,55 @@ func ExecuteCerts(options *CertsCmd) error {
 	klog.Infof("Successfully created certs secret %s/%s", options.Namespace, secretName)
 	return nil
 }
+
+func getExternalCA(ctx context.Context, kubeClient kubernetes.Interface, restConfig *rest.Config, options *CertsCmd) (*certs.ExternalCA, error) {
+	var err error
+	rootCert := []byte{}
+	if options.CARootCert != "" {
+		rootCert, err = os.ReadFile(options.CARootCert)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	switch {
+	case options.CACert != "":
+		klog.Infof("Using certificate authority %s", options.CACert)
+		return loadExternalCA(options.CACert, options.CAKey, rootCert)
+	case options.CAIssuer != "":
+		splitted := strings.Split(options.CAIssuer, "/")
+		if len(splitted) != 2 || (splitted[0] != "Issuer" && splitted[0] != "ClusterIssuer") {
+			return nil, fmt.Errorf("invalid --ca-issuer %s, please use Issuer/name or ClusterIssuer/name", options.CAIssuer)
+		}
+
+		dynamicClient, err := dynamic.NewForConfig(restConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		klog.Infof("Using certificate authority issued by %s", options.CAIssuer)
+		return certs.RequestCAFromCertManager(ctx, kubeClient, dynamicClient, options.Namespace, options.Prefix+"-ca", splitted[0], splitted[1])
+	case options.CASignerURL != "":
+		klog.Infof("Using certificate authority signed by %s", options.CASignerURL)
+		return certs.RequestCAFromSigner(ctx, options.CASignerURL, options.Prefix+"-ca", rootCert)
+	}
+
+	return nil, nil
+}
+
+func loadExternalCA(certPath, keyPath string, rootCert []byte) (*certs.ExternalCA, error) {
+	if keyPath == "" {
+		return nil, fmt.Errorf("a key is required for certificate authority %s", certPath)
+	}
+
+	cert, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return certs.ParseExternalCA(cert, key, rootCert)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/loft-sh/vcluster/pkg/certs"
+	"github.com/loft-sh/vcluster/pkg/util/clienthelper"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// CertsRotateCmd holds the certs rotate flags
+type CertsRotateCmd struct {
+	Prefix    string
+	Namespace string
+
+	CertificateDir string
+	RenewBefore    time.Duration
+	RotateCA       bool
+	Restart        bool
+}
+
+func NewCertsRotateCommand() *cobra.Command {
+	options := &CertsRotateCmd{}
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Renews control plane certificates that are about to expire",
+		Args:  cobra.NoArgs,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return ExecuteCertsRotate(options)
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Prefix, "prefix", "vcluster", "Release name and prefix of the certs secret")
+	cmd.Flags().StringVar(&options.Namespace, "namespace", "", "Namespace of the cert secret")
+	cmd.Flags().StringVar(&options.CertificateDir, "certificate-dir", "", "The directory in which a temporary directory for the certificates is created. Defaults to the system temporary directory")
+	cmd.Flags().DurationVar(&options.RenewBefore, "renew-before", certs.DefaultRenewBefore, "Renew all certificates and kube configs that expire within this duration")
+	cmd.Flags().BoolVar(&options.RotateCA, "rotate-ca", false, "If enabled, will create new certificate authorities and keep the old ones trusted until they expire")
+	cmd.Flags().BoolVar(&options.Restart, "restart", true, "If enabled, will restart the control plane components after the certificates were renewed")
+	return cmd
+}
+
+func ExecuteCertsRotate(options *CertsRotateCmd) error {
+	inClusterConfig := ctrl.GetConfigOrDie()
+	kubeClient, err := kubernetes.NewForConfig(inClusterConfig)
+	if err != nil {
+		return err
+	}
+
+	// get current namespace
+	if options.Namespace == "" {
+		options.Namespace, err = clienthelper.CurrentNamespace()
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := certs.RotateSecret(context.Background(), kubeClient, options.Namespace, options.Prefix, options.CertificateDir, certs.RotateOptions{
+		RenewBefore: options.RenewBefore,
+		RotateCA:    options.RotateCA,
+	})
+	if err != nil {
+		return errors.Wrap(err, "rotate certificates")
+	} else if !result.Changed() {
+		klog.Infof("No certificates expire within %s, skip rotation", options.RenewBefore.String())
+		return nil
+	}
+
+	klog.Infof("Successfully renewed certs secret %s/%s", options.Namespace, certs.SecretName(options.Prefix))
+	if !options.Restart {
+		klog.Infof("Please restart the control plane components to use the renewed certificates")
+		return nil
+	}
+
+	return certs.RestartControlPlane(context.Background(), kubeClient, options.Namespace, options.Prefix, result.EtcdChanged())
+}
@@ -19,12 +19,14 @@ import (
 	volumesnapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
 	context2 "github.com/loft-sh/vcluster/cmd/vcluster/context"
 	"github.com/loft-sh/vcluster/pkg/apis"
+	"github.com/loft-sh/vcluster/pkg/certs"
 	"github.com/loft-sh/vcluster/pkg/controllers"
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes"
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/nodeservice"
 	translatepods "github.com/loft-sh/vcluster/pkg/controllers/resources/pods/translate"
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/services"
 	"github.com/loft-sh/vcluster/pkg/coredns"
+	"github.com/loft-sh/vcluster/pkg/dns"
 	"github.com/loft-sh/vcluster/pkg/leaderelection"
 	"github.com/loft-sh/vcluster/pkg/server"
 	"github.com/loft-sh/vcluster/pkg/util/clienthelper"
@@ -50,6 +52,7 @@ import (
 	"k8s.io/klog"
 	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 )
 
 var (
@@ -91,14 +94,19 @@ func NewStartCommand() *cobra.Command {
 	cmd.Flags().StringSliceVar(&options.Controllers, "sync", []string{}, "A list of sync controllers to enable. 'foo' enables the sync controller named 'foo', '-foo' disables the sync controller named 'foo'")
 	cmd.Flags().StringVar(&options.RequestHeaderCaCert, "request-header-ca-cert", "/data/server/tls/request-header-ca.crt", "The path to the request header ca certificate")
 	cmd.Flags().StringVar(&options.ClientCaCert, "client-ca-cert", "/data/server/tls/client-ca.crt", "The path to the client ca certificate")
+	cmd.Flags().StringVar(&options.ClientCaKey, "client-ca-key", "/data/server/tls/client-ca.key", "The path to the client ca key, used to sign the client certificates of --out-kube-configs")
 	cmd.Flags().StringVar(&options.ServerCaCert, "server-ca-cert", "/data/server/tls/server-ca.crt", "The path to the server ca certificate")
 	cmd.Flags().StringVar(&options.ServerCaKey, "server-ca-key", "/data/server/tls/server-ca.key", "The path to the server ca key")
 	cmd.Flags().StringVar(&options.KubeConfigPath, "kube-config", "/data/server/cred/admin.kubeconfig", "The path to the virtual cluster admin kube config")
 	cmd.Flags().StringSliceVar(&options.TLSSANs, "tls-san", []string{}, "Add additional hostname or IP as a Subject Alternative Name in the TLS cert")
+	cmd.Flags().StringVar(&options.GatewayBaseDomain, "gateway-base-domain", "", "If set, adds the hostname NAME.NAMESPACE.BASE_DOMAIN as a Subject Alternative Name in the TLS cert, so that the vcluster can be reached through a shared gateway that routes by SNI")
+	cmd.Flags().BoolVar(&options.RotateCertificates, "rotate-certificates", false, "If enabled, the syncer will renew the control plane certificates in the certs secret before they expire and restart the control plane")
+	cmd.Flags().DurationVar(&options.CertificateRenewBefore, "certificate-renew-before", certs.DefaultRenewBefore, "If --rotate-certificates is enabled, certificates that expire within this duration will be renewed")
 
 	cmd.Flags().StringVar(&options.KubeConfigSecret, "out-kube-config-secret", "", "If specified, the virtual cluster will write the generated kube config to the given secret")
 	cmd.Flags().StringVar(&options.KubeConfigSecretNamespace, "out-kube-config-secret-namespace", "", "If specified, the virtual cluster will write the generated kube config in the given namespace")
 	cmd.Flags().StringVar(&options.KubeConfigServer, "out-kube-config-server", "", "If specified, the virtual cluster will use this server for the generated kube config (e.g. https://my-vcluster.domain.com)")
+	cmd.Flags().StringVar(&options.KubeConfigOutputs, "out-kube-configs", "", "If specified, the virtual cluster will generate a kube config for each user or service account listed in this yaml file, write it to the configured secret and renew it before it expires")
 
 	cmd.Flags().StringVar(&options.TargetNamespace, "target-namespace", "", "The namespace to run the virtual cluster in (defaults to current namespace)")
 	cmd.Flags().StringVar(&options.ServiceName, "service-name", "", "The service name where the vcluster proxy will be available")
@@ -110,18 +118,32 @@ func NewStartCommand() *cobra.Command {
 
 	cmd.Flags().BoolVar(&options.SyncAllNodes, "sync-all-nodes", false, "If enabled and --fake-nodes is false, the virtual cluster will sync all nodes instead of only the needed ones")
 	cmd.Flags().BoolVar(&options.EnableScheduler, "enable-scheduler", false, "If enabled, will expect a scheduler running in the virtual cluster")
+	cmd.Flags().BoolVar(&options.CapNodeAllocatableByQuota, "cap-node-allocatable-by-quota", false, "If enabled and --enable-scheduler is set, the allocatable resources of the virtual nodes are capped by the remaining resource quota of the vcluster namespace")
+	cmd.Flags().StringVar(&options.NodePools, "node-pools", "", "Path to a yaml file that defines virtual node pools. If set, the virtual cluster shows the nodes of the pools instead of the host nodes and pods scheduled to a pool node are placed on any host node that matches the pool. Requires --enable-scheduler")
+	cmd.Flags().StringVar(&options.NodeSyncRules, "node-sync-rules", "", "Path to a yaml file with allow, deny and rewrite rules for the labels, annotations, taints and addresses of synced nodes. The rules are also applied in reverse to the node selectors, affinities and tolerations of pods")
 	cmd.Flags().BoolVar(&options.DisableFakeKubelets, "disable-fake-kubelets", false, "If disabled, the virtual cluster will not create fake kubelet endpoints to support metrics-servers")
 
-	cmd.Flags().StringSliceVar(&options.TranslateImages, "translate-image", []string{}, "Translates image names from the virtual pod to the physical pod (e.g. coredns/coredns=mirror.io/coredns/coredns)")
+	cmd.Flags().StringSliceVar(&options.TranslateImages, "translate-image", []string{}, "Translates image names from the virtual pod to the physical pod (e.g. coredns/coredns=mirror.io/coredns/coredns). Supports * wildcards, e.g. docker.io/*=mirror.io/dockerhub/*")
+	cmd.Flags().StringSliceVar(&options.RejectImages, "reject-image", []string{}, "Pods with images matching this pattern are not synced to the host cluster (e.g. *:latest)")
+	cmd.Flags().BoolVar(&options.PinImageDigests, "pin-image-digests", false, "If enabled, vcluster will pin the images of synced pods to their current digest")
+	cmd.Flags().DurationVar(&options.ImageDigestCacheTTL, "image-digest-cache-ttl", time.Hour, "How long resolved image digests are cached if --pin-image-digests is enabled")
 	cmd.Flags().BoolVar(&options.EnforceNodeSelector, "enforce-node-selector", true, "If enabled and --node-selector is set then the virtual cluster will ensure that no pods are scheduled outside of the node selector")
 	cmd.Flags().StringSliceVar(&options.Tolerations, "enforce-toleration", []string{}, "If set will apply the provided tolerations to all pods in the vcluster")
+	cmd.Flags().StringVar(&options.PodPlacementRules, "pod-placement-rules", "", "If set, the virtual cluster will read host placement rules (service account, node selector, tolerations and runtime class) for pods from this yaml file")
+	cmd.Flags().StringVar(&options.PodResourcePolicy, "pod-resource-policy", "", "If set, the virtual cluster will read a policy with default, maximum and maximum limit to request ratio of container resources from this yaml file and apply it to synced pods")
 	cmd.Flags().StringVar(&options.NodeSelector, "node-selector", "", "If nodes sync is enabled, nodes with the given node selector will be synced to the virtual cluster. If fake nodes are used, and --enforce-node-selector flag is set, then vcluster will ensure that no pods are scheduled outside of the node selector.")
 	cmd.Flags().StringVar(&options.ServiceAccount, "service-account", "", "If set, will set this host service account on the synced pods")
+	cmd.Flags().StringVar(&options.ServiceAccountIdentityPolicy, "service-account-identity-policy", "", "Path to a yaml file that maps virtual service accounts to host service accounts and defines which cloud identity annotations (IRSA, GKE and Azure workload identity) virtual service accounts may sync to the host")
+	cmd.Flags().StringVar(&options.IngressPolicy, "ingress-policy", "", "Path to a yaml file that restricts the hosts, ingress classes and annotations of virtual ingresses. Ingresses that violate the policy are not synced to the host")
+	cmd.Flags().StringVar(&options.StoragePolicy, "storage-policy", "", "Path to a yaml file that maps virtual storage classes to host storage classes and limits the number and storage of persistent volume claims of the vcluster")
 
 	cmd.Flags().BoolVar(&options.OverrideHosts, "override-hosts", true, "If enabled, vcluster will override a containers /etc/hosts file if there is a subdomain specified for the pod (spec.subdomain).")
 	cmd.Flags().StringVar(&options.OverrideHostsContainerImage, "override-hosts-container-image", translatepods.HostsRewriteImage, "The image for the init container that is used for creating the override hosts file.")
 
 	cmd.Flags().StringVar(&options.ClusterDomain, "cluster-domain", "cluster.local", "The cluster domain ending that should be used for the virtual cluster")
+	cmd.Flags().StringVar(&options.CoreDNSConfig, "coredns-config", "", "Path to a yaml file with a declarative CoreDNS config (upstreams, stub domains, host forwarding, rewrites and hosts). If set, the syncer manages the CoreDNS Corefile and reverts changes made within the virtual cluster")
+	cmd.Flags().BoolVar(&options.ServeDNS, "serve-dns", false, "If enabled, the syncer serves DNS for the virtual cluster domain and pods use the vcluster service as nameserver, which makes CoreDNS within the virtual cluster optional. The vcluster service needs to expose port 53 to the dns bind address")
+	cmd.Flags().StringVar(&options.DNSBindAddress, "dns-bind-address", ":1053", "The address the syncer serves DNS on if --serve-dns is enabled")
 
 	cmd.Flags().BoolVar(&options.LeaderElect, "leader-elect", false, "If enabled, syncer will use leader election")
 	cmd.Flags().Int64Var(&options.LeaseDuration, "lease-duration", 60, "Lease duration of the leader election in seconds")
@@ -139,6 +161,9 @@ func NewStartCommand() *cobra.Command {
 
 	cmd.Flags().StringSliceVar(&options.MapVirtualServices, "map-virtual-service", []string{}, "Maps a given service inside the virtual cluster to a service inside the host cluster. E.g. default/test=physical-service")
 	cmd.Flags().StringSliceVar(&options.MapHostServices, "map-host-service", []string{}, "Maps a given service inside the host cluster to a service inside the virtual cluster. E.g. other-namespace/my-service=my-vcluster-namespace/my-service")
+	cmd.Flags().StringSliceVar(&options.SharedGateways, "shared-gateway", []string{}, "Host gateways (namespace/name) that are synced into the virtual cluster and that gateway api routes of the virtual cluster may attach to")
+	cmd.Flags().StringSliceVar(&options.ImportServices, "import-service", []string{}, "Imports a service of another vcluster on the same host cluster that allows it with the vcluster.loft.sh/export-to-vclusters annotation. E.g. host-namespace/vcluster-name/namespace/service=namespace/service")
+	cmd.Flags().StringVar(&options.ServiceMappingsConfigMap, "service-mappings-configmap", "", "Name of a ConfigMap in the vcluster namespace that declares service mappings and allowed exports at runtime. Virtual services can request a host mapping with the vcluster.loft.sh/export-to-host annotation")
 
 	// Deprecated Flags
 	cmd.Flags().BoolVar(&options.DeprecatedSyncNodeChanges, "sync-node-changes", false, "If enabled and --fake-nodes is false, the virtual cluster will proxy node updates from the virtual cluster to the host cluster. This is not recommended and should only be used if you know what you are doing.")
@@ -359,6 +384,14 @@ func ExecuteStart(options *context2.VirtualClusterOptions) error {
 		}
 	}()
 
+	// serve DNS for the virtual cluster domain on every replica
+	if ctx.Options.ServeDNS {
+		err = serveDNS(ctx)
+		if err != nil {
+			return errors.Wrap(err, "serve dns")
+		}
+	}
+
 	if ctx.Options.LeaderElect {
 		err = leaderelection.StartLeaderElection(ctx, scheme, func() error {
 			return startControllers(ctx, &rawConfig, serverVersion)
@@ -393,18 +426,20 @@ func ensureServiceCIDR(c kubernetes.Interface, currentNamespace, vclusterName st
 }
 
 func startControllers(ctx *context2.ControllerContext, rawConfig *api.Config, serverVersion *version.Info) error {
-	// setup CoreDNS according to the manifest file
-	go func() {
-		_ = wait.ExponentialBackoff(wait.Backoff{Duration: time.Second, Factor: 1.5, Cap: time.Minute, Steps: math.MaxInt32}, func() (bool, error) {
-			err := coredns.ApplyManifest(ctx.Options.DefaultImageRegistry, ctx.VirtualManager.GetConfig(), serverVersion)
-			if err != nil {
-				klog.Infof("Failed to apply CoreDNS configuration from the manifest file: %v", err)
-				return false, nil
-			}
-			klog.Infof("CoreDNS configuration from the manifest file applied successfully")
-			return true, nil
-		})
-	}()
+	// setup CoreDNS according to the manifest file, which is not needed if the syncer serves DNS itself
+	if !ctx.Options.ServeDNS {
+		go func() {
+			_ = wait.ExponentialBackoff(wait.Backoff{Duration: time.Second, Factor: 1.5, Cap: time.Minute, Steps: math.MaxInt32}, func() (bool, error) {
+				err := coredns.ApplyManifest(ctx.Options.DefaultImageRegistry, ctx.VirtualManager.GetConfig(), serverVersion)
+				if err != nil {
+					klog.Infof("Failed to apply CoreDNS configuration from the manifest file: %v", err)
+					return false, nil
+				}
+				klog.Infof("CoreDNS configuration from the manifest file applied successfully")
+				return true, nil
+			})
+		}()
+	}
 
 	// instantiate controllers
 	syncers, err := controllers.Create(ctx)
@@ -466,6 +501,30 @@ func startControllers(ctx *context2.ControllerContext, rawConfig *api.Config, se
 		}, time.Minute, ctx.StopChan)
 	}()
 
+	// write the additional scoped kube configs to their secrets
+	if ctx.Options.KubeConfigOutputs != "" {
+		go func() {
+			wait.Until(func() {
+				err := writeKubeConfigOutputs(ctx, rawConfig)
+				if err != nil {
+					klog.Errorf("Error writing kube config outputs: %v", err)
+				}
+			}, time.Minute, ctx.StopChan)
+		}()
+	}
+
+	// renew the control plane certificates before they expire
+	if ctx.Options.RotateCertificates {
+		go func() {
+			wait.Until(func() {
+				err := rotateCertificates(ctx)
+				if err != nil {
+					klog.Errorf("Error rotating certificates: %v", err)
+				}
+			}, time.Hour, ctx.StopChan)
+		}()
+	}
+
 	// register controllers
 	err = controllers.RegisterControllers(ctx, syncers)
 	if err != nil {
@@ -565,8 +624,86 @@ func createVClusterKubeConfig(config *api.Config, options *context2.VirtualClust
 	return config, nil
 }
 
+func rotateCertificates(ctx *context2.ControllerContext) error {
+	kubeClient, err := kubernetes.NewForConfig(ctx.LocalManager.GetConfig())
+	if err != nil {
+		return err
+	}
+
+	// the certs secret only exists for distros that use the certs command
+	_, err = kubeClient.CoreV1().Secrets(ctx.CurrentNamespace).Get(ctx.Context, certs.SecretName(translate.Suffix), metav1.GetOptions{})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			klog.V(1).Infof("Certs secret %s/%s not found, skip certificate rotation", ctx.CurrentNamespace, certs.SecretName(translate.Suffix))
+			return nil
+		}
+
+		return err
+	}
+
+	result, err := certs.RotateSecret(ctx.Context, kubeClient, ctx.CurrentNamespace, translate.Suffix, "", certs.RotateOptions{
+		RenewBefore: ctx.Options.CertificateRenewBefore,
+	})
+	if err != nil {
+		return err
+	} else if !result.Changed() {
+		return nil
+	}
+
+	klog.Infof("Renewed certificates %v and kube configs %v, restarting control plane", result.Certs, result.KubeConfigs)
+	return certs.RestartControlPlane(ctx.Context, kubeClient, ctx.CurrentNamespace, translate.Suffix, result.EtcdChanged())
+}
+
+// currentKubeConfig prefers the kube config on disk, as it might have been renewed in the meantime
+func currentKubeConfig(ctx *context2.ControllerContext, config *api.Config) *api.Config {
+	if ctx.Options.KubeConfigPath != "" {
+		currentConfig, err := clientcmd.LoadFromFile(ctx.Options.KubeConfigPath)
+		if err == nil {
+			return currentConfig
+		}
+	}
+
+	return config
+}
+
+func writeKubeConfigOutputs(ctx *context2.ControllerContext, config *api.Config) error {
+	// the outputs are read on every run, so that changes to the mounted file are picked up
+	outputs, err := kubeconfig.LoadOutputs(ctx.Options.KubeConfigOutputs, ctx.CurrentNamespace)
+	if err != nil {
+		return err
+	}
+
+	config, err = createVClusterKubeConfig(currentKubeConfig(ctx, config), ctx.Options)
+	if err != nil {
+		return err
+	}
+
+	kubeContext, ok := config.Contexts[config.CurrentContext]
+	if !ok || config.Clusters[kubeContext.Cluster] == nil {
+		return fmt.Errorf("couldn't find cluster of current context %s in kube config", config.CurrentContext)
+	}
+
+	hostClient, err := kubernetes.NewForConfig(ctx.LocalManager.GetConfig())
+	if err != nil {
+		return err
+	}
+	virtualClient, err := kubernetes.NewForConfig(ctx.VirtualManager.GetConfig())
+	if err != nil {
+		return err
+	}
+
+	writer := &kubeconfig.OutputWriter{
+		HostClient:    hostClient,
+		VirtualClient: virtualClient,
+		ClientCACert:  ctx.Options.ClientCaCert,
+		ClientCAKey:   ctx.Options.ClientCaKey,
+		Namespace:     ctx.CurrentNamespace,
+	}
+	return writer.Write(ctx.Context, config.Clusters[kubeContext.Cluster], outputs)
+}
+
 func writeKubeConfigToSecret(ctx *context2.ControllerContext, config *api.Config) error {
-	config, err := createVClusterKubeConfig(config, ctx.Options)
+	config, err := createVClusterKubeConfig(currentKubeConfig(ctx, config), ctx.Options)
 	if err != nil {
 		return err
 	}
@@ -616,3 +753,46 @@ func writeKubeConfigToSecret(ctx *context2.ControllerContext, config *api.Config
 	// write the default Secret
 	return kubeconfig.WriteKubeConfig(ctx.Context, ctx.CurrentNamespaceClient, kubeconfig.GetDefaultSecretName(translate.Suffix), ctx.CurrentNamespace, config)
 }
+
+func serveDNS(ctx *context2.ControllerContext) error {
+	upstream, err := dns.Upstream("")
+	if err != nil {
+		return errors.Wrap(err, "find upstream dns")
+	}
+
+	// the caches of the managers are only started on the leader, so the dns server
+	// needs its own caches to be able to serve on every replica
+	virtualCache, err := cache.New(ctx.VirtualManager.GetConfig(), cache.Options{Scheme: ctx.VirtualManager.GetScheme()})
+	if err != nil {
+		return errors.Wrap(err, "create virtual cache")
+	}
+	physicalCache, err := cache.New(ctx.LocalManager.GetConfig(), cache.Options{Scheme: ctx.LocalManager.GetScheme(), Namespace: ctx.Options.TargetNamespace})
+	if err != nil {
+		return errors.Wrap(err, "create physical cache")
+	}
+	for _, c := range []cache.Cache{virtualCache, physicalCache} {
+		go func(c cache.Cache) {
+			err := c.Start(ctx.Context)
+			if err != nil {
+				klog.Fatalf("Error starting dns cache: %v", err)
+			}
+		}(c)
+	}
+
+	server := &dns.Server{
+		VirtualClient:   virtualCache,
+		PhysicalClient:  physicalCache,
+		TargetNamespace: ctx.Options.TargetNamespace,
+		ClusterDomain:   ctx.Options.ClusterDomain,
+		Upstream:        upstream,
+	}
+	go func() {
+		klog.Infof("Serving DNS for %s on %s", ctx.Options.ClusterDomain, ctx.Options.DNSBindAddress)
+		err := server.ListenAndServe(ctx.Context, ctx.Options.DNSBindAddress)
+		if err != nil {
+			klog.Fatalf("Error serving dns: %v", err)
+		}
+	}()
+
+	return nil
+}
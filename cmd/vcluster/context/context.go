@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/nodepools"
 	"github.com/loft-sh/vcluster/pkg/util/blockingcacheclient"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
@@ -18,14 +20,20 @@ type VirtualClusterOptions struct {
 	ServerCaCert        string   `json:"serverCaCert,omitempty"`
 	ServerCaKey         string   `json:"serverCaKey,omitempty"`
 	TLSSANs             []string `json:"tlsSans,omitempty"`
+	GatewayBaseDomain   string   `json:"gatewayBaseDomain,omitempty"`
 	RequestHeaderCaCert string   `json:"requestHeaderCaCert,omitempty"`
 	ClientCaCert        string   `json:"clientCaCert,omitempty"`
+	ClientCaKey         string   `json:"clientCaKey,omitempty"`
 	KubeConfigPath      string   `json:"kubeConfig,omitempty"`
 
+	RotateCertificates     bool          `json:"rotateCertificates,omitempty"`
+	CertificateRenewBefore time.Duration `json:"certificateRenewBefore,omitempty"`
+
 	KubeConfigContextName     string   `json:"kubeConfigContextName,omitempty"`
 	KubeConfigSecret          string   `json:"kubeConfigSecret,omitempty"`
 	KubeConfigSecretNamespace string   `json:"kubeConfigSecretNamespace,omitempty"`
 	KubeConfigServer          string   `json:"kubeConfigServer,omitempty"`
+	KubeConfigOutputs         string   `json:"kubeConfigOutputs,omitempty"`
 	Tolerations               []string `json:"tolerations,omitempty"`
 
 	BindAddress string `json:"bindAddress,omitempty"`
@@ -38,20 +46,35 @@ type VirtualClusterOptions struct {
 
 	SetOwner bool `json:"setOwner,omitempty"`
 
-	SyncAllNodes        bool `json:"syncAllNodes,omitempty"`
-	EnableScheduler     bool `json:"enableScheduler,omitempty"`
-	DisableFakeKubelets bool `json:"disableFakeKubelets,omitempty"`
-
-	TranslateImages []string `json:"translateImages,omitempty"`
-
-	NodeSelector        string `json:"nodeSelector,omitempty"`
-	EnforceNodeSelector bool   `json:"enforceNodeSelector,omitempty"`
-	ServiceAccount      string `json:"serviceAccount,omitempty"`
+	SyncAllNodes              bool   `json:"syncAllNodes,omitempty"`
+	EnableScheduler           bool   `json:"enableScheduler,omitempty"`
+	CapNodeAllocatableByQuota bool   `json:"capNodeAllocatableByQuota,omitempty"`
+	DisableFakeKubelets       bool   `json:"disableFakeKubelets,omitempty"`
+	NodePools                 string `json:"nodePools,omitempty"`
+	NodeSyncRules             string `json:"nodeSyncRules,omitempty"`
+
+	TranslateImages     []string      `json:"translateImages,omitempty"`
+	RejectImages        []string      `json:"rejectImages,omitempty"`
+	PinImageDigests     bool          `json:"pinImageDigests,omitempty"`
+	ImageDigestCacheTTL time.Duration `json:"imageDigestCacheTTL,omitempty"`
+
+	NodeSelector                 string `json:"nodeSelector,omitempty"`
+	EnforceNodeSelector          bool   `json:"enforceNodeSelector,omitempty"`
+	ServiceAccount               string `json:"serviceAccount,omitempty"`
+	ServiceAccountIdentityPolicy string `json:"serviceAccountIdentityPolicy,omitempty"`
+	IngressPolicy                string `json:"ingressPolicy,omitempty"`
+	StoragePolicy                string `json:"storagePolicy,omitempty"`
+	PodPlacementRules            string `json:"podPlacementRules,omitempty"`
+	PodResourcePolicy            string `json:"podResourcePolicy,omitempty"`
 
 	OverrideHosts               bool   `json:"overrideHosts,omitempty"`
 	OverrideHostsContainerImage string `json:"overrideHostsContainerImage,omitempty"`
 
 	ClusterDomain string `json:"clusterDomain,omitempty"`
+	CoreDNSConfig string `json:"coreDNSConfig,omitempty"`
+
+	ServeDNS       bool   `json:"serveDNS,omitempty"`
+	DNSBindAddress string `json:"dnsBindAddress,omitempty"`
 
 	LeaderElect   bool  `json:"leaderElect,omitempty"`
 	LeaseDuration int64 `json:"leaseDuration,omitempty"`
@@ -69,6 +92,11 @@ type VirtualClusterOptions struct {
 	MapHostServices    []string `json:"mapHostServices,omitempty"`
 	MapVirtualServices []string `json:"mapVirtualServices,omitempty"`
 
+	ServiceMappingsConfigMap string   `json:"serviceMappingsConfigMap,omitempty"`
+	ImportServices           []string `json:"importServices,omitempty"`
+
+	SharedGateways []string `json:"sharedGateways,omitempty"`
+
 	SyncLabels []string `json:"syncLabels,omitempty"`
 
 	// DEPRECATED FLAGS
@@ -91,10 +119,14 @@ type ControllerContext struct {
 
 	CurrentNamespace       string
 	CurrentNamespaceClient client.Client
+	CurrentNamespaceCache  cache.Cache
 
 	Controllers map[string]bool
 	Options     *VirtualClusterOptions
 	StopChan    <-chan struct{}
+
+	// NodePools are loaded once from --node-pools and shared by the node and pod syncers
+	NodePools []nodepools.NodePool
 }
 
 var ExistingControllers = map[string]bool{
@@ -120,6 +152,9 @@ var ExistingControllers = map[string]bool{
 	"volumesnapshots":        true,
 	"poddisruptionbudgets":   true,
 	"serviceaccounts":        true,
+	"gateways":               true,
+	"grpcroutes":             true,
+	"tlsroutes":              true,
 }
 
 var DefaultEnabledControllers = []string{
@@ -141,7 +176,7 @@ func NewControllerContext(currentNamespace string, localManager ctrl.Manager, vi
 	ctx := context.Background()
 
 	// create a new current namespace client
-	currentNamespaceClient, err := newCurrentNamespaceClient(ctx, currentNamespace, localManager, options)
+	currentNamespaceCache, currentNamespaceClient, err := newCurrentNamespaceClient(ctx, currentNamespace, localManager, options)
 	if err != nil {
 		return nil, err
 	}
@@ -162,6 +197,16 @@ func NewControllerContext(currentNamespace string, localManager ctrl.Manager, vi
 		return nil, fmt.Errorf("you cannot sync storage classes and legacy storage classes at the same time. Choose only one of them")
 	}
 
+	// node pools replace the synced host nodes, so a node selector for them would be silently ignored
+	nodePools, err := nodepools.Load(options.NodePools)
+	if err != nil {
+		return nil, fmt.Errorf("load node pools: %v", err)
+	} else if len(nodePools) > 0 && !options.EnableScheduler {
+		return nil, fmt.Errorf("you cannot use --node-pools without --enable-scheduler")
+	} else if len(nodePools) > 0 && options.NodeSelector != "" {
+		return nil, fmt.Errorf("you cannot use --node-pools and --node-selector at the same time, as the nodes controller shows the pool nodes instead of the host nodes")
+	}
+
 	return &ControllerContext{
 		Context:        ctx,
 		Controllers:    controllers,
@@ -170,9 +215,12 @@ func NewControllerContext(currentNamespace string, localManager ctrl.Manager, vi
 
 		CurrentNamespace:       currentNamespace,
 		CurrentNamespaceClient: currentNamespaceClient,
+		CurrentNamespaceCache:  currentNamespaceCache,
 
 		StopChan: stopChan,
 		Options:  options,
+
+		NodePools: nodePools,
 	}, nil
 }
 
@@ -237,7 +285,7 @@ func availableControllers() string {
 	return strings.Join(controllers, ", ")
 }
 
-func newCurrentNamespaceClient(ctx context.Context, currentNamespace string, localManager ctrl.Manager, options *VirtualClusterOptions) (client.Client, error) {
+func newCurrentNamespaceClient(ctx context.Context, currentNamespace string, localManager ctrl.Manager, options *VirtualClusterOptions) (cache.Cache, client.Client, error) {
 	var err error
 
 	// currentNamespaceCache is needed for tasks such as finding out fake kubelet ips
@@ -254,7 +302,7 @@ func newCurrentNamespaceClient(ctx context.Context, currentNamespace string, loc
 			Namespace: currentNamespace,
 		})
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
@@ -275,8 +323,8 @@ func newCurrentNamespaceClient(ctx context.Context, currentNamespace string, loc
 		Mapper: localManager.GetRESTMapper(),
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return currentNamespaceClient, nil
+	return currentNamespaceCache, currentNamespaceClient, nil
 }
@@ -0,0 +1,96 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Start starts the current executable with the given arguments in the background, detached from the
+// current terminal. Output of the process is appended to logFile.
+func Start(args []string, logFile string) (*os.Process, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, errors.Wrap(err, "find executable")
+	}
+
+	err = os.MkdirAll(filepath.Dir(logFile), 0755)
+	if err != nil {
+		return nil, err
+	}
+	out, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "open log file")
+	}
+	defer out.Close()
+
+	cmd := exec.Command(executable, args...)
+	cmd.Env = os.Environ()
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.SysProcAttr = sysProcAttr()
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd.Process, nil
+}
+
+// WritePIDFile writes the pid of the current process into the given file
+func WritePIDFile(pidFile string) error {
+	err := os.MkdirAll(filepath.Dir(pidFile), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())), 0600)
+}
+
+// ReadPIDFile returns the pid stored in the given file
+func ReadPIDFile(pidFile string) (int, error) {
+	out, err := os.ReadFile(pidFile)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid pid file %s: %v", pidFile, err)
+	}
+
+	return pid, nil
+}
+
+// Stop terminates the process of the given pid file and removes the pid file. The process is only
+// terminated if its command line still references the pid file, as the pid might have been reused
+// by another process after the daemon exited.
+func Stop(pidFile string) error {
+	pid, err := ReadPIDFile(pidFile)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(pidFile)
+
+	commandLine, err := processCommandLine(pid)
+	if err != nil {
+		return errors.Wrapf(err, "get command line of process %d", pid)
+	} else if commandLine == "" {
+		// the process is gone already
+		return nil
+	} else if !strings.Contains(commandLine, pidFile) {
+		return fmt.Errorf("process %d is not the daemon of pid file %s anymore, removed the stale pid file", pid, pidFile)
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return nil
+	}
+
+	return terminate(process)
+}
@@ -0,0 +1,101 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+// TestHelperProcess is started by the tests as daemon and is no real test
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("VCLUSTER_DAEMON_HELPER") != "1" {
+		return
+	}
+
+	fmt.Println("daemon started")
+	if os.Getenv("VCLUSTER_DAEMON_HELPER_SLEEP") == "1" {
+		time.Sleep(time.Minute)
+	}
+	os.Exit(0)
+}
+
+func TestPIDFile(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "connect", "vcluster.pid")
+	assert.NilError(t, WritePIDFile(pidFile))
+
+	pid, err := ReadPIDFile(pidFile)
+	assert.NilError(t, err)
+	assert.Equal(t, pid, os.Getpid())
+
+	assert.NilError(t, os.WriteFile(pidFile, []byte("invalid"), 0600))
+	_, err = ReadPIDFile(pidFile)
+	assert.ErrorContains(t, err, "invalid pid file")
+}
+
+func TestStart(t *testing.T) {
+	t.Setenv("VCLUSTER_DAEMON_HELPER", "1")
+	logFile := filepath.Join(t.TempDir(), "logs", "daemon.log")
+	process, err := Start([]string{"-test.run=TestHelperProcess"}, logFile)
+	assert.NilError(t, err)
+	state, err := process.Wait()
+	assert.NilError(t, err)
+	assert.Assert(t, state.Success())
+
+	out, err := os.ReadFile(logFile)
+	assert.NilError(t, err)
+	assert.Assert(t, len(out) > 0)
+	assert.Equal(t, string(out[:len("daemon started")]), "daemon started")
+}
+
+func TestStop(t *testing.T) {
+	t.Setenv("VCLUSTER_DAEMON_HELPER", "1")
+	t.Setenv("VCLUSTER_DAEMON_HELPER_SLEEP", "1")
+	pidFile := filepath.Join(t.TempDir(), "vcluster.pid")
+	process, err := Start([]string{"-test.run=TestHelperProcess", "--", "--pid-file=" + pidFile}, filepath.Join(t.TempDir(), "daemon.log"))
+	assert.NilError(t, err)
+	exited := make(chan struct{})
+	go func() {
+		_, _ = process.Wait()
+		close(exited)
+	}()
+
+	assert.NilError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(process.Pid)), 0600))
+	assert.NilError(t, Stop(pidFile))
+
+	select {
+	case <-exited:
+	case <-time.After(time.Second * 10):
+		t.Fatal("process was not stopped")
+	}
+	_, err = os.Stat(pidFile)
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestStopReusedPID(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep is not available")
+	}
+
+	cmd := exec.Command(sleep, "60")
+	assert.NilError(t, cmd.Start())
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	// the pid of the daemon was reused by another process, which must not be stopped
+	pidFile := filepath.Join(t.TempDir(), "vcluster.pid")
+	assert.NilError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0600))
+	assert.ErrorContains(t, Stop(pidFile), "is not the daemon of pid file")
+	assert.NilError(t, cmd.Process.Signal(syscall.Signal(0)))
+	_, err = os.Stat(pidFile)
+	assert.Assert(t, os.IsNotExist(err))
+}
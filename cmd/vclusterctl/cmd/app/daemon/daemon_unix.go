@@ -0,0 +1,54 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+func sysProcAttr() *syscall.SysProcAttr {
+	// start a new session, so that the process keeps running after the terminal is closed
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// processCommandLine returns the command line of the given process or an empty string if there
+// is no such process
+func processCommandLine(pid int) (string, error) {
+	// linux exposes the arguments separated by null bytes in procfs
+	if _, err := os.Stat("/proc/self/cmdline"); err == nil {
+		out, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/cmdline")
+		if os.IsNotExist(err) {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+
+		return string(bytes.Join(bytes.Split(bytes.TrimRight(out, "\x00"), []byte{0}), []byte(" "))), nil
+	}
+
+	// other systems like darwin don't have procfs
+	out, err := exec.Command("ps", "-o", "command=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func terminate(process *os.Process) error {
+	err := process.Signal(syscall.SIGTERM)
+	if err != nil && err != os.ErrProcessDone {
+		return err
+	}
+
+	return nil
+}
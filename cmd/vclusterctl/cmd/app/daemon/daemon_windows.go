@@ -0,0 +1,36 @@
+//go:build windows
+
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+func sysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// processCommandLine returns the command line of the given process or an empty string if there
+// is no such process
+func processCommandLine(pid int) (string, error) {
+	out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", "(Get-CimInstance Win32_Process -Filter 'ProcessId="+strconv.Itoa(pid)+"').CommandLine").Output()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func terminate(process *os.Process) error {
+	// windows doesn't support sending SIGTERM to other processes
+	err := process.Kill()
+	if err != nil && err != os.ErrProcessDone {
+		return err
+	}
+
+	return nil
+}
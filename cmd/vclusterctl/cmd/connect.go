@@ -7,15 +7,19 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/loft-sh/vcluster/cmd/vclusterctl/cmd/app/daemon"
 	"github.com/loft-sh/vcluster/cmd/vclusterctl/cmd/app/localkubernetes"
 	"github.com/loft-sh/vcluster/cmd/vclusterctl/cmd/find"
+	"github.com/loft-sh/vcluster/pkg/util/gateway"
 	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"github.com/mitchellh/go-homedir"
 	authenticationv1 "k8s.io/api/authentication/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
@@ -57,6 +61,12 @@ type ConnectCmd struct {
 	Server   string
 	Insecure bool
 
+	GatewayBaseDomain string
+
+	KeepAlive time.Duration
+	Daemon    bool
+	PIDFile   string
+
 	Log log.Logger
 
 	kubeClientConfig clientcmd.ClientConfig
@@ -65,6 +75,7 @@ type ConnectCmd struct {
 	rawConfig        api.Config
 
 	portForwarding bool
+	gateway        bool
 	interruptChan  chan struct{}
 	errorChan      chan error
 }
@@ -114,6 +125,10 @@ vcluster connect test -n test -- kubectl get ns
 	cobraCmd.Flags().StringVar(&cmd.ServiceAccountClusterRole, "cluster-role", "", "If specified, vcluster will create the service account if it does not exist and also add a cluster role binding for the given cluster role to it. Requires --service-account to be set")
 	cobraCmd.Flags().IntVar(&cmd.ServiceAccountExpiration, "token-expiration", 0, "If specified, vcluster will create the service account token for the given duration in seconds. Defaults to eternal")
 	cobraCmd.Flags().BoolVar(&cmd.Insecure, "insecure", false, "If specified, vcluster will create the kube config with insecure-skip-tls-verify")
+	cobraCmd.Flags().StringVar(&cmd.GatewayBaseDomain, "gateway-base-domain", "", "If specified, vcluster will connect through the shared gateway at NAME.NAMESPACE.BASE_DOMAIN instead of port-forwarding. If empty, the gateway hostname of the vcluster service is used if there is one")
+	cobraCmd.Flags().DurationVar(&cmd.KeepAlive, "keepalive", 0, "If specified, vcluster will probe the port-forwarding in this interval and reconnect if the vcluster doesn't respond anymore (e.g. 30s)")
+	cobraCmd.Flags().BoolVar(&cmd.Daemon, "daemon", false, "If specified, vcluster will keep the port-forwarding running in the background. Use `vcluster disconnect` to stop it")
+	cobraCmd.Flags().StringVar(&cmd.PIDFile, "pid-file", "", "If specified, vcluster will write its process id into this file while port-forwarding")
 	cobraCmd.Flags().BoolVar(&cmd.BackgroundProxy, "background-proxy", false, "If specified, vcluster will create the background proxy in docker [its mainly used for vclusters with no nodeport service.]")
 	return cobraCmd
 }
@@ -125,9 +140,121 @@ func (cmd *ConnectCmd) Run(args []string) error {
 		vclusterName = args[0]
 	}
 
+	if cmd.Daemon {
+		return cmd.startDaemon(vclusterName, args[1:])
+	}
+
 	return cmd.Connect(vclusterName, args[1:])
 }
 
+// startDaemon starts vcluster connect again in the background and waits until it has connected
+func (cmd *ConnectCmd) startDaemon(vclusterName string, command []string) error {
+	if vclusterName == "" || len(command) > 0 || cmd.Print {
+		return fmt.Errorf("--daemon requires a vcluster name and cannot be used with a command or --print")
+	}
+
+	err := cmd.prepare(vclusterName)
+	if err != nil {
+		return err
+	}
+
+	contextName := cmd.KubeConfigContextName
+	if contextName == "" {
+		contextName = find.VClusterContextName(vclusterName, cmd.Namespace, cmd.rawConfig.CurrentContext)
+	}
+	pidFile, logFile, err := connectDaemonFiles(contextName)
+	if err != nil {
+		return err
+	}
+
+	// stop an already running daemon for the same vcluster
+	if _, err := os.Stat(pidFile); err == nil {
+		cmd.Log.Infof("Stop running vcluster connect daemon")
+		err = daemon.Stop(pidFile)
+		if err != nil {
+			cmd.Log.Warnf("Error stopping vcluster connect daemon: %v", err)
+		}
+	}
+
+	// start the same command again without --daemon
+	process, err := daemon.Start(daemonArgs(os.Args[1:], pidFile, cmd.Namespace), logFile)
+	if err != nil {
+		return errors.Wrap(err, "start vcluster connect daemon")
+	}
+
+	exited := make(chan *os.ProcessState, 1)
+	go func() {
+		state, _ := process.Wait()
+		exited <- state
+	}()
+
+	cmd.Log.StartWait("Waiting for vcluster connect daemon to connect")
+	defer cmd.Log.StopWait()
+	timeout := time.After(time.Minute * 5)
+	for {
+		select {
+		case state := <-exited:
+			if state == nil || !state.Success() {
+				return fmt.Errorf("vcluster connect daemon exited unexpectedly, please check the logs at %s", logFile)
+			}
+
+			// the vcluster is reachable without port-forwarding, e.g. exposed or through --server
+			cmd.Log.StopWait()
+			cmd.Log.Donef("Connected to vcluster %s without port-forwarding, so no background process is needed", vclusterName)
+			return nil
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for vcluster connect daemon, please check the logs at %s", logFile)
+		case <-time.After(time.Millisecond * 500):
+			pid, err := daemon.ReadPIDFile(pidFile)
+			if err != nil || pid != process.Pid {
+				continue
+			}
+
+			cmd.Log.StopWait()
+			cmd.Log.Donef("Started vcluster connect daemon with pid %d, logs are written to %s", pid, logFile)
+			cmd.Log.WriteString("- Use `vcluster disconnect` to stop the daemon and return to your previous kube context\n")
+			return nil
+		}
+	}
+}
+
+// daemonArgs returns the arguments of the connect command for the daemon, which are the given arguments
+// without --daemon and --pid-file and their values, and with the pid file and namespace of the daemon
+func daemonArgs(args []string, pidFile, namespace string) []string {
+	daemonArgs := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--pid-file" {
+			// skip the value as well
+			i++
+			continue
+		} else if arg == "--daemon" || strings.HasPrefix(arg, "--daemon=") || strings.HasPrefix(arg, "--pid-file=") {
+			continue
+		}
+
+		daemonArgs = append(daemonArgs, arg)
+	}
+
+	return append(daemonArgs, "--pid-file="+pidFile, "--namespace="+namespace)
+}
+
+// connectDaemonFiles returns the pid and log file of the connect daemon for the given vcluster context
+func connectDaemonFiles(contextName string) (string, string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", "", err
+	}
+
+	fileName := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, contextName)
+	dir := filepath.Join(home, DefaultHomeVClusterFolder, "connect")
+	return filepath.Join(dir, fileName+".pid"), filepath.Join(dir, fileName+".log"), nil
+}
+
 func (cmd *ConnectCmd) Connect(vclusterName string, command []string) error {
 	if vclusterName == "" && cmd.PodName == "" {
 		return fmt.Errorf("please specify either --pod or a name for the vcluster")
@@ -190,6 +317,9 @@ func (cmd *ConnectCmd) Connect(vclusterName string, command []string) error {
 			signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 			go func() {
 				<-c
+				if cmd.PIDFile != "" {
+					_ = os.Remove(cmd.PIDFile)
+				}
 				kubeConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{}).RawConfig()
 				if err == nil && kubeConfig.CurrentContext == cmd.KubeConfigContextName {
 					err = deleteContext(&kubeConfig, cmd.KubeConfigContextName, cmd.Context)
@@ -229,6 +359,16 @@ func (cmd *ConnectCmd) Connect(vclusterName string, command []string) error {
 		cmd.Log.WriteString(fmt.Sprintf("- Use `kubectl --kubeconfig %s get namespaces` to access the vcluster\n", cmd.KubeConfig))
 	}
 
+	// the connect daemon waits for the pid file, so it is written on every path as soon as the
+	// vcluster is connected. Without port-forwarding the daemon exits right away afterwards.
+	if cmd.PIDFile != "" {
+		err = daemon.WritePIDFile(cmd.PIDFile)
+		if err != nil {
+			return errors.Wrap(err, "write pid file")
+		}
+		defer os.Remove(cmd.PIDFile)
+	}
+
 	// wait for port-forwarding if necessary
 	if cmd.portForwarding {
 		if cmd.Server != "" {
@@ -315,29 +455,8 @@ func (cmd *ConnectCmd) getVClusterKubeConfig(vclusterName string, command []stri
 	podName := cmd.PodName
 	if podName == "" {
 		waitErr := wait.PollImmediate(time.Second, time.Second*6, func() (bool, error) {
-			// get vcluster pod name
-			var pods *corev1.PodList
-			pods, err = cmd.kubeClient.CoreV1().Pods(cmd.Namespace).List(context.Background(), metav1.ListOptions{
-				LabelSelector: "app=vcluster,release=" + vclusterName,
-			})
-			if err != nil {
-				return false, err
-			} else if len(pods.Items) == 0 {
-				err = fmt.Errorf("can't find a running vcluster pod in namespace %s", cmd.Namespace)
-				return false, nil
-			}
-
-			// sort by newest
-			sort.Slice(pods.Items, func(i, j int) bool {
-				return pods.Items[i].CreationTimestamp.Unix() > pods.Items[j].CreationTimestamp.Unix()
-			})
-			if pods.Items[0].DeletionTimestamp != nil {
-				err = fmt.Errorf("can't find a running vcluster pod in namespace %s", cmd.Namespace)
-				return false, nil
-			}
-
-			podName = pods.Items[0].Name
-			return true, nil
+			podName, err = cmd.findVClusterPod(context.Background(), vclusterName, false)
+			return err == nil, nil
 		})
 		if waitErr != nil {
 			return nil, fmt.Errorf("finding vcluster pod: %v - %v", waitErr, err)
@@ -361,6 +480,14 @@ func (cmd *ConnectCmd) getVClusterKubeConfig(vclusterName string, command []stri
 		return nil, err
 	}
 
+	// check if the vcluster is reachable through a shared gateway
+	if vclusterName != "" && cmd.Server == "" {
+		err = cmd.setServerIfGateway(vclusterName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// check if the vcluster is exposed and set server
 	if vclusterName != "" && cmd.Server == "" && len(command) == 0 {
 		err = cmd.setServerIfExposed(vclusterName, kubeConfig)
@@ -396,7 +523,7 @@ func (cmd *ConnectCmd) getVClusterKubeConfig(vclusterName string, command []stri
 	}
 
 	// start port forwarding
-	if cmd.ServiceAccount != "" || cmd.Server == "" || len(command) > 0 {
+	if !cmd.gateway && (cmd.ServiceAccount != "" || cmd.Server == "" || len(command) > 0) {
 		cmd.portForwarding = true
 		cmd.interruptChan = make(chan struct{})
 		cmd.errorChan = make(chan error)
@@ -408,9 +535,36 @@ func (cmd *ConnectCmd) getVClusterKubeConfig(vclusterName string, command []stri
 			stdout = io.Discard
 			stderr = io.Discard
 		}
+		if cmd.PIDFile != "" {
+			// don't fill the daemon log with every forwarded connection
+			stdout = io.Discard
+		}
+
+		// the first pod is already known, afterwards the pod is resolved again as it might have changed
+		firstPod := podName
+		resolvePod := func(ctx context.Context) (string, error) {
+			if firstPod != "" {
+				pod := firstPod
+				firstPod = ""
+				return pod, nil
+			} else if cmd.PodName != "" {
+				return cmd.PodName, portforward.WaitForReadyPod(ctx, cmd.kubeClient, cmd.Namespace, cmd.PodName)
+			}
+
+			return cmd.findVClusterPod(ctx, vclusterName, true)
+		}
 
 		go func() {
-			cmd.errorChan <- portforward.StartPortForwardingWithRestart(cmd.restConfig, cmd.Address, podName, cmd.Namespace, strconv.Itoa(cmd.LocalPort), port, cmd.interruptChan, stdout, stderr, cmd.Log)
+			cmd.errorChan <- portforward.StartPortForwardingWithRecovery(cmd.restConfig, portforward.RestartOptions{
+				Address:    cmd.Address,
+				Namespace:  cmd.Namespace,
+				LocalPort:  strconv.Itoa(cmd.LocalPort),
+				RemotePort: port,
+				ResolvePod: resolvePod,
+				KeepAlive:  cmd.KeepAlive,
+				Stdout:     stdout,
+				Stderr:     stderr,
+			}, cmd.interruptChan, cmd.Log)
 		}()
 	}
 
@@ -434,6 +588,74 @@ func (cmd *ConnectCmd) getVClusterKubeConfig(vclusterName string, command []stri
 	return kubeConfig, nil
 }
 
+// findVClusterPod returns the newest running vcluster pod. If ready is true, only ready pods are considered.
+func (cmd *ConnectCmd) findVClusterPod(ctx context.Context, vclusterName string, ready bool) (string, error) {
+	pods, err := cmd.kubeClient.CoreV1().Pods(cmd.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=vcluster,release=" + vclusterName,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// sort by newest
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].CreationTimestamp.Unix() > pods.Items[j].CreationTimestamp.Unix()
+	})
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			if !ready {
+				break
+			}
+
+			continue
+		} else if ready && !isPodReady(&pod) {
+			continue
+		}
+
+		return pod.Name, nil
+	}
+
+	return "", fmt.Errorf("can't find a running vcluster pod in namespace %s", cmd.Namespace)
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// setServerIfGateway uses the hostname of the shared gateway as server if either --gateway-base-domain
+// is set or the vcluster service was annotated with a gateway hostname
+func (cmd *ConnectCmd) setServerIfGateway(vClusterName string) error {
+	hostname := ""
+	if cmd.GatewayBaseDomain != "" {
+		hostname = gateway.Hostname(vClusterName, cmd.Namespace, cmd.GatewayBaseDomain)
+	} else {
+		service, err := cmd.kubeClient.CoreV1().Services(cmd.Namespace).Get(context.TODO(), vClusterName, metav1.GetOptions{})
+		if err != nil {
+			if kerrors.IsNotFound(err) {
+				return nil
+			}
+
+			return errors.Wrap(err, "get vcluster service")
+		}
+
+		hostname = service.Annotations[gateway.HostnameAnnotation]
+		if hostname == "" {
+			return nil
+		}
+	}
+
+	cmd.Server = "https://" + hostname
+	cmd.gateway = true
+	cmd.Log.Infof("Using vcluster %s gateway endpoint: %s", vClusterName, cmd.Server)
+	return nil
+}
+
 func (cmd *ConnectCmd) setServerIfExposed(vClusterName string, vClusterConfig *api.Config) error {
 	printedWaiting := false
 	err := wait.PollImmediate(time.Second*2, time.Minute*5, func() (done bool, err error) {
@@ -527,12 +749,12 @@ func (cmd *ConnectCmd) exchangeContextName(kubeConfig *api.Config, vclusterName
 }
 
 func (cmd *ConnectCmd) executeCommand(vKubeConfig api.Config, command []string) error {
-	if !cmd.portForwarding {
+	if !cmd.portForwarding && !cmd.gateway {
 		return fmt.Errorf("command is specified, but port-forwarding isn't started")
+	} else if cmd.portForwarding {
+		defer close(cmd.interruptChan)
 	}
 
-	defer close(cmd.interruptChan)
-
 	// wait for vcluster to be ready
 	err := cmd.waitForVCluster(vKubeConfig, cmd.errorChan)
 	if err != nil {
@@ -600,6 +822,10 @@ func (cmd *ConnectCmd) executeCommand(vKubeConfig api.Config, command []string)
 func (cmd *ConnectCmd) getLocalVClusterConfig(vKubeConfig api.Config) api.Config {
 	// wait until we can access the virtual cluster
 	vKubeConfig = *vKubeConfig.DeepCopy()
+	if cmd.gateway {
+		return vKubeConfig
+	}
+
 	for k := range vKubeConfig.Clusters {
 		vKubeConfig.Clusters[k].Server = "https://localhost:" + strconv.Itoa(cmd.LocalPort)
 	}
@@ -0,0 +1,12 @@
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestDaemonArgs(t *testing.T) {
+	args := daemonArgs([]string{"connect", "test", "--daemon", "--pid-file", "/tmp/old.pid", "--local-port=8443", "--pid-file=/tmp/other.pid", "--daemon=true"}, "/home/.vcluster/connect/test.pid", "team-a")
+	assert.DeepEqual(t, args, []string{"connect", "test", "--local-port=8443", "--pid-file=/home/.vcluster/connect/test.pid", "--namespace=team-a"})
+}
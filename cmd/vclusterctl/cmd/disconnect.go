@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+
+	"github.com/loft-sh/vcluster/cmd/vclusterctl/cmd/app/daemon"
 	"github.com/loft-sh/vcluster/cmd/vclusterctl/cmd/find"
 	"github.com/loft-sh/vcluster/cmd/vclusterctl/flags"
 	"github.com/loft-sh/vcluster/cmd/vclusterctl/log"
@@ -36,7 +39,9 @@ func NewDisconnectCmd(globalFlags *flags.GlobalFlags) *cobra.Command {
 ################# vcluster disconnect #################
 #######################################################
 Disconnect switches back the kube context if
-vcluster connect --update-current was used
+vcluster connect --update-current was used and
+stops the background port-forwarding of
+vcluster connect --daemon
 
 Example:
 vcluster connect --update-current
@@ -76,6 +81,19 @@ func (cmd *DisconnectCmd) Run(cobraCmd *cobra.Command, args []string) error {
 		return errors.Wrap(err, "switch kube context")
 	}
 
+	// stop the connect daemon if there is one
+	pidFile, _, err := connectDaemonFiles(cmd.Context)
+	if err != nil {
+		return err
+	} else if _, err := os.Stat(pidFile); err == nil {
+		err = daemon.Stop(pidFile)
+		if err != nil {
+			cmd.log.Warnf("Error stopping vcluster connect daemon: %v", err)
+		} else {
+			cmd.log.Infof("Stopped vcluster connect daemon")
+		}
+	}
+
 	cmd.log.Infof("Successfully disconnected from vcluster: %s and switched back to the original context: %s", vClusterName, otherContext)
 	return nil
 }
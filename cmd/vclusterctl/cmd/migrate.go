@@ -0,0 +1,670 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/loft-sh/vcluster/cmd/vclusterctl/cmd/find"
+	"github.com/loft-sh/vcluster/cmd/vclusterctl/flags"
+	"github.com/loft-sh/vcluster/cmd/vclusterctl/log"
+	"github.com/loft-sh/vcluster/pkg/certs"
+	"github.com/loft-sh/vcluster/pkg/helm"
+	"github.com/loft-sh/vcluster/pkg/util/podhelper"
+	"github.com/loft-sh/vcluster/pkg/util/servicecidr"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// DefaultMoverImage is the image of the pods that copy the data of persistent volume claims
+const DefaultMoverImage = "alpine:3.16"
+
+// moverDataPath is where the mover pods mount the persistent volume claim
+const moverDataPath = "/data"
+
+// MigrateCmd holds the cmd flags
+type MigrateCmd struct {
+	*flags.GlobalFlags
+	Log log.Logger
+
+	ToContext     string
+	ToNamespace   string
+	CopyVolumes   bool
+	MoverImage    string
+	ChartRepo     string
+	LocalChartDir string
+	Rollback      bool
+	Timeout       time.Duration
+
+	vCluster *find.VCluster
+	release  *helm.Release
+
+	sourceClient *kubernetes.Clientset
+	sourceConfig *rest.Config
+	targetClient kubernetes.Interface
+	targetConfig *rest.Config
+	targetRaw    clientcmdapi.Config
+
+	// rollback holds the steps that undo the migration, which are executed in reverse order on failure
+	rollback []rollbackStep
+}
+
+type rollbackStep struct {
+	name string
+	undo func() error
+}
+
+// NewMigrateCmd creates a new command
+func NewMigrateCmd(globalFlags *flags.GlobalFlags) *cobra.Command {
+	cmd := &MigrateCmd{
+		GlobalFlags: globalFlags,
+		Log:         log.GetInstance(),
+	}
+
+	cobraCmd := &cobra.Command{
+		Use:   "migrate [flags] vcluster_name",
+		Short: "Migrates a virtual cluster to another namespace or cluster",
+		Long: `
+#######################################################
+################## vcluster migrate ###################
+#######################################################
+Migrate moves a virtual cluster to another namespace or
+kube context. The source virtual cluster is paused, its
+data store, certificates and helm values are copied to
+the target and the release is recreated there. All
+workloads are recreated by the virtual cluster at the
+target. With --copy-volumes, the data of the persistent
+volume claims of the workloads is copied as well.
+
+The source virtual cluster stays paused after a
+successful migration and can be deleted afterwards. If
+the migration fails, everything created at the target
+is removed and the source virtual cluster is resumed.
+
+The k8s and eks distros can only be migrated to a
+namespace with the same name, because the etcd
+certificates and peer urls contain the namespace.
+
+Example:
+vcluster migrate test --namespace test --to-context prod --to-namespace test
+vcluster migrate test --namespace test --to-namespace team-a --copy-volumes
+#######################################################
+	`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: newValidVClusterNameFunc(globalFlags),
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Run(args)
+		},
+	}
+
+	cobraCmd.Flags().StringVar(&cmd.ToContext, "to-context", "", "The kube context to migrate the virtual cluster to. Defaults to the context of the virtual cluster")
+	cobraCmd.Flags().StringVar(&cmd.ToNamespace, "to-namespace", "", "The namespace to migrate the virtual cluster to. Defaults to the namespace of the virtual cluster")
+	cobraCmd.Flags().BoolVar(&cmd.CopyVolumes, "copy-volumes", false, "If enabled, copies the data of the persistent volume claims of the virtual cluster workloads")
+	cobraCmd.Flags().StringVar(&cmd.MoverImage, "mover-image", DefaultMoverImage, "The image of the pods that copy the persistent volume data, needs to contain tar")
+	cobraCmd.Flags().StringVar(&cmd.ChartRepo, "chart-repo", LoftChartRepo, "The virtual cluster chart repo to use")
+	cobraCmd.Flags().StringVar(&cmd.LocalChartDir, "local-chart-dir", "", "The virtual cluster local chart dir to use")
+	cobraCmd.Flags().BoolVar(&cmd.Rollback, "rollback", true, "If enabled, removes everything created at the target and resumes the source virtual cluster if the migration fails")
+	cobraCmd.Flags().DurationVar(&cmd.Timeout, "timeout", time.Minute*10, "How long to wait for the virtual cluster to become ready at the target")
+	return cobraCmd
+}
+
+// Run executes the functionality
+func (cmd *MigrateCmd) Run(args []string) error {
+	// test for helm
+	helmBinaryPath, err := GetHelmBinaryPath(cmd.Log)
+	if err != nil {
+		return err
+	}
+
+	err = cmd.prepare(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = cmd.migrate(args[0], helmBinaryPath)
+	if err != nil {
+		cmd.Log.Errorf("Migration of vcluster %s failed: %v", args[0], err)
+		if cmd.Rollback {
+			cmd.rollBack()
+		} else {
+			cmd.Log.Warnf("Skipping rollback, the source vcluster %s/%s stays paused", cmd.vCluster.Namespace, args[0])
+		}
+
+		return err
+	}
+
+	cmd.Log.Donef("Successfully migrated vcluster %s from %s/%s to %s/%s", args[0], cmd.vCluster.Context, cmd.vCluster.Namespace, cmd.ToContext, cmd.ToNamespace)
+	cmd.Log.Infof("The source vcluster is still paused. After verifying the migrated vcluster, delete it with `vcluster delete %s --namespace %s --context %s`", args[0], cmd.vCluster.Namespace, cmd.vCluster.Context)
+	return nil
+}
+
+func (cmd *MigrateCmd) prepare(vClusterName string) error {
+	vCluster, err := find.GetVCluster(cmd.Context, vClusterName, cmd.Namespace)
+	if err != nil {
+		return err
+	}
+
+	cmd.vCluster = vCluster
+	if cmd.ToContext == "" {
+		cmd.ToContext = vCluster.Context
+	}
+	if cmd.ToNamespace == "" {
+		cmd.ToNamespace = vCluster.Namespace
+	}
+	if cmd.ToContext == vCluster.Context && cmd.ToNamespace == vCluster.Namespace {
+		return fmt.Errorf("vcluster %s already is in namespace %s of context %s, please specify another target with --to-context or --to-namespace", vClusterName, vCluster.Namespace, vCluster.Context)
+	}
+
+	// load the source rest config
+	cmd.sourceConfig, err = vCluster.ClientFactory.ClientConfig()
+	if err != nil {
+		return fmt.Errorf("there is an error loading your current kube config (%v), please make sure you have access to a kubernetes cluster and the command `kubectl get namespaces` is working", err)
+	}
+	cmd.sourceClient, err = kubernetes.NewForConfig(cmd.sourceConfig)
+	if err != nil {
+		return err
+	}
+
+	// load the target configs
+	targetClientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{
+		CurrentContext: cmd.ToContext,
+	})
+	cmd.targetRaw, err = targetClientConfig.RawConfig()
+	if err != nil {
+		return errors.Wrap(err, "load target kube config")
+	}
+	cmd.targetRaw.CurrentContext = cmd.ToContext
+	cmd.targetConfig, err = targetClientConfig.ClientConfig()
+	if err != nil {
+		return errors.Wrapf(err, "load kube config of context %s", cmd.ToContext)
+	}
+	cmd.targetClient, err = kubernetes.NewForConfig(cmd.targetConfig)
+	if err != nil {
+		return err
+	}
+
+	// get the helm release of the source
+	cmd.release, err = helm.NewSecrets(cmd.sourceClient).Get(context.Background(), vClusterName, vCluster.Namespace)
+	if err != nil {
+		return errors.Wrap(err, "get helm release")
+	} else if cmd.release.Chart == nil || cmd.release.Chart.Metadata == nil || cmd.release.Chart.Metadata.Name == "" {
+		return fmt.Errorf("helm release of vcluster %s has no chart information", vClusterName)
+	} else if cmd.ToNamespace != vCluster.Namespace && usesEtcd(cmd.release.Chart.Metadata.Name) {
+		return fmt.Errorf("vcluster %s uses the chart %s, which can't be migrated to another namespace, because the etcd certificates and member peer urls contain the namespace %s", vClusterName, cmd.release.Chart.Metadata.Name, vCluster.Namespace)
+	}
+
+	// make sure there is no vcluster at the target yet
+	_, err = helm.NewSecrets(cmd.targetClient).Get(context.Background(), vClusterName, cmd.ToNamespace)
+	if err == nil {
+		return fmt.Errorf("there already is a helm release %s in namespace %s of context %s", vClusterName, cmd.ToNamespace, cmd.ToContext)
+	} else if !kerrors.IsNotFound(err) {
+		return errors.Wrap(err, "get target helm release")
+	}
+
+	return nil
+}
+
+func (cmd *MigrateCmd) migrate(vClusterName, helmBinaryPath string) error {
+	ctx := context.Background()
+	sourceNamespace := cmd.vCluster.Namespace
+
+	// find the persistent volume claims to copy before the workloads are gone
+	dataClaims, err := cmd.sourceClient.CoreV1().PersistentVolumeClaims(sourceNamespace).List(ctx, metav1.ListOptions{LabelSelector: "app in (vcluster,vcluster-etcd),release=" + vClusterName})
+	if err != nil {
+		return errors.Wrap(err, "list data store persistent volume claims")
+	}
+	claims := dataClaims.Items
+	if cmd.CopyVolumes {
+		workloadClaims, err := cmd.sourceClient.CoreV1().PersistentVolumeClaims(sourceNamespace).List(ctx, metav1.ListOptions{LabelSelector: translate.MarkerLabel + "=" + vClusterName})
+		if err != nil {
+			return errors.Wrap(err, "list workload persistent volume claims")
+		}
+
+		claims = append(claims, workloadClaims.Items...)
+	}
+
+	// pause the source
+	cmd.Log.Infof("[1/5] Pause vcluster %s/%s...", sourceNamespace, vClusterName)
+	pauseCmd := &PauseCmd{
+		GlobalFlags: &flags.GlobalFlags{Namespace: sourceNamespace},
+		Log:         cmd.Log,
+		kubeClient:  cmd.sourceClient,
+	}
+	err = pauseCmd.pause(vClusterName)
+	if err != nil {
+		return errors.Wrap(err, "pause source vcluster")
+	}
+	if cmd.vCluster.Status != find.StatusPaused {
+		cmd.addRollback(fmt.Sprintf("resume vcluster %s/%s", sourceNamespace, vClusterName), func() error {
+			return resumeVCluster(cmd.sourceClient, vClusterName, sourceNamespace, cmd.Log)
+		})
+	}
+
+	// the data store and workload volumes are only consistent once nothing writes to them anymore
+	for _, labelSelector := range []string{
+		"app in (vcluster,vcluster-api,vcluster-controller,vcluster-etcd),release=" + vClusterName,
+		"vcluster.loft.sh/managed-by=" + vClusterName,
+	} {
+		err = waitForPodsDeleted(cmd.sourceClient, sourceNamespace, labelSelector)
+		if err != nil {
+			return errors.Wrap(err, "wait for vcluster pods deleted")
+		}
+	}
+
+	// prepare the target namespace
+	cmd.Log.Infof("[2/5] Prepare namespace %s in context %s...", cmd.ToNamespace, cmd.ToContext)
+	err = cmd.ensureTargetNamespace()
+	if err != nil {
+		return err
+	}
+
+	// copy the certificates of vclusters that store them in a secret
+	err = cmd.copyCerts(vClusterName)
+	if err != nil {
+		return errors.Wrap(err, "copy certificates")
+	}
+
+	// copy the data store and workload volumes
+	cmd.Log.Infof("[3/5] Copy %d persistent volume claims...", len(claims))
+	for i := range claims {
+		err = cmd.copyPersistentVolumeClaim(&claims[i])
+		if err != nil {
+			return errors.Wrapf(err, "copy persistent volume claim %s", claims[i].Name)
+		}
+	}
+
+	// recreate the release at the target
+	cmd.Log.Infof("[4/5] Create vcluster %s in namespace %s of context %s...", vClusterName, cmd.ToNamespace, cmd.ToContext)
+	err = cmd.deployChart(vClusterName, helmBinaryPath)
+	if err != nil {
+		return errors.Wrap(err, "deploy vcluster")
+	}
+
+	// wait until the vcluster is up
+	cmd.Log.Infof("[5/5] Wait for vcluster %s to become ready...", vClusterName)
+	return cmd.waitForVCluster(vClusterName)
+}
+
+// usesEtcd returns true for the distros that run a separate etcd, whose certificates and
+// member peer urls are bound to the namespace of the vcluster
+func usesEtcd(chartName string) bool {
+	return chartName == "vcluster-k8s" || chartName == "vcluster-eks"
+}
+
+// waitForPodsDeleted waits until all pods matching the label selector are gone, so that their
+// persistent volume claims can be mounted by the mover pods and the copied data is consistent
+func waitForPodsDeleted(kubeClient kubernetes.Interface, namespace, labelSelector string) error {
+	return wait.PollImmediate(time.Second*2, time.Minute*5, func() (bool, error) {
+		pods, err := kubeClient.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: labelSelector})
+		if err != nil {
+			return false, err
+		}
+
+		return len(pods.Items) == 0, nil
+	})
+}
+
+func (cmd *MigrateCmd) addRollback(name string, undo func() error) {
+	cmd.rollback = append(cmd.rollback, rollbackStep{name: name, undo: undo})
+}
+
+func (cmd *MigrateCmd) rollBack() {
+	cmd.Log.Infof("Rolling back migration...")
+	for i := len(cmd.rollback) - 1; i >= 0; i-- {
+		step := cmd.rollback[i]
+		cmd.Log.Infof("Rollback: %s", step.name)
+		err := step.undo()
+		if err != nil {
+			cmd.Log.Warnf("Error during rollback step %s: %v", step.name, err)
+		}
+	}
+}
+
+func (cmd *MigrateCmd) ensureTargetNamespace() error {
+	_, err := cmd.targetClient.CoreV1().Namespaces().Get(context.Background(), cmd.ToNamespace, metav1.GetOptions{})
+	if err == nil || kerrors.IsForbidden(err) {
+		return nil
+	} else if !kerrors.IsNotFound(err) {
+		return errors.Wrap(err, "get target namespace")
+	}
+
+	cmd.Log.Infof("Creating namespace %s", cmd.ToNamespace)
+	_, err = cmd.targetClient.CoreV1().Namespaces().Create(context.Background(), &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: cmd.ToNamespace,
+			Annotations: map[string]string{
+				CreatedByVClusterAnnotation: "true",
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "create namespace")
+	}
+
+	cmd.addRollback("delete namespace "+cmd.ToNamespace, func() error {
+		return cmd.targetClient.CoreV1().Namespaces().Delete(context.Background(), cmd.ToNamespace, metav1.DeleteOptions{})
+	})
+	return nil
+}
+
+func (cmd *MigrateCmd) copyCerts(vClusterName string) error {
+	secret, err := cmd.sourceClient.CoreV1().Secrets(cmd.vCluster.Namespace).Get(context.Background(), certs.SecretName(vClusterName), metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		// k3s and k0s keep the certificates within the data store
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	cmd.Log.Infof("Copy certificates secret %s", secret.Name)
+	_, err = cmd.targetClient.CoreV1().Secrets(cmd.ToNamespace).Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        secret.Name,
+			Namespace:   cmd.ToNamespace,
+			Labels:      secret.Labels,
+			Annotations: secret.Annotations,
+		},
+		Type: secret.Type,
+		Data: secret.Data,
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+
+	cmd.addRollback("delete secret "+secret.Name, func() error {
+		return cmd.targetClient.CoreV1().Secrets(cmd.ToNamespace).Delete(context.Background(), secret.Name, metav1.DeleteOptions{})
+	})
+	return nil
+}
+
+func (cmd *MigrateCmd) copyPersistentVolumeClaim(pvc *corev1.PersistentVolumeClaim) error {
+	if pvc.Spec.VolumeMode != nil && *pvc.Spec.VolumeMode == corev1.PersistentVolumeBlock {
+		cmd.Log.Warnf("Skip persistent volume claim %s, copying block volumes is not supported", pvc.Name)
+		return nil
+	}
+
+	// create the target claim, the vcluster and its workloads will pick it up by its name
+	targetPVC, err := cmd.targetClient.CoreV1().PersistentVolumeClaims(cmd.ToNamespace).Create(context.Background(), &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pvc.Name,
+			Namespace:   cmd.ToNamespace,
+			Labels:      pvc.Labels,
+			Annotations: filterClaimAnnotations(pvc.Annotations),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      pvc.Spec.AccessModes,
+			Resources:        pvc.Spec.Resources,
+			VolumeMode:       pvc.Spec.VolumeMode,
+			StorageClassName: cmd.targetStorageClass(pvc.Spec.StorageClassName),
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	cmd.addRollback("delete persistent volume claim "+targetPVC.Name, func() error {
+		return cmd.targetClient.CoreV1().PersistentVolumeClaims(cmd.ToNamespace).Delete(context.Background(), targetPVC.Name, metav1.DeleteOptions{})
+	})
+
+	// start the mover pods
+	sourcePod, err := startMoverPod(cmd.sourceClient, cmd.vCluster.Namespace, pvc.Name, cmd.MoverImage, true)
+	if err != nil {
+		return errors.Wrap(err, "start source mover pod")
+	}
+	defer deleteMoverPod(cmd.sourceClient, sourcePod, cmd.Log)
+	targetPod, err := startMoverPod(cmd.targetClient, cmd.ToNamespace, targetPVC.Name, cmd.MoverImage, false)
+	if err != nil {
+		return errors.Wrap(err, "start target mover pod")
+	}
+	defer deleteMoverPod(cmd.targetClient, targetPod, cmd.Log)
+
+	// stream the data from the source to the target
+	reader, writer := io.Pipe()
+	sourceErr := make(chan error, 1)
+	go func() {
+		stderr := &bytes.Buffer{}
+		err := podhelper.ExecStream(cmd.sourceConfig, &podhelper.ExecStreamOptions{
+			Pod:       sourcePod.Name,
+			Namespace: sourcePod.Namespace,
+			Container: "mover",
+			Command:   []string{"tar", "czf", "-", "-C", moverDataPath, "."},
+			Stdout:    writer,
+			Stderr:    stderr,
+		})
+		if err != nil {
+			err = errors.Wrapf(err, "read data: %s", stderr.String())
+		}
+
+		_ = writer.CloseWithError(err)
+		sourceErr <- err
+	}()
+
+	stderr := &bytes.Buffer{}
+	progress := &progressReader{Reader: reader, name: pvc.Name, log: cmd.Log, lastReport: time.Now()}
+	err = podhelper.ExecStream(cmd.targetConfig, &podhelper.ExecStreamOptions{
+		Pod:       targetPod.Name,
+		Namespace: targetPod.Namespace,
+		Container: "mover",
+		Command:   []string{"tar", "xzf", "-", "-C", moverDataPath},
+		Stdin:     progress,
+		Stderr:    stderr,
+	})
+	if err != nil {
+		_ = reader.CloseWithError(err)
+		<-sourceErr
+		return errors.Wrapf(err, "write data: %s", stderr.String())
+	}
+	err = <-sourceErr
+	if err != nil {
+		return err
+	}
+
+	cmd.Log.Donef("Copied persistent volume claim %s (%s)", pvc.Name, formatBytes(progress.copied))
+	return nil
+}
+
+// targetStorageClass keeps the storage class of the source claim if it also exists at the target and
+// uses the default storage class of the target otherwise
+func (cmd *MigrateCmd) targetStorageClass(storageClass *string) *string {
+	if storageClass == nil || *storageClass == "" {
+		return storageClass
+	}
+
+	_, err := cmd.targetClient.StorageV1().StorageClasses().Get(context.Background(), *storageClass, metav1.GetOptions{})
+	if err != nil {
+		cmd.Log.Debugf("Storage class %s is not available at the target, using the default storage class: %v", *storageClass, err)
+		return nil
+	}
+
+	return storageClass
+}
+
+// filterClaimAnnotations removes the annotations that bind the claim to the volume of the source cluster
+func filterClaimAnnotations(annotations map[string]string) map[string]string {
+	filtered := map[string]string{}
+	for k, v := range annotations {
+		if strings.HasPrefix(k, "pv.kubernetes.io/") || strings.HasPrefix(k, "volume.kubernetes.io/") || strings.HasPrefix(k, "volume.beta.kubernetes.io/") {
+			continue
+		}
+
+		filtered[k] = v
+	}
+
+	return filtered
+}
+
+func startMoverPod(kubeClient kubernetes.Interface, namespace, claimName, image string, readOnly bool) (*corev1.Pod, error) {
+	zero := int64(0)
+	pod, err := kubeClient.CoreV1().Pods(namespace).Create(context.Background(), &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "vcluster-migrate-",
+			Namespace:    namespace,
+			Labels: map[string]string{
+				"app": "vcluster-migrate",
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:                 corev1.RestartPolicyNever,
+			TerminationGracePeriodSeconds: &zero,
+			Containers: []corev1.Container{
+				{
+					Name:    "mover",
+					Image:   image,
+					Command: []string{"sleep", "86400"},
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      "data",
+							MountPath: moverDataPath,
+							ReadOnly:  readOnly,
+						},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: claimName,
+							ReadOnly:  readOnly,
+						},
+					},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// wait until the pod is running
+	err = wait.PollImmediate(time.Second*2, time.Minute*5, func() (bool, error) {
+		pod, err = kubeClient.CoreV1().Pods(namespace).Get(context.Background(), pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		} else if HasPodProblem(pod) || pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded {
+			return false, fmt.Errorf("mover pod %s/%s has status %s", namespace, pod.Name, find.GetPodStatus(pod))
+		}
+
+		return pod.Status.Phase == corev1.PodRunning, nil
+	})
+	if err != nil {
+		_ = kubeClient.CoreV1().Pods(namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &zero})
+		return nil, errors.Wrap(err, "wait for mover pod")
+	}
+
+	return pod, nil
+}
+
+func deleteMoverPod(kubeClient kubernetes.Interface, pod *corev1.Pod, log log.Logger) {
+	zero := int64(0)
+	err := kubeClient.CoreV1().Pods(pod.Namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &zero})
+	if err != nil && !kerrors.IsNotFound(err) {
+		log.Warnf("Error deleting mover pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		return
+	}
+
+	// wait until the pod is gone, so the claim can be mounted by the vcluster again
+	_ = wait.PollImmediate(time.Second, time.Minute, func() (bool, error) {
+		_, err := kubeClient.CoreV1().Pods(pod.Namespace).Get(context.Background(), pod.Name, metav1.GetOptions{})
+		return kerrors.IsNotFound(err), nil
+	})
+}
+
+// progressReader counts the copied bytes and reports them periodically
+type progressReader struct {
+	io.Reader
+
+	name       string
+	log        log.Logger
+	copied     int64
+	lastReport time.Time
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.Reader.Read(b)
+	p.copied += int64(n)
+	if time.Since(p.lastReport) > time.Second*5 {
+		p.log.Infof("Copying persistent volume claim %s: %s copied", p.name, formatBytes(p.copied))
+		p.lastReport = time.Now()
+	}
+
+	return n, err
+}
+
+func formatBytes(b int64) string {
+	if b < 1024*1024 {
+		return fmt.Sprintf("%.1f KiB", float64(b)/1024)
+	}
+
+	return fmt.Sprintf("%.1f MiB", float64(b)/(1024*1024))
+}
+
+func (cmd *MigrateCmd) deployChart(vClusterName, helmBinaryPath string) error {
+	// reuse the values of the source, but with the service cidr of the target
+	values := map[string]interface{}{}
+	for k, v := range cmd.release.Config {
+		values[k] = v
+	}
+	values["serviceCIDR"] = servicecidr.GetServiceCIDR(cmd.targetClient, cmd.ToNamespace)
+	out, err := yaml.Marshal(values)
+	if err != nil {
+		return errors.Wrap(err, "marshal helm values")
+	}
+
+	chartName := cmd.release.Chart.Metadata.Name
+	chartVersion := cmd.release.Chart.Metadata.Version
+	chartRepo := cmd.ChartRepo
+	chartPath := cmd.LocalChartDir
+
+	// rewrite chart location, this is an optimization to avoid
+	// downloading the whole index.yaml and parsing it
+	if chartPath == "" && chartVersion != "" && chartRepo == LoftChartRepo {
+		chartPath = LoftChartRepo + "/charts/" + chartName + "-" + strings.TrimPrefix(chartVersion, "v") + ".tgz"
+		chartVersion = ""
+		chartRepo = ""
+	}
+
+	helmClient := helm.NewClient(&cmd.targetRaw, cmd.Log, helmBinaryPath)
+	cmd.addRollback("delete helm release "+vClusterName, func() error {
+		return helmClient.Delete(vClusterName, cmd.ToNamespace)
+	})
+	return helmClient.Upgrade(context.Background(), vClusterName, cmd.ToNamespace, helm.UpgradeOptions{
+		Chart:   chartName,
+		Path:    chartPath,
+		Repo:    chartRepo,
+		Version: chartVersion,
+		Values:  string(out),
+	})
+}
+
+func (cmd *MigrateCmd) waitForVCluster(vClusterName string) error {
+	return wait.PollImmediate(time.Second*2, cmd.Timeout, func() (bool, error) {
+		pods, err := cmd.targetClient.CoreV1().Pods(cmd.ToNamespace).List(context.Background(), metav1.ListOptions{LabelSelector: "app=vcluster,release=" + vClusterName})
+		if err != nil {
+			return false, err
+		}
+
+		for i := range pods.Items {
+			if HasPodProblem(&pods.Items[i]) {
+				return false, fmt.Errorf("vcluster pod %s has status %s", pods.Items[i].Name, find.GetPodStatus(&pods.Items[i]))
+			} else if pods.Items[i].Status.Phase == corev1.PodRunning && allContainersReady(&pods.Items[i]) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/loft-sh/vcluster/cmd/vclusterctl/log"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFilterClaimAnnotations(t *testing.T) {
+	filtered := filterClaimAnnotations(map[string]string{
+		"pv.kubernetes.io/bind-completed":               "yes",
+		"pv.kubernetes.io/bound-by-controller":          "yes",
+		"volume.kubernetes.io/selected-node":            "node-1",
+		"volume.kubernetes.io/storage-provisioner":      "ebs.csi.aws.com",
+		"volume.beta.kubernetes.io/storage-provisioner": "ebs.csi.aws.com",
+		"vcluster.loft.sh/object-name":                  "data",
+		"backup":                                        "daily",
+	})
+	assert.DeepEqual(t, filtered, map[string]string{
+		"vcluster.loft.sh/object-name": "data",
+		"backup":                       "daily",
+	})
+	assert.DeepEqual(t, filterClaimAnnotations(nil), map[string]string{})
+}
+
+func TestTargetStorageClass(t *testing.T) {
+	cmd := &MigrateCmd{
+		Log:          &log.DiscardLogger{},
+		targetClient: fake.NewSimpleClientset(&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}}),
+	}
+	fast, slow, empty := "fast", "slow", ""
+
+	// classes that exist at the target are kept, the others fall back to the default class
+	assert.Equal(t, *cmd.targetStorageClass(&fast), "fast")
+	assert.Assert(t, cmd.targetStorageClass(&slow) == nil)
+	assert.Assert(t, cmd.targetStorageClass(nil) == nil)
+	assert.Equal(t, *cmd.targetStorageClass(&empty), "")
+}
+
+func TestRollBack(t *testing.T) {
+	cmd := &MigrateCmd{Log: &log.DiscardLogger{}}
+	undone := []string{}
+	for _, name := range []string{"namespace", "secret", "claim"} {
+		name := name
+		cmd.addRollback(name, func() error {
+			undone = append(undone, name)
+			return errors.New("failed")
+		})
+	}
+
+	// the steps are undone in reverse order and failing steps don't stop the rollback
+	cmd.rollBack()
+	assert.DeepEqual(t, undone, []string{"claim", "secret", "namespace"})
+}
+
+func TestWaitForPodsDeleted(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "test", Labels: map[string]string{"vcluster.loft.sh/managed-by": "other"}}})
+	assert.NilError(t, waitForPodsDeleted(kubeClient, "test", "vcluster.loft.sh/managed-by=test"))
+}
+
+func TestUsesEtcd(t *testing.T) {
+	assert.Assert(t, usesEtcd("vcluster-k8s"))
+	assert.Assert(t, usesEtcd("vcluster-eks"))
+	assert.Assert(t, !usesEtcd("vcluster"))
+	assert.Assert(t, !usesEtcd("vcluster-k0s"))
+}
@@ -73,8 +73,19 @@ func (cmd *PauseCmd) Run(args []string) error {
 		return err
 	}
 
+	err = cmd.pause(args[0])
+	if err != nil {
+		return err
+	}
+
+	cmd.Log.Donef("Successfully paused vcluster %s/%s", cmd.Namespace, args[0])
+	return nil
+}
+
+// pause scales down the vcluster and deletes all workloads created through it
+func (cmd *PauseCmd) pause(name string) error {
 	// scale down vcluster itself
-	labelSelector := "app=vcluster,release=" + args[0]
+	labelSelector := "app=vcluster,release=" + name
 	found, err := cmd.scaleDownStatefulSet(cmd.kubeClient, labelSelector)
 	if err != nil {
 		return err
@@ -83,35 +94,34 @@ func (cmd *PauseCmd) Run(args []string) error {
 		if err != nil {
 			return err
 		} else if !found {
-			return errors.Errorf("couldn't find vcluster %s in namespace %s", args[0], cmd.Namespace)
+			return errors.Errorf("couldn't find vcluster %s in namespace %s", name, cmd.Namespace)
 		}
 
 		// scale down kube api server
-		_, err = cmd.scaleDownDeployment(cmd.kubeClient, "app=vcluster-api,release="+args[0])
+		_, err = cmd.scaleDownDeployment(cmd.kubeClient, "app=vcluster-api,release="+name)
 		if err != nil {
 			return err
 		}
 
 		// scale down kube controller
-		_, err = cmd.scaleDownDeployment(cmd.kubeClient, "app=vcluster-controller,release="+args[0])
+		_, err = cmd.scaleDownDeployment(cmd.kubeClient, "app=vcluster-controller,release="+name)
 		if err != nil {
 			return err
 		}
 
 		// scale down etcd
-		_, err = cmd.scaleDownStatefulSet(cmd.kubeClient, "app=vcluster-etcd,release="+args[0])
+		_, err = cmd.scaleDownStatefulSet(cmd.kubeClient, "app=vcluster-etcd,release="+name)
 		if err != nil {
 			return err
 		}
 	}
 
 	// delete vcluster workloads
-	err = cmd.deleteVClusterWorkloads(cmd.kubeClient, "vcluster.loft.sh/managed-by="+args[0])
+	err = cmd.deleteVClusterWorkloads(cmd.kubeClient, "vcluster.loft.sh/managed-by="+name)
 	if err != nil {
 		return errors.Wrap(err, "delete vcluster workloads")
 	}
 
-	cmd.Log.Donef("Successfully paused vcluster %s/%s", cmd.Namespace, args[0])
 	return nil
 }
 
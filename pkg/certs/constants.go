@@ -27,6 +27,8 @@ const (
 	CACertName = "ca.crt"
 	// CAKeyName defines certificate name
 	CAKeyName = "ca.key"
+	// CAChainCertName defines the name of the bundle of an external certificate authority and its chain
+	CAChainCertName = "ca-chain.crt"
 
 	// APIServerCertAndKeyBaseName defines API's server certificate and key base name
 	APIServerCertAndKeyBaseName = "apiserver"
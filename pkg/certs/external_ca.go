@@ -0,0 +1,272 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	cryptorand "crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/klog/v2"
+)
+
+// signerClient is used to send certificate signing requests to external signing endpoints
+var signerClient = &http.Client{Timeout: time.Second * 30}
+
+// CertificateGVR is the group version resource of the cert-manager certificate
+var CertificateGVR = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+
+// ExternalCA is an existing certificate authority that is used to sign the control plane certificates
+// instead of a newly created self-signed one.
+type ExternalCA struct {
+	// Cert is the certificate authority that signs the control plane certificates
+	Cert *x509.Certificate
+	// Key is the private key of the certificate authority
+	Key crypto.Signer
+	// Chain holds the certificates from the issuer of Cert up to the root certificate authority
+	Chain []*x509.Certificate
+}
+
+// ParseExternalCA parses the given certificate authority bundle and key. The bundle has to start with the
+// certificate authority that should be used for signing, followed by its chain. If root is empty, the
+// bundle itself has to end in a self-signed root certificate authority.
+func ParseExternalCA(certPEM, keyPEM, rootPEM []byte) (*ExternalCA, error) {
+	certs, err := certutil.ParseCertsPEM(certPEM)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse ca certificate")
+	}
+
+	privateKey, err := keyutil.ParsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse ca key")
+	}
+	key, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("ca key is not a valid signer")
+	}
+
+	chain := certs[1:]
+	if len(rootPEM) > 0 {
+		roots, err := certutil.ParseCertsPEM(rootPEM)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse root certificate")
+		}
+
+		chain = append(chain, roots...)
+	}
+
+	ca := &ExternalCA{
+		Cert:  certs[0],
+		Key:   key,
+		Chain: chain,
+	}
+	err = ca.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return ca, nil
+}
+
+// Validate makes sure the certificate authority can sign certificates, matches the key and
+// has a complete chain up to a self-signed root certificate authority.
+func (e *ExternalCA) Validate() error {
+	if !e.Cert.IsCA || e.Cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("certificate %s is not a certificate authority that is allowed to sign certificates", e.Cert.Subject.CommonName)
+	} else if publicKey, ok := e.Key.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !publicKey.Equal(e.Cert.PublicKey) {
+		return fmt.Errorf("ca key does not match certificate %s", e.Cert.Subject.CommonName)
+	} else if err := ValidateCertPeriod(e.Cert, 0); err != nil {
+		return errors.Wrapf(err, "certificate %s", e.Cert.Subject.CommonName)
+	}
+
+	// a self-signed ca without a chain is its own root
+	if len(e.Chain) == 0 && isSelfSigned(e.Cert) {
+		return nil
+	}
+
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+	hasRoot := false
+	for _, cert := range e.Chain {
+		if isSelfSigned(cert) {
+			roots.AddCert(cert)
+			hasRoot = true
+		} else {
+			intermediates.AddCert(cert)
+		}
+	}
+	if !hasRoot {
+		return fmt.Errorf("certificate chain of %s is incomplete: no self-signed root certificate authority found", e.Cert.Subject.CommonName)
+	}
+
+	_, err := e.Cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "certificate chain of %s is incomplete", e.Cert.Subject.CommonName)
+	}
+
+	return nil
+}
+
+// WriteToDir writes the certificate authority with the given base name, e.g. ca, front-proxy-ca or etcd/ca,
+// into the certificate directory. CreatePKIAssets will then use it to sign the certificates of this trust
+// domain instead of creating a self-signed certificate authority. Only the issuing certificate authority is
+// written to <baseName>.crt, as this file is used to verify client certificates and must not trust every
+// certificate of the external pki. The chain is written to <baseName>-chain.crt for clients instead.
+func (e *ExternalCA) WriteToDir(pkiDir, baseName string) error {
+	err := os.MkdirAll(filepath.Dir(pathForCert(pkiDir, baseName)), 0755)
+	if err != nil {
+		return err
+	}
+
+	err = WriteKey(pkiDir, baseName, e.Key)
+	if err != nil {
+		return errors.Wrapf(err, "write %s key", baseName)
+	}
+
+	err = certutil.WriteCert(pathForCert(pkiDir, baseName), EncodeCertPEM(e.Cert))
+	if err != nil {
+		return errors.Wrapf(err, "write %s certificate", baseName)
+	}
+
+	if len(e.Chain) > 0 {
+		bundle := EncodeCertPEM(e.Cert)
+		for _, cert := range e.Chain {
+			bundle = append(bundle, EncodeCertPEM(cert)...)
+		}
+
+		err = certutil.WriteCert(pathForCert(pkiDir, baseName+"-chain"), bundle)
+		if err != nil {
+			return errors.Wrapf(err, "write %s certificate chain", baseName)
+		}
+	}
+
+	return nil
+}
+
+// RequestCAFromSigner creates a new key and sends a certificate signing request for an intermediate
+// certificate authority to the given signing endpoint. The endpoint is expected to answer with the
+// PEM encoded certificate followed by its chain.
+func RequestCAFromSigner(ctx context.Context, signerURL, commonName string, rootPEM []byte) (*ExternalCA, error) {
+	key, err := NewPrivateKey(x509.RSA)
+	if err != nil {
+		return nil, err
+	}
+
+	csr, err := x509.CreateCertificateRequest(cryptorand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: commonName},
+	}, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "create certificate request")
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, signerURL, bytes.NewReader(pem.EncodeToMemory(&pem.Block{Type: certutil.CertificateRequestBlockType, Bytes: csr})))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/pkcs10")
+
+	response, err := signerClient.Do(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "send certificate request")
+	}
+	defer response.Body.Close()
+
+	certPEM, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	} else if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("signer %s returned %d: %s", signerURL, response.StatusCode, string(certPEM))
+	}
+
+	keyPEM, err := keyutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseExternalCA(certPEM, keyPEM, rootPEM)
+}
+
+// RequestCAFromCertManager creates a cert-manager certificate for an intermediate certificate authority
+// issued by the given issuer and waits until cert-manager has written it into the target secret.
+func RequestCAFromCertManager(ctx context.Context, kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, namespace, name, issuerKind, issuerName string) (*ExternalCA, error) {
+	certificate := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": CertificateGVR.GroupVersion().String(),
+		"kind":       "Certificate",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+		},
+		"spec": map[string]interface{}{
+			"secretName": name,
+			"commonName": name,
+			"isCA":       true,
+			"usages":     []interface{}{"cert sign", "crl sign", "digital signature"},
+			"privateKey": map[string]interface{}{
+				"algorithm": "RSA",
+				"size":      int64(rsaKeySize),
+			},
+			"issuerRef": map[string]interface{}{
+				"group": CertificateGVR.Group,
+				"kind":  issuerKind,
+				"name":  issuerName,
+			},
+		},
+	}}
+	_, err := dynamicClient.Resource(CertificateGVR).Namespace(namespace).Create(ctx, certificate, metav1.CreateOptions{})
+	if err != nil && !kerrors.IsAlreadyExists(err) {
+		return nil, errors.Wrap(err, "create cert-manager certificate")
+	}
+
+	klog.Infof("Waiting for cert-manager to issue certificate authority %s/%s...", namespace, name)
+	var ca *ExternalCA
+	err = wait.PollImmediate(time.Second*2, time.Minute*5, func() (bool, error) {
+		secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if kerrors.IsNotFound(err) {
+				return false, nil
+			}
+
+			return false, err
+		} else if len(secret.Data["tls.crt"]) == 0 || len(secret.Data["tls.key"]) == 0 {
+			return false, nil
+		}
+
+		ca, err = ParseExternalCA(secret.Data["tls.crt"], secret.Data["tls.key"], secret.Data["ca.crt"])
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "wait for cert-manager certificate")
+	}
+
+	return ca, nil
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
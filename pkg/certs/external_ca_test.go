@@ -0,0 +1,170 @@
+package certs
+
+import (
+	"context"
+	"crypto"
+	cryptorand "crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+)
+
+func newTestRootCA(t *testing.T) (*x509.Certificate, crypto.Signer) {
+	cert, key, err := NewCertificateAuthority(&CertConfig{Config: certutil.Config{CommonName: "root"}})
+	assert.NilError(t, err)
+	return cert, key
+}
+
+func newTestIntermediateCA(t *testing.T, root *x509.Certificate, rootKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+	key, err := NewPrivateKey(x509.RSA)
+	assert.NilError(t, err)
+	cert, err := NewSignedCert(&CertConfig{Config: certutil.Config{CommonName: "intermediate"}}, key, root, rootKey, true)
+	assert.NilError(t, err)
+	return cert, key
+}
+
+func encodeKey(t *testing.T, key crypto.Signer) []byte {
+	out, err := keyutil.MarshalPrivateKeyToPEM(key)
+	assert.NilError(t, err)
+	return out
+}
+
+func TestParseExternalCA(t *testing.T) {
+	root, rootKey := newTestRootCA(t)
+	intermediate, intermediateKey := newTestIntermediateCA(t, root, rootKey)
+
+	// a self-signed ca is its own root
+	ca, err := ParseExternalCA(EncodeCertPEM(root), encodeKey(t, rootKey), nil)
+	assert.NilError(t, err)
+	assert.Equal(t, len(ca.Chain), 0)
+
+	// an intermediate needs its chain, either within the bundle or as separate root
+	_, err = ParseExternalCA(EncodeCertPEM(intermediate), encodeKey(t, intermediateKey), nil)
+	assert.ErrorContains(t, err, "no self-signed root certificate authority found")
+	ca, err = ParseExternalCA(EncodeCertPEM(intermediate), encodeKey(t, intermediateKey), EncodeCertPEM(root))
+	assert.NilError(t, err)
+	assert.Assert(t, ca.Chain[0].Equal(root))
+	_, err = ParseExternalCA(append(EncodeCertPEM(intermediate), EncodeCertPEM(root)...), encodeKey(t, intermediateKey), nil)
+	assert.NilError(t, err)
+
+	// the key has to match the certificate
+	_, err = ParseExternalCA(EncodeCertPEM(root), encodeKey(t, intermediateKey), nil)
+	assert.ErrorContains(t, err, "ca key does not match certificate root")
+}
+
+func TestExternalCAKeepsTrustDomains(t *testing.T) {
+	root, rootKey := newTestRootCA(t)
+	ca, err := ParseExternalCA(EncodeCertPEM(root), encodeKey(t, rootKey), nil)
+	assert.NilError(t, err)
+
+	dir := t.TempDir()
+	assert.NilError(t, ca.WriteToDir(dir, CACertAndKeyBaseName))
+	assert.NilError(t, CreatePKIAssets(newTestInitConfiguration(t, dir)))
+
+	// only the root ca is the external one, front proxy and etcd have their own
+	caCert, err := TryLoadCertFromDisk(dir, CACertAndKeyBaseName)
+	assert.NilError(t, err)
+	assert.Assert(t, caCert.Equal(root))
+	for _, baseName := range []string{FrontProxyCACertAndKeyBaseName, EtcdCACertAndKeyBaseName} {
+		otherCA, err := TryLoadCertFromDisk(dir, baseName)
+		assert.NilError(t, err)
+		assert.Assert(t, !otherCA.Equal(root), baseName)
+	}
+
+	apiServerCert, err := TryLoadCertFromDisk(dir, APIServerCertAndKeyBaseName)
+	assert.NilError(t, err)
+	assert.NilError(t, apiServerCert.CheckSignatureFrom(root))
+}
+
+func TestExternalCAWritesOnlyIssuerAsTrustAnchor(t *testing.T) {
+	root, rootKey := newTestRootCA(t)
+	intermediate, intermediateKey := newTestIntermediateCA(t, root, rootKey)
+	ca, err := ParseExternalCA(EncodeCertPEM(intermediate), encodeKey(t, intermediateKey), EncodeCertPEM(root))
+	assert.NilError(t, err)
+
+	dir := t.TempDir()
+	assert.NilError(t, ca.WriteToDir(dir, CACertAndKeyBaseName))
+
+	// client certificates are only verified against the intermediate, never the corporate root
+	trusted, err := certutil.CertsFromFile(filepath.Join(dir, CACertName))
+	assert.NilError(t, err)
+	assert.Equal(t, len(trusted), 1)
+	assert.Assert(t, trusted[0].Equal(intermediate))
+
+	// the chain is kept separately for clients
+	chain, err := certutil.CertsFromFile(filepath.Join(dir, CAChainCertName))
+	assert.NilError(t, err)
+	assert.Equal(t, len(chain), 2)
+	assert.Assert(t, chain[0].Equal(intermediate))
+	assert.Assert(t, chain[1].Equal(root))
+
+	// the chain is stored in the certs secret if it exists
+	assert.NilError(t, CreatePKIAssets(newTestInitConfiguration(t, dir)))
+	assert.NilError(t, CreateJoinControlPlaneKubeConfigFiles(dir, newTestInitConfiguration(t, dir)))
+	secret := &corev1.Secret{}
+	assert.NilError(t, ReadDirToSecret(dir, secret))
+	assert.DeepEqual(t, secret.Data[CAChainCertName], append(EncodeCertPEM(intermediate), EncodeCertPEM(root)...))
+}
+
+func TestRequestCAFromSigner(t *testing.T) {
+	root, rootKey := newTestRootCA(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		block, _ := pem.Decode(body)
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		raw, err := x509.CreateCertificate(cryptorand.Reader, &x509.Certificate{
+			SerialNumber:          big.NewInt(2),
+			Subject:               pkix.Name{CommonName: csr.Subject.CommonName},
+			NotBefore:             root.NotBefore,
+			NotAfter:              time.Now().Add(time.Hour),
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}, root, csr.PublicKey, rootKey)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		_, _ = w.Write(pem.EncodeToMemory(&pem.Block{Type: certutil.CertificateBlockType, Bytes: raw}))
+	}))
+	defer server.Close()
+
+	ca, err := RequestCAFromSigner(context.Background(), server.URL, "vcluster-ca", EncodeCertPEM(root))
+	assert.NilError(t, err)
+	assert.Equal(t, ca.Cert.Subject.CommonName, "vcluster-ca")
+	assert.NilError(t, ca.Cert.CheckSignatureFrom(root))
+}
+
+func TestRequestCAFromSignerTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	oldTimeout := signerClient.Timeout
+	signerClient.Timeout = time.Millisecond * 100
+	defer func() { signerClient.Timeout = oldTimeout }()
+
+	_, err := RequestCAFromSigner(context.Background(), server.URL, "vcluster-ca", nil)
+	assert.ErrorContains(t, err, "send certificate request")
+}
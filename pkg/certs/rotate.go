@@ -0,0 +1,245 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/klog/v2"
+)
+
+// DefaultRenewBefore is the default duration before expiry in which certificates are renewed
+const DefaultRenewBefore = time.Hour * 24 * 30
+
+// RotateOptions configures how certificates are rotated
+type RotateOptions struct {
+	// RenewBefore renews all certificates that expire within this duration
+	RenewBefore time.Duration
+
+	// RotateCA will create new certificate authorities and re-sign all leaf certificates with them.
+	// The old certificate authorities are kept in the ca bundle, so that existing clients continue
+	// to work until they pick up the new certificates.
+	RotateCA bool
+}
+
+// RotateResult holds the names of the renewed certificates and kubeconfigs
+type RotateResult struct {
+	CAs         []string
+	Certs       []string
+	KubeConfigs []string
+}
+
+// Changed returns true if any certificate or kubeconfig was renewed
+func (r *RotateResult) Changed() bool {
+	return len(r.CAs) > 0 || len(r.Certs) > 0 || len(r.KubeConfigs) > 0
+}
+
+// EtcdChanged returns true if any certificate used by etcd was renewed
+func (r *RotateResult) EtcdChanged() bool {
+	for _, name := range append(r.CAs, r.Certs...) {
+		if name == EtcdCACertAndKeyBaseName || name == EtcdServerCertAndKeyBaseName || name == EtcdPeerCertAndKeyBaseName {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpiresWithin returns true if the given certificate expires within the given duration
+func ExpiresWithin(cert *x509.Certificate, d time.Duration) bool {
+	return time.Now().Add(d).After(cert.NotAfter)
+}
+
+// RotateCerts renews the certificate authorities, leaf certificates and kubeconfigs found
+// in pkiDir that are about to expire. Leaf certificates are renewed with the same subject,
+// SANs and usages as before, so there is no need for the original InitConfiguration.
+func RotateCerts(pkiDir string, options RotateOptions) (*RotateResult, error) {
+	result := &RotateResult{}
+	rotatedCAs := map[string]bool{}
+
+	certTree, err := GetDefaultCertList().AsMap().CertTree()
+	if err != nil {
+		return nil, err
+	}
+
+	for ca, leaves := range certTree {
+		if !CertOrKeyExist(pkiDir, ca.BaseName) {
+			continue
+		}
+
+		caCert, caKey, err := TryLoadCertAndKeyFromDisk(pkiDir, ca.BaseName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "load %s", ca.BaseName)
+		}
+
+		// certificate authorities that were issued by an external pki need to be renewed there
+		if !isSelfSigned(caCert) {
+			if ExpiresWithin(caCert, options.RenewBefore) {
+				klog.Warningf("Certificate authority %s expires at %s and was not created by vcluster, please renew it with your pki", ca.BaseName, caCert.NotAfter.String())
+			}
+		} else if options.RotateCA || ExpiresWithin(caCert, options.RenewBefore) {
+			caCert, caKey, err = rotateCA(pkiDir, ca.BaseName, caCert)
+			if err != nil {
+				return nil, errors.Wrapf(err, "rotate %s", ca.BaseName)
+			}
+
+			rotatedCAs[ca.Name] = true
+			result.CAs = append(result.CAs, ca.BaseName)
+		}
+
+		for _, leaf := range leaves {
+			if !CertOrKeyExist(pkiDir, leaf.BaseName) {
+				continue
+			}
+
+			cert, err := TryLoadCertFromDisk(pkiDir, leaf.BaseName)
+			if err != nil {
+				return nil, errors.Wrapf(err, "load %s", leaf.BaseName)
+			} else if !rotatedCAs[ca.Name] && !ExpiresWithin(cert, options.RenewBefore) {
+				continue
+			}
+
+			newCert, newKey, err := RenewCertificate(cert, caCert, caKey)
+			if err != nil {
+				return nil, errors.Wrapf(err, "renew %s", leaf.BaseName)
+			}
+
+			err = WriteCertAndKey(pkiDir, leaf.BaseName, newCert, newKey)
+			if err != nil {
+				return nil, errors.Wrapf(err, "write %s", leaf.BaseName)
+			}
+
+			klog.Infof("Renewed certificate %s, valid until %s", leaf.BaseName, newCert.NotAfter.String())
+			result.Certs = append(result.Certs, leaf.BaseName)
+		}
+	}
+
+	// kubeconfigs are always signed by the root ca
+	if !CertOrKeyExist(pkiDir, CACertAndKeyBaseName) {
+		return result, nil
+	}
+	caCert, caKey, err := TryLoadCertAndKeyFromDisk(pkiDir, CACertAndKeyBaseName)
+	if err != nil {
+		return nil, errors.Wrap(err, "load root ca")
+	}
+	caBundle, err := os.ReadFile(pathForCert(pkiDir, CACertAndKeyBaseName))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range []string{AdminKubeConfigFileName, ControllerManagerKubeConfigFileName, SchedulerKubeConfigFileName} {
+		renewed, err := renewKubeConfig(filepath.Join(pkiDir, file), caBundle, caCert, caKey, rotatedCAs["ca"], options.RenewBefore)
+		if err != nil {
+			return nil, errors.Wrapf(err, "renew kubeconfig %s", file)
+		} else if renewed {
+			result.KubeConfigs = append(result.KubeConfigs, file)
+		}
+	}
+
+	return result, nil
+}
+
+// RenewCertificate creates a new certificate and key with the same subject, SANs and usages as
+// the given certificate, signed by the given certificate authority.
+func RenewCertificate(cert *x509.Certificate, caCert *x509.Certificate, caKey crypto.Signer) (*x509.Certificate, crypto.Signer, error) {
+	return NewCertAndKey(caCert, caKey, &CertConfig{
+		Config: certutil.Config{
+			CommonName:   cert.Subject.CommonName,
+			Organization: cert.Subject.Organization,
+			AltNames: certutil.AltNames{
+				DNSNames: cert.DNSNames,
+				IPs:      cert.IPAddresses,
+			},
+			Usages: cert.ExtKeyUsage,
+		},
+		PublicKeyAlgorithm: cert.PublicKeyAlgorithm,
+	})
+}
+
+// rotateCA creates a new certificate authority with the same common name and writes it together with
+// the old certificate authority as bundle, so that certificates signed by the old one stay valid.
+func rotateCA(pkiDir, baseName string, oldCA *x509.Certificate) (*x509.Certificate, crypto.Signer, error) {
+	caCert, caKey, err := NewCertificateAuthority(&CertConfig{
+		Config: certutil.Config{
+			CommonName: oldCA.Subject.CommonName,
+		},
+		PublicKeyAlgorithm: oldCA.PublicKeyAlgorithm,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = WriteKey(pkiDir, baseName, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the new ca needs to be the first one, as this is the one used for signing
+	bundle := EncodeCertPEM(caCert)
+	if !ExpiresWithin(oldCA, 0) {
+		bundle = append(bundle, EncodeCertPEM(oldCA)...)
+	}
+	err = certutil.WriteCert(pathForCert(pkiDir, baseName), bundle)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	klog.Infof("Rotated certificate authority %s, valid until %s", baseName, caCert.NotAfter.String())
+	return caCert, caKey, nil
+}
+
+// renewKubeConfig renews the client certificate embedded in the kubeconfig at the given path
+func renewKubeConfig(path string, caBundle []byte, caCert *x509.Certificate, caKey crypto.Signer, force bool, renewBefore time.Duration) (bool, error) {
+	config, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	renewed := false
+	for name, authInfo := range config.AuthInfos {
+		if len(authInfo.ClientCertificateData) == 0 {
+			continue
+		}
+
+		certs, err := certutil.ParseCertsPEM(authInfo.ClientCertificateData)
+		if err != nil {
+			return false, errors.Wrapf(err, "parse client certificate of user %s", name)
+		} else if !force && !ExpiresWithin(certs[0], renewBefore) {
+			continue
+		}
+
+		clientCert, clientKey, err := RenewCertificate(certs[0], caCert, caKey)
+		if err != nil {
+			return false, err
+		}
+		encodedKey, err := keyutil.MarshalPrivateKeyToPEM(clientKey)
+		if err != nil {
+			return false, err
+		}
+
+		authInfo.ClientCertificateData = EncodeCertPEM(clientCert)
+		authInfo.ClientKeyData = encodedKey
+		renewed = true
+	}
+	if !renewed {
+		return false, nil
+	}
+
+	for _, cluster := range config.Clusters {
+		if len(cluster.CertificateAuthorityData) > 0 {
+			cluster.CertificateAuthorityData = caBundle
+		}
+	}
+
+	klog.Infof("Renewed kubeconfig %s", filepath.Base(path))
+	return true, WriteToDisk(path, config)
+}
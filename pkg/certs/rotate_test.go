@@ -0,0 +1,103 @@
+package certs
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+func createTestPKI(t *testing.T) string {
+	dir := t.TempDir()
+	cfg := newTestInitConfiguration(t, dir)
+	assert.NilError(t, CreatePKIAssets(cfg))
+	assert.NilError(t, CreateJoinControlPlaneKubeConfigFiles(dir, cfg))
+	return dir
+}
+
+func newTestInitConfiguration(t *testing.T, dir string) *InitConfiguration {
+	cfg, err := SetInitDynamicDefaults()
+	assert.NilError(t, err)
+
+	cfg.ClusterName = "kubernetes"
+	cfg.NodeRegistration.Name = "vcluster-api"
+	cfg.Etcd.Local = &LocalEtcd{}
+	cfg.Networking.ServiceSubnet = "10.96.0.0/12"
+	cfg.Networking.DNSDomain = "cluster.local"
+	cfg.ControlPlaneEndpoint = "vcluster-api"
+	cfg.CertificatesDir = dir
+	cfg.LocalAPIEndpoint.AdvertiseAddress = "0.0.0.0"
+	cfg.LocalAPIEndpoint.BindPort = 443
+	return cfg
+}
+
+func TestRotateCerts(t *testing.T) {
+	dir := createTestPKI(t)
+
+	// nothing expires soon
+	result, err := RotateCerts(dir, RotateOptions{RenewBefore: DefaultRenewBefore})
+	assert.NilError(t, err)
+	assert.Assert(t, !result.Changed())
+
+	// replace the api server certificate with one that expires within the next hour
+	oldCert, err := TryLoadCertFromDisk(dir, APIServerCertAndKeyBaseName)
+	assert.NilError(t, err)
+	caCert, caKey, err := TryLoadCertAndKeyFromDisk(dir, CACertAndKeyBaseName)
+	assert.NilError(t, err)
+	notAfter := time.Now().Add(time.Hour)
+	expiringCert, expiringKey, err := NewCertAndKey(caCert, caKey, &CertConfig{
+		Config: certutil.Config{
+			CommonName: oldCert.Subject.CommonName,
+			AltNames:   certutil.AltNames{DNSNames: oldCert.DNSNames, IPs: oldCert.IPAddresses},
+			Usages:     oldCert.ExtKeyUsage,
+		},
+		NotAfter: &notAfter,
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, WriteCertAndKey(dir, APIServerCertAndKeyBaseName, expiringCert, expiringKey))
+
+	result, err = RotateCerts(dir, RotateOptions{RenewBefore: DefaultRenewBefore})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, result.CAs, []string(nil))
+	assert.DeepEqual(t, result.Certs, []string{APIServerCertAndKeyBaseName})
+	assert.Assert(t, !result.EtcdChanged())
+
+	// the renewed certificate keeps subject and sans
+	newCert, err := TryLoadCertFromDisk(dir, APIServerCertAndKeyBaseName)
+	assert.NilError(t, err)
+	assert.Assert(t, !ExpiresWithin(newCert, DefaultRenewBefore))
+	assert.Equal(t, newCert.Subject.CommonName, oldCert.Subject.CommonName)
+	assert.DeepEqual(t, newCert.DNSNames, oldCert.DNSNames)
+	assert.NilError(t, newCert.CheckSignatureFrom(caCert))
+}
+
+func TestRotateCA(t *testing.T) {
+	dir := createTestPKI(t)
+	oldCA, err := TryLoadCertFromDisk(dir, CACertAndKeyBaseName)
+	assert.NilError(t, err)
+
+	result, err := RotateCerts(dir, RotateOptions{RenewBefore: DefaultRenewBefore, RotateCA: true})
+	assert.NilError(t, err)
+	sort.Strings(result.CAs)
+	assert.DeepEqual(t, result.CAs, []string{CACertAndKeyBaseName, EtcdCACertAndKeyBaseName, FrontProxyCACertAndKeyBaseName})
+	assert.Assert(t, result.EtcdChanged())
+	assert.Equal(t, len(result.KubeConfigs), 3)
+
+	// the bundle contains the new ca first and the old one second
+	bundle, err := os.ReadFile(filepath.Join(dir, CACertName))
+	assert.NilError(t, err)
+	cas, err := certutil.ParseCertsPEM(bundle)
+	assert.NilError(t, err)
+	assert.Equal(t, len(cas), 2)
+	assert.Assert(t, !cas[0].Equal(oldCA))
+	assert.Assert(t, cas[1].Equal(oldCA))
+
+	// the leaves are signed by the new ca
+	cert, err := TryLoadCertFromDisk(dir, APIServerCertAndKeyBaseName)
+	assert.NilError(t, err)
+	assert.NilError(t, cert.CheckSignatureFrom(cas[0]))
+}
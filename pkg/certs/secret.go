@@ -0,0 +1,311 @@
+package certs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// RestartedAtAnnotation is set on the pod templates of the control plane components to restart them
+const RestartedAtAnnotation = "vcluster.loft.sh/restartedAt"
+
+// SecretFiles maps the files within the certificate directory to the keys within the certs secret
+var SecretFiles = map[string]string{
+	AdminKubeConfigFileName:             AdminKubeConfigFileName,
+	ControllerManagerKubeConfigFileName: ControllerManagerKubeConfigFileName,
+	SchedulerKubeConfigFileName:         SchedulerKubeConfigFileName,
+
+	APIServerCertName: APIServerCertName,
+	APIServerKeyName:  APIServerKeyName,
+
+	APIServerEtcdClientCertName: APIServerEtcdClientCertName,
+	APIServerEtcdClientKeyName:  APIServerEtcdClientKeyName,
+
+	APIServerKubeletClientCertName: APIServerKubeletClientCertName,
+	APIServerKubeletClientKeyName:  APIServerKubeletClientKeyName,
+
+	CACertName: CACertName,
+	CAKeyName:  CAKeyName,
+
+	FrontProxyCACertName: FrontProxyCACertName,
+	FrontProxyCAKeyName:  FrontProxyCAKeyName,
+
+	FrontProxyClientCertName: FrontProxyClientCertName,
+	FrontProxyClientKeyName:  FrontProxyClientKeyName,
+
+	ServiceAccountPrivateKeyName: ServiceAccountPrivateKeyName,
+	ServiceAccountPublicKeyName:  ServiceAccountPublicKeyName,
+
+	EtcdCACertName: strings.ReplaceAll(EtcdCACertName, "/", "-"),
+	EtcdCAKeyName:  strings.ReplaceAll(EtcdCAKeyName, "/", "-"),
+
+	EtcdHealthcheckClientCertName: strings.ReplaceAll(EtcdHealthcheckClientCertName, "/", "-"),
+	EtcdHealthcheckClientKeyName:  strings.ReplaceAll(EtcdHealthcheckClientKeyName, "/", "-"),
+
+	EtcdPeerCertName: strings.ReplaceAll(EtcdPeerCertName, "/", "-"),
+	EtcdPeerKeyName:  strings.ReplaceAll(EtcdPeerKeyName, "/", "-"),
+
+	EtcdServerCertName: strings.ReplaceAll(EtcdServerCertName, "/", "-"),
+	EtcdServerKeyName:  strings.ReplaceAll(EtcdServerKeyName, "/", "-"),
+}
+
+// OptionalSecretFiles maps the files within the certificate directory that only exist in some setups,
+// e.g. the chain of an external certificate authority, to the keys within the certs secret
+var OptionalSecretFiles = map[string]string{
+	CAChainCertName: CAChainCertName,
+}
+
+// SecretName returns the name of the certs secret for the given vcluster
+func SecretName(prefix string) string {
+	return prefix + "-certs"
+}
+
+// WriteSecretToDir writes the contents of the certs secret into the given certificate directory
+func WriteSecretToDir(secret *corev1.Secret, dir string) error {
+	for fileName, key := range allSecretFiles() {
+		data, ok := secret.Data[key]
+		if !ok {
+			continue
+		}
+
+		path := filepath.Join(dir, fileName)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(path, data, 0600)
+		if err != nil {
+			return errors.Wrap(err, "write "+fileName)
+		}
+	}
+
+	return nil
+}
+
+// ReadDirToSecret reads the files of the certificate directory into the given secret
+func ReadDirToSecret(dir string, secret *corev1.Secret) error {
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	for fileName, key := range SecretFiles {
+		data, err := os.ReadFile(filepath.Join(dir, fileName))
+		if err != nil {
+			return errors.Wrap(err, "read "+fileName)
+		}
+
+		secret.Data[key] = data
+	}
+
+	for fileName, key := range OptionalSecretFiles {
+		data, err := os.ReadFile(filepath.Join(dir, fileName))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return errors.Wrap(err, "read "+fileName)
+		}
+
+		secret.Data[key] = data
+	}
+
+	return nil
+}
+
+func allSecretFiles() map[string]string {
+	files := map[string]string{}
+	for fileName, key := range SecretFiles {
+		files[fileName] = key
+	}
+	for fileName, key := range OptionalSecretFiles {
+		files[fileName] = key
+	}
+
+	return files
+}
+
+// RotateSecret renews the certificates stored in the certs secret of the given vcluster and
+// updates the secret if anything was renewed. The certificates are written to a temporary directory
+// within certificateDir, or the default temporary directory if certificateDir is empty, which is
+// removed afterwards.
+func RotateSecret(ctx context.Context, kubeClient kubernetes.Interface, namespace, prefix, certificateDir string, options RotateOptions) (*RotateResult, error) {
+	secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, SecretName(prefix), metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "get certs secret")
+	}
+
+	tempDir, cleanup, err := createTempDir(certificateDir)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	return rotateSecret(ctx, kubeClient, secret, tempDir, options)
+}
+
+// createTempDir creates a temporary directory within parent. The returned cleanup func only removes
+// the directories that were created, but never parent itself if it existed before.
+func createTempDir(parent string) (string, func(), error) {
+	removeDir := ""
+	if parent != "" {
+		_, err := os.Stat(parent)
+		if os.IsNotExist(err) {
+			err = os.MkdirAll(parent, 0755)
+			if err != nil {
+				return "", nil, err
+			}
+
+			removeDir = parent
+		} else if err != nil {
+			return "", nil, err
+		}
+	}
+
+	dir, err := os.MkdirTemp(parent, "rotate")
+	if err != nil {
+		if removeDir != "" {
+			_ = os.RemoveAll(removeDir)
+		}
+
+		return "", nil, err
+	}
+	if removeDir == "" {
+		removeDir = dir
+	}
+
+	return dir, func() {
+		_ = os.RemoveAll(removeDir)
+	}, nil
+}
+
+func rotateSecret(ctx context.Context, kubeClient kubernetes.Interface, secret *corev1.Secret, certificateDir string, options RotateOptions) (*RotateResult, error) {
+	err := WriteSecretToDir(secret, certificateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := RotateCerts(certificateDir, options)
+	if err != nil {
+		return nil, err
+	} else if !result.Changed() {
+		return result, nil
+	}
+
+	err = ReadDirToSecret(certificateDir, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = kubeClient.CoreV1().Secrets(secret.Namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "update certs secret")
+	}
+
+	return result, nil
+}
+
+// RestartControlPlane restarts the control plane components of the given vcluster one after another
+// in the order etcd, api server, controller manager, scheduler and syncer and waits for each one
+// to become ready before the next one is restarted.
+func RestartControlPlane(ctx context.Context, kubeClient kubernetes.Interface, namespace, prefix string, restartEtcd bool) error {
+	restartedAt := time.Now().Format(time.RFC3339)
+	patch := []byte(`{"spec":{"template":{"metadata":{"annotations":{"` + RestartedAtAnnotation + `":"` + restartedAt + `"}}}}}`)
+
+	if restartEtcd {
+		klog.Infof("Restart etcd %s/%s-etcd", namespace, prefix)
+		_, err := kubeClient.AppsV1().StatefulSets(namespace).Patch(ctx, prefix+"-etcd", types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			return errors.Wrap(err, "restart etcd")
+		} else if err == nil {
+			err = waitForStatefulSet(ctx, kubeClient, namespace, prefix+"-etcd")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	components := []string{prefix + "-api", prefix + "-controller", prefix + "-scheduler"}
+	for _, name := range components {
+		klog.Infof("Restart %s/%s", namespace, name)
+		_, err := kubeClient.AppsV1().Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+		if err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+
+			return errors.Wrapf(err, "restart %s", name)
+		}
+
+		err = waitForDeployment(ctx, kubeClient, namespace, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the syncer might be the one executing this and is restarted last, so make sure every other
+	// component, including the ones that were not restarted, is rolled out before
+	err := waitForStatefulSet(ctx, kubeClient, namespace, prefix+"-etcd")
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	for _, name := range components {
+		err = waitForDeployment(ctx, kubeClient, namespace, name)
+		if err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	klog.Infof("Restart %s/%s", namespace, prefix)
+	_, err = kubeClient.AppsV1().Deployments(namespace).Patch(ctx, prefix, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return errors.Wrapf(err, "restart %s", prefix)
+	}
+
+	return nil
+}
+
+func waitForStatefulSet(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) error {
+	return wait.PollImmediate(time.Second*2, time.Minute*10, func() (bool, error) {
+		statefulSet, err := kubeClient.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		replicas := int32(1)
+		if statefulSet.Spec.Replicas != nil {
+			replicas = *statefulSet.Spec.Replicas
+		}
+		return statefulSet.Status.ObservedGeneration >= statefulSet.Generation &&
+			statefulSet.Status.UpdatedReplicas == replicas &&
+			statefulSet.Status.ReadyReplicas == replicas, nil
+	})
+}
+
+func waitForDeployment(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) error {
+	return wait.PollImmediate(time.Second*2, time.Minute*10, func() (bool, error) {
+		deployment, err := kubeClient.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+		return deployment.Status.ObservedGeneration >= deployment.Generation &&
+			deployment.Status.UpdatedReplicas == replicas &&
+			deployment.Status.AvailableReplicas == replicas &&
+			deployment.Status.Replicas == replicas, nil
+	})
+}
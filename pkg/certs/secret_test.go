@@ -0,0 +1,111 @@
+package certs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRotateSecret(t *testing.T) {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: SecretName("vcluster"), Namespace: "test"}}
+	assert.NilError(t, ReadDirToSecret(createTestPKI(t), secret))
+	kubeClient := fake.NewSimpleClientset(secret)
+
+	// the given certificate directory and its contents are kept
+	certificateDir := t.TempDir()
+	assert.NilError(t, os.WriteFile(filepath.Join(certificateDir, "keep"), []byte("keep"), 0600))
+
+	result, err := RotateSecret(context.Background(), kubeClient, "test", "vcluster", certificateDir, RotateOptions{RenewBefore: time.Hour * 24 * 365 * 20})
+	assert.NilError(t, err)
+	assert.Assert(t, len(result.Certs) > 0)
+
+	entries, err := os.ReadDir(certificateDir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 1)
+	assert.Equal(t, entries[0].Name(), "keep")
+
+	// the secret was updated with the renewed certificates
+	updated, err := kubeClient.CoreV1().Secrets("test").Get(context.Background(), SecretName("vcluster"), metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Assert(t, string(updated.Data[APIServerCertName]) != string(secret.Data[APIServerCertName]))
+}
+
+func TestCreateTempDir(t *testing.T) {
+	// a parent that does not exist yet is created and removed again
+	parent := filepath.Join(t.TempDir(), "certs")
+	dir, cleanup, err := createTempDir(parent)
+	assert.NilError(t, err)
+	assert.Equal(t, filepath.Dir(dir), parent)
+	cleanup()
+	_, err = os.Stat(parent)
+	assert.Assert(t, os.IsNotExist(err))
+
+	// an existing parent is kept
+	parent = t.TempDir()
+	dir, cleanup, err = createTempDir(parent)
+	assert.NilError(t, err)
+	cleanup()
+	_, err = os.Stat(dir)
+	assert.Assert(t, os.IsNotExist(err))
+	_, err = os.Stat(parent)
+	assert.NilError(t, err)
+}
+
+func TestRestartControlPlane(t *testing.T) {
+	deployment := func(name string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+			Status:     appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+		}
+	}
+	etcd := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "vcluster-etcd", Namespace: "test"},
+		Status:     appsv1.StatefulSetStatus{UpdatedReplicas: 1, ReadyReplicas: 1},
+	}
+	kubeClient := fake.NewSimpleClientset(etcd, deployment("vcluster-api"), deployment("vcluster-controller"), deployment("vcluster"))
+
+	// etcd is not restarted, the missing scheduler is skipped
+	assert.NilError(t, RestartControlPlane(context.Background(), kubeClient, "test", "vcluster", false))
+
+	// the syncer is restarted last, after the rollout of every other component was checked
+	actions := []string{}
+	for _, action := range kubeClient.Actions() {
+		actions = append(actions, action.GetVerb()+" "+action.GetResource().Resource+"/"+nameOf(action))
+	}
+	assert.DeepEqual(t, actions, []string{
+		"patch deployments/vcluster-api",
+		"get deployments/vcluster-api",
+		"patch deployments/vcluster-controller",
+		"get deployments/vcluster-controller",
+		"patch deployments/vcluster-scheduler",
+		"get statefulsets/vcluster-etcd",
+		"get deployments/vcluster-api",
+		"get deployments/vcluster-controller",
+		"get deployments/vcluster-scheduler",
+		"patch deployments/vcluster",
+	})
+
+	syncer, err := kubeClient.AppsV1().Deployments("test").Get(context.Background(), "vcluster", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Assert(t, syncer.Spec.Template.Annotations[RestartedAtAnnotation] != "")
+}
+
+func nameOf(action k8stesting.Action) string {
+	switch action := action.(type) {
+	case k8stesting.GetAction:
+		return action.GetName()
+	case k8stesting.PatchAction:
+		return action.GetName()
+	}
+
+	return ""
+}
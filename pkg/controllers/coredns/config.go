@@ -0,0 +1,307 @@
+package coredns
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/loft-sh/vcluster/pkg/dns"
+	"github.com/pkg/errors"
+)
+
+const (
+	// CorefileKey is the key of the Corefile within the CoreDNS ConfigMap
+	CorefileKey = "Corefile"
+
+	// resolvConf is used to find the host cluster DNS, as the syncer runs within the host cluster
+	resolvConf = dns.ResolvConf
+)
+
+// Config is the declarative CoreDNS configuration of the virtual cluster. If it is set, the syncer
+// owns the Corefile and reverts changes that are made within the virtual cluster.
+type Config struct {
+	// Upstreams are the servers non cluster queries are forwarded to, defaults to the forward of the chart
+	Upstreams []string `json:"upstreams,omitempty"`
+	// StubDomains forward queries of a zone to the given servers
+	StubDomains []StubDomain `json:"stubDomains,omitempty"`
+	// HostForwarding makes zones of the host cluster DNS reachable under a different zone
+	HostForwarding []HostForwarding `json:"hostForwarding,omitempty"`
+	// Rewrites rewrite query names before they are resolved
+	Rewrites []Rewrite `json:"rewrites,omitempty"`
+	// Hosts are additional static host entries
+	Hosts []Host `json:"hosts,omitempty"`
+	// HostDNS is the address of the host cluster DNS, defaults to the nameserver of the syncer
+	HostDNS string `json:"hostDNS,omitempty"`
+}
+
+// StubDomain forwards all queries of the zone to the given servers
+type StubDomain struct {
+	Zone    string   `json:"zone"`
+	Servers []string `json:"servers"`
+}
+
+// HostForwarding forwards all queries of the zone to the host cluster DNS, e.g. with zone host.local
+// and host zone cluster.local, my-service.my-namespace.svc.host.local resolves to the host service
+// my-service.my-namespace.svc.cluster.local
+type HostForwarding struct {
+	Zone     string `json:"zone"`
+	HostZone string `json:"hostZone,omitempty"`
+}
+
+// Rewrite rewrites the query name to the target. Name and target may contain a single * wildcard,
+// e.g. *.db.internal to *.databases.svc.cluster.local
+type Rewrite struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+}
+
+// Host is a static host entry
+type Host struct {
+	IP        string   `json:"ip"`
+	Hostnames []string `json:"hostnames"`
+}
+
+// LoadConfig reads and validates the CoreDNS config from the given yaml file
+func LoadConfig(path string) (*Config, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	out, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	err = yaml.Unmarshal(out, config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse coredns config %s", path)
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return nil, errors.Wrap(err, "coredns config")
+	}
+
+	return config, nil
+}
+
+var zoneRegEx = regexp.MustCompile(`^([a-z0-9]([-a-z0-9]*[a-z0-9])?\.)*[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Validate checks the config, as an invalid Corefile would break DNS within the virtual cluster
+func (c *Config) Validate() error {
+	for _, stubDomain := range c.StubDomains {
+		if !zoneRegEx.MatchString(stubDomain.Zone) {
+			return fmt.Errorf("stub domain: invalid zone %q", stubDomain.Zone)
+		} else if len(stubDomain.Servers) == 0 {
+			return fmt.Errorf("stub domain %s: at least one server is required", stubDomain.Zone)
+		}
+		for _, server := range stubDomain.Servers {
+			if !isServer(server) {
+				return fmt.Errorf("stub domain %s: invalid server %q", stubDomain.Zone, server)
+			}
+		}
+	}
+	for _, hostForwarding := range c.HostForwarding {
+		if !zoneRegEx.MatchString(hostForwarding.Zone) {
+			return fmt.Errorf("host forwarding: invalid zone %q", hostForwarding.Zone)
+		} else if hostForwarding.HostZone != "" && !zoneRegEx.MatchString(hostForwarding.HostZone) {
+			return fmt.Errorf("host forwarding %s: invalid host zone %q", hostForwarding.Zone, hostForwarding.HostZone)
+		}
+	}
+	for _, rewrite := range c.Rewrites {
+		if !isRewriteName(rewrite.Name) || !isRewriteName(rewrite.Target) {
+			return fmt.Errorf("rewrite: invalid name %q or target %q", rewrite.Name, rewrite.Target)
+		} else if strings.Count(rewrite.Name, "*") != strings.Count(rewrite.Target, "*") {
+			return fmt.Errorf("rewrite %s: name and target need the same number of wildcards", rewrite.Name)
+		}
+	}
+	for _, host := range c.Hosts {
+		if net.ParseIP(host.IP) == nil {
+			return fmt.Errorf("hosts: invalid ip %q", host.IP)
+		}
+		for _, hostname := range host.Hostnames {
+			if !zoneRegEx.MatchString(hostname) {
+				return fmt.Errorf("hosts %s: invalid hostname %q", host.IP, hostname)
+			}
+		}
+	}
+	for _, upstream := range c.Upstreams {
+		if upstream != resolvConf && !isServer(upstream) {
+			return fmt.Errorf("invalid upstream %q", upstream)
+		}
+	}
+	if c.HostDNS != "" && !isServer(c.HostDNS) {
+		return fmt.Errorf("invalid host dns %q", c.HostDNS)
+	}
+
+	return nil
+}
+
+func isServer(server string) bool {
+	host := server
+	if h, _, err := net.SplitHostPort(server); err == nil {
+		host = h
+	}
+
+	return net.ParseIP(host) != nil
+}
+
+func isRewriteName(name string) bool {
+	return strings.Count(name, "*") <= 1 && zoneRegEx.MatchString(strings.Replace(name, "*", "x", 1))
+}
+
+// Corefile renders the Corefile based on the given Corefile of the chart, so the defaults of the
+// chart and a custom coredns.config are kept. Rewrites and upstreams are applied to the root server
+// block and stub domains and host forwardings are added as separate server blocks.
+func (c *Config) Corefile(baseCorefile string) (string, error) {
+	lines := strings.Split(strings.TrimRight(baseCorefile, "\n"), "\n")
+	start, end := findServerBlock(lines, ".:1053")
+	if start == -1 {
+		return "", fmt.Errorf("the Corefile doesn't contain a .:1053 server block")
+	}
+
+	buffer := &strings.Builder{}
+	for _, line := range lines[:start+1] {
+		buffer.WriteString(line + "\n")
+	}
+	for _, rewrite := range c.Rewrites {
+		writeRewrite(buffer, rewrite.Name, rewrite.Target)
+	}
+	forwardWritten := false
+	for i := start + 1; i < end; i++ {
+		if len(c.Upstreams) == 0 || !strings.HasPrefix(strings.TrimSpace(lines[i]), "forward ") {
+			buffer.WriteString(lines[i] + "\n")
+			continue
+		}
+
+		// replace the forward directive of the chart including its options
+		i += blockLength(lines[i:]) - 1
+		if !forwardWritten {
+			writeForward(buffer, c.Upstreams)
+			forwardWritten = true
+		}
+	}
+	if len(c.Upstreams) > 0 && !forwardWritten {
+		writeForward(buffer, c.Upstreams)
+	}
+	for _, line := range lines[end:] {
+		buffer.WriteString(line + "\n")
+	}
+
+	for _, stubDomain := range c.StubDomains {
+		fmt.Fprintf(buffer, "\n%s:1053 {\n", stubDomain.Zone)
+		buffer.WriteString("    errors\n")
+		buffer.WriteString("    cache 30\n")
+		buffer.WriteString("    loop\n")
+		fmt.Fprintf(buffer, "    forward . %s\n", strings.Join(stubDomain.Servers, " "))
+		buffer.WriteString("}\n")
+	}
+
+	if len(c.HostForwarding) > 0 {
+		hostDNS := c.HostDNS
+		if hostDNS == "" {
+			var err error
+			hostDNS, err = dns.Nameserver(resolvConf)
+			if err != nil {
+				return "", errors.Wrap(err, "find host cluster dns")
+			}
+		}
+
+		for _, hostForwarding := range c.HostForwarding {
+			hostZone := hostForwarding.HostZone
+			if hostZone == "" {
+				hostZone = "cluster.local"
+			}
+
+			fmt.Fprintf(buffer, "\n%s:1053 {\n", hostForwarding.Zone)
+			buffer.WriteString("    errors\n")
+			buffer.WriteString("    cache 30\n")
+			buffer.WriteString("    loop\n")
+			writeRewrite(buffer, "*."+hostForwarding.Zone, "*."+hostZone)
+			fmt.Fprintf(buffer, "    forward . %s\n", hostDNS)
+			buffer.WriteString("}\n")
+		}
+	}
+
+	return buffer.String(), nil
+}
+
+// findServerBlock returns the first and the closing line of the given server block
+func findServerBlock(lines []string, server string) (int, int) {
+	for i, line := range lines {
+		fields := strings.Fields(stripComment(line))
+		if len(fields) == 2 && fields[0] == server && fields[1] == "{" {
+			return i, i + blockLength(lines[i:]) - 1
+		}
+	}
+
+	return -1, -1
+}
+
+// blockLength returns the number of lines of the directive starting at the first line, which
+// includes all lines up to the closing brace if the directive opens a block
+func blockLength(lines []string) int {
+	depth := 0
+	for i, line := range lines {
+		line = stripComment(line)
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if depth <= 0 {
+			return i + 1
+		}
+	}
+
+	return len(lines)
+}
+
+func stripComment(line string) string {
+	if index := strings.Index(line, "#"); index != -1 {
+		return line[:index]
+	}
+
+	return line
+}
+
+func writeForward(buffer *strings.Builder, upstreams []string) {
+	if len(upstreams) > 1 {
+		fmt.Fprintf(buffer, "    forward . %s {\n", strings.Join(upstreams, " "))
+		buffer.WriteString("      policy sequential\n")
+		buffer.WriteString("    }\n")
+	} else {
+		fmt.Fprintf(buffer, "    forward . %s\n", upstreams[0])
+	}
+}
+
+// writeRewrite writes a rewrite rule that also rewrites the answer back to the queried name,
+// as clients would otherwise reject the response
+func writeRewrite(buffer *strings.Builder, name, target string) {
+	buffer.WriteString("    rewrite stop {\n")
+	fmt.Fprintf(buffer, "      name regex %s %s\n", rewritePattern(name), rewriteReplacement(target))
+	fmt.Fprintf(buffer, "      answer name %s %s\n", rewritePattern(target), rewriteReplacement(name))
+	buffer.WriteString("    }\n")
+}
+
+func rewritePattern(name string) string {
+	return "^" + strings.Replace(regexp.QuoteMeta(name), `\*`, "(.*)", 1) + `\.?$`
+}
+
+func rewriteReplacement(name string) string {
+	return strings.Replace(name, "*", "{1}", 1)
+}
+
+// HostsEntries returns the static host entries in the hosts file format
+func (c *Config) HostsEntries() []string {
+	entries := []string{}
+	for _, host := range c.Hosts {
+		if len(host.Hostnames) == 0 {
+			continue
+		}
+
+		entries = append(entries, fmt.Sprintf("%s %s", host.IP, strings.Join(host.Hostnames, " ")))
+	}
+
+	return entries
+}
@@ -0,0 +1,102 @@
+package coredns
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+// chartCorefile is the Corefile of the chart with isolation enabled
+const chartCorefile = `.:1053 {
+    log
+    errors
+    health
+    ready
+    kubernetes cluster.local in-addr.arpa ip6.arpa {
+      pods insecure
+      fallthrough in-addr.arpa ip6.arpa
+    }
+    hosts /etc/coredns/NodeHosts {
+      ttl 60
+      reload 15s
+      fallthrough
+    }
+    prometheus :9153
+    forward . /etc/resolv.conf 8.8.8.8 {
+      policy sequential
+    }
+    cache 30
+    loop
+    reload
+    loadbalance
+}
+
+import /etc/coredns/custom/*.server
+`
+
+func TestConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+stubDomains:
+- zone: corp.example.com
+  servers: ["10.0.0.10", "10.0.0.11:5353"]
+hostForwarding:
+- zone: host.local
+hostDNS: 10.96.0.10
+rewrites:
+- name: "*.db.internal"
+  target: "*.databases.svc.cluster.local"
+hosts:
+- ip: 10.0.0.5
+  hostnames: ["registry.internal"]`), 0600)
+	assert.NilError(t, err)
+
+	config, err := LoadConfig(path)
+	assert.NilError(t, err)
+
+	corefile, err := config.Corefile(chartCorefile)
+	assert.NilError(t, err)
+	for _, expected := range []string{
+		".:1053 {\n    rewrite stop {\n",
+		"    log\n",
+		"    kubernetes cluster.local in-addr.arpa ip6.arpa {\n",
+		"    forward . /etc/resolv.conf 8.8.8.8 {\n      policy sequential\n    }\n",
+		"      name regex ^(.*)\\.db\\.internal\\.?$ {1}.databases.svc.cluster.local\n",
+		"      answer name ^(.*)\\.databases\\.svc\\.cluster\\.local\\.?$ {1}.db.internal\n",
+		"import /etc/coredns/custom/*.server\n",
+		"corp.example.com:1053 {\n    errors\n    cache 30\n    loop\n    forward . 10.0.0.10 10.0.0.11:5353\n}\n",
+		"      name regex ^(.*)\\.host\\.local\\.?$ {1}.cluster.local\n",
+		"    forward . 10.96.0.10\n",
+	} {
+		assert.Assert(t, strings.Contains(corefile, expected), "expected %q in Corefile:\n%s", expected, corefile)
+	}
+	assert.Equal(t, strings.Count(corefile, "    loop\n"), 3, "expected loop in every server block:\n%s", corefile)
+
+	// upstreams replace the forward of the chart
+	corefile, err = (&Config{Upstreams: []string{"10.0.0.53"}}).Corefile(chartCorefile)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(corefile, "    prometheus :9153\n    forward . 10.0.0.53\n    cache 30\n"), "unexpected Corefile:\n%s", corefile)
+	assert.Assert(t, !strings.Contains(corefile, "8.8.8.8"), "unexpected Corefile:\n%s", corefile)
+
+	// a custom Corefile needs the root server block
+	_, err = (&Config{}).Corefile("example.org:1053 {\n    forward . 10.0.0.53\n}\n")
+	assert.Assert(t, err != nil)
+	assert.DeepEqual(t, config.HostsEntries(), []string{"10.0.0.5 registry.internal"})
+
+	// invalid configs
+	for _, invalid := range []string{
+		`stubDomains: [{zone: "corp.example.com"}]`,
+		`stubDomains: [{zone: "corp.example.com", servers: ["dns.example.com"]}]`,
+		`hostForwarding: [{zone: "host local"}]`,
+		`rewrites: [{name: "*.db.internal", target: "db.svc.cluster.local"}]`,
+		`hosts: [{ip: "10.0.0", hostnames: ["registry.internal"]}]`,
+	} {
+		err = os.WriteFile(path, []byte(invalid), 0600)
+		assert.NilError(t, err)
+		_, err = LoadConfig(path)
+		assert.Assert(t, err != nil, "expected error for %s", invalid)
+	}
+}
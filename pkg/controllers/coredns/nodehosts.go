@@ -30,6 +30,11 @@ const (
 type CoreDNSNodeHostsReconciler struct {
 	client.Client
 	Log loghelper.Logger
+
+	// Config is the optional declarative CoreDNS config, if set the Corefile is managed as well
+	Config *Config
+	// BaseCorefile is the Corefile of the chart the managed Corefile is based on
+	BaseCorefile string
 }
 
 func (r *CoreDNSNodeHostsReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -39,6 +44,15 @@ func (r *CoreDNSNodeHostsReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{RequeueAfter: time.Second}, err
 	}
 
+	// prepare the Corefile, changes made within the virtual cluster are reverted
+	corefile := ""
+	if r.Config != nil {
+		corefile, err = r.Config.Corefile(r.BaseCorefile)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	// create or patch configmap preserving other data keys (Corefile)
 	configmap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
 		Namespace: Namespace,
@@ -49,6 +63,9 @@ func (r *CoreDNSNodeHostsReconciler) Reconcile(ctx context.Context, req ctrl.Req
 			configmap.Data = make(map[string]string)
 		}
 		configmap.Data[NodeHostsKey] = nodehosts
+		if corefile != "" {
+			configmap.Data[CorefileKey] = corefile
+		}
 		return nil
 	})
 	if err != nil {
@@ -80,6 +97,9 @@ func (r *CoreDNSNodeHostsReconciler) compileNodeHosts(ctx context.Context) (stri
 		nodehosts = append(nodehosts, fmt.Sprintf("%s %s", nodeAddress, nodeHostname))
 	}
 	sort.Strings(nodehosts)
+	if r.Config != nil {
+		nodehosts = append(nodehosts, r.Config.HostsEntries()...)
+	}
 	return strings.Join(nodehosts, "\n"), nil
 }
 
@@ -0,0 +1,162 @@
+package gatewaysync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/gateways"
+	"github.com/loft-sh/vcluster/pkg/util/loghelper"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// GatewaySyncer mirrors shared gateways of the host cluster into the virtual cluster under the same namespace
+// and name, so routes of the virtual cluster can reference them and see their addresses and listeners.
+type GatewaySyncer struct {
+	// Gateways are the shared host gateways
+	Gateways map[types.NamespacedName]bool
+	GVK      schema.GroupVersionKind
+
+	From ctrl.Manager
+	To   ctrl.Manager
+
+	Log loghelper.Logger
+}
+
+// ParseGateways parses the shared gateways in the form namespace/name
+func ParseGateways(sharedGateways []string) (map[types.NamespacedName]bool, error) {
+	ret := map[types.NamespacedName]bool{}
+	for _, gateway := range sharedGateways {
+		splitted := strings.Split(strings.TrimSpace(gateway), "/")
+		if len(splitted) != 2 || splitted[0] == "" || splitted[1] == "" {
+			return nil, fmt.Errorf("invalid shared gateway %s, please use namespace/name", gateway)
+		}
+
+		ret[types.NamespacedName{Namespace: splitted[0], Name: splitted[1]}] = true
+	}
+
+	return ret, nil
+}
+
+func (e *GatewaySyncer) Register() error {
+	isSharedGateway := predicate.NewPredicateFuncs(func(object client.Object) bool {
+		return e.Gateways[types.NamespacedName{Namespace: object.GetNamespace(), Name: object.GetName()}]
+	})
+
+	return ctrl.NewControllerManagedBy(e.From).
+		Named("gatewaysync").
+		For(gateways.NewObject(e.GVK), builder.WithPredicates(isSharedGateway)).
+		Watches(source.NewKindWithCache(gateways.NewObject(e.GVK), e.To.GetCache()), handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: object.GetNamespace(), Name: object.GetName()}}}
+		}), builder.WithPredicates(isSharedGateway)).
+		Complete(e)
+}
+
+func (e *GatewaySyncer) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	fromGateway := gateways.NewObject(e.GVK)
+	err := e.From.GetClient().Get(ctx, req.NamespacedName, fromGateway)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, e.deleteTarget(ctx, req.NamespacedName)
+	}
+
+	toGateway := gateways.NewObject(e.GVK)
+	err = e.To.GetClient().Get(ctx, req.NamespacedName, toGateway)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		err = e.ensureNamespace(ctx, req.Namespace)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		toGateway = gateways.NewObject(e.GVK)
+		toGateway.SetNamespace(req.Namespace)
+		toGateway.SetName(req.Name)
+		toGateway.SetLabels(map[string]string{translate.ControllerLabel: "vcluster"})
+		toGateway.Object["spec"] = fromGateway.Object["spec"]
+		e.Log.Infof("Create shared gateway %s/%s in the virtual cluster", req.Namespace, req.Name)
+		return ctrl.Result{}, e.To.GetClient().Create(ctx, toGateway)
+	} else if toGateway.GetLabels()[translate.ControllerLabel] != "vcluster" {
+		e.Log.Infof("Skip shared gateway %s/%s, because the gateway in the virtual cluster was not created by vcluster", req.Namespace, req.Name)
+		return ctrl.Result{}, nil
+	}
+
+	// the virtual gateway is read only, so we revert changes to the spec
+	if !equality.Semantic.DeepEqual(toGateway.Object["spec"], fromGateway.Object["spec"]) {
+		e.Log.Infof("Update shared gateway %s/%s in the virtual cluster, because the spec changed", req.Namespace, req.Name)
+		toGateway.Object["spec"] = fromGateway.Object["spec"]
+		return ctrl.Result{}, e.To.GetClient().Update(ctx, toGateway)
+	}
+
+	if !equality.Semantic.DeepEqual(toGateway.Object["status"], fromGateway.Object["status"]) {
+		e.Log.Infof("Update shared gateway %s/%s in the virtual cluster, because the status changed", req.Namespace, req.Name)
+		toGateway.Object["status"] = fromGateway.Object["status"]
+		return ctrl.Result{}, e.To.GetClient().Status().Update(ctx, toGateway)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (e *GatewaySyncer) ensureNamespace(ctx context.Context, name string) error {
+	namespace := &corev1.Namespace{}
+	err := e.To.GetClient().Get(ctx, types.NamespacedName{Name: name}, namespace)
+	if err == nil {
+		return nil
+	} else if !kerrors.IsNotFound(err) {
+		return err
+	}
+
+	e.Log.Infof("Create namespace %s because it is missing", name)
+	err = e.To.GetClient().Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	})
+	if err != nil && !kerrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	return nil
+}
+
+// deleteTarget deletes the virtual gateway if it was created by vcluster
+func (e *GatewaySyncer) deleteTarget(ctx context.Context, name types.NamespacedName) error {
+	toGateway := gateways.NewObject(e.GVK)
+	err := e.To.GetClient().Get(ctx, name, toGateway)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+
+		return err
+	} else if toGateway.GetLabels()[translate.ControllerLabel] != "vcluster" {
+		return nil
+	}
+
+	e.Log.Infof("Delete shared gateway %s/%s in the virtual cluster, because the host gateway is missing", name.Namespace, name.Name)
+	err = e.To.GetClient().Delete(ctx, toGateway)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package gatewaysync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/gateways"
+	generictesting "github.com/loft-sh/vcluster/pkg/controllers/syncer/testing"
+	"github.com/loft-sh/vcluster/pkg/util/loghelper"
+	testingutil "github.com/loft-sh/vcluster/pkg/util/testing"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestParseGateways(t *testing.T) {
+	gateways, err := ParseGateways([]string{"gateway-system/shared", " infra/public "})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, gateways, map[types.NamespacedName]bool{
+		{Namespace: "gateway-system", Name: "shared"}: true,
+		{Namespace: "infra", Name: "public"}:          true,
+	})
+
+	_, err = ParseGateways([]string{"shared"})
+	assert.ErrorContains(t, err, "invalid shared gateway shared")
+	_, err = ParseGateways([]string{"gateway-system/"})
+	assert.ErrorContains(t, err, "invalid shared gateway")
+}
+
+func TestReconcile(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: gateways.Group, Version: "v1beta1", Kind: "Gateway"}
+	name := types.NamespacedName{Namespace: "gateway-system", Name: "shared"}
+	hostGateway := gateways.NewObject(gvk)
+	hostGateway.SetNamespace(name.Namespace)
+	hostGateway.SetName(name.Name)
+	hostGateway.Object["spec"] = map[string]interface{}{"gatewayClassName": "envoy"}
+	hostGateway.Object["status"] = map[string]interface{}{"addresses": []interface{}{map[string]interface{}{"value": "1.2.3.4"}}}
+
+	scheme := testingutil.NewScheme()
+	scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	pClient := testingutil.NewFakeClient(scheme)
+	vClient := testingutil.NewFakeClient(scheme)
+	assert.NilError(t, pClient.Create(context.TODO(), hostGateway.DeepCopy()))
+	registerContext := generictesting.NewFakeRegisterContext(pClient, vClient)
+	syncer := &GatewaySyncer{
+		Gateways: map[types.NamespacedName]bool{name: true},
+		GVK:      gvk,
+		From:     registerContext.PhysicalManager,
+		To:       registerContext.VirtualManager,
+		Log:      loghelper.New("shared-gateway-syncer"),
+	}
+	reconcile := func() {
+		_, err := syncer.Reconcile(context.TODO(), ctrl.Request{NamespacedName: name})
+		assert.NilError(t, err)
+	}
+	getVirtual := func() *unstructured.Unstructured {
+		virtualGateway := gateways.NewObject(gvk)
+		assert.NilError(t, vClient.Get(context.TODO(), name, virtualGateway))
+		return virtualGateway
+	}
+
+	// the gateway and its namespace are created in the virtual cluster
+	reconcile()
+	assert.NilError(t, vClient.Get(context.TODO(), types.NamespacedName{Name: name.Namespace}, &corev1.Namespace{}))
+	virtualGateway := getVirtual()
+	assert.Equal(t, virtualGateway.GetLabels()[translate.ControllerLabel], "vcluster")
+	assert.DeepEqual(t, virtualGateway.Object["spec"], hostGateway.Object["spec"])
+
+	// the status is synced and changes to the virtual spec are reverted
+	reconcile()
+	assert.DeepEqual(t, getVirtual().Object["status"], hostGateway.Object["status"])
+	virtualGateway = getVirtual()
+	virtualGateway.Object["spec"] = map[string]interface{}{"gatewayClassName": "other"}
+	assert.NilError(t, vClient.Update(context.TODO(), virtualGateway))
+	reconcile()
+	assert.DeepEqual(t, getVirtual().Object["spec"], hostGateway.Object["spec"])
+
+	// the virtual gateway is deleted with the host gateway
+	assert.NilError(t, pClient.Delete(context.TODO(), hostGateway.DeepCopy()))
+	reconcile()
+	assert.Assert(t, kerrors.IsNotFound(vClient.Get(context.TODO(), name, gateways.NewObject(gvk))))
+}
+
+func TestReconcileKeepsUnmanagedGateways(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: gateways.Group, Version: "v1beta1", Kind: "Gateway"}
+	name := types.NamespacedName{Namespace: "gateway-system", Name: "shared"}
+	virtualGateway := gateways.NewObject(gvk)
+	virtualGateway.SetNamespace(name.Namespace)
+	virtualGateway.SetName(name.Name)
+	virtualGateway.Object["spec"] = map[string]interface{}{"gatewayClassName": "own"}
+
+	scheme := testingutil.NewScheme()
+	scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	vClient := testingutil.NewFakeClient(scheme)
+	assert.NilError(t, vClient.Create(context.TODO(), virtualGateway.DeepCopy()))
+	registerContext := generictesting.NewFakeRegisterContext(testingutil.NewFakeClient(scheme), vClient)
+	syncer := &GatewaySyncer{
+		Gateways: map[types.NamespacedName]bool{name: true},
+		GVK:      gvk,
+		From:     registerContext.PhysicalManager,
+		To:       registerContext.VirtualManager,
+		Log:      loghelper.New("shared-gateway-syncer"),
+	}
+
+	// a gateway that was not created by vcluster is not deleted
+	_, err := syncer.Reconcile(context.TODO(), ctrl.Request{NamespacedName: name})
+	assert.NilError(t, err)
+	assert.NilError(t, vClient.Get(context.TODO(), name, gateways.NewObject(gvk)))
+}
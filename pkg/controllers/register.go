@@ -5,6 +5,7 @@ import (
 	"github.com/loft-sh/vcluster/cmd/vclusterctl/cmd"
 	"strings"
 
+	"github.com/loft-sh/vcluster/pkg/controllers/gatewaysync"
 	"github.com/loft-sh/vcluster/pkg/controllers/servicesync"
 	"github.com/loft-sh/vcluster/pkg/helm"
 	"github.com/loft-sh/vcluster/pkg/plugin"
@@ -14,6 +15,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 
 	"github.com/loft-sh/vcluster/pkg/controllers/k8sdefaultendpoint"
 	"github.com/loft-sh/vcluster/pkg/controllers/manifests"
@@ -27,6 +29,10 @@ import (
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/configmaps"
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/endpoints"
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/events"
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/gateways"
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/gateways/gatewayclasses"
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/gateways/referencegrants"
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/gateways/routes"
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/ingresses"
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/networkpolicies"
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes"
@@ -43,7 +49,10 @@ import (
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/volumesnapshots/volumesnapshots"
 	"github.com/loft-sh/vcluster/pkg/controllers/syncer"
 	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	corednsmanifest "github.com/loft-sh/vcluster/pkg/coredns"
 	"github.com/loft-sh/vcluster/pkg/util/loghelper"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
 )
@@ -65,6 +74,9 @@ var ResourceControllers = map[string][]func(*synccontext.RegisterContext) (synce
 	"networkpolicies":        newControllers(networkpolicies.New),
 	"volumesnapshots":        newControllers(volumesnapshotclasses.New, volumesnapshots.New, volumesnapshotcontents.New),
 	"serviceaccounts":        newControllers(serviceaccounts.New),
+	"gateways":               newControllers(gatewayclasses.New, routes.NewHTTPRouteSyncer, referencegrants.New),
+	"grpcroutes":             newControllers(routes.NewGRPCRouteSyncer),
+	"tlsroutes":              newControllers(routes.NewTLSRouteSyncer),
 	"persistentvolumes,fake-persistentvolumes": newControllers(persistentvolumes.New),
 }
 
@@ -85,6 +97,12 @@ func Create(ctx *context.ControllerContext) ([]syncer.Object, error) {
 					}
 
 					syncers = append(syncers, ctrl)
+
+					// sync host events of the objects that are synced by a namespaced translator
+					err = registerEventKind(registerContext, ctrl)
+					if err != nil {
+						return nil, errors.Wrapf(err, "register %s controller", controller)
+					}
 					break
 				}
 			}
@@ -94,6 +112,21 @@ func Create(ctx *context.ControllerContext) ([]syncer.Object, error) {
 	return syncers, nil
 }
 
+func registerEventKind(ctx *synccontext.RegisterContext, s syncer.Object) error {
+	_, ok := s.(translator.NamespacedTranslator)
+	if !ok {
+		return nil
+	}
+
+	gvk, err := apiutil.GVKForObject(s.Resource(), ctx.VirtualManager.GetScheme())
+	if err != nil {
+		return err
+	}
+
+	events.RegisterKind(gvk)
+	return nil
+}
+
 func ExecuteInitializers(controllerCtx *context.ControllerContext, syncers []syncer.Object) error {
 	registerContext := ToRegisterContext(controllerCtx)
 
@@ -165,6 +198,14 @@ func RegisterControllers(ctx *context.ControllerContext, syncers []syncer.Object
 		return err
 	}
 
+	// register controller that mirrors the shared host gateways
+	if ctx.Controllers["gateways"] && len(ctx.Options.SharedGateways) > 0 {
+		err = registerSharedGatewayController(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
 	// register controllers for resource synchronization
 	for _, v := range syncers {
 		// fake syncer?
@@ -253,7 +294,14 @@ func registerInitManifestsController(ctx *context.ControllerContext) error {
 }
 
 func registerServiceSyncControllers(ctx *context.ControllerContext) error {
-	if len(ctx.Options.MapHostServices) > 0 {
+	dynamicMappings := ctx.Options.ServiceMappingsConfigMap != ""
+	imports, err := servicesync.ParseImports(ctx.Options.ImportServices)
+	if err != nil {
+		return errors.Wrap(err, "parse service imports")
+	}
+
+	var hostServiceSyncer *servicesync.ServiceSyncer
+	if len(ctx.Options.MapHostServices) > 0 || dynamicMappings || len(imports) > 0 {
 		mapping, err := parseMapping(ctx.Options.MapHostServices, ctx.Options.TargetNamespace, "")
 		if err != nil {
 			return errors.Wrap(err, "parse physical service mapping")
@@ -261,63 +309,138 @@ func registerServiceSyncControllers(ctx *context.ControllerContext) error {
 
 		// sync we are syncing from arbitrary physical namespaces we need to create a new
 		// manager that listens on global services
-		globalLocalManager, err := ctrl.NewManager(ctx.LocalManager.GetConfig(), ctrl.Options{
-			Scheme: ctx.LocalManager.GetScheme(),
-			MapperProvider: func(c *rest.Config) (meta.RESTMapper, error) {
-				return ctx.LocalManager.GetRESTMapper(), nil
-			},
-			MetricsBindAddress: "0",
-			LeaderElection:     false,
-			NewClient:          blockingcacheclient.NewCacheClient,
-		})
+		globalLocalManager, err := startGlobalLocalManager(ctx)
 		if err != nil {
 			return err
 		}
 
-		// start the manager
-		go func() {
-			err := globalLocalManager.Start(ctx.Context)
-			if err != nil {
-				panic(err)
-			}
-		}()
-
-		// Wait for caches to be synced
-		globalLocalManager.GetCache().WaitForCacheSync(ctx.Context)
-
 		// register controller
-		controller := &servicesync.ServiceSyncer{
+		hostServiceSyncer = &servicesync.ServiceSyncer{
 			SyncServices:    mapping,
 			CreateNamespace: true,
 			CreateEndpoints: true,
 			From:            globalLocalManager,
 			To:              ctx.VirtualManager,
 			Log:             loghelper.New("map-host-service-syncer"),
+
+			CopyImportedEndpoints: len(imports) > 0,
 		}
-		err = controller.Register()
+		err = hostServiceSyncer.Register()
 		if err != nil {
 			return errors.Wrap(err, "register physical service sync controller")
 		}
+
+		if len(imports) > 0 {
+			controller := &servicesync.ImportReconciler{
+				Imports:           imports,
+				HostNamespace:     ctx.Options.TargetNamespace,
+				VCluster:          translate.Suffix,
+				HostClient:        globalLocalManager.GetClient(),
+				HostServiceSyncer: hostServiceSyncer,
+				Log:               loghelper.New("service-import-controller"),
+			}
+			err = controller.SetupWithManager(globalLocalManager)
+			if err != nil {
+				return errors.Wrap(err, "register service import controller")
+			}
+		}
 	}
 
-	if len(ctx.Options.MapVirtualServices) > 0 {
+	var virtualServiceSyncer *servicesync.ServiceSyncer
+	if len(ctx.Options.MapVirtualServices) > 0 || dynamicMappings {
 		mapping, err := parseMapping(ctx.Options.MapVirtualServices, "", ctx.Options.TargetNamespace)
 		if err != nil {
 			return errors.Wrap(err, "parse physical service mapping")
 		}
 
-		controller := &servicesync.ServiceSyncer{
+		virtualServiceSyncer = &servicesync.ServiceSyncer{
 			SyncServices: mapping,
 			From:         ctx.VirtualManager,
 			To:           ctx.LocalManager,
 			Log:          loghelper.New("map-virtual-service-syncer"),
 		}
-		err = controller.Register()
+		err = virtualServiceSyncer.Register()
 		if err != nil {
 			return errors.Wrap(err, "register virtual service sync controller")
 		}
 	}
 
+	if dynamicMappings {
+		controller := &servicesync.MappingReconciler{
+			ConfigMap:            types.NamespacedName{Namespace: ctx.CurrentNamespace, Name: ctx.Options.ServiceMappingsConfigMap},
+			ConfigMapClient:      ctx.CurrentNamespaceClient,
+			VirtualClient:        ctx.VirtualManager.GetClient(),
+			TargetNamespace:      ctx.Options.TargetNamespace,
+			HostServiceSyncer:    hostServiceSyncer,
+			VirtualServiceSyncer: virtualServiceSyncer,
+			EventRecorder:        ctx.VirtualManager.GetEventRecorderFor("servicemapping"),
+			Log:                  loghelper.New("service-mapping-controller"),
+		}
+		err = controller.SetupWithManager(ctx.VirtualManager, ctx.CurrentNamespaceCache)
+		if err != nil {
+			return errors.Wrap(err, "register service mapping controller")
+		}
+	}
+
+	return nil
+}
+
+// startGlobalLocalManager starts a new manager that watches objects in all host namespaces
+func startGlobalLocalManager(ctx *context.ControllerContext) (ctrl.Manager, error) {
+	globalLocalManager, err := ctrl.NewManager(ctx.LocalManager.GetConfig(), ctrl.Options{
+		Scheme: ctx.LocalManager.GetScheme(),
+		MapperProvider: func(c *rest.Config) (meta.RESTMapper, error) {
+			return ctx.LocalManager.GetRESTMapper(), nil
+		},
+		MetricsBindAddress: "0",
+		LeaderElection:     false,
+		NewClient:          blockingcacheclient.NewCacheClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// start the manager
+	go func() {
+		err := globalLocalManager.Start(ctx.Context)
+		if err != nil {
+			panic(err)
+		}
+	}()
+
+	// Wait for caches to be synced
+	globalLocalManager.GetCache().WaitForCacheSync(ctx.Context)
+	return globalLocalManager, nil
+}
+
+func registerSharedGatewayController(ctx *context.ControllerContext) error {
+	sharedGateways, err := gatewaysync.ParseGateways(ctx.Options.SharedGateways)
+	if err != nil {
+		return errors.Wrap(err, "parse shared gateways")
+	}
+
+	gvk, err := gateways.ServedKind(ctx.LocalManager.GetConfig(), "Gateway")
+	if err != nil {
+		return err
+	}
+
+	globalLocalManager, err := startGlobalLocalManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	controller := &gatewaysync.GatewaySyncer{
+		Gateways: sharedGateways,
+		GVK:      gvk,
+		From:     globalLocalManager,
+		To:       ctx.VirtualManager,
+		Log:      loghelper.New("shared-gateway-syncer"),
+	}
+	err = controller.Register()
+	if err != nil {
+		return errors.Wrap(err, "register shared gateway controller")
+	}
+
 	return nil
 }
 
@@ -370,11 +493,27 @@ func parseMapping(mappings []string, fromDefaultNamespace, toDefaultNamespace st
 }
 
 func registerCoreDNSController(ctx *context.ControllerContext) error {
+	config, err := coredns.LoadConfig(ctx.Options.CoreDNSConfig)
+	if err != nil {
+		return errors.Wrap(err, "load coredns config")
+	}
+
+	// the managed Corefile extends the Corefile of the chart
+	baseCorefile := ""
+	if config != nil {
+		baseCorefile, err = corednsmanifest.Corefile()
+		if err != nil {
+			return errors.Wrap(err, "read coredns manifest")
+		}
+	}
+
 	controller := &coredns.CoreDNSNodeHostsReconciler{
-		Client: ctx.VirtualManager.GetClient(),
-		Log:    loghelper.New("corednsnodehosts-controller"),
+		Client:       ctx.VirtualManager.GetClient(),
+		Log:          loghelper.New("corednsnodehosts-controller"),
+		Config:       config,
+		BaseCorefile: baseCorefile,
 	}
-	err := controller.SetupWithManager(ctx.VirtualManager)
+	err = controller.SetupWithManager(ctx.VirtualManager)
 	if err != nil {
 		return fmt.Errorf("unable to setup CoreDNS NodeHosts controller: %v", err)
 	}
@@ -400,6 +539,7 @@ func ToRegisterContext(ctx *context.ControllerContext) *synccontext.RegisterCont
 
 		Options:     ctx.Options,
 		Controllers: ctx.Controllers,
+		NodePools:   ctx.NodePools,
 
 		TargetNamespace:        ctx.Options.TargetNamespace,
 		CurrentNamespace:       ctx.CurrentNamespace,
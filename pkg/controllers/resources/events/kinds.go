@@ -0,0 +1,39 @@
+package events
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	acceptedKindsMutex sync.RWMutex
+	acceptedKinds      = map[schema.GroupKind]schema.GroupVersionKind{}
+)
+
+func init() {
+	for _, kind := range []string{"Pod", "Service", "Endpoints", "Secret", "ConfigMap"} {
+		RegisterKind(corev1.SchemeGroupVersion.WithKind(kind))
+	}
+}
+
+// RegisterKind registers a kind whose host events should be synced into the virtual cluster. The
+// virtual objects of the kind need to be indexed by their physical name (constants.IndexByPhysicalName),
+// which is the case for every syncer that uses a namespaced translator. Events are matched by group and
+// kind, so host events that reference another version of the kind are synced as well.
+func RegisterKind(gvk schema.GroupVersionKind) {
+	acceptedKindsMutex.Lock()
+	defer acceptedKindsMutex.Unlock()
+
+	acceptedKinds[gvk.GroupKind()] = gvk
+}
+
+// AcceptedKind returns the registered version of the given kind, if events for it should be synced
+func AcceptedKind(gk schema.GroupKind) (schema.GroupVersionKind, bool) {
+	acceptedKindsMutex.RLock()
+	defer acceptedKindsMutex.RUnlock()
+
+	gvk, ok := acceptedKinds[gk]
+	return gvk, ok
+}
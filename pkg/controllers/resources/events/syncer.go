@@ -1,8 +1,8 @@
 package events
 
 import (
-	"k8s.io/apimachinery/pkg/api/equality"
 	"strings"
+	"time"
 
 	"github.com/loft-sh/vcluster/pkg/controllers/syncer"
 	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
@@ -11,30 +11,64 @@ import (
 	"github.com/loft-sh/vcluster/pkg/constants"
 	"github.com/loft-sh/vcluster/pkg/util/clienthelper"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/meta"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/utils/lru"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-var AcceptedKinds = map[schema.GroupVersionKind]bool{
-	corev1.SchemeGroupVersion.WithKind("Pod"):       true,
-	corev1.SchemeGroupVersion.WithKind("Service"):   true,
-	corev1.SchemeGroupVersion.WithKind("Endpoint"):  true,
-	corev1.SchemeGroupVersion.WithKind("Secret"):    true,
-	corev1.SchemeGroupVersion.WithKind("ConfigMap"): true,
-}
+const (
+	// eventBurst is the amount of new events that can be created for a single involved object at once
+	eventBurst = 25
+	// eventQPS is the rate new events for a single involved object can be created after the burst is used up
+	eventQPS = 1.0 / 10.0
+	// eventUpdateInterval is the minimum interval between updates of the same event, e.g. the count
+	// of an event of a crash looping pod
+	eventUpdateInterval = time.Second * 10
+	// eventCacheSize is the amount of involved objects and events the rate limiting state is kept for
+	eventCacheSize = 4096
+)
 
 func New(ctx *synccontext.RegisterContext) (syncer.Object, error) {
 	return &eventSyncer{
 		virtualClient: ctx.VirtualManager.GetClient(),
+		rateLimiters:  lru.New(eventCacheSize),
+		lastUpdates:   lru.New(eventCacheSize),
+		similarEvents: lru.New(eventCacheSize),
+		aggregated:    lru.New(eventCacheSize),
+		hostCounts:    lru.New(eventCacheSize),
 	}, nil
 }
 
+// eventSyncer syncs the host events of synced objects into the virtual cluster. Events created through
+// events.k8s.io/v1 are served by the core api as well, with their note as message, their regarding object
+// as involved object and their series, so they are synced through the core api.
 type eventSyncer struct {
 	virtualClient client.Client
+
+	// rateLimiters holds a token bucket for each involved object to limit new events
+	rateLimiters *lru.Cache
+	// lastUpdates holds the time each virtual event was last updated
+	lastUpdates *lru.Cache
+	// similarEvents holds the name of the last virtual event that was created for an involved object,
+	// type and reason, which rate limited host events are aggregated into
+	similarEvents *lru.Cache
+	// aggregated holds the occurrences that were aggregated into a virtual event
+	aggregated *lru.Cache
+	// hostCounts holds the count of each host event when it was last aggregated
+	hostCounts *lru.Cache
+}
+
+// occurrences are the occurrences of rate limited host events that were aggregated into a virtual event
+type occurrences struct {
+	count        int32
+	lastObserved metav1.MicroTime
 }
 
 func (s *eventSyncer) Resource() client.Object {
@@ -60,69 +94,33 @@ func (s *eventSyncer) PhysicalToVirtual(pObj client.Object) types.NamespacedName
 	}
 }
 
-var _ syncer.Starter = &eventSyncer{}
+var _ syncer.ResultStarter = &eventSyncer{}
 
-func (s *eventSyncer) ReconcileStart(ctx *synccontext.SyncContext, req ctrl.Request) (bool, error) {
-	return true, s.reconcile(ctx, req) // true will tell the syncer to return after this reconcile
+func (s *eventSyncer) ReconcileStartWithResult(ctx *synccontext.SyncContext, req ctrl.Request) (ctrl.Result, bool, error) {
+	result, err := s.reconcile(ctx, req)
+	return result, true, err // true will tell the syncer to return after this reconcile
 }
 
-func (s *eventSyncer) reconcile(ctx *synccontext.SyncContext, req ctrl.Request) error {
+func (s *eventSyncer) reconcile(ctx *synccontext.SyncContext, req ctrl.Request) (ctrl.Result, error) {
 	pObj := s.Resource()
 	err := ctx.PhysicalClient.Get(ctx.Context, req.NamespacedName, pObj)
 	if err != nil {
 		if !kerrors.IsNotFound(err) {
-			return err
+			return ctrl.Result{}, err
 		}
 
-		return nil
+		return ctrl.Result{}, nil
 	}
 
 	pEvent, ok := pObj.(*corev1.Event)
 	if !ok {
-		return nil
-	}
-
-	// check if the involved object is accepted
-	gvk := pEvent.InvolvedObject.GroupVersionKind()
-	if !AcceptedKinds[gvk] {
-		return nil
-	}
-
-	vInvolvedObj, err := ctx.VirtualClient.Scheme().New(gvk)
-	if err != nil {
-		return err
-	}
-
-	index := ""
-	switch pEvent.InvolvedObject.Kind {
-	case "Pod":
-		index = constants.IndexByPhysicalName
-	case "Service":
-		index = constants.IndexByPhysicalName
-	case "Endpoint":
-		index = constants.IndexByPhysicalName
-	case "Secret":
-		index = constants.IndexByPhysicalName
-	case "ConfigMap":
-		index = constants.IndexByPhysicalName
-	default:
-		return nil
+		return ctrl.Result{}, nil
 	}
 
 	// get involved object
-	err = clienthelper.GetByIndex(ctx.Context, ctx.VirtualClient, vInvolvedObj, index, pEvent.InvolvedObject.Name)
-	if err != nil {
-		if kerrors.IsNotFound(err) {
-			return nil
-		}
-
-		return err
-	}
-
-	// we found the related object
-	m, err := meta.Accessor(vInvolvedObj)
-	if err != nil {
-		return err
+	vInvolvedObj, err := s.getVirtualObject(ctx, pEvent.InvolvedObject)
+	if err != nil || vInvolvedObj == nil {
+		return ctrl.Result{}, err
 	}
 
 	// copy physical object
@@ -130,11 +128,20 @@ func (s *eventSyncer) reconcile(ctx *synccontext.SyncContext, req ctrl.Request)
 	translator.ResetObjectMetadata(vObj)
 
 	// set the correct involved object meta
-	vObj.Namespace = m.GetNamespace()
-	vObj.InvolvedObject.Namespace = m.GetNamespace()
-	vObj.InvolvedObject.Name = m.GetName()
-	vObj.InvolvedObject.UID = m.GetUID()
-	vObj.InvolvedObject.ResourceVersion = m.GetResourceVersion()
+	vObj.Namespace = vInvolvedObj.GetNamespace()
+	vObj.InvolvedObject.Namespace = vInvolvedObj.GetNamespace()
+	vObj.InvolvedObject.Name = vInvolvedObj.GetName()
+	vObj.InvolvedObject.UID = vInvolvedObj.GetUID()
+	vObj.InvolvedObject.ResourceVersion = vInvolvedObj.GetResourceVersion()
+
+	// events created through events.k8s.io/v1 might reference a related object, which
+	// we either translate as well or remove as it would reference a host object
+	if vObj.Related != nil {
+		vObj.Related, err = s.translateRelated(ctx, vObj.Related)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
 
 	// replace name of object
 	if strings.HasPrefix(vObj.Name, pEvent.InvolvedObject.Name) {
@@ -150,13 +157,13 @@ func (s *eventSyncer) reconcile(ctx *synccontext.SyncContext, req ctrl.Request)
 	err = ctx.VirtualClient.Get(ctx.Context, client.ObjectKey{Name: vObj.Namespace}, namespace)
 	if err != nil {
 		if kerrors.IsNotFound(err) {
-			return nil
+			return ctrl.Result{}, nil
 		}
 
-		return err
+		return ctrl.Result{}, err
 	} else if namespace.DeletionTimestamp != nil {
 		// cannot create events in terminating namespaces
-		return nil
+		return ctrl.Result{}, nil
 	}
 
 	// check if there is such an event already
@@ -167,24 +174,212 @@ func (s *eventSyncer) reconcile(ctx *synccontext.SyncContext, req ctrl.Request)
 	}, vOldObj)
 	if err != nil {
 		if !kerrors.IsNotFound(err) {
-			return err
+			return ctrl.Result{}, err
+		}
+
+		// don't flood the virtual cluster with events of a single object, instead the event is
+		// aggregated into a similar event the same way the event recorders of kubernetes do
+		if !s.rateLimiter(vObj.InvolvedObject.UID).TryAccept() {
+			return s.aggregate(ctx, pEvent, vObj)
 		}
 
 		ctx.Log.Infof("create virtual event %s/%s", vObj.Namespace, vObj.Name)
-		return ctx.VirtualClient.Create(ctx.Context, vObj)
+		s.lastUpdates.Add(eventKey(vObj), time.Now())
+		s.similarEvents.Add(similarEventKey(vObj), vObj.Name)
+		return ctrl.Result{}, ctx.VirtualClient.Create(ctx.Context, vObj)
 	}
 
-	// copy metadata
+	// copy metadata and keep the occurrences of the host events that were aggregated into the event
 	vObj.ObjectMeta = *vOldObj.ObjectMeta.DeepCopy()
+	if aggregated, ok := s.aggregated.Get(eventKey(vObj)); ok {
+		addOccurrences(vObj, aggregated.(occurrences))
+	}
 
 	// update existing event only if changed
 	if equality.Semantic.DeepEqual(vObj, vOldObj) {
-		return nil
+		return ctrl.Result{}, nil
+	}
+
+	// a repeating event (e.g. BackOff of a crash looping pod) is only updated in an interval
+	// and requeued, so the last change is not lost if the host event doesn't change anymore
+	if lastUpdate, ok := s.lastUpdates.Get(eventKey(vObj)); ok {
+		if wait := eventUpdateInterval - time.Since(lastUpdate.(time.Time)); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
 	}
 
 	ctx.Log.Infof("update virtual event %s/%s", vObj.Namespace, vObj.Name)
 	translator.PrintChanges(vOldObj, vObj, ctx.Log)
-	return ctx.VirtualClient.Update(ctx.Context, vObj)
+	s.lastUpdates.Add(eventKey(vObj), time.Now())
+	return ctrl.Result{}, ctx.VirtualClient.Update(ctx.Context, vObj)
+}
+
+// aggregate counts the occurrences of a rate limited host event in the last virtual event that was created
+// for the same involved object, type and reason. Events created through events.k8s.io/v1 are counted in
+// their series, core events in their count.
+func (s *eventSyncer) aggregate(ctx *synccontext.SyncContext, pEvent *corev1.Event, vObj *corev1.Event) (ctrl.Result, error) {
+	name, ok := s.similarEvents.Get(similarEventKey(vObj))
+	if !ok {
+		ctx.Log.Debugf("skip virtual event %s/%s, because too many events were created for %s %s/%s", vObj.Namespace, vObj.Name, vObj.InvolvedObject.Kind, vObj.InvolvedObject.Namespace, vObj.InvolvedObject.Name)
+		return ctrl.Result{}, nil
+	}
+
+	// only count the occurrences of the host event that were not aggregated before
+	count := eventCount(pEvent)
+	if hostCount, ok := s.hostCounts.Get(pEvent.UID); ok {
+		count -= hostCount.(int32)
+	}
+	if count <= 0 {
+		return ctrl.Result{}, nil
+	}
+
+	vAggregated := &corev1.Event{}
+	err := ctx.VirtualClient.Get(ctx.Context, types.NamespacedName{Namespace: vObj.Namespace, Name: name.(string)}, vAggregated)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			s.similarEvents.Remove(similarEventKey(vObj))
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	// the aggregated event is only updated in an interval as well
+	if lastUpdate, ok := s.lastUpdates.Get(eventKey(vAggregated)); ok {
+		if wait := eventUpdateInterval - time.Since(lastUpdate.(time.Time)); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
+	newOccurrences := occurrences{count: count, lastObserved: lastObserved(pEvent)}
+	vNewAggregated := vAggregated.DeepCopy()
+	vNewAggregated.Message = vObj.Message
+	addOccurrences(vNewAggregated, newOccurrences)
+
+	// remember the occurrences, so they are kept when the host event of the aggregated event changes
+	if aggregated, ok := s.aggregated.Get(eventKey(vAggregated)); ok {
+		previous := aggregated.(occurrences)
+		newOccurrences.count += previous.count
+		if newOccurrences.lastObserved.Before(&previous.lastObserved) {
+			newOccurrences.lastObserved = previous.lastObserved
+		}
+	}
+	s.aggregated.Add(eventKey(vAggregated), newOccurrences)
+	s.hostCounts.Add(pEvent.UID, eventCount(pEvent))
+
+	ctx.Log.Infof("aggregate virtual event %s/%s into %s/%s", vObj.Namespace, vObj.Name, vAggregated.Namespace, vAggregated.Name)
+	s.lastUpdates.Add(eventKey(vAggregated), time.Now())
+	return ctrl.Result{}, ctx.VirtualClient.Update(ctx.Context, vNewAggregated)
+}
+
+// getVirtualObject returns the virtual object of the given host object reference or nil if the
+// kind is not accepted or the object doesn't exist in the virtual cluster
+func (s *eventSyncer) getVirtualObject(ctx *synccontext.SyncContext, ref corev1.ObjectReference) (client.Object, error) {
+	gvk, ok := AcceptedKind(ref.GroupVersionKind().GroupKind())
+	if !ok {
+		return nil, nil
+	}
+
+	obj, err := ctx.VirtualClient.Scheme().New(gvk)
+	if runtime.IsNotRegisteredError(err) {
+		// kinds that are synced as unstructured objects, e.g. gateway api routes
+		unstructuredObj := &unstructured.Unstructured{}
+		unstructuredObj.SetGroupVersionKind(gvk)
+		obj = unstructuredObj
+	} else if err != nil {
+		return nil, err
+	}
+	vObj, ok := obj.(client.Object)
+	if !ok {
+		return nil, nil
+	}
+
+	err = clienthelper.GetByIndex(ctx.Context, ctx.VirtualClient, vObj, constants.IndexByPhysicalName, ref.Name)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return vObj, nil
+}
+
+func (s *eventSyncer) translateRelated(ctx *synccontext.SyncContext, related *corev1.ObjectReference) (*corev1.ObjectReference, error) {
+	vRelated, err := s.getVirtualObject(ctx, *related)
+	if err != nil || vRelated == nil {
+		return nil, err
+	}
+
+	translated := related.DeepCopy()
+	translated.Namespace = vRelated.GetNamespace()
+	translated.Name = vRelated.GetName()
+	translated.UID = vRelated.GetUID()
+	translated.ResourceVersion = vRelated.GetResourceVersion()
+	return translated, nil
+}
+
+func (s *eventSyncer) rateLimiter(involvedObject types.UID) flowcontrol.PassiveRateLimiter {
+	rateLimiter, ok := s.rateLimiters.Get(involvedObject)
+	if !ok {
+		rateLimiter = flowcontrol.NewTokenBucketPassiveRateLimiter(eventQPS, eventBurst)
+		s.rateLimiters.Add(involvedObject, rateLimiter)
+	}
+
+	return rateLimiter.(flowcontrol.PassiveRateLimiter)
+}
+
+func eventKey(event *corev1.Event) string {
+	return event.Namespace + "/" + event.Name
+}
+
+func similarEventKey(event *corev1.Event) string {
+	return string(event.InvolvedObject.UID) + "/" + event.Type + "/" + event.Reason
+}
+
+// eventCount returns how often the event occurred
+func eventCount(event *corev1.Event) int32 {
+	if event.Series != nil {
+		return event.Series.Count
+	} else if event.Count > 0 {
+		return event.Count
+	}
+
+	return 1
+}
+
+// lastObserved returns when the event occurred the last time
+func lastObserved(event *corev1.Event) metav1.MicroTime {
+	if event.Series != nil {
+		return event.Series.LastObservedTime
+	} else if !event.EventTime.IsZero() {
+		return event.EventTime
+	} else if !event.LastTimestamp.IsZero() {
+		return metav1.NewMicroTime(event.LastTimestamp.Time)
+	}
+
+	return metav1.NowMicro()
+}
+
+// addOccurrences adds the given occurrences to the series of an event created through events.k8s.io/v1
+// or the count of a core event
+func addOccurrences(event *corev1.Event, added occurrences) {
+	if event.Series != nil || !event.EventTime.IsZero() {
+		if event.Series == nil {
+			event.Series = &corev1.EventSeries{Count: 1, LastObservedTime: event.EventTime}
+		}
+		event.Series.Count += added.count
+		if event.Series.LastObservedTime.Before(&added.lastObserved) {
+			event.Series.LastObservedTime = added.lastObserved
+		}
+		return
+	}
+
+	event.Count = eventCount(event) + added.count
+	if event.LastTimestamp.Time.Before(added.lastObserved.Time) {
+		event.LastTimestamp = metav1.NewTime(added.lastObserved.Time)
+	}
 }
 
 var _ syncer.Syncer = &eventSyncer{}
@@ -11,9 +11,11 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/flowcontrol"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"testing"
+	"time"
 )
 
 var targetNamespace = "p-test"
@@ -84,6 +86,25 @@ func TestSync(t *testing.T) {
 		InvolvedObject: vEvent.InvolvedObject,
 	}
 
+	pEventRateLimited := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-event-2",
+			Namespace: targetNamespace,
+			UID:       "rate-limited",
+		},
+		Count:          3,
+		LastTimestamp:  metav1.Unix(1000, 0),
+		Message:        "back-off restarting failed container",
+		InvolvedObject: pEvent.InvolvedObject,
+	}
+	vEventAggregated := &corev1.Event{
+		ObjectMeta:     vEvent.ObjectMeta,
+		Count:          1 + pEventRateLimited.Count,
+		LastTimestamp:  pEventRateLimited.LastTimestamp,
+		Message:        pEventRateLimited.Message,
+		InvolvedObject: vEvent.InvolvedObject,
+	}
+
 	generictesting.RunTests(t, []*generictesting.SyncTest{
 		{
 			Name: "Create new event",
@@ -102,7 +123,7 @@ func TestSync(t *testing.T) {
 			},
 			Sync: func(registerContext *synccontext.RegisterContext) {
 				syncContext, syncer := newFakeSyncer(t, registerContext)
-				_, err := syncer.ReconcileStart(syncContext, ctrl.Request{NamespacedName: types.NamespacedName{
+				_, _, err := syncer.ReconcileStartWithResult(syncContext, ctrl.Request{NamespacedName: types.NamespacedName{
 					Namespace: pEvent.Namespace,
 					Name:      pEvent.Name,
 				}})
@@ -127,12 +148,118 @@ func TestSync(t *testing.T) {
 			},
 			Sync: func(registerContext *synccontext.RegisterContext) {
 				syncContext, syncer := newFakeSyncer(t, registerContext)
-				_, err := syncer.ReconcileStart(syncContext, ctrl.Request{NamespacedName: types.NamespacedName{
+				_, _, err := syncer.ReconcileStartWithResult(syncContext, ctrl.Request{NamespacedName: types.NamespacedName{
+					Namespace: pEvent.Namespace,
+					Name:      pEvent.Name,
+				}})
+				assert.NilError(t, err)
+			},
+		},
+		{
+			Name: "Requeue throttled event update",
+			InitialVirtualState: []runtime.Object{
+				vNamespace,
+				vPod,
+				vEvent,
+			},
+			InitialPhysicalState: []runtime.Object{
+				pPod,
+				pEventUpdated,
+			},
+			ExpectedVirtualState: map[schema.GroupVersionKind][]runtime.Object{
+				corev1.SchemeGroupVersion.WithKind("Event"): {
+					vEvent,
+				},
+			},
+			Sync: func(registerContext *synccontext.RegisterContext) {
+				syncContext, syncer := newFakeSyncer(t, registerContext)
+				syncer.lastUpdates.Add(eventKey(vEvent), time.Now())
+				result, _, err := syncer.ReconcileStartWithResult(syncContext, ctrl.Request{NamespacedName: types.NamespacedName{
 					Namespace: pEvent.Namespace,
 					Name:      pEvent.Name,
 				}})
 				assert.NilError(t, err)
+				assert.Assert(t, result.RequeueAfter > 0 && result.RequeueAfter <= eventUpdateInterval, "expected throttled update to be requeued, got %v", result.RequeueAfter)
+			},
+		},
+		{
+			Name: "Aggregate rate limited event",
+			InitialVirtualState: []runtime.Object{
+				vNamespace,
+				vPod,
+				vEvent,
+			},
+			InitialPhysicalState: []runtime.Object{
+				pPod,
+				pEventRateLimited,
+			},
+			ExpectedVirtualState: map[schema.GroupVersionKind][]runtime.Object{
+				corev1.SchemeGroupVersion.WithKind("Event"): {
+					vEventAggregated,
+				},
+			},
+			Sync: func(registerContext *synccontext.RegisterContext) {
+				syncContext, syncer := newFakeSyncer(t, registerContext)
+				syncer.rateLimiters.Add(vPod.UID, flowcontrol.NewFakeNeverRateLimiter())
+				syncer.similarEvents.Add(similarEventKey(vEvent), vEvent.Name)
+
+				// the occurrences of the host event are only counted once
+				for i := 0; i < 2; i++ {
+					_, _, err := syncer.ReconcileStartWithResult(syncContext, ctrl.Request{NamespacedName: types.NamespacedName{
+						Namespace: pEventRateLimited.Namespace,
+						Name:      pEventRateLimited.Name,
+					}})
+					assert.NilError(t, err)
+				}
 			},
 		},
 	})
 }
+
+func TestAddOccurrences(t *testing.T) {
+	// events created through events.k8s.io/v1 start a series
+	event := &corev1.Event{EventTime: metav1.NewMicroTime(time.Unix(1000, 0))}
+	addOccurrences(event, occurrences{count: 2, lastObserved: metav1.NewMicroTime(time.Unix(2000, 0))})
+	assert.Equal(t, event.Series.Count, int32(3))
+	assert.Equal(t, event.Series.LastObservedTime.Unix(), int64(2000))
+	assert.Equal(t, event.Count, int32(0))
+
+	// older occurrences don't move the series back
+	addOccurrences(event, occurrences{count: 1, lastObserved: metav1.NewMicroTime(time.Unix(1500, 0))})
+	assert.Equal(t, event.Series.Count, int32(4))
+	assert.Equal(t, event.Series.LastObservedTime.Unix(), int64(2000))
+
+	// core events are counted in their count
+	event = &corev1.Event{Count: 2}
+	addOccurrences(event, occurrences{count: 2, lastObserved: metav1.NewMicroTime(time.Unix(2000, 0))})
+	assert.Equal(t, event.Count, int32(4))
+	assert.Equal(t, event.LastTimestamp.Unix(), int64(2000))
+	assert.Assert(t, event.Series == nil)
+}
+
+func TestAcceptedKinds(t *testing.T) {
+	// restore the registered kinds afterwards, as they are shared with the other tests
+	acceptedKindsMutex.Lock()
+	registeredKinds := map[schema.GroupKind]schema.GroupVersionKind{}
+	for gk, gvk := range acceptedKinds {
+		registeredKinds[gk] = gvk
+	}
+	acceptedKindsMutex.Unlock()
+	defer func() {
+		acceptedKindsMutex.Lock()
+		acceptedKinds = registeredKinds
+		acceptedKindsMutex.Unlock()
+	}()
+
+	_, ok := AcceptedKind(schema.GroupKind{Kind: "Endpoints"})
+	assert.Assert(t, ok, "expected endpoints to be accepted")
+
+	ingressKind := schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}
+	_, ok = AcceptedKind(ingressKind.GroupKind())
+	assert.Assert(t, !ok, "expected ingresses not to be accepted before registration")
+
+	RegisterKind(ingressKind)
+	gvk, ok := AcceptedKind(schema.GroupKind{Group: "networking.k8s.io", Kind: "Ingress"})
+	assert.Assert(t, ok, "expected ingresses to be accepted after registration")
+	assert.Equal(t, gvk, ingressKind)
+}
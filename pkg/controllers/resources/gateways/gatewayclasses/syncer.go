@@ -0,0 +1,85 @@
+package gatewayclasses
+
+import (
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/gateways"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer"
+	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	"github.com/loft-sh/vcluster/pkg/util"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func New(ctx *synccontext.RegisterContext) (syncer.Object, error) {
+	gvk, err := gateways.ServedKind(ctx.PhysicalManager.GetConfig(), "GatewayClass")
+	if err != nil {
+		return nil, err
+	}
+
+	return &gatewayClassSyncer{
+		Translator: translator.NewMirrorPhysicalTranslator("gatewayclass", gateways.NewObject(gvk)),
+
+		gvk: gvk,
+	}, nil
+}
+
+type gatewayClassSyncer struct {
+	translator.Translator
+
+	gvk schema.GroupVersionKind
+}
+
+var _ syncer.Initializer = &gatewayClassSyncer{}
+
+func (g *gatewayClassSyncer) Init(ctx *synccontext.RegisterContext) error {
+	// the gateways are needed as well, because the gateway classes are used by the shared gateways
+	gatewayGVK := g.gvk.GroupVersion().WithKind("Gateway")
+	err := util.EnsureCRDFromPhysicalCluster(ctx.Context, ctx.PhysicalManager.GetConfig(), ctx.VirtualManager.GetConfig(), gatewayGVK)
+	if err != nil {
+		return err
+	}
+
+	return util.EnsureCRDFromPhysicalCluster(ctx.Context, ctx.PhysicalManager.GetConfig(), ctx.VirtualManager.GetConfig(), g.gvk)
+}
+
+var _ syncer.UpSyncer = &gatewayClassSyncer{}
+var _ syncer.Syncer = &gatewayClassSyncer{}
+
+func (g *gatewayClassSyncer) SyncUp(ctx *synccontext.SyncContext, pObj client.Object) (ctrl.Result, error) {
+	vObj := g.TranslateMetadata(pObj).(*unstructured.Unstructured)
+	ctx.Log.Infof("create gateway class %s, because it does not exist in virtual cluster", vObj.GetName())
+	return ctrl.Result{}, ctx.VirtualClient.Create(ctx.Context, vObj)
+}
+
+func (g *gatewayClassSyncer) Sync(ctx *synccontext.SyncContext, pObj, vObj client.Object) (ctrl.Result, error) {
+	pGatewayClass := pObj.(*unstructured.Unstructured)
+	vGatewayClass := vObj.(*unstructured.Unstructured)
+
+	changed, updatedAnnotations, updatedLabels := g.TranslateMetadataUpdate(vGatewayClass, pGatewayClass)
+	if changed || !equality.Semantic.DeepEqual(vGatewayClass.Object["spec"], pGatewayClass.Object["spec"]) {
+		updated := vGatewayClass.DeepCopy()
+		updated.SetAnnotations(updatedAnnotations)
+		updated.SetLabels(updatedLabels)
+		updated.Object["spec"] = pGatewayClass.Object["spec"]
+		ctx.Log.Infof("update gateway class %s", vObj.GetName())
+		translator.PrintChanges(vObj, updated, ctx.Log)
+		return ctrl.Result{}, ctx.VirtualClient.Update(ctx.Context, updated)
+	}
+
+	if !equality.Semantic.DeepEqual(vGatewayClass.Object["status"], pGatewayClass.Object["status"]) {
+		updated := vGatewayClass.DeepCopy()
+		updated.Object["status"] = pGatewayClass.Object["status"]
+		ctx.Log.Infof("update gateway class %s, because status is out of sync", vObj.GetName())
+		return ctrl.Result{}, ctx.VirtualClient.Status().Update(ctx.Context, updated)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (g *gatewayClassSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
+	ctx.Log.Infof("delete virtual gateway class %s, because physical object is missing", vObj.GetName())
+	return ctrl.Result{}, ctx.VirtualClient.Delete(ctx.Context, vObj)
+}
@@ -0,0 +1,68 @@
+package gatewayclasses
+
+import (
+	"context"
+	"testing"
+
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/gateways"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer"
+	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
+	generictesting "github.com/loft-sh/vcluster/pkg/controllers/syncer/testing"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	testingutil "github.com/loft-sh/vcluster/pkg/util/testing"
+	"gotest.tools/assert"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestSync(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: gateways.Group, Version: "v1beta1", Kind: "GatewayClass"}
+	pGatewayClass := gateways.NewObject(gvk)
+	pGatewayClass.SetName("envoy")
+	pGatewayClass.SetLabels(map[string]string{"team": "network"})
+	pGatewayClass.Object["spec"] = map[string]interface{}{"controllerName": "gateway.envoyproxy.io/gatewayclass-controller"}
+	pGatewayClass.Object["status"] = map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Accepted", "status": "True"}}}
+
+	scheme := testingutil.NewScheme()
+	scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	vClient := testingutil.NewFakeClient(scheme)
+	syncCtx, obj := generictesting.FakeStartSyncer(t, generictesting.NewFakeRegisterContext(testingutil.NewFakeClient(scheme), vClient), func(ctx *synccontext.RegisterContext) (syncer.Object, error) {
+		return &gatewayClassSyncer{
+			Translator: translator.NewMirrorPhysicalTranslator("gatewayclass", gateways.NewObject(gvk)),
+			gvk:        gvk,
+		}, nil
+	})
+	s := obj.(*gatewayClassSyncer)
+	getVirtual := func() *unstructured.Unstructured {
+		vGatewayClass := gateways.NewObject(gvk)
+		assert.NilError(t, vClient.Get(context.TODO(), types.NamespacedName{Name: "envoy"}, vGatewayClass))
+		return vGatewayClass
+	}
+
+	// the host gateway class is created in the virtual cluster
+	_, err := s.SyncUp(syncCtx, pGatewayClass.DeepCopy())
+	assert.NilError(t, err)
+	vGatewayClass := getVirtual()
+	assert.Equal(t, vGatewayClass.GetLabels()["team"], "network")
+	assert.DeepEqual(t, vGatewayClass.Object["spec"], pGatewayClass.Object["spec"])
+
+	// changes to the virtual spec are reverted
+	vGatewayClass.Object["spec"] = map[string]interface{}{"controllerName": "example.com/other"}
+	assert.NilError(t, vClient.Update(context.TODO(), vGatewayClass))
+	_, err = s.Sync(syncCtx, pGatewayClass.DeepCopy(), getVirtual())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, getVirtual().Object["spec"], pGatewayClass.Object["spec"])
+
+	// the status of the host gateway class is synced
+	_, err = s.Sync(syncCtx, pGatewayClass.DeepCopy(), getVirtual())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, getVirtual().Object["status"], pGatewayClass.Object["status"])
+
+	// the virtual gateway class is deleted with the host gateway class
+	_, err = s.SyncDown(syncCtx, getVirtual())
+	assert.NilError(t, err)
+	assert.Assert(t, kerrors.IsNotFound(vClient.Get(context.TODO(), types.NamespacedName{Name: "envoy"}, gateways.NewObject(gvk))))
+}
@@ -0,0 +1,39 @@
+package gateways
+
+import (
+	"fmt"
+
+	"github.com/loft-sh/vcluster/pkg/util"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+// Group is the api group of the gateway api. The gateway api resources are synced as unstructured objects,
+// so the virtual cluster serves the same versions and fields as the gateway api installed in the host cluster.
+const Group = "gateway.networking.k8s.io"
+
+// Versions are the gateway api versions in the order they are preferred
+var Versions = []string{"v1", "v1beta1", "v1alpha2"}
+
+// ServedKind returns the preferred version of the given gateway api kind that is served by the host cluster
+func ServedKind(config *rest.Config, kind string) (schema.GroupVersionKind, error) {
+	for _, version := range Versions {
+		gvk := schema.GroupVersionKind{Group: Group, Version: version, Kind: kind}
+		exists, err := util.KindExists(config, gvk)
+		if err != nil {
+			return schema.GroupVersionKind{}, err
+		} else if exists {
+			return gvk, nil
+		}
+	}
+
+	return schema.GroupVersionKind{}, fmt.Errorf("the host cluster does not serve the gateway api kind %s, please install the gateway api CRDs in the host cluster", kind)
+}
+
+// NewObject returns an empty unstructured object of the given kind
+func NewObject(gvk schema.GroupVersionKind) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	return obj
+}
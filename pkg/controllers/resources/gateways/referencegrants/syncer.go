@@ -0,0 +1,81 @@
+package referencegrants
+
+import (
+	"strings"
+
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/gateways"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer"
+	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	"github.com/loft-sh/vcluster/pkg/util"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func New(ctx *synccontext.RegisterContext) (syncer.Object, error) {
+	gvk, err := gateways.ServedKind(ctx.PhysicalManager.GetConfig(), "ReferenceGrant")
+	if err != nil {
+		return nil, err
+	}
+
+	sharedGatewayNamespaces := map[string]bool{}
+	for _, gateway := range ctx.Options.SharedGateways {
+		sharedGatewayNamespaces[strings.Split(strings.TrimSpace(gateway), "/")[0]] = true
+	}
+
+	return &referenceGrantSyncer{
+		NamespacedTranslator: translator.NewNamespacedTranslator(ctx, "referencegrant", gateways.NewObject(gvk)),
+
+		gvk:                     gvk,
+		sharedGatewayNamespaces: sharedGatewayNamespaces,
+	}, nil
+}
+
+type referenceGrantSyncer struct {
+	translator.NamespacedTranslator
+
+	gvk schema.GroupVersionKind
+
+	// sharedGatewayNamespaces are the host namespaces of the shared gateways
+	sharedGatewayNamespaces map[string]bool
+}
+
+var _ syncer.Initializer = &referenceGrantSyncer{}
+
+func (s *referenceGrantSyncer) Init(ctx *synccontext.RegisterContext) error {
+	return util.EnsureCRDFromPhysicalCluster(ctx.Context, ctx.PhysicalManager.GetConfig(), ctx.VirtualManager.GetConfig(), s.gvk)
+}
+
+var _ syncer.Syncer = &referenceGrantSyncer{}
+
+func (s *referenceGrantSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
+	pReferenceGrant := s.translate(vObj.(*unstructured.Unstructured))
+	if pReferenceGrant == nil {
+		return ctrl.Result{}, nil
+	}
+
+	return s.SyncDownCreate(ctx, vObj, pReferenceGrant)
+}
+
+func (s *referenceGrantSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
+	spec := s.translateSpec(vObj.(*unstructured.Unstructured))
+	if spec == nil {
+		ctx.Log.Infof("delete physical reference grant %s/%s, because it does not grant anything in the host cluster", pObj.GetNamespace(), pObj.GetName())
+		err := ctx.PhysicalClient.Delete(ctx.Context, pObj)
+		if err != nil && !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	newReferenceGrant := s.translateUpdate(pObj.(*unstructured.Unstructured), vObj.(*unstructured.Unstructured), spec)
+	if newReferenceGrant != nil {
+		translator.PrintChanges(pObj, newReferenceGrant, ctx.Log)
+	}
+
+	return s.SyncDownUpdate(ctx, vObj, newReferenceGrant)
+}
@@ -0,0 +1,126 @@
+package referencegrants
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/gateways"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer"
+	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
+	generictesting "github.com/loft-sh/vcluster/pkg/controllers/syncer/testing"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	testingutil "github.com/loft-sh/vcluster/pkg/util/testing"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"gotest.tools/assert"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var gvk = schema.GroupVersionKind{Group: gateways.Group, Version: "v1beta1", Kind: "ReferenceGrant"}
+
+func newFakeSyncer(t *testing.T, vObjs ...*unstructured.Unstructured) (*synccontext.SyncContext, *referenceGrantSyncer, *testingutil.FakeIndexClient) {
+	scheme := testingutil.NewScheme()
+	scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	pClient := testingutil.NewFakeClient(scheme)
+	vClient := testingutil.NewFakeClient(scheme)
+	for _, vObj := range vObjs {
+		assert.NilError(t, vClient.Create(context.TODO(), vObj))
+	}
+
+	syncCtx, syncer := generictesting.FakeStartSyncer(t, generictesting.NewFakeRegisterContext(pClient, vClient), func(ctx *synccontext.RegisterContext) (syncer.Object, error) {
+		return &referenceGrantSyncer{
+			NamespacedTranslator:    translator.NewNamespacedTranslator(ctx, "referencegrant", gateways.NewObject(gvk)),
+			gvk:                     gvk,
+			sharedGatewayNamespaces: map[string]bool{"gateway-system": true},
+		}, nil
+	})
+	return syncCtx, syncer.(*referenceGrantSyncer), pClient
+}
+
+func TestSync(t *testing.T) {
+	translate.Suffix = generictesting.DefaultTestVclusterName
+	vGrant := parseObject(t, `
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: ReferenceGrant
+metadata:
+  name: certs
+  namespace: default
+spec:
+  from:
+  - group: gateway.networking.k8s.io
+    kind: Gateway
+    namespace: gateway-system
+  - group: gateway.networking.k8s.io
+    kind: HTTPRoute
+    namespace: other
+  to:
+  - group: ""
+    kind: Secret
+    name: tls
+  - group: ""
+    kind: Secret`)
+	pName := types.NamespacedName{Namespace: generictesting.DefaultTestTargetNamespace, Name: translate.PhysicalName("certs", "default")}
+
+	syncCtx, syncer, pClient := newFakeSyncer(t, vGrant)
+	_, err := syncer.SyncDown(syncCtx, vGrant)
+	assert.NilError(t, err)
+
+	// only the shared gateway namespace is granted and the secret is translated to the physical secret,
+	// while the grant for all secrets is dropped
+	pGrant := gateways.NewObject(gvk)
+	assert.NilError(t, pClient.Get(context.TODO(), pName, pGrant))
+	assert.DeepEqual(t, pGrant.Object["spec"], map[string]interface{}{
+		"from": []interface{}{
+			map[string]interface{}{"group": gateways.Group, "kind": "Gateway", "namespace": "gateway-system"},
+		},
+		"to": []interface{}{
+			map[string]interface{}{"group": "", "kind": "Secret", "name": translate.PhysicalName("tls", "default")},
+		},
+	})
+
+	// the physical grant is deleted if it doesn't grant anything in the host cluster anymore
+	vGrant.Object["spec"].(map[string]interface{})["from"] = []interface{}{
+		map[string]interface{}{"group": gateways.Group, "kind": "HTTPRoute", "namespace": "other"},
+	}
+	_, err = syncer.Sync(syncCtx, pGrant, vGrant)
+	assert.NilError(t, err)
+	assert.Assert(t, kerrors.IsNotFound(pClient.Get(context.TODO(), pName, gateways.NewObject(gvk))))
+}
+
+func TestSyncDownRoutesOnly(t *testing.T) {
+	translate.Suffix = generictesting.DefaultTestVclusterName
+	vGrant := parseObject(t, `
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: ReferenceGrant
+metadata:
+  name: backends
+  namespace: default
+spec:
+  from:
+  - group: gateway.networking.k8s.io
+    kind: HTTPRoute
+    namespace: other
+  to:
+  - group: ""
+    kind: Service`)
+
+	// grants between namespaces of the virtual cluster are enforced by the route syncer only
+	syncCtx, syncer, pClient := newFakeSyncer(t, vGrant)
+	_, err := syncer.SyncDown(syncCtx, vGrant)
+	assert.NilError(t, err)
+	grants := &unstructured.UnstructuredList{}
+	grants.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	assert.NilError(t, pClient.List(context.TODO(), grants))
+	assert.Equal(t, len(grants.Items), 0)
+}
+
+func parseObject(t *testing.T, raw string) *unstructured.Unstructured {
+	obj := map[string]interface{}{}
+	err := yaml.Unmarshal([]byte(raw), &obj)
+	assert.NilError(t, err)
+	return &unstructured.Unstructured{Object: obj}
+}
@@ -0,0 +1,99 @@
+package referencegrants
+
+import (
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/gateways"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// translate returns the physical reference grant or nil if the grant does not grant anything in the host cluster
+func (s *referenceGrantSyncer) translate(vReferenceGrant *unstructured.Unstructured) *unstructured.Unstructured {
+	spec := s.translateSpec(vReferenceGrant)
+	if spec == nil {
+		return nil
+	}
+
+	pReferenceGrant := s.TranslateMetadata(vReferenceGrant).(*unstructured.Unstructured)
+	pReferenceGrant.Object["spec"] = spec
+	return pReferenceGrant
+}
+
+func (s *referenceGrantSyncer) translateUpdate(pObj, vObj *unstructured.Unstructured, spec map[string]interface{}) *unstructured.Unstructured {
+	var updated *unstructured.Unstructured
+
+	if !equality.Semantic.DeepEqual(spec, pObj.Object["spec"]) {
+		updated = newIfNil(updated, pObj)
+		updated.Object["spec"] = spec
+	}
+
+	changed, translatedAnnotations, translatedLabels := s.TranslateMetadataUpdate(vObj, pObj)
+	if changed {
+		updated = newIfNil(updated, pObj)
+		updated.SetAnnotations(translatedAnnotations)
+		updated.SetLabels(translatedLabels)
+	}
+
+	return updated
+}
+
+// translateSpec translates the grant for the host cluster. All objects of the virtual cluster are synced
+// into the target namespace, so references between them need no grant in the host cluster and are
+// checked by the route syncer instead. Only grants for the shared gateways, which keep their host
+// namespace, are kept and the names of the referenced objects, e.g. secrets, are translated to their
+// physical names. References without a name are removed. Returns nil if nothing is granted in the
+// host cluster.
+func (s *referenceGrantSyncer) translateSpec(vReferenceGrant *unstructured.Unstructured) map[string]interface{} {
+	spec, ok := vReferenceGrant.Object["spec"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	spec = runtime.DeepCopyJSONValue(spec).(map[string]interface{})
+
+	from, _ := spec["from"].([]interface{})
+	pFrom := []interface{}{}
+	for _, ref := range from {
+		if ref, ok := ref.(map[string]interface{}); ok && s.isSharedGatewayRef(ref) {
+			pFrom = append(pFrom, ref)
+		}
+	}
+	if len(pFrom) == 0 {
+		return nil
+	}
+	spec["from"] = pFrom
+
+	// a grant without a name would permit all objects of the kind in the target namespace, which includes
+	// the objects of the vcluster itself and of other virtual namespaces, so those are dropped
+	to, _ := spec["to"].([]interface{})
+	pTo := []interface{}{}
+	for _, ref := range to {
+		if ref, ok := ref.(map[string]interface{}); ok {
+			if name, _ := ref["name"].(string); name != "" {
+				ref["name"] = translate.PhysicalName(name, vReferenceGrant.GetNamespace())
+				pTo = append(pTo, ref)
+			}
+		}
+	}
+	if len(pTo) == 0 {
+		return nil
+	}
+	spec["to"] = pTo
+
+	return spec
+}
+
+// isSharedGatewayRef checks if the grant is for gateways in a namespace of a shared gateway
+func (s *referenceGrantSyncer) isSharedGatewayRef(ref map[string]interface{}) bool {
+	group, _ := ref["group"].(string)
+	kind, _ := ref["kind"].(string)
+	namespace, _ := ref["namespace"].(string)
+	return group == gateways.Group && kind == "Gateway" && s.sharedGatewayNamespaces[namespace]
+}
+
+func newIfNil(updated *unstructured.Unstructured, pObj *unstructured.Unstructured) *unstructured.Unstructured {
+	if updated == nil {
+		return pObj.DeepCopy()
+	}
+	return updated
+}
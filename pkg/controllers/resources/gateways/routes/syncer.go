@@ -0,0 +1,189 @@
+package routes
+
+import (
+	"strings"
+
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/gateways"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer"
+	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	"github.com/loft-sh/vcluster/pkg/util"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+func NewHTTPRouteSyncer(ctx *synccontext.RegisterContext) (syncer.Object, error) {
+	return newRouteSyncer(ctx, "HTTPRoute", "httproute")
+}
+
+func NewGRPCRouteSyncer(ctx *synccontext.RegisterContext) (syncer.Object, error) {
+	return newRouteSyncer(ctx, "GRPCRoute", "grpcroute")
+}
+
+func NewTLSRouteSyncer(ctx *synccontext.RegisterContext) (syncer.Object, error) {
+	return newRouteSyncer(ctx, "TLSRoute", "tlsroute")
+}
+
+func newRouteSyncer(ctx *synccontext.RegisterContext, kind, name string) (syncer.Object, error) {
+	gvk, err := gateways.ServedKind(ctx.PhysicalManager.GetConfig(), kind)
+	if err != nil {
+		return nil, err
+	}
+
+	referenceGrantGVK, err := gateways.ServedKind(ctx.PhysicalManager.GetConfig(), "ReferenceGrant")
+	if err != nil {
+		return nil, err
+	}
+
+	sharedGateways := map[string]bool{}
+	for _, gateway := range ctx.Options.SharedGateways {
+		sharedGateways[strings.TrimSpace(gateway)] = true
+	}
+
+	return &routeSyncer{
+		NamespacedTranslator: translator.NewNamespacedTranslator(ctx, name, gateways.NewObject(gvk)),
+
+		gvk:               gvk,
+		referenceGrantGVK: referenceGrantGVK,
+		targetNamespace:   ctx.TargetNamespace,
+		sharedGateways:    sharedGateways,
+	}, nil
+}
+
+// routeSyncer syncs gateway api routes, which all share the same parentRefs and backendRefs
+type routeSyncer struct {
+	translator.NamespacedTranslator
+
+	gvk               schema.GroupVersionKind
+	referenceGrantGVK schema.GroupVersionKind
+	targetNamespace   string
+
+	// sharedGateways are the host gateways (namespace/name) the routes may attach to
+	sharedGateways map[string]bool
+}
+
+var _ syncer.Initializer = &routeSyncer{}
+
+func (s *routeSyncer) Init(ctx *synccontext.RegisterContext) error {
+	// the reference grants are needed to permit backend references to other namespaces
+	err := util.EnsureCRDFromPhysicalCluster(ctx.Context, ctx.PhysicalManager.GetConfig(), ctx.VirtualManager.GetConfig(), s.referenceGrantGVK)
+	if err != nil {
+		return err
+	}
+
+	return util.EnsureCRDFromPhysicalCluster(ctx.Context, ctx.PhysicalManager.GetConfig(), ctx.VirtualManager.GetConfig(), s.gvk)
+}
+
+var _ syncer.ControllerModifier = &routeSyncer{}
+
+func (s *routeSyncer) ModifyController(ctx *synccontext.RegisterContext, builder *builder.Builder) (*builder.Builder, error) {
+	// reconcile the routes of the namespaces a reference grant is for, as their backends might have changed
+	return builder.Watches(&source.Kind{Type: gateways.NewObject(s.referenceGrantGVK)}, handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		grant, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return nil
+		}
+
+		requests := []reconcile.Request{}
+		from, _, _ := unstructured.NestedSlice(grant.Object, "spec", "from")
+		for _, ref := range from {
+			ref, ok := ref.(map[string]interface{})
+			if !ok || refField(ref, "kind") != s.gvk.Kind || refField(ref, "namespace") == "" {
+				continue
+			}
+
+			routes := &unstructured.UnstructuredList{}
+			routes.SetGroupVersionKind(s.gvk.GroupVersion().WithKind(s.gvk.Kind + "List"))
+			err := ctx.VirtualManager.GetClient().List(ctx.Context, routes, client.InNamespace(refField(ref, "namespace")))
+			if err != nil {
+				klog.Errorf("error listing %s in namespace %s: %v", s.Name(), refField(ref, "namespace"), err)
+				continue
+			}
+
+			for _, route := range routes.Items {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: route.GetNamespace(), Name: route.GetName()}})
+			}
+		}
+
+		return requests
+	})), nil
+}
+
+var _ syncer.Syncer = &routeSyncer{}
+
+func (s *routeSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
+	vRoute := vObj.(*unstructured.Unstructured)
+	grants, err := s.referenceGrants(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	spec, _, denied, deniedBackends := s.translateSpec(vRoute, grants)
+	s.reportDenied(vRoute, denied, deniedBackends)
+	return s.SyncDownCreate(ctx, vObj, s.translate(vRoute, spec))
+}
+
+func (s *routeSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
+	vRoute := vObj.(*unstructured.Unstructured)
+	pRoute := pObj.(*unstructured.Unstructured)
+
+	grants, err := s.referenceGrants(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	spec, parentRefs, denied, deniedBackends := s.translateSpec(vRoute, grants)
+	status := translateStatusBackwards(pRoute, parentRefs)
+	if !equality.Semantic.DeepEqual(vRoute.Object["status"], status) {
+		newRoute := vRoute.DeepCopy()
+		newRoute.Object["status"] = status
+		ctx.Log.Infof("update virtual %s %s/%s, because status is out of sync", s.Name(), vRoute.GetNamespace(), vRoute.GetName())
+		err := ctx.VirtualClient.Status().Update(ctx.Context, newRoute)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// we will requeue anyways
+		return ctrl.Result{}, nil
+	}
+
+	newRoute := s.translateUpdate(pRoute, vRoute, spec)
+	if newRoute != nil {
+		s.reportDenied(vRoute, denied, deniedBackends)
+		translator.PrintChanges(pObj, newRoute, ctx.Log)
+	}
+
+	return s.SyncDownUpdate(ctx, vObj, newRoute)
+}
+
+// referenceGrants returns the reference grants of the virtual cluster
+func (s *routeSyncer) referenceGrants(ctx *synccontext.SyncContext) ([]unstructured.Unstructured, error) {
+	grants := &unstructured.UnstructuredList{}
+	grants.SetGroupVersionKind(s.referenceGrantGVK.GroupVersion().WithKind(s.referenceGrantGVK.Kind + "List"))
+	err := ctx.VirtualClient.List(ctx.Context, grants)
+	if err != nil {
+		return nil, errors.Wrap(err, "list reference grants")
+	}
+
+	return grants.Items, nil
+}
+
+// reportDenied sends an event for each parent and backend reference that was removed from the physical route
+func (s *routeSyncer) reportDenied(vRoute *unstructured.Unstructured, deniedParents, deniedBackends []string) {
+	for _, parent := range deniedParents {
+		s.EventRecorder().Eventf(vRoute, "Warning", "ParentRefNotAllowed", "Parent %s is not a shared gateway of the host cluster and was not synced", parent)
+	}
+	for _, backend := range deniedBackends {
+		s.EventRecorder().Eventf(vRoute, "Warning", "RefNotPermitted", "Backend %s is not a service or not permitted by a ReferenceGrant and was not synced", backend)
+	}
+}
@@ -0,0 +1,318 @@
+package routes
+
+import (
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/gateways"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// parentRefTranslation holds a virtual parent reference and the physical parent reference it was translated to
+type parentRefTranslation struct {
+	virtual  map[string]interface{}
+	physical map[string]interface{}
+}
+
+func (s *routeSyncer) translate(vRoute *unstructured.Unstructured, spec map[string]interface{}) *unstructured.Unstructured {
+	pRoute := s.TranslateMetadata(vRoute).(*unstructured.Unstructured)
+	if spec != nil {
+		pRoute.Object["spec"] = spec
+	}
+	delete(pRoute.Object, "status")
+	return pRoute
+}
+
+func (s *routeSyncer) translateUpdate(pRoute, vRoute *unstructured.Unstructured, spec map[string]interface{}) *unstructured.Unstructured {
+	var updated *unstructured.Unstructured
+
+	if !equality.Semantic.DeepEqual(spec, pRoute.Object["spec"]) {
+		updated = newIfNil(updated, pRoute)
+		updated.Object["spec"] = spec
+	}
+
+	changed, translatedAnnotations, translatedLabels := s.TranslateMetadataUpdate(vRoute, pRoute)
+	if changed {
+		updated = newIfNil(updated, pRoute)
+		updated.SetAnnotations(translatedAnnotations)
+		updated.SetLabels(translatedLabels)
+	}
+
+	return updated
+}
+
+// translateSpec translates the parent and backend references of the virtual route. Parent references
+// to gateways that are not shared and to kinds other than gateways and services are removed and
+// returned as denied. Backend references to kinds other than services and to services in other
+// namespaces that are not permitted by one of the given virtual reference grants are removed and
+// returned as denied as well.
+func (s *routeSyncer) translateSpec(vRoute *unstructured.Unstructured, grants []unstructured.Unstructured) (map[string]interface{}, []parentRefTranslation, []string, []string) {
+	spec, ok := vRoute.Object["spec"].(map[string]interface{})
+	if !ok {
+		return nil, nil, nil, nil
+	}
+	spec = runtime.DeepCopyJSONValue(spec).(map[string]interface{})
+
+	parentRefs := []parentRefTranslation{}
+	denied := []string{}
+	if vParentRefs, ok := spec["parentRefs"].([]interface{}); ok {
+		pParentRefs := []interface{}{}
+		for _, ref := range vParentRefs {
+			vRef, ok := ref.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			pRef, allowed := s.translateParentRef(vRef, vRoute.GetNamespace())
+			if !allowed {
+				denied = append(denied, refName(vRef, vRoute.GetNamespace()))
+				continue
+			}
+
+			pParentRefs = append(pParentRefs, pRef)
+			parentRefs = append(parentRefs, parentRefTranslation{virtual: vRef, physical: pRef})
+		}
+		spec["parentRefs"] = pParentRefs
+	}
+
+	deniedBackends := []string{}
+	if rules, ok := spec["rules"].([]interface{}); ok {
+		for _, rule := range rules {
+			rule, ok := rule.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			deniedBackends = append(deniedBackends, s.translateFilters(rule, vRoute.GetNamespace(), grants)...)
+			if backendRefs, ok := rule["backendRefs"].([]interface{}); ok {
+				pBackendRefs := []interface{}{}
+				for _, backendRef := range backendRefs {
+					backendRef, ok := backendRef.(map[string]interface{})
+					if !ok {
+						continue
+					}
+
+					if !s.translateBackendRef(backendRef, vRoute.GetNamespace(), grants) {
+						deniedBackends = append(deniedBackends, backendRefName(backendRef, vRoute.GetNamespace()))
+						continue
+					}
+
+					deniedBackends = append(deniedBackends, s.translateFilters(backendRef, vRoute.GetNamespace(), grants)...)
+					pBackendRefs = append(pBackendRefs, backendRef)
+				}
+				rule["backendRefs"] = pBackendRefs
+			}
+		}
+	}
+
+	return spec, parentRefs, denied, deniedBackends
+}
+
+// translateParentRef returns the physical parent reference. Gateways are shared between the host and
+// virtual cluster and keep their name, services are translated to their physical name.
+func (s *routeSyncer) translateParentRef(vRef map[string]interface{}, namespace string) (map[string]interface{}, bool) {
+	group, kind := refGroupKind(vRef, gateways.Group, "Gateway")
+	name := refField(vRef, "name")
+	if refField(vRef, "namespace") != "" {
+		namespace = refField(vRef, "namespace")
+	}
+
+	pRef := runtime.DeepCopyJSONValue(vRef).(map[string]interface{})
+	switch {
+	case group == gateways.Group && kind == "Gateway":
+		if !s.sharedGateways[namespace+"/"+name] {
+			return nil, false
+		}
+
+		pRef["namespace"] = namespace
+	case group == "" && kind == "Service":
+		pRef["name"] = translate.PhysicalName(name, namespace)
+		pRef["namespace"] = s.targetNamespace
+	default:
+		return nil, false
+	}
+
+	return pRef, true
+}
+
+// translateBackendRef translates a service backend reference to the physical service. Returns false
+// if the reference points to another kind, which can't be translated to an object of the virtual
+// cluster, or to a service in another namespace that is not permitted by a reference grant.
+func (s *routeSyncer) translateBackendRef(ref map[string]interface{}, routeNamespace string, grants []unstructured.Unstructured) bool {
+	group, kind := refGroupKind(ref, "", "Service")
+	if group != "" || kind != "Service" || refField(ref, "name") == "" {
+		return false
+	}
+	namespace := routeNamespace
+	if refField(ref, "namespace") != "" {
+		namespace = refField(ref, "namespace")
+	}
+
+	// all services are synced into the target namespace, so the reference grants of the virtual
+	// cluster have to be checked here, because the host cluster would allow all references
+	if namespace != routeNamespace && !s.isReferenceGranted(grants, routeNamespace, namespace, refField(ref, "name")) {
+		return false
+	}
+
+	ref["name"] = translate.PhysicalName(refField(ref, "name"), namespace)
+	ref["namespace"] = s.targetNamespace
+	return true
+}
+
+// isReferenceGranted checks if a reference grant in the namespace of the service permits routes of the
+// syncer's kind from the given namespace to reference the service
+func (s *routeSyncer) isReferenceGranted(grants []unstructured.Unstructured, fromNamespace, namespace, name string) bool {
+	for _, grant := range grants {
+		if grant.GetNamespace() != namespace {
+			continue
+		}
+
+		from, _, _ := unstructured.NestedSlice(grant.Object, "spec", "from")
+		to, _, _ := unstructured.NestedSlice(grant.Object, "spec", "to")
+		if containsRef(from, func(ref map[string]interface{}) bool {
+			return refField(ref, "group") == gateways.Group && refField(ref, "kind") == s.gvk.Kind && refField(ref, "namespace") == fromNamespace
+		}) && containsRef(to, func(ref map[string]interface{}) bool {
+			return refField(ref, "group") == "" && refField(ref, "kind") == "Service" && (refField(ref, "name") == "" || refField(ref, "name") == name)
+		}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsRef(refs []interface{}, matches func(ref map[string]interface{}) bool) bool {
+	for _, ref := range refs {
+		if ref, ok := ref.(map[string]interface{}); ok && matches(ref) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// translateFilters translates the backend references of the request mirror filters of the given rule or
+// backend reference. Filters that mirror to services that are not permitted are removed and returned.
+func (s *routeSyncer) translateFilters(obj map[string]interface{}, namespace string, grants []unstructured.Unstructured) []string {
+	filters, ok := obj["filters"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	denied := []string{}
+	pFilters := []interface{}{}
+	for _, filter := range filters {
+		if filter, ok := filter.(map[string]interface{}); ok {
+			requestMirror, _ := filter["requestMirror"].(map[string]interface{})
+			if backendRef, ok := requestMirror["backendRef"].(map[string]interface{}); ok && !s.translateBackendRef(backendRef, namespace, grants) {
+				denied = append(denied, backendRefName(backendRef, namespace))
+				continue
+			}
+		}
+
+		pFilters = append(pFilters, filter)
+	}
+
+	obj["filters"] = pFilters
+	return denied
+}
+
+// translateStatusBackwards returns the status of the physical route with the parent references that
+// were translated back to the virtual parent references. Parents that are unknown are removed.
+func translateStatusBackwards(pRoute *unstructured.Unstructured, parentRefs []parentRefTranslation) interface{} {
+	status, ok := pRoute.Object["status"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	status = runtime.DeepCopyJSONValue(status).(map[string]interface{})
+
+	parents, ok := status["parents"].([]interface{})
+	if !ok {
+		return status
+	}
+
+	vParents := []interface{}{}
+	for _, parent := range parents {
+		parent, ok := parent.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		pRef, ok := parent["parentRef"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, parentRef := range parentRefs {
+			if sameParentRef(parentRef.physical, pRef, pRoute.GetNamespace()) {
+				parent["parentRef"] = runtime.DeepCopyJSONValue(parentRef.virtual)
+				vParents = append(vParents, parent)
+				break
+			}
+		}
+	}
+
+	status["parents"] = vParents
+	return status
+}
+
+func sameParentRef(a, b map[string]interface{}, namespace string) bool {
+	aGroup, aKind := refGroupKind(a, gateways.Group, "Gateway")
+	bGroup, bKind := refGroupKind(b, gateways.Group, "Gateway")
+	aNamespace, bNamespace := refField(a, "namespace"), refField(b, "namespace")
+	if aNamespace == "" {
+		aNamespace = namespace
+	}
+	if bNamespace == "" {
+		bNamespace = namespace
+	}
+
+	return aGroup == bGroup && aKind == bKind && aNamespace == bNamespace &&
+		refField(a, "name") == refField(b, "name") &&
+		refField(a, "sectionName") == refField(b, "sectionName") &&
+		equality.Semantic.DeepEqual(a["port"], b["port"])
+}
+
+// refGroupKind returns the group and kind of the reference with the given defaults
+func refGroupKind(ref map[string]interface{}, defaultGroup, defaultKind string) (string, string) {
+	group, ok := ref["group"].(string)
+	if !ok {
+		group = defaultGroup
+	}
+	kind := refField(ref, "kind")
+	if kind == "" {
+		kind = defaultKind
+	}
+
+	return group, kind
+}
+
+func refField(ref map[string]interface{}, field string) string {
+	value, _ := ref[field].(string)
+	return value
+}
+
+func refName(ref map[string]interface{}, namespace string) string {
+	if refField(ref, "namespace") != "" {
+		namespace = refField(ref, "namespace")
+	}
+
+	_, kind := refGroupKind(ref, gateways.Group, "Gateway")
+	return kind + " " + namespace + "/" + refField(ref, "name")
+}
+
+func backendRefName(ref map[string]interface{}, namespace string) string {
+	if refField(ref, "namespace") != "" {
+		namespace = refField(ref, "namespace")
+	}
+
+	_, kind := refGroupKind(ref, "", "Service")
+	return kind + " " + namespace + "/" + refField(ref, "name")
+}
+
+func newIfNil(updated *unstructured.Unstructured, pObj *unstructured.Unstructured) *unstructured.Unstructured {
+	if updated == nil {
+		return pObj.DeepCopy()
+	}
+	return updated
+}
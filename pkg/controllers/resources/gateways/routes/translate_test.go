@@ -0,0 +1,145 @@
+package routes
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/gateways"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestTranslateSpec(t *testing.T) {
+	s := &routeSyncer{
+		gvk:             schema.GroupVersionKind{Group: gateways.Group, Version: "v1beta1", Kind: "HTTPRoute"},
+		targetNamespace: "vcluster",
+		sharedGateways:  map[string]bool{"gateway-system/shared": true},
+	}
+
+	vRoute := parseRoute(t, `
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: HTTPRoute
+metadata:
+  name: web
+  namespace: default
+spec:
+  parentRefs:
+  - name: shared
+    namespace: gateway-system
+    sectionName: https
+  - name: private
+  - group: ""
+    kind: Service
+    name: mesh
+  rules:
+  - backendRefs:
+    - name: web
+      port: 80
+      filters:
+      - type: RequestMirror
+        requestMirror:
+          backendRef:
+            name: mirror
+            namespace: other
+            port: 80
+    - group: example.com
+      kind: Bucket
+      name: static
+    - name: api
+      namespace: private
+      port: 80
+    - name: admin
+      namespace: other
+      port: 80`)
+
+	// the grant in the other namespace permits the mirror service, while another grant permits all
+	// services for a different kind only
+	grants := []unstructured.Unstructured{
+		*parseRoute(t, `
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: ReferenceGrant
+metadata:
+  name: mirror
+  namespace: other
+spec:
+  from:
+  - group: gateway.networking.k8s.io
+    kind: HTTPRoute
+    namespace: default
+  to:
+  - group: ""
+    kind: Service
+    name: mirror`),
+		*parseRoute(t, `
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: ReferenceGrant
+metadata:
+  name: grpc
+  namespace: private
+spec:
+  from:
+  - group: gateway.networking.k8s.io
+    kind: GRPCRoute
+    namespace: default
+  to:
+  - group: ""
+    kind: Service`),
+	}
+
+	spec, parentRefs, denied, deniedBackends := s.translateSpec(vRoute, grants)
+	assert.DeepEqual(t, denied, []string{"Gateway default/private"})
+	assert.DeepEqual(t, deniedBackends, []string{"Bucket default/static", "Service private/api", "Service other/admin"})
+	assert.Equal(t, len(parentRefs), 2)
+	assert.DeepEqual(t, spec["parentRefs"], []interface{}{
+		map[string]interface{}{"name": "shared", "namespace": "gateway-system", "sectionName": "https"},
+		map[string]interface{}{"group": "", "kind": "Service", "name": translate.PhysicalName("mesh", "default"), "namespace": "vcluster"},
+	})
+
+	backendRefs := spec["rules"].([]interface{})[0].(map[string]interface{})["backendRefs"].([]interface{})
+	web := backendRefs[0].(map[string]interface{})
+	assert.Equal(t, web["name"], translate.PhysicalName("web", "default"))
+	assert.Equal(t, web["namespace"], "vcluster")
+	mirror := web["filters"].([]interface{})[0].(map[string]interface{})["requestMirror"].(map[string]interface{})["backendRef"].(map[string]interface{})
+	assert.Equal(t, mirror["name"], translate.PhysicalName("mirror", "other"))
+	assert.Equal(t, mirror["namespace"], "vcluster")
+	assert.Equal(t, len(backendRefs), 1)
+
+	// the virtual route must not be changed
+	assert.Equal(t, len(vRoute.Object["spec"].(map[string]interface{})["parentRefs"].([]interface{})), 3)
+
+	// the status of the physical route is translated back to the virtual parent refs
+	pRoute := parseRoute(t, `
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: HTTPRoute
+metadata:
+  name: web-x-default-x-suffix
+  namespace: vcluster
+status:
+  parents:
+  - controllerName: gateway.envoyproxy.io/gatewayclass-controller
+    parentRef:
+      group: gateway.networking.k8s.io
+      kind: Gateway
+      name: shared
+      namespace: gateway-system
+      sectionName: https
+  - controllerName: gateway.envoyproxy.io/gatewayclass-controller
+    parentRef:
+      name: unknown`)
+	status := translateStatusBackwards(pRoute, parentRefs).(map[string]interface{})
+	assert.DeepEqual(t, status["parents"], []interface{}{
+		map[string]interface{}{
+			"controllerName": "gateway.envoyproxy.io/gatewayclass-controller",
+			"parentRef":      map[string]interface{}{"name": "shared", "namespace": "gateway-system", "sectionName": "https"},
+		},
+	})
+}
+
+func parseRoute(t *testing.T, raw string) *unstructured.Unstructured {
+	obj := map[string]interface{}{}
+	err := yaml.Unmarshal([]byte(raw), &obj)
+	assert.NilError(t, err)
+	return &unstructured.Unstructured{Object: obj}
+}
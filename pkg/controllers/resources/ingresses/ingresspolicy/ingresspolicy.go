@@ -0,0 +1,222 @@
+package ingresspolicy
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// IngressClassAnnotation is the deprecated annotation that selects the ingress class
+const IngressClassAnnotation = "kubernetes.io/ingress.class"
+
+// HostAnnotations are the ingress annotations that add hosts to an ingress in addition to the hosts of
+// its rules. Their hosts are translated and validated like the hosts of the rules.
+var HostAnnotations = []string{
+	"nginx.ingress.kubernetes.io/server-alias",
+}
+
+// DefaultDeniedAnnotations are the ingress annotations that inject raw configuration into the shared
+// ingress controller of the host, which would allow a tenant to change the routing of other tenants
+var DefaultDeniedAnnotations = []string{
+	"nginx.ingress.kubernetes.io/configuration-snippet",
+	"nginx.ingress.kubernetes.io/server-snippet",
+	"nginx.ingress.kubernetes.io/auth-snippet",
+	"nginx.ingress.kubernetes.io/stream-snippet",
+	"nginx.ingress.kubernetes.io/modsecurity-snippet",
+	"nginx.org/server-snippets",
+	"nginx.org/location-snippets",
+}
+
+// Policy restricts the hosts, ingress classes and annotations of virtual ingresses, as all ingresses of the
+// vcluster end up in the shared ingress controllers of the host cluster. Ingresses that violate the policy
+// are not synced to the host cluster.
+type Policy struct {
+	// Hosts are the hosts the ingress rules and tls sections may use, supports * wildcards. Empty allows all hosts.
+	Hosts []string `json:"hosts,omitempty"`
+	// HostSuffix is appended to each host that does not end with it yet, e.g. app becomes app.team-a.dev.corp
+	HostSuffix string `json:"hostSuffix,omitempty"`
+	// IngressClasses are the ingress classes the ingresses may use. Empty allows all ingress classes.
+	IngressClasses []string `json:"ingressClasses,omitempty"`
+	// DefaultIngressClass is used for ingresses without an ingress class
+	DefaultIngressClass string `json:"defaultIngressClass,omitempty"`
+	// DeniedAnnotations are removed from the ingresses before they are synced, supports * wildcards.
+	// Defaults to DefaultDeniedAnnotations.
+	DeniedAnnotations []string `json:"deniedAnnotations,omitempty"`
+
+	hosts             []*regexp.Regexp
+	ingressClasses    map[string]bool
+	deniedAnnotations []*regexp.Regexp
+}
+
+// Load reads and validates the ingress policy from the given yaml file. If path is empty,
+// nil is returned, which syncs all ingresses as they are.
+func Load(path string) (*Policy, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	out, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{}
+	err = yaml.Unmarshal(out, policy)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse ingress policy %s", path)
+	}
+
+	err = policy.compile()
+	if err != nil {
+		return nil, errors.Wrap(err, "ingress policy")
+	}
+
+	return policy, nil
+}
+
+func (p *Policy) compile() error {
+	p.HostSuffix = strings.Trim(p.HostSuffix, ".")
+	p.hosts = compilePatterns(p.Hosts)
+	p.ingressClasses = map[string]bool{}
+	for _, ingressClass := range p.IngressClasses {
+		p.ingressClasses[ingressClass] = true
+	}
+	if p.DefaultIngressClass != "" && len(p.ingressClasses) > 0 && !p.ingressClasses[p.DefaultIngressClass] {
+		return fmt.Errorf("defaultIngressClass %s is not part of ingressClasses", p.DefaultIngressClass)
+	}
+
+	deniedAnnotations := p.DeniedAnnotations
+	if deniedAnnotations == nil {
+		deniedAnnotations = DefaultDeniedAnnotations
+	}
+	p.deniedAnnotations = compilePatterns(deniedAnnotations)
+	return nil
+}
+
+func compilePatterns(patterns []string) []*regexp.Regexp {
+	compiled := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		compiled = append(compiled, regexp.MustCompile("^"+strings.ReplaceAll(regexp.QuoteMeta(pattern), "\\*", ".*")+"$"))
+	}
+
+	return compiled
+}
+
+func matchesAny(patterns []*regexp.Regexp, value string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TranslateHost appends the host suffix to the given host if it does not end with it yet
+func (p *Policy) TranslateHost(host string) string {
+	if p == nil || p.HostSuffix == "" || host == "" || host == p.HostSuffix || strings.HasSuffix(host, "."+p.HostSuffix) {
+		return host
+	}
+
+	return host + "." + p.HostSuffix
+}
+
+// IngressClass returns the ingress class the host ingress should use, which is the default
+// ingress class of the policy if the virtual ingress has none
+func (p *Policy) IngressClass(ingressClass string) string {
+	if p == nil || ingressClass != "" {
+		return ingressClass
+	}
+
+	return p.DefaultIngressClass
+}
+
+// Validate checks the translated hosts and ingress class of an ingress against the policy and returns the
+// violations. An empty host stands for a rule or default backend that matches all hosts, which is not allowed
+// as soon as hosts are restricted.
+func (p *Policy) Validate(hosts []string, ingressClass string) []string {
+	if p == nil {
+		return nil
+	}
+
+	violations := []string{}
+	if len(p.ingressClasses) > 0 {
+		if ingressClass == "" {
+			violations = append(violations, "an ingress class is required")
+		} else if !p.ingressClasses[ingressClass] {
+			violations = append(violations, fmt.Sprintf("ingress class %s is not allowed", ingressClass))
+		}
+	}
+
+	if len(p.hosts) == 0 && p.HostSuffix == "" {
+		return violations
+	}
+
+	catchAll := false
+	for _, host := range hosts {
+		if host == "" {
+			catchAll = true
+		} else if len(p.hosts) > 0 && !matchesAny(p.hosts, host) {
+			violations = append(violations, fmt.Sprintf("host %s is not allowed", host))
+		}
+	}
+	if catchAll {
+		violations = append(violations, "rules and default backends without a host are not allowed")
+	}
+
+	return violations
+}
+
+// AnnotationHosts returns the hosts of the host annotations of an ingress
+func AnnotationHosts(annotations map[string]string) []string {
+	hosts := []string{}
+	for _, annotation := range HostAnnotations {
+		hosts = append(hosts, splitHosts(annotations[annotation])...)
+	}
+
+	return hosts
+}
+
+func splitHosts(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// FilterAnnotations removes the denied annotations and applies the host suffix to the hosts of the host
+// annotations. Returns the filtered annotations and the keys of the removed annotations.
+func (p *Policy) FilterAnnotations(annotations map[string]string) (map[string]string, []string) {
+	if p == nil || annotations == nil {
+		return annotations, nil
+	}
+
+	filtered := map[string]string{}
+	denied := []string{}
+	for k, v := range annotations {
+		if matchesAny(p.deniedAnnotations, k) {
+			denied = append(denied, k)
+			continue
+		}
+
+		filtered[k] = v
+	}
+	for _, annotation := range HostAnnotations {
+		if filtered[annotation] == "" {
+			continue
+		}
+
+		hosts := []string{}
+		for _, host := range splitHosts(filtered[annotation]) {
+			hosts = append(hosts, p.TranslateHost(host))
+		}
+		filtered[annotation] = strings.Join(hosts, ",")
+	}
+
+	sort.Strings(denied)
+	return filtered, denied
+}
@@ -0,0 +1,51 @@
+package ingresspolicy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	err := os.WriteFile(path, []byte(`
+hosts: ["*.team-a.dev.corp"]
+hostSuffix: .team-a.dev.corp
+ingressClasses: ["nginx", "internal"]
+defaultIngressClass: nginx`), 0600)
+	assert.NilError(t, err)
+
+	policy, err := Load(path)
+	assert.NilError(t, err)
+
+	assert.Equal(t, policy.TranslateHost("app"), "app.team-a.dev.corp")
+	assert.Equal(t, policy.TranslateHost("app.team-a.dev.corp"), "app.team-a.dev.corp")
+	assert.Equal(t, policy.TranslateHost(""), "")
+	assert.Equal(t, policy.IngressClass(""), "nginx")
+	assert.Equal(t, policy.IngressClass("internal"), "internal")
+
+	assert.Equal(t, len(policy.Validate([]string{"app.team-a.dev.corp", "*.team-a.dev.corp"}, "nginx")), 0)
+	assert.DeepEqual(t, policy.Validate([]string{"app.team-b.dev.corp", ""}, "public"), []string{
+		"ingress class public is not allowed",
+		"host app.team-b.dev.corp is not allowed",
+		"rules and default backends without a host are not allowed",
+	})
+
+	annotations, denied := policy.FilterAnnotations(map[string]string{
+		"nginx.ingress.kubernetes.io/server-snippet": "location / { proxy_pass http://other; }",
+		"nginx.ingress.kubernetes.io/rewrite-target": "/",
+	})
+	assert.DeepEqual(t, annotations, map[string]string{"nginx.ingress.kubernetes.io/rewrite-target": "/"})
+	assert.DeepEqual(t, denied, []string{"nginx.ingress.kubernetes.io/server-snippet"})
+
+	// hosts added by annotations get the host suffix
+	annotations, _ = policy.FilterAnnotations(map[string]string{"nginx.ingress.kubernetes.io/server-alias": "www api.team-a.dev.corp"})
+	assert.DeepEqual(t, AnnotationHosts(annotations), []string{"www.team-a.dev.corp", "api.team-a.dev.corp"})
+
+	// without a policy everything is allowed
+	var noPolicy *Policy
+	assert.Equal(t, noPolicy.TranslateHost("app"), "app")
+	assert.Equal(t, len(noPolicy.Validate([]string{""}, "public")), 0)
+}
@@ -1,6 +1,7 @@
 package legacy
 
 import (
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/ingresses/ingresspolicy"
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/ingresses/util"
 	"github.com/loft-sh/vcluster/pkg/util/translate"
 	networkingv1beta1 "k8s.io/api/networking/v1beta1"
@@ -18,14 +19,14 @@ func (s *ingressSyncer) TranslateMetadataUpdate(vObj client.Object, pObj client.
 
 func (s *ingressSyncer) translate(vIngress *networkingv1beta1.Ingress) *networkingv1beta1.Ingress {
 	newIngress := s.TranslateMetadata(vIngress).(*networkingv1beta1.Ingress)
-	newIngress.Spec = *translateSpec(vIngress.Namespace, &vIngress.Spec)
+	newIngress.Spec = *s.translateSpec(vIngress)
 	return newIngress
 }
 
 func (s *ingressSyncer) translateUpdate(pObj, vObj *networkingv1beta1.Ingress) *networkingv1beta1.Ingress {
 	var updated *networkingv1beta1.Ingress
 
-	translatedSpec := *translateSpec(vObj.Namespace, &vObj.Spec)
+	translatedSpec := *s.translateSpec(vObj)
 	if !equality.Semantic.DeepEqual(translatedSpec, pObj.Spec) {
 		updated = newIfNil(updated, pObj)
 		updated.Spec = translatedSpec
@@ -52,6 +53,24 @@ func (s *ingressSyncer) translateUpdateBackwards(pObj, vObj *networkingv1beta1.I
 	return updated
 }
 
+// translateSpec translates the spec and applies the host suffix and default ingress class of the ingress policy
+func (s *ingressSyncer) translateSpec(vIngress *networkingv1beta1.Ingress) *networkingv1beta1.IngressSpec {
+	retSpec := translateSpec(vIngress.Namespace, &vIngress.Spec)
+	for i := range retSpec.Rules {
+		retSpec.Rules[i].Host = s.policy.TranslateHost(retSpec.Rules[i].Host)
+	}
+	for i := range retSpec.TLS {
+		for j := range retSpec.TLS[i].Hosts {
+			retSpec.TLS[i].Hosts[j] = s.policy.TranslateHost(retSpec.TLS[i].Hosts[j])
+		}
+	}
+	if ingressClass := s.policy.IngressClass(ingressClassName(vIngress)); retSpec.IngressClassName == nil && ingressClass != "" && ingressClass != vIngress.Annotations[ingresspolicy.IngressClassAnnotation] {
+		retSpec.IngressClassName = &ingressClass
+	}
+
+	return retSpec
+}
+
 func translateSpec(namespace string, vIngressSpec *networkingv1beta1.IngressSpec) *networkingv1beta1.IngressSpec {
 	retSpec := vIngressSpec.DeepCopy()
 	if retSpec.Backend != nil {
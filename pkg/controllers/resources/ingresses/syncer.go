@@ -1,31 +1,54 @@
 package ingresses
 
 import (
+	"strings"
+
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/ingresses/ingresspolicy"
 	"github.com/loft-sh/vcluster/pkg/controllers/syncer"
 	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
 	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
 	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"github.com/pkg/errors"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"strings"
 )
 
 func NewSyncer(ctx *synccontext.RegisterContext) (syncer.Object, error) {
+	policy, err := ingresspolicy.Load(ctx.Options.IngressPolicy)
+	if err != nil {
+		return nil, errors.Wrap(err, "load ingress policy")
+	}
+
 	return &ingressSyncer{
 		NamespacedTranslator: translator.NewNamespacedTranslator(ctx, "ingress", &networkingv1.Ingress{}),
+
+		policy: policy,
+		events: translator.NewEventCache(),
 	}, nil
 }
 
 type ingressSyncer struct {
 	translator.NamespacedTranslator
+
+	policy *ingresspolicy.Policy
+	events *translator.EventCache
 }
 
 var _ syncer.Syncer = &ingressSyncer{}
 
 func (s *ingressSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
-	return s.SyncDownCreate(ctx, vObj, s.translate(vObj.(*networkingv1.Ingress)))
+	vIngress := vObj.(*networkingv1.Ingress)
+	filtered, denied := s.filterAnnotations(vIngress)
+	pIngress := s.translate(filtered)
+	if s.violatesPolicy(vIngress, pIngress) {
+		return ctrl.Result{}, nil
+	}
+
+	s.recordDeniedAnnotations(vIngress, denied)
+	return s.SyncDownCreate(ctx, vObj, pIngress)
 }
 
 func (s *ingressSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
@@ -59,14 +82,92 @@ func (s *ingressSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, v
 		return ctrl.Result{}, nil
 	}
 
-	newIngress := s.translateUpdate(pIngress, vIngress)
+	filtered, denied := s.filterAnnotations(vIngress)
+	newIngress := s.translateUpdate(pIngress, filtered)
+
+	// validate the translated ingress on every sync, as the physical ingress might have been created
+	// with a different policy
+	translated := pIngress
+	if newIngress != nil {
+		translated = newIngress
+	}
+	if s.violatesPolicy(vIngress, translated) {
+		// the ingress would otherwise keep serving hosts it is not allowed to
+		ctx.Log.Infof("delete physical ingress %s/%s, because it violates the ingress policy", pIngress.Namespace, pIngress.Name)
+		err := ctx.PhysicalClient.Delete(ctx.Context, pIngress)
+		if err != nil && !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, nil
+	}
+
 	if newIngress != nil {
+		s.recordDeniedAnnotations(vIngress, denied)
 		translator.PrintChanges(pObj, newIngress, ctx.Log)
 	}
 
 	return s.SyncDownUpdate(ctx, vObj, newIngress)
 }
 
+// filterAnnotations returns a copy of the virtual ingress without the annotations the ingress policy denies
+// and with the translated host annotations, as well as the denied annotations
+func (s *ingressSyncer) filterAnnotations(vIngress *networkingv1.Ingress) (*networkingv1.Ingress, []string) {
+	annotations, denied := s.policy.FilterAnnotations(vIngress.Annotations)
+	if equality.Semantic.DeepEqual(annotations, vIngress.Annotations) {
+		return vIngress, nil
+	}
+
+	filtered := vIngress.DeepCopy()
+	filtered.Annotations = annotations
+	return filtered, denied
+}
+
+// recordDeniedAnnotations records an event for the denied annotations, which is only done when the physical
+// ingress is created or updated to not record the same event on every sync
+func (s *ingressSyncer) recordDeniedAnnotations(vIngress *networkingv1.Ingress, denied []string) {
+	if len(denied) == 0 {
+		return
+	}
+
+	s.EventRecorder().Eventf(vIngress, "Warning", "IngressAnnotationDenied", "Annotations %s are not allowed by the ingress policy and are not synced to the host cluster", strings.Join(denied, ", "))
+}
+
+// violatesPolicy checks the translated ingress against the ingress policy and reports the violations
+// as event on the virtual ingress, which is only done once per spec and annotations of the virtual ingress
+func (s *ingressSyncer) violatesPolicy(vIngress, pIngress *networkingv1.Ingress) bool {
+	hosts := []string{}
+	if pIngress.Spec.DefaultBackend != nil {
+		hosts = append(hosts, "")
+	}
+	for _, rule := range pIngress.Spec.Rules {
+		hosts = append(hosts, rule.Host)
+	}
+	for _, tls := range pIngress.Spec.TLS {
+		hosts = append(hosts, tls.Hosts...)
+	}
+	hosts = append(hosts, ingresspolicy.AnnotationHosts(pIngress.Annotations)...)
+
+	violations := s.policy.Validate(hosts, ingressClassName(pIngress))
+	if len(violations) == 0 {
+		s.events.Forget(vIngress, "IngressPolicyViolation")
+		return false
+	} else if !s.events.ShouldRecord(vIngress, "IngressPolicyViolation", []interface{}{vIngress.Spec, vIngress.Annotations}) {
+		return true
+	}
+
+	s.EventRecorder().Eventf(vIngress, "Warning", "IngressPolicyViolation", "Ingress is not synced to the host cluster: %s", strings.Join(violations, ", "))
+	return true
+}
+
+func ingressClassName(ingress *networkingv1.Ingress) string {
+	if ingress.Spec.IngressClassName != nil {
+		return *ingress.Spec.IngressClassName
+	}
+
+	return ingress.Annotations[ingresspolicy.IngressClassAnnotation]
+}
+
 func SecretNamesFromIngress(ingress *networkingv1.Ingress) []string {
 	secrets := []string{}
 	_, extraSecrets := translateIngressAnnotations(ingress.Annotations, ingress.Namespace)
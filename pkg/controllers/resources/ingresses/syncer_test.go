@@ -5,13 +5,17 @@ import (
 	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
 	"gotest.tools/assert"
 	"k8s.io/apimachinery/pkg/types"
+	"os"
+	"path/filepath"
 	"testing"
 
 	generictesting "github.com/loft-sh/vcluster/pkg/controllers/syncer/testing"
+	testingutil "github.com/loft-sh/vcluster/pkg/util/testing"
 	"github.com/loft-sh/vcluster/pkg/util/translate"
 
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -227,6 +231,26 @@ func TestSync(t *testing.T) {
 				assert.NilError(t, err)
 			},
 		},
+		{
+			Name:                 "Delete unchanged ingress that violates the policy",
+			InitialVirtualState:  []runtime.Object{baseIngress.DeepCopy()},
+			InitialPhysicalState: []runtime.Object{createdIngress.DeepCopy()},
+			ExpectedVirtualState: map[schema.GroupVersionKind][]runtime.Object{
+				networkingv1.SchemeGroupVersion.WithKind("Ingress"): {baseIngress.DeepCopy()},
+			},
+			ExpectedPhysicalState: map[schema.GroupVersionKind][]runtime.Object{
+				networkingv1.SchemeGroupVersion.WithKind("Ingress"): {},
+			},
+			Sync: func(registerContext *synccontext.RegisterContext) {
+				policyPath := filepath.Join(t.TempDir(), "policy.yaml")
+				assert.NilError(t, os.WriteFile(policyPath, []byte("ingressClasses:\n- nginx\n"), 0644))
+				registerContext.Options.IngressPolicy = policyPath
+				syncCtx, syncer := generictesting.FakeStartSyncer(t, registerContext, NewSyncer)
+
+				_, err := syncer.(*ingressSyncer).Sync(syncCtx, createdIngress.DeepCopy(), baseIngress.DeepCopy())
+				assert.NilError(t, err)
+			},
+		},
 		{
 			Name:                 "Update backwards",
 			InitialVirtualState:  []runtime.Object{baseIngress.DeepCopy()},
@@ -431,3 +455,43 @@ func TestSync(t *testing.T) {
 func stringPointer(str string) *string {
 	return &str
 }
+
+func TestServerAlias(t *testing.T) {
+	translate.Suffix = generictesting.DefaultTestVclusterName
+	newSyncer := func(policy string) (*synccontext.SyncContext, *ingressSyncer, *testingutil.FakeIndexClient) {
+		policyPath := filepath.Join(t.TempDir(), "policy.yaml")
+		assert.NilError(t, os.WriteFile(policyPath, []byte(policy), 0644))
+		scheme := testingutil.NewScheme()
+		pClient := testingutil.NewFakeClient(scheme)
+		registerContext := generictesting.NewFakeRegisterContext(pClient, testingutil.NewFakeClient(scheme))
+		registerContext.Options.IngressPolicy = policyPath
+		syncCtx, syncer := generictesting.FakeStartSyncer(t, registerContext, NewSyncer)
+		return syncCtx, syncer.(*ingressSyncer), pClient
+	}
+
+	vIngress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Namespace:   "default",
+			Annotations: map[string]string{"nginx.ingress.kubernetes.io/server-alias": "www.team-b.dev.corp"},
+		},
+		Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{Host: "app.team-a.dev.corp"}}},
+	}
+	pName := types.NamespacedName{Namespace: generictesting.DefaultTestTargetNamespace, Name: translate.PhysicalName(vIngress.Name, vIngress.Namespace)}
+
+	// aliases are not allowed to claim hosts of other tenants and the violation is only reported once
+	syncCtx, s, pClient := newSyncer("hosts: [\"*.team-a.dev.corp\"]\n")
+	_, err := s.SyncDown(syncCtx, vIngress.DeepCopy())
+	assert.NilError(t, err)
+	assert.Assert(t, kerrors.IsNotFound(pClient.Get(syncCtx.Context, pName, &networkingv1.Ingress{})))
+	assert.Assert(t, !s.events.ShouldRecord(vIngress, "IngressPolicyViolation", []interface{}{vIngress.Spec, vIngress.Annotations}))
+
+	// aliases get the host suffix like the hosts of the rules
+	syncCtx, s, pClient = newSyncer("hostSuffix: team-a.dev.corp\n")
+	vIngress.Annotations["nginx.ingress.kubernetes.io/server-alias"] = "www, api.team-a.dev.corp"
+	_, err = s.SyncDown(syncCtx, vIngress.DeepCopy())
+	assert.NilError(t, err)
+	pIngress := &networkingv1.Ingress{}
+	assert.NilError(t, pClient.Get(syncCtx.Context, pName, pIngress))
+	assert.Equal(t, pIngress.Annotations["nginx.ingress.kubernetes.io/server-alias"], "www.team-a.dev.corp,api.team-a.dev.corp")
+}
@@ -1,6 +1,7 @@
 package ingresses
 
 import (
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/ingresses/ingresspolicy"
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/ingresses/util"
 	"github.com/loft-sh/vcluster/pkg/util/translate"
 	networkingv1 "k8s.io/api/networking/v1"
@@ -10,7 +11,7 @@ import (
 
 func (s *ingressSyncer) translate(vIngress *networkingv1.Ingress) *networkingv1.Ingress {
 	newIngress := s.TranslateMetadata(vIngress).(*networkingv1.Ingress)
-	newIngress.Spec = *translateSpec(vIngress.Namespace, &vIngress.Spec)
+	newIngress.Spec = *s.translateSpec(vIngress)
 	newIngress.Annotations, _ = translateIngressAnnotations(newIngress.Annotations, vIngress.Namespace)
 	return newIngress
 }
@@ -26,7 +27,7 @@ func (s *ingressSyncer) TranslateMetadataUpdate(vObj client.Object, pObj client.
 func (s *ingressSyncer) translateUpdate(pObj, vObj *networkingv1.Ingress) *networkingv1.Ingress {
 	var updated *networkingv1.Ingress
 
-	translatedSpec := *translateSpec(vObj.Namespace, &vObj.Spec)
+	translatedSpec := *s.translateSpec(vObj)
 	if !equality.Semantic.DeepEqual(translatedSpec, pObj.Spec) {
 		updated = newIfNil(updated, pObj)
 		updated.Spec = translatedSpec
@@ -54,6 +55,24 @@ func (s *ingressSyncer) translateUpdateBackwards(pObj, vObj *networkingv1.Ingres
 	return updated
 }
 
+// translateSpec translates the spec and applies the host suffix and default ingress class of the ingress policy
+func (s *ingressSyncer) translateSpec(vIngress *networkingv1.Ingress) *networkingv1.IngressSpec {
+	retSpec := translateSpec(vIngress.Namespace, &vIngress.Spec)
+	for i := range retSpec.Rules {
+		retSpec.Rules[i].Host = s.policy.TranslateHost(retSpec.Rules[i].Host)
+	}
+	for i := range retSpec.TLS {
+		for j := range retSpec.TLS[i].Hosts {
+			retSpec.TLS[i].Hosts[j] = s.policy.TranslateHost(retSpec.TLS[i].Hosts[j])
+		}
+	}
+	if ingressClass := s.policy.IngressClass(ingressClassName(vIngress)); retSpec.IngressClassName == nil && ingressClass != "" && ingressClass != vIngress.Annotations[ingresspolicy.IngressClassAnnotation] {
+		retSpec.IngressClassName = &ingressClass
+	}
+
+	return retSpec
+}
+
 func translateSpec(namespace string, vIngressSpec *networkingv1.IngressSpec) *networkingv1.IngressSpec {
 	retSpec := vIngressSpec.DeepCopy()
 	if retSpec.DefaultBackend != nil {
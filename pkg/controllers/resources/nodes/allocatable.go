@@ -0,0 +1,162 @@
+package nodes
+
+import (
+	"context"
+
+	"github.com/loft-sh/vcluster/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// allocatableResources are the resources of a node that are reduced by the requests of other pods
+var allocatableResources = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage, corev1.ResourcePods}
+
+// freeAllocatable calculates the resources of the host node that are still available for the
+// virtual cluster, which is the allocatable of the host node minus the requests of all pods on
+// the node that are not synced by this virtual cluster. Optionally the result is capped by
+// the resource quotas in the target namespace.
+func (s *nodeSyncer) freeAllocatable(ctx context.Context, pNode *corev1.Node) corev1.ResourceList {
+	free := corev1.ResourceList{}
+	for _, name := range allocatableResources {
+		if value, ok := pNode.Status.Allocatable[name]; ok {
+			free[name] = value.DeepCopy()
+		}
+	}
+
+	podList := &corev1.PodList{}
+	err := s.podCache.List(ctx, podList, client.MatchingFields{indexPodByRunningNonVClusterNode: pNode.Name})
+	if err != nil {
+		klog.Errorf("Error listing pods: %v", err)
+	} else {
+		for i := range podList.Items {
+			subtractResources(free, podRequests(&podList.Items[i]))
+		}
+	}
+
+	if s.capAllocatableByQuota {
+		s.capByQuota(ctx, pNode, free)
+	}
+
+	return free
+}
+
+// capByQuota caps the given resources by what is left in the resource quotas of the target
+// namespace. As the virtual scheduler accounts for the virtual pods on the node itself, the
+// requests of the virtual cluster pods on this node are added back to the remaining quota.
+func (s *nodeSyncer) capByQuota(ctx context.Context, pNode *corev1.Node, free corev1.ResourceList) {
+	quotaList := &corev1.ResourceQuotaList{}
+	err := s.physicalClient.List(ctx, quotaList, client.InNamespace(s.targetNamespace))
+	if err != nil {
+		klog.Errorf("Error listing resource quotas: %v", err)
+		return
+	} else if len(quotaList.Items) == 0 {
+		return
+	}
+
+	nodeRequests := corev1.ResourceList{}
+	podList := &corev1.PodList{}
+	err = s.physicalClient.List(ctx, podList, client.MatchingFields{constants.IndexByAssigned: pNode.Name})
+	if err != nil {
+		klog.Errorf("Error listing pods: %v", err)
+		return
+	}
+	for i := range podList.Items {
+		if podList.Items[i].Status.Phase == corev1.PodSucceeded || podList.Items[i].Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		addResources(nodeRequests, podRequests(&podList.Items[i]))
+	}
+
+	for _, quota := range quotaList.Items {
+		for quotaName, hard := range quota.Status.Hard {
+			name, ok := quotaResourceName(quotaName)
+			if !ok {
+				continue
+			}
+			current, ok := free[name]
+			if !ok {
+				continue
+			}
+
+			remaining := hard.DeepCopy()
+			if used, ok := quota.Status.Used[quotaName]; ok {
+				remaining.Sub(used)
+			}
+			if nodeUsed, ok := nodeRequests[name]; ok {
+				remaining.Add(nodeUsed)
+			}
+			if remaining.Cmp(current) < 0 {
+				free[name] = remaining
+			}
+		}
+	}
+	clampResources(free)
+}
+
+// quotaResourceName maps the resource names of a quota that limit requests to the node resource names
+func quotaResourceName(name corev1.ResourceName) (corev1.ResourceName, bool) {
+	switch name {
+	case corev1.ResourceCPU, corev1.ResourceRequestsCPU:
+		return corev1.ResourceCPU, true
+	case corev1.ResourceMemory, corev1.ResourceRequestsMemory:
+		return corev1.ResourceMemory, true
+	case corev1.ResourceEphemeralStorage, corev1.ResourceRequestsEphemeralStorage:
+		return corev1.ResourceEphemeralStorage, true
+	case corev1.ResourcePods, "count/pods":
+		return corev1.ResourcePods, true
+	}
+
+	return "", false
+}
+
+// podRequests returns the effective requests of a pod the same way the scheduler calculates them,
+// which is the maximum of the sum of all containers and each init container plus the pod overhead
+func podRequests(pod *corev1.Pod) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		addResources(requests, container.Resources.Requests)
+	}
+	for _, container := range pod.Spec.InitContainers {
+		for name, value := range container.Resources.Requests {
+			if current, ok := requests[name]; !ok || value.Cmp(current) > 0 {
+				requests[name] = value.DeepCopy()
+			}
+		}
+	}
+	addResources(requests, pod.Spec.Overhead)
+	requests[corev1.ResourcePods] = *resource.NewQuantity(1, resource.DecimalSI)
+	return requests
+}
+
+func addResources(a corev1.ResourceList, b corev1.ResourceList) {
+	for name, value := range b {
+		current := a[name]
+		current.Add(value)
+		a[name] = current
+	}
+}
+
+// subtractResources subtracts b from a for all resources of a and caps the result at zero
+func subtractResources(a corev1.ResourceList, b corev1.ResourceList) {
+	for name, value := range b {
+		current, ok := a[name]
+		if !ok {
+			continue
+		}
+
+		current.Sub(value)
+		a[name] = current
+	}
+	clampResources(a)
+}
+
+func clampResources(list corev1.ResourceList) {
+	for name, value := range list {
+		if value.Sign() < 0 {
+			list[name] = *resource.NewQuantity(0, value.Format)
+		}
+	}
+}
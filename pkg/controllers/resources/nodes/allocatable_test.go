@@ -0,0 +1,67 @@
+package nodes
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestPodRequests(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m"), corev1.ResourceMemory: resource.MustParse("128Mi")}}},
+				{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("300m")}}},
+			},
+			InitContainers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("1Gi")}}},
+			},
+			Overhead: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m")},
+		},
+	}
+
+	requests := podRequests(pod)
+	assertQuantity(t, requests, corev1.ResourceCPU, "550m")
+	assertQuantity(t, requests, corev1.ResourceMemory, "1Gi")
+	assertQuantity(t, requests, corev1.ResourcePods, "1")
+}
+
+func TestSubtractResources(t *testing.T) {
+	free := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("2"),
+		corev1.ResourceMemory: resource.MustParse("1Gi"),
+		corev1.ResourcePods:   resource.MustParse("110"),
+	}
+	subtractResources(free, corev1.ResourceList{
+		corev1.ResourceCPU:              resource.MustParse("500m"),
+		corev1.ResourceMemory:           resource.MustParse("2Gi"),
+		corev1.ResourcePods:             resource.MustParse("1"),
+		corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+	})
+
+	assertQuantity(t, free, corev1.ResourceCPU, "1500m")
+	assertQuantity(t, free, corev1.ResourceMemory, "0")
+	assertQuantity(t, free, corev1.ResourcePods, "109")
+	_, ok := free[corev1.ResourceEphemeralStorage]
+	assert.Assert(t, !ok, "unexpected ephemeral storage")
+}
+
+func TestQuotaResourceName(t *testing.T) {
+	name, ok := quotaResourceName(corev1.ResourceRequestsCPU)
+	assert.Assert(t, ok)
+	assert.Equal(t, name, corev1.ResourceCPU)
+
+	name, ok = quotaResourceName("count/pods")
+	assert.Assert(t, ok)
+	assert.Equal(t, name, corev1.ResourcePods)
+
+	_, ok = quotaResourceName(corev1.ResourceLimitsCPU)
+	assert.Assert(t, !ok, "limits should not cap the allocatable resources")
+}
+
+func assertQuantity(t *testing.T, list corev1.ResourceList, name corev1.ResourceName, expected string) {
+	actual := list[name]
+	assert.Equal(t, actual.Cmp(resource.MustParse(expected)), 0, "unexpected %s: %s", name, actual.String())
+}
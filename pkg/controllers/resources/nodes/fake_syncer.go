@@ -128,7 +128,26 @@ func CreateFakeNode(ctx context.Context, nodeServiceProvider nodeservice.NodeSer
 	}
 
 	orig := node.DeepCopy()
-	node.Status = corev1.NodeStatus{
+	node.Status = newFakeNodeStatus(nodeIP)
+	err = virtualClient.Status().Patch(ctx, node, client.MergeFrom(orig))
+	if err != nil {
+		return err
+	}
+
+	// remove not ready taints
+	orig = node.DeepCopy()
+	node.Spec.Taints = []corev1.Taint{}
+	err = virtualClient.Patch(ctx, node, client.MergeFrom(orig))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// newFakeNodeStatus returns the status of a ready fake node with the given ip
+func newFakeNodeStatus(nodeIP string) corev1.NodeStatus {
+	return corev1.NodeStatus{
 		Capacity: corev1.ResourceList{
 			corev1.ResourceCPU:                     resource.MustParse("16"),
 			corev1.ResourceMemory:                  resource.MustParse("32Gi"),
@@ -204,20 +223,6 @@ func CreateFakeNode(ctx context.Context, nodeServiceProvider nodeservice.NodeSer
 		},
 		Images: []corev1.ContainerImage{},
 	}
-	err = virtualClient.Status().Patch(ctx, node, client.MergeFrom(orig))
-	if err != nil {
-		return err
-	}
-
-	// remove not ready taints
-	orig = node.DeepCopy()
-	node.Spec.Taints = []corev1.Taint{}
-	err = virtualClient.Patch(ctx, node, client.MergeFrom(orig))
-	if err != nil {
-		return err
-	}
-
-	return nil
 }
 
 // Filter away  virtual DaemonSet Pods using OwnerReferences to enable scale down
@@ -0,0 +1,154 @@
+package nodepools
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// PoolLabel is set on every virtual node of a pool and contains the name of the pool
+	PoolLabel = "vcluster.loft.sh/node-pool"
+)
+
+// DefaultCapacity is the capacity of a pool node if the pool doesn't define the resource
+var DefaultCapacity = corev1.ResourceList{
+	corev1.ResourceCPU:              resource.MustParse("16"),
+	corev1.ResourceMemory:           resource.MustParse("32Gi"),
+	corev1.ResourceEphemeralStorage: resource.MustParse("100Gi"),
+	corev1.ResourcePods:             resource.MustParse("110"),
+}
+
+// NodePool is a set of abstract virtual nodes that don't mirror a host node. Pods that are
+// scheduled to a node of the pool within the virtual cluster are placed on any host node that
+// matches the host node selector of the pool.
+type NodePool struct {
+	// Name of the pool, the virtual nodes are named <name>-<index>
+	Name string `json:"name"`
+	// Nodes is the number of virtual nodes in the pool, defaults to 1
+	Nodes *int `json:"nodes,omitempty"`
+
+	// Labels are added to the virtual nodes
+	Labels map[string]string `json:"labels,omitempty"`
+	// Taints are added to the virtual nodes
+	Taints []corev1.Taint `json:"taints,omitempty"`
+	// Capacity is the capacity and allocatable of each virtual node
+	Capacity corev1.ResourceList `json:"capacity,omitempty"`
+
+	// HostNodeSelector is added to the node selector of the physical pods of the pool
+	HostNodeSelector map[string]string `json:"hostNodeSelector,omitempty"`
+	// HostTolerations are added to the physical pods of the pool
+	HostTolerations []corev1.Toleration `json:"hostTolerations,omitempty"`
+}
+
+// Size returns the number of virtual nodes of the pool
+func (p *NodePool) Size() int {
+	if p.Nodes == nil {
+		return 1
+	}
+
+	return *p.Nodes
+}
+
+// NodeName returns the name of the virtual node with the given index
+func (p *NodePool) NodeName(index int) string {
+	return p.Name + "-" + strconv.Itoa(index)
+}
+
+// NodeNames returns the names of all virtual nodes of the pool
+func (p *NodePool) NodeNames() []string {
+	names := []string{}
+	for i := 0; i < p.Size(); i++ {
+		names = append(names, p.NodeName(i))
+	}
+
+	return names
+}
+
+// NodeCapacity returns the capacity of a single virtual node of the pool
+func (p *NodePool) NodeCapacity() corev1.ResourceList {
+	capacity := corev1.ResourceList{}
+	for name, value := range DefaultCapacity {
+		capacity[name] = value.DeepCopy()
+	}
+	for name, value := range p.Capacity {
+		capacity[name] = value.DeepCopy()
+	}
+
+	return capacity
+}
+
+// Validate checks the pool definition
+func (p *NodePool) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("node pool is missing a name")
+	} else if errs := validation.IsDNS1123Label(p.Name); len(errs) > 0 {
+		return fmt.Errorf("node pool %s: invalid name: %s", p.Name, strings.Join(errs, ", "))
+	} else if p.Size() < 0 {
+		return fmt.Errorf("node pool %s: nodes cannot be negative", p.Name)
+	}
+	for k := range p.Labels {
+		if k == PoolLabel {
+			return fmt.Errorf("node pool %s: label %s is reserved", p.Name, PoolLabel)
+		}
+	}
+
+	return nil
+}
+
+// Load reads and validates the node pools from the given yaml file
+func Load(path string) ([]NodePool, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	out, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pools := []NodePool{}
+	err = yaml.Unmarshal(out, &pools)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse node pools %s", path)
+	}
+
+	names := map[string]bool{}
+	for i := range pools {
+		err = pools[i].Validate()
+		if err != nil {
+			return nil, err
+		} else if names[pools[i].Name] {
+			return nil, fmt.Errorf("node pool %s is defined more than once", pools[i].Name)
+		}
+
+		names[pools[i].Name] = true
+	}
+
+	return pools, nil
+}
+
+// Find returns the pool the given virtual node belongs to or nil if the node is not part of a pool
+func Find(pools []NodePool, nodeName string) *NodePool {
+	for i := range pools {
+		if !strings.HasPrefix(nodeName, pools[i].Name+"-") {
+			continue
+		}
+
+		index, err := strconv.Atoi(strings.TrimPrefix(nodeName, pools[i].Name+"-"))
+		if err != nil || index < 0 || index >= pools[i].Size() || pools[i].NodeName(index) != nodeName {
+			continue
+		}
+
+		return &pools[i]
+	}
+
+	return nil
+}
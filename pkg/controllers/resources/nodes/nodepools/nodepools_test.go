@@ -0,0 +1,70 @@
+package nodepools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pools.yaml")
+	err := os.WriteFile(path, []byte(`
+- name: gpu
+  nodes: 2
+  labels:
+    hardware: gpu
+  taints:
+  - key: nvidia.com/gpu
+    effect: NoSchedule
+  capacity:
+    cpu: "32"
+    nvidia.com/gpu: "4"
+  hostNodeSelector:
+    pool: gpu
+  hostTolerations:
+  - key: nvidia.com/gpu
+    operator: Exists
+- name: general`), 0600)
+	assert.NilError(t, err)
+
+	pools, err := Load(path)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pools), 2)
+	assert.DeepEqual(t, pools[0].NodeNames(), []string{"gpu-0", "gpu-1"})
+	assert.DeepEqual(t, pools[1].NodeNames(), []string{"general-0"})
+
+	capacity := pools[0].NodeCapacity()
+	cpu := capacity[corev1.ResourceCPU]
+	assert.Equal(t, cpu.Cmp(resource.MustParse("32")), 0)
+	gpu := capacity["nvidia.com/gpu"]
+	assert.Equal(t, gpu.Cmp(resource.MustParse("4")), 0)
+	pods := capacity[corev1.ResourcePods]
+	assert.Equal(t, pods.Cmp(resource.MustParse("110")), 0)
+
+	// find
+	assert.Equal(t, Find(pools, "gpu-1").Name, "gpu")
+	assert.Equal(t, Find(pools, "general-0").Name, "general")
+	assert.Assert(t, Find(pools, "gpu-2") == nil)
+	assert.Assert(t, Find(pools, "gpu-01") == nil)
+	assert.Assert(t, Find(pools, "worker-1") == nil)
+	assert.Assert(t, Find(pools, "") == nil)
+
+	// invalid pools
+	for _, invalid := range []string{
+		`- nodes: 1`,
+		`- name: Invalid_Name`,
+		`[{name: gpu}, {name: gpu}]`,
+		`- name: gpu
+  labels:
+    vcluster.loft.sh/node-pool: other`,
+	} {
+		err = os.WriteFile(path, []byte(invalid), 0600)
+		assert.NilError(t, err)
+		_, err = Load(path)
+		assert.Assert(t, err != nil, "expected error for %s", invalid)
+	}
+}
@@ -0,0 +1,337 @@
+package noderules
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Rules define which host node labels, annotations, taints and addresses are visible in the
+// virtual cluster and how they are renamed. The same rules are used in reverse to translate
+// node selectors, affinities and tolerations of virtual pods, so scheduling on the host still works.
+type Rules struct {
+	// Labels are the rules for node labels
+	Labels MetadataRules `json:"labels,omitempty"`
+	// Annotations are the rules for node annotations
+	Annotations MetadataRules `json:"annotations,omitempty"`
+	// Taints are the rules for node taints, which are matched by the taint key
+	Taints MetadataRules `json:"taints,omitempty"`
+	// Addresses are the rules for the node status addresses
+	Addresses AddressRules `json:"addresses,omitempty"`
+}
+
+// MetadataRules filter and rewrite keys. A key is kept if it is rewritten or if it matches one
+// of the allow patterns (or there are none) and none of the deny patterns. Patterns may contain
+// * wildcards, e.g. *.amazonaws.com/*
+type MetadataRules struct {
+	Allow   []string      `json:"allow,omitempty"`
+	Deny    []string      `json:"deny,omitempty"`
+	Rewrite []RewriteRule `json:"rewrite,omitempty"`
+
+	allow []*regexp.Regexp
+	deny  []*regexp.Regexp
+}
+
+// RewriteRule renames a host key and optionally maps its values
+type RewriteRule struct {
+	// From is the key on the host node
+	From string `json:"from"`
+	// To is the key on the virtual node
+	To string `json:"to"`
+	// Values maps host values to virtual values, values that are not in the map are kept
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// AddressRules filter the node addresses by type, e.g. InternalIP, ExternalIP or Hostname
+type AddressRules struct {
+	Allow []corev1.NodeAddressType `json:"allow,omitempty"`
+	Deny  []corev1.NodeAddressType `json:"deny,omitempty"`
+}
+
+// Load reads and validates the node rules from the given yaml file. If path is empty, nil
+// is returned, which keeps all node metadata as is.
+func Load(path string) (*Rules, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	out, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := &Rules{}
+	err = yaml.Unmarshal(out, rules)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse node rules %s", path)
+	}
+
+	for name, metadataRules := range map[string]*MetadataRules{"labels": &rules.Labels, "annotations": &rules.Annotations, "taints": &rules.Taints} {
+		err = metadataRules.compile()
+		if err != nil {
+			return nil, errors.Wrap(err, name)
+		}
+	}
+
+	return rules, nil
+}
+
+func (m *MetadataRules) compile() error {
+	m.allow = compilePatterns(m.Allow)
+	m.deny = compilePatterns(m.Deny)
+
+	hostKeys := map[string]bool{}
+	virtualKeys := map[string]bool{}
+	for _, rule := range m.Rewrite {
+		if rule.From == "" || rule.To == "" {
+			return fmt.Errorf("rewrite rule is missing from or to")
+		} else if hostKeys[rule.From] {
+			return fmt.Errorf("key %s is rewritten more than once", rule.From)
+		} else if virtualKeys[rule.To] {
+			return fmt.Errorf("more than one key is rewritten to %s", rule.To)
+		}
+
+		// values have to be unique, as we need to translate them back
+		values := map[string]bool{}
+		for _, v := range rule.Values {
+			if values[v] {
+				return fmt.Errorf("rewrite rule %s: value %s is used more than once", rule.From, v)
+			}
+			values[v] = true
+		}
+
+		hostKeys[rule.From] = true
+		virtualKeys[rule.To] = true
+	}
+
+	return nil
+}
+
+func compilePatterns(patterns []string) []*regexp.Regexp {
+	compiled := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		compiled = append(compiled, regexp.MustCompile("^"+strings.ReplaceAll(regexp.QuoteMeta(pattern), "\\*", ".*")+"$"))
+	}
+
+	return compiled
+}
+
+func matchesAny(patterns []*regexp.Regexp, key string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ToVirtual translates a host key and value. If the key should not be visible in the
+// virtual cluster, false is returned.
+func (m *MetadataRules) ToVirtual(key, value string) (string, string, bool) {
+	for _, rule := range m.Rewrite {
+		if rule.From != key {
+			continue
+		}
+
+		if mapped, ok := rule.Values[value]; ok {
+			value = mapped
+		}
+		return rule.To, value, true
+	}
+
+	if len(m.allow) > 0 && !matchesAny(m.allow, key) {
+		return "", "", false
+	} else if matchesAny(m.deny, key) {
+		return "", "", false
+	}
+
+	return key, value, true
+}
+
+// ToHost translates a virtual key and value back to the host key and value
+func (m *MetadataRules) ToHost(key, value string) (string, string) {
+	for _, rule := range m.Rewrite {
+		if rule.To != key {
+			continue
+		}
+
+		for hostValue, virtualValue := range rule.Values {
+			if virtualValue == value {
+				return rule.From, hostValue
+			}
+		}
+		return rule.From, value
+	}
+
+	return key, value
+}
+
+// ToHostKey translates a virtual key back to the host key
+func (m *MetadataRules) ToHostKey(key string) string {
+	hostKey, _ := m.ToHost(key, "")
+	return hostKey
+}
+
+// ToHostValues translates the values of a virtual key back to the host values
+func (m *MetadataRules) ToHostValues(key string, values []string) []string {
+	if values == nil {
+		return nil
+	}
+
+	hostValues := []string{}
+	for _, value := range values {
+		_, hostValue := m.ToHost(key, value)
+		hostValues = append(hostValues, hostValue)
+	}
+
+	return hostValues
+}
+
+func (m *MetadataRules) translateMap(hostMap map[string]string) map[string]string {
+	if hostMap == nil {
+		return nil
+	}
+
+	translated := map[string]string{}
+	for k, v := range hostMap {
+		key, value, ok := m.ToVirtual(k, v)
+		if ok {
+			translated[key] = value
+		}
+	}
+
+	return translated
+}
+
+// TranslateNode returns a copy of the host node with the rules applied to its labels, annotations,
+// taints and addresses, which is the node as it should be visible within the virtual cluster
+func (r *Rules) TranslateNode(pNode *corev1.Node) *corev1.Node {
+	if r == nil {
+		return pNode
+	}
+
+	translated := pNode.DeepCopy()
+	translated.Labels = r.Labels.translateMap(pNode.Labels)
+	translated.Annotations = r.Annotations.translateMap(pNode.Annotations)
+	if pNode.Spec.Taints != nil {
+		translated.Spec.Taints = []corev1.Taint{}
+		for _, taint := range pNode.Spec.Taints {
+			key, value, ok := r.Taints.ToVirtual(taint.Key, taint.Value)
+			if !ok {
+				continue
+			}
+
+			taint.Key = key
+			taint.Value = value
+			translated.Spec.Taints = append(translated.Spec.Taints, taint)
+		}
+	}
+	if pNode.Status.Addresses != nil {
+		translated.Status.Addresses = []corev1.NodeAddress{}
+		for _, address := range pNode.Status.Addresses {
+			if r.Addresses.allowed(address.Type) {
+				translated.Status.Addresses = append(translated.Status.Addresses, address)
+			}
+		}
+	}
+
+	return translated
+}
+
+func (a *AddressRules) allowed(addressType corev1.NodeAddressType) bool {
+	if len(a.Allow) > 0 && !containsAddressType(a.Allow, addressType) {
+		return false
+	}
+
+	return !containsAddressType(a.Deny, addressType)
+}
+
+func containsAddressType(addressTypes []corev1.NodeAddressType, addressType corev1.NodeAddressType) bool {
+	for _, t := range addressTypes {
+		if t == addressType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TranslatePodSpec translates the node selector, node affinity, topology keys and tolerations of a pod
+// that reference virtual node labels and taints back to the host node labels and taints
+func (r *Rules) TranslatePodSpec(spec *corev1.PodSpec) {
+	if r == nil {
+		return
+	}
+
+	if spec.NodeSelector != nil {
+		nodeSelector := map[string]string{}
+		for k, v := range spec.NodeSelector {
+			key, value := r.Labels.ToHost(k, v)
+			nodeSelector[key] = value
+		}
+		spec.NodeSelector = nodeSelector
+	}
+
+	if spec.Affinity != nil {
+		if spec.Affinity.NodeAffinity != nil {
+			if spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+				r.translateNodeSelectorTerms(spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms)
+			}
+			for i := range spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+				r.translateNodeSelectorRequirements(spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution[i].Preference.MatchExpressions)
+			}
+		}
+		if spec.Affinity.PodAffinity != nil {
+			for i := range spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+				term := &spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i]
+				term.TopologyKey = r.Labels.ToHostKey(term.TopologyKey)
+			}
+			for i := range spec.Affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+				term := &spec.Affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution[i].PodAffinityTerm
+				term.TopologyKey = r.Labels.ToHostKey(term.TopologyKey)
+			}
+		}
+		if spec.Affinity.PodAntiAffinity != nil {
+			for i := range spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+				term := &spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i]
+				term.TopologyKey = r.Labels.ToHostKey(term.TopologyKey)
+			}
+			for i := range spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+				term := &spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[i].PodAffinityTerm
+				term.TopologyKey = r.Labels.ToHostKey(term.TopologyKey)
+			}
+		}
+	}
+
+	for i := range spec.TopologySpreadConstraints {
+		spec.TopologySpreadConstraints[i].TopologyKey = r.Labels.ToHostKey(spec.TopologySpreadConstraints[i].TopologyKey)
+	}
+
+	for i := range spec.Tolerations {
+		if spec.Tolerations[i].Key == "" {
+			continue
+		}
+
+		spec.Tolerations[i].Key, spec.Tolerations[i].Value = r.Taints.ToHost(spec.Tolerations[i].Key, spec.Tolerations[i].Value)
+	}
+}
+
+func (r *Rules) translateNodeSelectorTerms(terms []corev1.NodeSelectorTerm) {
+	for i := range terms {
+		r.translateNodeSelectorRequirements(terms[i].MatchExpressions)
+	}
+}
+
+func (r *Rules) translateNodeSelectorRequirements(requirements []corev1.NodeSelectorRequirement) {
+	for i := range requirements {
+		key := requirements[i].Key
+		requirements[i].Key = r.Labels.ToHostKey(key)
+		requirements[i].Values = r.Labels.ToHostValues(key, requirements[i].Values)
+	}
+}
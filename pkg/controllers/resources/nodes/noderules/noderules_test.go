@@ -0,0 +1,120 @@
+package noderules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	err := os.WriteFile(path, []byte(`
+labels:
+  allow: ["kubernetes.io/*", "topology.kubernetes.io/*"]
+  deny: ["kubernetes.io/hostname"]
+  rewrite:
+  - from: node.kubernetes.io/instance-type
+    to: example.com/size
+    values:
+      m5.large: small
+      m5.4xlarge: large
+annotations:
+  deny: ["*.amazonaws.com/*", "node.alpha.kubernetes.io/*"]
+taints:
+  deny: ["internal.example.com/*"]
+  rewrite:
+  - from: nvidia.com/gpu
+    to: example.com/gpu
+addresses:
+  deny: ["ExternalIP"]`), 0600)
+	assert.NilError(t, err)
+
+	rules, err := Load(path)
+	assert.NilError(t, err)
+
+	pNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "ip-10-0-0-1",
+			Labels: map[string]string{
+				"kubernetes.io/os":                 "linux",
+				"kubernetes.io/hostname":           "ip-10-0-0-1",
+				"topology.kubernetes.io/zone":      "eu-west-1a",
+				"node.kubernetes.io/instance-type": "m5.large",
+				"eks.amazonaws.com/nodegroup":      "workers",
+			},
+			Annotations: map[string]string{
+				"csi.volume.kubernetes.io/nodeid": "{}",
+				"alpha.eks.amazonaws.com/account": "123456789012",
+			},
+		},
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{
+				{Key: "nvidia.com/gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "internal.example.com/team", Value: "infra", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "1.2.3.4"},
+			},
+		},
+	}
+
+	vNode := rules.TranslateNode(pNode)
+	assert.DeepEqual(t, vNode.Labels, map[string]string{
+		"kubernetes.io/os":            "linux",
+		"topology.kubernetes.io/zone": "eu-west-1a",
+		"example.com/size":            "small",
+	})
+	assert.DeepEqual(t, vNode.Annotations, map[string]string{
+		"csi.volume.kubernetes.io/nodeid": "{}",
+	})
+	assert.DeepEqual(t, vNode.Spec.Taints, []corev1.Taint{{Key: "example.com/gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}})
+	assert.DeepEqual(t, vNode.Status.Addresses, []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}})
+	assert.Equal(t, len(pNode.Labels), 5, "host node must not be changed")
+
+	// translate a pod back to the host labels
+	spec := &corev1.PodSpec{
+		NodeSelector: map[string]string{"example.com/size": "large", "kubernetes.io/os": "linux"},
+		Affinity: &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "example.com/size", Operator: corev1.NodeSelectorOpIn, Values: []string{"small", "medium"}}},
+					}},
+				},
+			},
+		},
+		TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{TopologyKey: "example.com/size"}},
+		Tolerations:               []corev1.Toleration{{Key: "example.com/gpu", Operator: corev1.TolerationOpExists}, {Operator: corev1.TolerationOpExists}},
+	}
+	rules.TranslatePodSpec(spec)
+	assert.DeepEqual(t, spec.NodeSelector, map[string]string{"node.kubernetes.io/instance-type": "m5.4xlarge", "kubernetes.io/os": "linux"})
+	requirement := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0]
+	assert.Equal(t, requirement.Key, "node.kubernetes.io/instance-type")
+	assert.DeepEqual(t, requirement.Values, []string{"m5.large", "medium"})
+	assert.Equal(t, spec.TopologySpreadConstraints[0].TopologyKey, "node.kubernetes.io/instance-type")
+	assert.Equal(t, spec.Tolerations[0].Key, "nvidia.com/gpu")
+	assert.Equal(t, spec.Tolerations[1].Key, "")
+
+	// nil rules keep everything
+	var noRules *Rules
+	assert.Assert(t, noRules.TranslateNode(pNode) == pNode)
+
+	// invalid rules
+	err = os.WriteFile(path, []byte(`
+labels:
+  rewrite:
+  - from: a
+    to: b
+  - from: c
+    to: b`), 0600)
+	assert.NilError(t, err)
+	_, err = Load(path)
+	assert.Assert(t, err != nil)
+}
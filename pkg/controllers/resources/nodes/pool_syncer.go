@@ -0,0 +1,239 @@
+package nodes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/loft-sh/vcluster/pkg/constants"
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/nodepools"
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/nodeservice"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer"
+	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// poolNodeHeartbeatInterval is the interval in which the conditions of the pool nodes are refreshed,
+// which needs to be well below the node monitor grace period of the virtual controller manager
+// (180s in the k8s and eks charts)
+var poolNodeHeartbeatInterval = time.Second * 30
+
+func NewNodePoolSyncer(ctx *synccontext.RegisterContext, nodeService nodeservice.NodeServiceProvider) (syncer.Object, error) {
+	if !ctx.Options.EnableScheduler {
+		return nil, fmt.Errorf("node pools require --enable-scheduler")
+	}
+
+	return &nodePoolSyncer{
+		pools:               ctx.NodePools,
+		nodeServiceProvider: nodeService,
+	}, nil
+}
+
+// nodePoolSyncer maintains the virtual nodes of the configured node pools instead of
+// syncing the host nodes, so the host topology is hidden from the virtual cluster
+type nodePoolSyncer struct {
+	pools               []nodepools.NodePool
+	nodeServiceProvider nodeservice.NodeServiceProvider
+}
+
+func (r *nodePoolSyncer) Resource() client.Object {
+	return &corev1.Node{}
+}
+
+func (r *nodePoolSyncer) Name() string {
+	return "node-pool"
+}
+
+var _ syncer.IndicesRegisterer = &nodePoolSyncer{}
+
+func (r *nodePoolSyncer) RegisterIndices(ctx *synccontext.RegisterContext) error {
+	return registerIndices(ctx)
+}
+
+var _ syncer.ControllerModifier = &nodePoolSyncer{}
+
+func (r *nodePoolSyncer) ModifyController(ctx *synccontext.RegisterContext, builder *builder.Builder) (*builder.Builder, error) {
+	// enqueue all pool nodes once on startup, so that missing nodes are created
+	nodeNames := []string{}
+	for i := range r.pools {
+		nodeNames = append(nodeNames, r.pools[i].NodeNames()...)
+	}
+	initialEvents := make(chan event.GenericEvent, len(nodeNames))
+	for _, nodeName := range nodeNames {
+		initialEvents <- event.GenericEvent{Object: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}}
+	}
+
+	builder, err := modifyController(ctx, r.nodeServiceProvider, builder)
+	if err != nil {
+		return nil, err
+	}
+
+	return builder.Watches(&source.Channel{Source: initialEvents}, &handler.EnqueueRequestForObject{}), nil
+}
+
+var _ syncer.FakeSyncer = &nodePoolSyncer{}
+
+func (r *nodePoolSyncer) FakeSyncUp(ctx *synccontext.SyncContext, name types.NamespacedName) (ctrl.Result, error) {
+	pool := nodepools.Find(r.pools, name.Name)
+	if pool == nil {
+		return ctrl.Result{}, nil
+	}
+
+	ctx.Log.Infof("Create virtual node %s of node pool %s", name.Name, pool.Name)
+	err := r.createPoolNode(ctx, pool, name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: poolNodeHeartbeatInterval}, nil
+}
+
+func (r *nodePoolSyncer) FakeSync(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
+	node, ok := vObj.(*corev1.Node)
+	if !ok || node == nil {
+		return ctrl.Result{}, fmt.Errorf("%#v is not a node", vObj)
+	}
+
+	pool := nodepools.Find(r.pools, node.Name)
+	if pool == nil {
+		// we only delete nodes that are not part of a pool anymore, if there are no pods
+		// scheduled on them, as otherwise the pods would be deleted as well
+		podList := &corev1.PodList{}
+		err := ctx.VirtualClient.List(ctx.Context, podList, client.MatchingFields{constants.IndexByAssigned: node.Name})
+		if err != nil {
+			return ctrl.Result{}, err
+		} else if len(filterOutVirtualDaemonSets(podList)) > 0 {
+			return ctrl.Result{}, nil
+		}
+
+		ctx.Log.Infof("Delete virtual node %s, because it is not part of a node pool", node.Name)
+		return ctrl.Result{}, ctx.VirtualClient.Delete(ctx.Context, node)
+	}
+
+	updated := translatePoolNodeSpec(pool, node)
+	if updated != nil {
+		ctx.Log.Infof("Update virtual node %s, because node pool %s has changed", node.Name, pool.Name)
+		translator.PrintChanges(node, updated, ctx.Log)
+		return ctrl.Result{}, ctx.VirtualClient.Update(ctx.Context, updated)
+	}
+
+	updated = translatePoolNodeStatus(pool, node)
+	if updated != nil {
+		translator.PrintChanges(node, updated, ctx.Log)
+		err := ctx.VirtualClient.Status().Update(ctx.Context, updated)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: poolNodeHeartbeatInterval}, nil
+}
+
+func (r *nodePoolSyncer) createPoolNode(ctx *synccontext.SyncContext, pool *nodepools.NodePool, name types.NamespacedName) error {
+	r.nodeServiceProvider.Lock()
+	defer r.nodeServiceProvider.Unlock()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name.Name,
+			Labels: poolNodeLabels(pool, name.Name),
+			Annotations: map[string]string{
+				"node.alpha.kubernetes.io/ttl":                           "0",
+				"volumes.kubernetes.io/controller-managed-attach-detach": "false",
+			},
+		},
+		Spec: corev1.NodeSpec{
+			Taints: pool.Taints,
+		},
+	}
+	err := ctx.VirtualClient.Create(ctx.Context, node)
+	if err != nil {
+		return err
+	}
+
+	nodeIP, err := r.nodeServiceProvider.GetNodeIP(ctx.Context, name)
+	if err != nil {
+		return errors.Wrap(err, "create pool node ip")
+	}
+
+	orig := node.DeepCopy()
+	node.Status = newFakeNodeStatus(nodeIP)
+	node.Status.Capacity = pool.NodeCapacity()
+	node.Status.Allocatable = pool.NodeCapacity()
+	node.Status.NodeInfo.OSImage = "Virtual Node Pool"
+	return ctx.VirtualClient.Status().Patch(ctx.Context, node, client.MergeFrom(orig))
+}
+
+func poolNodeLabels(pool *nodepools.NodePool, nodeName string) map[string]string {
+	nodeLabels := map[string]string{
+		"beta.kubernetes.io/arch": "amd64",
+		"beta.kubernetes.io/os":   "linux",
+		"kubernetes.io/arch":      "amd64",
+		"kubernetes.io/hostname":  nodeName,
+		"kubernetes.io/os":        "linux",
+	}
+	for k, v := range pool.Labels {
+		nodeLabels[k] = v
+	}
+	nodeLabels[nodepools.PoolLabel] = pool.Name
+	return nodeLabels
+}
+
+// translatePoolNodeSpec ensures the labels and taints of the pool are set on the virtual node. Additional
+// labels and taints, e.g. from a kubectl cordon within the virtual cluster, are kept.
+func translatePoolNodeSpec(pool *nodepools.NodePool, vNode *corev1.Node) *corev1.Node {
+	updated := vNode.DeepCopy()
+	for k, v := range poolNodeLabels(pool, vNode.Name) {
+		if updated.Labels == nil {
+			updated.Labels = map[string]string{}
+		}
+		updated.Labels[k] = v
+	}
+	for _, taint := range pool.Taints {
+		found := false
+		for i := range updated.Spec.Taints {
+			if updated.Spec.Taints[i].MatchTaint(&taint) {
+				updated.Spec.Taints[i].Value = taint.Value
+				found = true
+				break
+			}
+		}
+		if !found {
+			updated.Spec.Taints = append(updated.Spec.Taints, taint)
+		}
+	}
+
+	if equality.Semantic.DeepEqual(vNode.ObjectMeta, updated.ObjectMeta) && equality.Semantic.DeepEqual(vNode.Spec, updated.Spec) {
+		return nil
+	}
+
+	return updated
+}
+
+// translatePoolNodeStatus ensures the capacity of the pool and refreshes the heartbeat of the node conditions
+func translatePoolNodeStatus(pool *nodepools.NodePool, vNode *corev1.Node) *corev1.Node {
+	updated := vNode.DeepCopy()
+	updated.Status.Capacity = pool.NodeCapacity()
+	updated.Status.Allocatable = pool.NodeCapacity()
+	for i := range updated.Status.Conditions {
+		if time.Since(updated.Status.Conditions[i].LastHeartbeatTime.Time) >= poolNodeHeartbeatInterval/2 {
+			updated.Status.Conditions[i].LastHeartbeatTime = metav1.Now()
+		}
+	}
+
+	if equality.Semantic.DeepEqual(vNode.Status, updated.Status) {
+		return nil
+	}
+
+	return updated
+}
@@ -0,0 +1,60 @@
+package nodes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/nodepools"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTranslatePoolNode(t *testing.T) {
+	pool := &nodepools.NodePool{
+		Name:   "gpu",
+		Labels: map[string]string{"hardware": "gpu"},
+		Taints: []corev1.Taint{{Key: "nvidia.com/gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}},
+	}
+
+	vNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "gpu-0",
+			Labels: poolNodeLabels(pool, "gpu-0"),
+		},
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{
+				{Key: "nvidia.com/gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule},
+				{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+		Status: corev1.NodeStatus{
+			Capacity:    pool.NodeCapacity(),
+			Allocatable: pool.NodeCapacity(),
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastHeartbeatTime: metav1.Now()},
+			},
+		},
+	}
+
+	// node is up to date, additional taints are kept
+	assert.Assert(t, translatePoolNodeSpec(pool, vNode) == nil)
+	assert.Assert(t, translatePoolNodeStatus(pool, vNode) == nil)
+
+	// removed pool labels and taints are restored
+	changed := vNode.DeepCopy()
+	delete(changed.Labels, "hardware")
+	changed.Spec.Taints = changed.Spec.Taints[1:]
+	updated := translatePoolNodeSpec(pool, changed)
+	assert.Assert(t, updated != nil)
+	assert.Equal(t, updated.Labels["hardware"], "gpu")
+	assert.Equal(t, updated.Labels[nodepools.PoolLabel], "gpu")
+	assert.Equal(t, len(updated.Spec.Taints), 2)
+
+	// stale heartbeat is refreshed
+	changed = vNode.DeepCopy()
+	changed.Status.Conditions[0].LastHeartbeatTime = metav1.NewTime(time.Now().Add(-poolNodeHeartbeatInterval))
+	updated = translatePoolNodeStatus(pool, changed)
+	assert.Assert(t, updated != nil)
+	assert.Assert(t, time.Since(updated.Status.Conditions[0].LastHeartbeatTime.Time) < time.Minute)
+}
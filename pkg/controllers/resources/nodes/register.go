@@ -4,6 +4,7 @@ import (
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/nodeservice"
 	"github.com/loft-sh/vcluster/pkg/controllers/syncer"
 	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
+	"github.com/loft-sh/vcluster/pkg/util/loghelper"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -17,7 +18,10 @@ func New(ctx *synccontext.RegisterContext) (syncer.Object, error) {
 	}
 
 	nodeService := nodeservice.NewNodeServiceProvider(ctx.Options.ServiceName, ctx.CurrentNamespace, ctx.CurrentNamespaceClient, ctx.VirtualManager.GetClient(), uncachedVirtualClient)
-	if !ctx.Controllers["nodes"] {
+	if len(ctx.NodePools) > 0 {
+		loghelper.Infof("Node pools are configured, the nodes controller shows the pool nodes instead of the host nodes")
+		return NewNodePoolSyncer(ctx, nodeService)
+	} else if !ctx.Controllers["nodes"] {
 		return NewFakeSyncer(ctx, nodeService)
 	}
 
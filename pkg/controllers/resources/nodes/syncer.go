@@ -6,6 +6,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 
 	"github.com/loft-sh/vcluster/pkg/constants"
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/noderules"
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/nodeservice"
 	"github.com/loft-sh/vcluster/pkg/controllers/syncer"
 	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
@@ -52,11 +53,18 @@ func NewSyncer(ctx *synccontext.RegisterContext, nodeService nodeservice.NodeSer
 		}
 	}
 
+	nodeRules, err := noderules.Load(ctx.Options.NodeSyncRules)
+	if err != nil {
+		return nil, errors.Wrap(err, "load node sync rules")
+	}
+
 	return &nodeSyncer{
-		enableScheduler: ctx.Options.EnableScheduler,
+		enableScheduler:       ctx.Options.EnableScheduler,
+		capAllocatableByQuota: ctx.Options.CapNodeAllocatableByQuota,
 
 		nodeServiceProvider: nodeService,
 		nodeSelector:        nodeSelector,
+		nodeRules:           nodeRules,
 		useFakeKubelets:     !ctx.Options.DisableFakeKubelets,
 
 		targetNamespace:     ctx.Options.TargetNamespace,
@@ -67,9 +75,11 @@ func NewSyncer(ctx *synccontext.RegisterContext, nodeService nodeservice.NodeSer
 }
 
 type nodeSyncer struct {
-	enableScheduler bool
+	enableScheduler       bool
+	capAllocatableByQuota bool
 
 	nodeSelector    labels.Selector
+	nodeRules       *noderules.Rules
 	useFakeKubelets bool
 
 	physicalClient client.Client
@@ -229,6 +239,9 @@ func (s *nodeSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj
 		return ctrl.Result{}, ctx.VirtualClient.Delete(ctx.Context, vObj)
 	}
 
+	// hide or rewrite the host node metadata that shouldn't be visible in the virtual cluster
+	pNode = s.nodeRules.TranslateNode(pNode)
+
 	updatedVNode, err := s.translateUpdateStatus(ctx, pNode, vNode)
 	if err != nil {
 		return ctrl.Result{}, errors.Wrap(err, "update node status")
@@ -269,6 +282,7 @@ func (s *nodeSyncer) SyncUp(ctx *synccontext.SyncContext, pObj client.Object) (c
 	}
 
 	ctx.Log.Infof("create virtual node %s, because there is a virtual pod with that node", pNode.Name)
+	pNode = s.nodeRules.TranslateNode(pNode)
 	err = ctx.VirtualClient.Create(ctx.Context, &corev1.Node{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        pNode.Name,
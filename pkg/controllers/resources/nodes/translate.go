@@ -1,11 +1,8 @@
 package nodes
 
 import (
-	"context"
 	"encoding/json"
-	"k8s.io/apimachinery/pkg/api/resource"
 	"os"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/nodeservice"
 	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
@@ -158,50 +155,16 @@ func (s *nodeSyncer) translateUpdateStatus(ctx *synccontext.SyncContext, pNode *
 	// if scheduler is enabled we allow custom capacity and allocatable
 	if s.enableScheduler {
 
-		// calculate what's really allocatable
+		// calculate what's really allocatable, which is also used as capacity, so that the
+		// virtual scheduler only places pods on nodes where they would fit on the host
 		if translatedStatus.Allocatable != nil {
-			cpu := translatedStatus.Allocatable.Cpu().MilliValue()
-			memory := translatedStatus.Allocatable.Memory().Value()
-			storageEphemeral := translatedStatus.Allocatable.StorageEphemeral().Value()
-			pods := translatedStatus.Allocatable.Pods().Value()
-
-			var nonVClusterPods int64
-			podList := &corev1.PodList{}
-			err := s.podCache.List(context.TODO(), podList, client.MatchingFields{indexPodByRunningNonVClusterNode: pNode.Name})
-			if err != nil {
-				klog.Errorf("Error listing pods: %v", err)
-			} else {
-				for _, pod := range podList.Items {
-					if !translate.IsManaged(&pod) {
-						// count pods that are not synced by this vcluster
-						nonVClusterPods++
-					}
-					for _, container := range pod.Spec.InitContainers {
-						cpu -= container.Resources.Requests.Cpu().MilliValue()
-						memory -= container.Resources.Requests.Memory().Value()
-						storageEphemeral -= container.Resources.Requests.StorageEphemeral().Value()
-					}
-					for _, container := range pod.Spec.Containers {
-						cpu -= container.Resources.Requests.Cpu().MilliValue()
-						memory -= container.Resources.Requests.Memory().Value()
-						storageEphemeral -= container.Resources.Requests.StorageEphemeral().Value()
-					}
+			free := s.freeAllocatable(ctx.Context, pNode)
+			for name, value := range free {
+				translatedStatus.Allocatable[name] = value
+				if translatedStatus.Capacity != nil {
+					translatedStatus.Capacity[name] = value
 				}
 			}
-
-			pods -= nonVClusterPods
-			if pods > 0 {
-				translatedStatus.Allocatable[corev1.ResourcePods] = *resource.NewQuantity(pods, resource.DecimalSI)
-			}
-			if cpu > 0 {
-				translatedStatus.Allocatable[corev1.ResourceCPU] = *resource.NewMilliQuantity(cpu, resource.DecimalSI)
-			}
-			if memory > 0 {
-				translatedStatus.Allocatable[corev1.ResourceMemory] = *resource.NewQuantity(memory, resource.BinarySI)
-			}
-			if storageEphemeral > 0 {
-				translatedStatus.Allocatable[corev1.ResourceEphemeralStorage] = *resource.NewQuantity(storageEphemeral, resource.BinarySI)
-			}
 		}
 
 		// calculate what's in capacity & allocatable
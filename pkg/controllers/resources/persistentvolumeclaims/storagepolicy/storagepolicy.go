@@ -0,0 +1,138 @@
+package storagepolicy
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Policy controls which host storage classes the persistent volume claims of the vcluster use and
+// how many claims and storage the vcluster may request in total
+type Policy struct {
+	// StorageClasses maps virtual storage classes to host storage classes
+	StorageClasses StorageClasses `json:"storageClasses,omitempty"`
+	// Quota limits the persistent volume claims of the vcluster
+	Quota Quota `json:"quota,omitempty"`
+
+	denied []*regexp.Regexp
+}
+
+// StorageClasses maps virtual storage class names to host storage classes
+type StorageClasses struct {
+	// Mappings maps virtual storage class names, e.g. fast, to host storage class names
+	Mappings map[string]string `json:"mappings,omitempty"`
+	// Default is the host storage class used for persistent volume claims without a storage class
+	Default string `json:"default,omitempty"`
+	// Denied are host storage classes persistent volume claims may not use, supports * wildcards
+	Denied []string `json:"denied,omitempty"`
+}
+
+// Quota limits the persistent volume claims of the vcluster
+type Quota struct {
+	// PersistentVolumeClaims is the maximum number of persistent volume claims
+	PersistentVolumeClaims *int `json:"persistentVolumeClaims,omitempty"`
+	// RequestsStorage is the maximum of storage all persistent volume claims may request together
+	RequestsStorage *resource.Quantity `json:"requestsStorage,omitempty"`
+}
+
+// Load reads and validates the storage policy from the given yaml file. If path is empty,
+// nil is returned, which keeps the storage classes as they are and does not enforce a quota.
+func Load(path string) (*Policy, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	out, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{}
+	err = yaml.Unmarshal(out, policy)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse storage policy %s", path)
+	}
+
+	err = policy.compile()
+	if err != nil {
+		return nil, errors.Wrap(err, "storage policy")
+	}
+
+	return policy, nil
+}
+
+func (p *Policy) compile() error {
+	for _, pattern := range p.StorageClasses.Denied {
+		p.denied = append(p.denied, regexp.MustCompile("^"+strings.ReplaceAll(regexp.QuoteMeta(pattern), "\\*", ".*")+"$"))
+	}
+
+	for from, to := range p.StorageClasses.Mappings {
+		if to == "" {
+			return fmt.Errorf("mapping of storage class %s has no host storage class", from)
+		} else if p.Denied(to) {
+			return fmt.Errorf("storage class %s is mapped to the denied host storage class %s", from, to)
+		}
+	}
+	if p.Denied(p.StorageClasses.Default) {
+		return fmt.Errorf("default storage class %s is denied", p.StorageClasses.Default)
+	}
+	if p.Quota.PersistentVolumeClaims != nil && *p.Quota.PersistentVolumeClaims < 0 {
+		return fmt.Errorf("quota persistentVolumeClaims must not be negative")
+	}
+
+	return nil
+}
+
+// HostStorageClass returns the host storage class for the given virtual storage class and true, if the policy
+// maps the virtual storage class. An empty virtual storage class is mapped to the default storage class.
+func (p *Policy) HostStorageClass(storageClass string) (string, bool) {
+	if p == nil {
+		return "", false
+	} else if storageClass == "" {
+		return p.StorageClasses.Default, p.StorageClasses.Default != ""
+	}
+
+	hostStorageClass, ok := p.StorageClasses.Mappings[storageClass]
+	return hostStorageClass, ok
+}
+
+// Denied checks if persistent volume claims may not use the given host storage class
+func (p *Policy) Denied(hostStorageClass string) bool {
+	if p == nil || hostStorageClass == "" {
+		return false
+	}
+
+	for _, pattern := range p.denied {
+		if pattern.MatchString(hostStorageClass) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckQuota returns why the given number of persistent volume claims and requested storage exceed
+// the quota or an empty string if they don't
+func (p *Policy) CheckQuota(claims int, storage resource.Quantity) string {
+	if p == nil {
+		return ""
+	}
+
+	if p.Quota.PersistentVolumeClaims != nil && claims > *p.Quota.PersistentVolumeClaims {
+		return fmt.Sprintf("the vcluster may have at most %d persistent volume claims", *p.Quota.PersistentVolumeClaims)
+	} else if p.Quota.RequestsStorage != nil && storage.Cmp(*p.Quota.RequestsStorage) > 0 {
+		return fmt.Sprintf("the persistent volume claims of the vcluster may request at most %s storage, but would request %s", p.Quota.RequestsStorage.String(), storage.String())
+	}
+
+	return ""
+}
+
+// HasQuota checks if the policy limits the persistent volume claims of the vcluster
+func (p *Policy) HasQuota() bool {
+	return p != nil && (p.Quota.PersistentVolumeClaims != nil || p.Quota.RequestsStorage != nil)
+}
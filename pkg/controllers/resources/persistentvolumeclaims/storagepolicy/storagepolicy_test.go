@@ -0,0 +1,53 @@
+package storagepolicy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	err := os.WriteFile(path, []byte(`
+storageClasses:
+  mappings:
+    fast: gp3-encrypted
+  default: standard
+  denied: ["io2-*"]
+quota:
+  persistentVolumeClaims: 5
+  requestsStorage: 100Gi`), 0600)
+	assert.NilError(t, err)
+
+	policy, err := Load(path)
+	assert.NilError(t, err)
+
+	hostStorageClass, ok := policy.HostStorageClass("fast")
+	assert.Assert(t, ok)
+	assert.Equal(t, hostStorageClass, "gp3-encrypted")
+	hostStorageClass, ok = policy.HostStorageClass("")
+	assert.Assert(t, ok)
+	assert.Equal(t, hostStorageClass, "standard")
+	_, ok = policy.HostStorageClass("other")
+	assert.Assert(t, !ok)
+
+	assert.Assert(t, policy.Denied("io2-premium"))
+	assert.Assert(t, !policy.Denied("gp3-encrypted"))
+
+	assert.Equal(t, policy.CheckQuota(5, resource.MustParse("100Gi")), "")
+	assert.Equal(t, policy.CheckQuota(6, resource.MustParse("10Gi")), "the vcluster may have at most 5 persistent volume claims")
+	assert.Equal(t, policy.CheckQuota(1, resource.MustParse("101Gi")), "the persistent volume claims of the vcluster may request at most 100Gi storage, but would request 101Gi")
+
+	// mappings to denied storage classes are invalid
+	err = os.WriteFile(path, []byte(`
+storageClasses:
+  mappings:
+    fast: io2-premium
+  denied: ["io2-*"]`), 0600)
+	assert.NilError(t, err)
+	_, err = Load(path)
+	assert.ErrorContains(t, err, "denied host storage class io2-premium")
+}
@@ -2,6 +2,9 @@ package persistentvolumeclaims
 
 import (
 	"context"
+	"time"
+
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/persistentvolumeclaims/storagepolicy"
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/persistentvolumes"
 	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
 	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
@@ -12,9 +15,12 @@ import (
 	"github.com/loft-sh/vcluster/pkg/controllers/syncer"
 	"github.com/loft-sh/vcluster/pkg/util/clienthelper"
 	"github.com/loft-sh/vcluster/pkg/util/loghelper"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -32,15 +38,25 @@ const (
 	boundByControllerAnnotation  = "pv.kubernetes.io/bound-by-controller"
 	storageProvisionerAnnotation = "volume.beta.kubernetes.io/storage-provisioner"
 	selectedNodeAnnotation       = "volume.kubernetes.io/selected-node"
+
+	defaultStorageClassAnnotation     = "storageclass.kubernetes.io/is-default-class"
+	betaDefaultStorageClassAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
 )
 
 func New(ctx *synccontext.RegisterContext) (syncer.Object, error) {
+	storagePolicy, err := storagepolicy.Load(ctx.Options.StoragePolicy)
+	if err != nil {
+		return nil, errors.Wrap(err, "load storage policy")
+	}
+
 	return &persistentVolumeClaimSyncer{
 		NamespacedTranslator: translator.NewNamespacedTranslator(ctx, "persistent-volume-claim", &corev1.PersistentVolumeClaim{}, bindCompletedAnnotation, boundByControllerAnnotation, storageProvisionerAnnotation, selectedNodeAnnotation),
 
 		storageClassesEnabled:    ctx.Controllers["storageclasses"],
 		schedulerEnabled:         ctx.Options.EnableScheduler,
 		useFakePersistentVolumes: !ctx.Controllers["persistentvolumes"],
+		storagePolicy:            storagePolicy,
+		events:                   translator.NewEventCache(),
 	}, nil
 }
 
@@ -50,6 +66,9 @@ type persistentVolumeClaimSyncer struct {
 	storageClassesEnabled    bool
 	schedulerEnabled         bool
 	useFakePersistentVolumes bool
+
+	storagePolicy *storagepolicy.Policy
+	events        *translator.EventCache
 }
 
 var _ syncer.Syncer = &persistentVolumeClaimSyncer{}
@@ -74,6 +93,30 @@ func (s *persistentVolumeClaimSyncer) SyncDown(ctx *synccontext.SyncContext, vOb
 		return ctrl.Result{}, err
 	}
 
+	// check the storage policy
+	hostStorageClass, err := s.hostStorageClass(ctx, newPvc)
+	if err != nil {
+		return ctrl.Result{}, err
+	} else if s.storagePolicy.Denied(hostStorageClass) {
+		s.EventRecorder().Eventf(vPvc, "Warning", "StorageClassDenied", "Persistent volume claim is not synced to the host cluster, because storage class %s is not allowed by the storage policy", hostStorageClass)
+		return ctrl.Result{}, nil
+	}
+	if s.storagePolicy.HasQuota() {
+		reason, err := s.checkQuota(ctx, nil, newPvc)
+		if err != nil {
+			return ctrl.Result{}, err
+		} else if reason != "" {
+			// other claims might get deleted in the meantime, so we check again later, but only report
+			// the exceeded quota again if the claim changes
+			if s.events.ShouldRecord(vPvc, "QuotaExceeded", vPvc.Spec) {
+				s.EventRecorder().Eventf(vPvc, "Warning", "QuotaExceeded", "Persistent volume claim is not synced to the host cluster, because %s", reason)
+			}
+			return ctrl.Result{RequeueAfter: time.Minute}, nil
+		}
+
+		s.events.Forget(vPvc, "QuotaExceeded")
+	}
+
 	return s.SyncDownCreate(ctx, vObj, newPvc)
 }
 
@@ -147,6 +190,22 @@ func (s *persistentVolumeClaimSyncer) Sync(ctx *synccontext.SyncContext, pObj cl
 		return ctrl.Result{}, nil
 	}
 
+	// check if a resize would exceed the quota
+	if vStorage := storageRequest(vPvc); s.storagePolicy.HasQuota() && vStorage.Cmp(storageRequest(pPvc)) > 0 {
+		reason, err := s.checkQuota(ctx, pPvc, vPvc)
+		if err != nil {
+			return ctrl.Result{}, err
+		} else if reason != "" {
+			if s.events.ShouldRecord(vPvc, "QuotaExceeded", vPvc.Spec) {
+				s.EventRecorder().Eventf(vPvc, "Warning", "QuotaExceeded", "Persistent volume claim is not resized in the host cluster, because %s", reason)
+			}
+			vPvc = vPvc.DeepCopy()
+			vPvc.Spec.Resources.Requests[corev1.ResourceStorage] = storageRequest(pPvc)
+		} else {
+			s.events.Forget(vPvc, "QuotaExceeded")
+		}
+	}
+
 	// forward update
 	newPvc, err := s.translateUpdate(ctx, pPvc, vPvc)
 	if err != nil {
@@ -155,7 +214,67 @@ func (s *persistentVolumeClaimSyncer) Sync(ctx *synccontext.SyncContext, pObj cl
 		translator.PrintChanges(pPvc, newPvc, ctx.Log)
 	}
 
-	return s.SyncDownUpdate(ctx, vPvc, newPvc)
+	return s.SyncDownUpdate(ctx, vObj, newPvc)
+}
+
+// checkQuota sums up the physical persistent volume claims of the vcluster including the new or resized
+// claim and returns why they exceed the quota of the storage policy or an empty string if they don't
+func (s *persistentVolumeClaimSyncer) checkQuota(ctx *synccontext.SyncContext, pPvc, newPvc *corev1.PersistentVolumeClaim) (string, error) {
+	pPvcList := &corev1.PersistentVolumeClaimList{}
+	err := ctx.PhysicalClient.List(ctx.Context, pPvcList, client.InNamespace(ctx.TargetNamespace), client.MatchingLabels{translate.MarkerLabel: translate.Suffix})
+	if err != nil {
+		return "", errors.Wrap(err, "list physical persistent volume claims")
+	}
+
+	claims := 1
+	storage := storageRequest(newPvc)
+	for i := range pPvcList.Items {
+		if pPvcList.Items[i].DeletionTimestamp != nil || (pPvc != nil && pPvcList.Items[i].Name == pPvc.Name) {
+			continue
+		}
+
+		claims++
+		storage.Add(storageRequest(&pPvcList.Items[i]))
+	}
+
+	return s.storagePolicy.CheckQuota(claims, storage), nil
+}
+
+// hostStorageClass returns the storage class the host cluster provisions the physical claim with. Claims
+// without a storage class get the default storage class of the host cluster, which is the newest one if
+// there are several.
+func (s *persistentVolumeClaimSyncer) hostStorageClass(ctx *synccontext.SyncContext, pPvc *corev1.PersistentVolumeClaim) (string, error) {
+	if s.storagePolicy == nil || pPvc.Spec.StorageClassName != nil || storageClassName(pPvc) != "" {
+		return storageClassName(pPvc), nil
+	}
+
+	storageClasses := &storagev1.StorageClassList{}
+	err := ctx.PhysicalClient.List(ctx.Context, storageClasses)
+	if err != nil {
+		return "", errors.Wrap(err, "list physical storage classes")
+	}
+
+	var defaultStorageClass *storagev1.StorageClass
+	for i := range storageClasses.Items {
+		storageClass := &storageClasses.Items[i]
+		if storageClass.Annotations[defaultStorageClassAnnotation] != "true" && storageClass.Annotations[betaDefaultStorageClassAnnotation] != "true" {
+			continue
+		}
+
+		if defaultStorageClass == nil || defaultStorageClass.CreationTimestamp.Before(&storageClass.CreationTimestamp) ||
+			(defaultStorageClass.CreationTimestamp.Equal(&storageClass.CreationTimestamp) && storageClass.Name < defaultStorageClass.Name) {
+			defaultStorageClass = storageClass
+		}
+	}
+	if defaultStorageClass == nil {
+		return "", nil
+	}
+
+	return defaultStorageClass.Name, nil
+}
+
+func storageRequest(pvc *corev1.PersistentVolumeClaim) resource.Quantity {
+	return pvc.Spec.Resources.Requests.Storage().DeepCopy()
 }
 
 func (s *persistentVolumeClaimSyncer) ensurePersistentVolume(ctx *synccontext.SyncContext, pObj *corev1.PersistentVolumeClaim, vObj *corev1.PersistentVolumeClaim, log loghelper.Logger) (bool, error) {
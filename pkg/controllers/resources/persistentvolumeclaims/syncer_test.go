@@ -1,6 +1,10 @@
 package persistentvolumeclaims
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+
 	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
 	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
 	testingutil "github.com/loft-sh/vcluster/pkg/util/testing"
@@ -13,6 +17,8 @@ import (
 	"github.com/loft-sh/vcluster/pkg/util/translate"
 
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -38,9 +44,7 @@ func TestSync(t *testing.T) {
 	}
 	changedResources := corev1.ResourceRequirements{
 		Requests: map[corev1.ResourceName]resource.Quantity{
-			"storage": {
-				Format: "teststoragerequest",
-			},
+			"storage": resource.MustParse("5Gi"),
 		},
 	}
 	basePvc := &corev1.PersistentVolumeClaim{
@@ -126,6 +130,36 @@ func TestSync(t *testing.T) {
 		Status:     backwardUpdateStatusPvc.Status,
 	}
 
+	snapshotGroup := volumeSnapshotGroup
+	dataSourcePvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: vObjectMeta,
+		Spec: corev1.PersistentVolumeClaimSpec{
+			DataSource: &corev1.TypedLocalObjectReference{
+				Kind: "PersistentVolumeClaim",
+				Name: "source",
+			},
+			DataSourceRef: &corev1.TypedLocalObjectReference{
+				APIGroup: &snapshotGroup,
+				Kind:     "VolumeSnapshot",
+				Name:     "snapshot",
+			},
+		},
+	}
+	createdDataSourcePvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: pObjectMeta,
+		Spec: corev1.PersistentVolumeClaimSpec{
+			DataSource: &corev1.TypedLocalObjectReference{
+				Kind: "PersistentVolumeClaim",
+				Name: translate.PhysicalName("source", vObjectMeta.Namespace),
+			},
+			DataSourceRef: &corev1.TypedLocalObjectReference{
+				APIGroup: &snapshotGroup,
+				Kind:     "VolumeSnapshot",
+				Name:     translate.PhysicalName("snapshot", vObjectMeta.Namespace),
+			},
+		},
+	}
+
 	generictesting.RunTestsWithContext(t, func(pClient *testingutil.FakeIndexClient, vClient *testingutil.FakeIndexClient) *synccontext.RegisterContext {
 		ctx := generictesting.NewFakeRegisterContext(pClient, vClient)
 		ctx.Controllers["storageclasses"] = false
@@ -146,6 +180,21 @@ func TestSync(t *testing.T) {
 				assert.NilError(t, err)
 			},
 		},
+		{
+			Name:                "Create forward with data source",
+			InitialVirtualState: []runtime.Object{dataSourcePvc},
+			ExpectedVirtualState: map[schema.GroupVersionKind][]runtime.Object{
+				corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"): {dataSourcePvc},
+			},
+			ExpectedPhysicalState: map[schema.GroupVersionKind][]runtime.Object{
+				corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"): {createdDataSourcePvc},
+			},
+			Sync: func(ctx *synccontext.RegisterContext) {
+				syncCtx, syncer := generictesting.FakeStartSyncer(t, ctx, New)
+				_, err := syncer.(*persistentVolumeClaimSyncer).SyncDown(syncCtx, dataSourcePvc)
+				assert.NilError(t, err)
+			},
+		},
 		{
 			Name:                 "Delete forward with create function",
 			InitialVirtualState:  []runtime.Object{basePvc},
@@ -305,3 +354,100 @@ func TestSync(t *testing.T) {
 		},
 	})
 }
+
+func TestStoragePolicy(t *testing.T) {
+	translate.Suffix = generictesting.DefaultTestVclusterName
+	newVirtualPvc := func(name string, storageClass *string, storage string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "testns"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: storageClass,
+				Resources:        corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(storage)}},
+			},
+		}
+	}
+	newPhysicalPvc := func(name string, storage string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      translate.PhysicalName(name, "testns"),
+				Namespace: generictesting.DefaultTestTargetNamespace,
+				Labels:    map[string]string{translate.MarkerLabel: translate.Suffix},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(storage)}},
+			},
+		}
+	}
+	newSyncer := func(policy string, pObjs ...runtime.Object) (*synccontext.SyncContext, *persistentVolumeClaimSyncer, *testingutil.FakeIndexClient) {
+		policyPath := filepath.Join(t.TempDir(), "policy.yaml")
+		assert.NilError(t, os.WriteFile(policyPath, []byte(policy), 0644))
+		scheme := testingutil.NewScheme()
+		pClient := testingutil.NewFakeClient(scheme, pObjs...)
+		ctx := generictesting.NewFakeRegisterContext(pClient, testingutil.NewFakeClient(scheme))
+		ctx.Controllers["storageclasses"] = false
+		ctx.Options.StoragePolicy = policyPath
+		syncCtx, syncer := generictesting.FakeStartSyncer(t, ctx, New)
+		return syncCtx, syncer.(*persistentVolumeClaimSyncer), pClient
+	}
+	getPhysical := func(pClient *testingutil.FakeIndexClient, name string) (*corev1.PersistentVolumeClaim, error) {
+		pPvc := &corev1.PersistentVolumeClaim{}
+		err := pClient.Get(context.TODO(), types.NamespacedName{Namespace: generictesting.DefaultTestTargetNamespace, Name: translate.PhysicalName(name, "testns")}, pPvc)
+		return pPvc, err
+	}
+	fast := "fast"
+	premium := "premium-ssd"
+
+	// virtual storage classes are mapped to host storage classes and claims without one get the default
+	syncCtx, syncer, pClient := newSyncer("storageClasses:\n  mappings:\n    fast: ssd\n  default: standard\n")
+	_, err := syncer.SyncDown(syncCtx, newVirtualPvc("mapped", &fast, "1Gi"))
+	assert.NilError(t, err)
+	pPvc, err := getPhysical(pClient, "mapped")
+	assert.NilError(t, err)
+	assert.Equal(t, *pPvc.Spec.StorageClassName, "ssd")
+	_, err = syncer.SyncDown(syncCtx, newVirtualPvc("default", nil, "1Gi"))
+	assert.NilError(t, err)
+	pPvc, err = getPhysical(pClient, "default")
+	assert.NilError(t, err)
+	assert.Equal(t, *pPvc.Spec.StorageClassName, "standard")
+
+	// denied storage classes are not synced, even if the claim would get the host default storage class
+	hostDefault := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "premium-ssd", Annotations: map[string]string{defaultStorageClassAnnotation: "true"}}}
+	syncCtx, syncer, pClient = newSyncer("storageClasses:\n  denied:\n  - premium*\n", hostDefault)
+	_, err = syncer.SyncDown(syncCtx, newVirtualPvc("denied", &premium, "1Gi"))
+	assert.NilError(t, err)
+	_, err = getPhysical(pClient, "denied")
+	assert.Assert(t, kerrors.IsNotFound(err))
+	_, err = syncer.SyncDown(syncCtx, newVirtualPvc("host-default", nil, "1Gi"))
+	assert.NilError(t, err)
+	_, err = getPhysical(pClient, "host-default")
+	assert.Assert(t, kerrors.IsNotFound(err))
+
+	// claims exceeding the quota are not synced and checked again later
+	syncCtx, syncer, pClient = newSyncer("quota:\n  persistentVolumeClaims: 1\n", newPhysicalPvc("existing", "1Gi"))
+	exceeded := newVirtualPvc("exceeded", nil, "1Gi")
+	result, err := syncer.SyncDown(syncCtx, exceeded.DeepCopy())
+	assert.NilError(t, err)
+	assert.Equal(t, result.RequeueAfter, time.Minute)
+	_, err = getPhysical(pClient, "exceeded")
+	assert.Assert(t, kerrors.IsNotFound(err))
+
+	// the exceeded quota is only reported again after the claim changed
+	assert.Assert(t, !syncer.events.ShouldRecord(exceeded, "QuotaExceeded", exceeded.Spec), "expected the exceeded quota to be reported")
+	exceeded.Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("2Gi")
+	assert.Assert(t, syncer.events.ShouldRecord(exceeded, "QuotaExceeded", exceeded.Spec), "expected the changed claim to be reported again")
+
+	// resizes exceeding the quota are not synced, other resizes are
+	syncCtx, syncer, pClient = newSyncer("quota:\n  requestsStorage: 10Gi\n", newPhysicalPvc("existing", "4Gi"), newPhysicalPvc("resized", "5Gi"))
+	pPvc, err = getPhysical(pClient, "resized")
+	assert.NilError(t, err)
+	_, err = syncer.Sync(syncCtx, pPvc, newVirtualPvc("resized", nil, "8Gi"))
+	assert.NilError(t, err)
+	pPvc, err = getPhysical(pClient, "resized")
+	assert.NilError(t, err)
+	assert.Equal(t, pPvc.Spec.Resources.Requests.Storage().String(), "5Gi")
+	_, err = syncer.Sync(syncCtx, pPvc, newVirtualPvc("resized", nil, "6Gi"))
+	assert.NilError(t, err)
+	pPvc, err = getPhysical(pClient, "resized")
+	assert.NilError(t, err)
+	assert.Equal(t, pPvc.Spec.Resources.Requests.Storage().String(), "6Gi")
+}
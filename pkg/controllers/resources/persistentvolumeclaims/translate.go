@@ -19,6 +19,7 @@ import (
 
 var (
 	deprecatedStorageClassAnnotation = "volume.beta.kubernetes.io/storage-class"
+	volumeSnapshotGroup              = "snapshot.storage.k8s.io"
 )
 
 func (s *persistentVolumeClaimSyncer) translate(ctx *synccontext.SyncContext, vPvc *corev1.PersistentVolumeClaim) (*corev1.PersistentVolumeClaim, error) {
@@ -27,27 +28,49 @@ func (s *persistentVolumeClaimSyncer) translate(ctx *synccontext.SyncContext, vP
 	if err != nil {
 		return nil, err
 	}
-	if newPvc.Spec.DataSource != nil && vPvc.Annotations[constants.SkipTranslationAnnotation] != "true" &&
-		(newPvc.Spec.DataSource.Kind == "PersistentVolumeClaim" || newPvc.Spec.DataSource.Kind == "VolumeSnapshot") {
-		newPvc.Spec.DataSource.Name = translate.PhysicalName(newPvc.Spec.DataSource.Name, vPvc.Namespace)
+	if vPvc.Annotations[constants.SkipTranslationAnnotation] != "true" {
+		// the api server keeps dataSource and dataSourceRef in sync, so we translate both the same way
+		translateDataSource(newPvc.Spec.DataSource, vPvc.Namespace)
+		translateDataSource(newPvc.Spec.DataSourceRef, vPvc.Namespace)
 	}
 
-	//TODO: add support for the .Spec.DataSourceRef field
 	return newPvc, nil
 }
 
+// translateDataSource translates a data source that clones another virtual persistent volume claim or restores
+// a virtual volume snapshot to the physical name. Other data sources, e.g. volume populators, are kept as they are.
+func translateDataSource(dataSource *corev1.TypedLocalObjectReference, namespace string) {
+	if dataSource == nil || dataSource.Name == "" {
+		return
+	}
+
+	apiGroup := ""
+	if dataSource.APIGroup != nil {
+		apiGroup = *dataSource.APIGroup
+	}
+	if (apiGroup == "" && dataSource.Kind == "PersistentVolumeClaim") || (apiGroup == volumeSnapshotGroup && dataSource.Kind == "VolumeSnapshot") {
+		dataSource.Name = translate.PhysicalName(dataSource.Name, namespace)
+	}
+}
+
 func (s *persistentVolumeClaimSyncer) translateSelector(ctx *synccontext.SyncContext, vPvc *corev1.PersistentVolumeClaim) (*corev1.PersistentVolumeClaim, error) {
 	vPvc = vPvc.DeepCopy()
+	storageClassName := storageClassName(vPvc)
 
-	storageClassName := ""
-	if vPvc.Spec.StorageClassName != nil && *vPvc.Spec.StorageClassName != "" {
-		storageClassName = *vPvc.Spec.StorageClassName
-	} else if vPvc.Annotations != nil && vPvc.Annotations[deprecatedStorageClassAnnotation] != "" {
-		storageClassName = vPvc.Annotations[deprecatedStorageClassAnnotation]
+	// map the storage class through the storage policy for dynamically provisioned claims, which takes
+	// precedence over the storage classes synced from the virtual cluster
+	mapped := false
+	if vPvc.Spec.Selector == nil && vPvc.Spec.VolumeName == "" {
+		var hostStorageClass string
+		hostStorageClass, mapped = s.storagePolicy.HostStorageClass(storageClassName)
+		if mapped {
+			delete(vPvc.Annotations, deprecatedStorageClassAnnotation)
+			vPvc.Spec.StorageClassName = &hostStorageClass
+		}
 	}
 
 	// translate storage class if we manage those in vcluster
-	if s.storageClassesEnabled {
+	if s.storageClassesEnabled && !mapped {
 		if storageClassName == "" && vPvc.Spec.Selector == nil && vPvc.Spec.VolumeName == "" {
 			return nil, fmt.Errorf("no storage class defined for pvc %s/%s", vPvc.Namespace, vPvc.Name)
 		}
@@ -70,7 +93,7 @@ func (s *persistentVolumeClaimSyncer) translateSelector(ctx *synccontext.SyncCon
 				vPvc.Spec.VolumeName = translate.PhysicalNameClusterScoped(vPvc.Spec.VolumeName, ctx.TargetNamespace)
 			}
 			// check if the storage class exists in the physical cluster
-			if !s.storageClassesEnabled && storageClassName != "" {
+			if !s.storageClassesEnabled && storageClassName != "" && !mapped {
 				// Should the PVC be dynamically provisioned or not?
 				if vPvc.Spec.Selector == nil && vPvc.Spec.VolumeName == "" {
 					err := ctx.PhysicalClient.Get(context.TODO(), types.NamespacedName{Name: storageClassName}, &storagev1.StorageClass{})
@@ -90,16 +113,27 @@ func (s *persistentVolumeClaimSyncer) translateSelector(ctx *synccontext.SyncCon
 	return vPvc, nil
 }
 
+func storageClassName(pvc *corev1.PersistentVolumeClaim) string {
+	if pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName != "" {
+		return *pvc.Spec.StorageClassName
+	} else if pvc.Annotations != nil && pvc.Annotations[deprecatedStorageClassAnnotation] != "" {
+		return pvc.Annotations[deprecatedStorageClassAnnotation]
+	}
+
+	return ""
+}
+
 func (s *persistentVolumeClaimSyncer) translateUpdate(ctx *synccontext.SyncContext, pObj, vObj *corev1.PersistentVolumeClaim) (*corev1.PersistentVolumeClaim, error) {
 	var updated *corev1.PersistentVolumeClaim
 
-	// allow storage size to be increased
-	if pObj.Spec.Resources.Requests["storage"] != vObj.Spec.Resources.Requests["storage"] {
+	// allow storage size to be increased, the host cluster rejects shrinking a persistent volume claim
+	vStorage, pStorage := vObj.Spec.Resources.Requests.Storage(), pObj.Spec.Resources.Requests.Storage()
+	if vStorage.Cmp(*pStorage) > 0 {
 		updated = newIfNil(updated, pObj)
 		if updated.Spec.Resources.Requests == nil {
 			updated.Spec.Resources.Requests = make(map[corev1.ResourceName]resource.Quantity)
 		}
-		updated.Spec.Resources.Requests["storage"] = vObj.Spec.Resources.Requests["storage"]
+		updated.Spec.Resources.Requests[corev1.ResourceStorage] = vStorage.DeepCopy()
 	}
 
 	changed, updatedAnnotations, updatedLabels := s.TranslateMetadataUpdate(vObj, pObj)
@@ -94,7 +94,7 @@ func (r *fakePersistentVolumeSyncer) FakeSync(ctx *synccontext.SyncContext, vObj
 	if err != nil {
 		return ctrl.Result{}, err
 	} else if needed {
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, r.updateCapacity(ctx, persistentVolume)
 	}
 
 	ctx.Log.Infof("Delete fake persistent volume %s", vObj.GetName())
@@ -131,6 +131,28 @@ func (r *fakePersistentVolumeSyncer) pvNeeded(ctx *synccontext.SyncContext, pvNa
 	return len(pvcList.Items) > 0, nil
 }
 
+// updateCapacity updates the capacity of the fake persistent volume after the persistent volume claim was resized
+func (r *fakePersistentVolumeSyncer) updateCapacity(ctx *synccontext.SyncContext, persistentVolume *corev1.PersistentVolume) error {
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	err := ctx.VirtualClient.List(ctx.Context, pvcList, client.MatchingFields{constants.IndexByAssigned: persistentVolume.Name})
+	if err != nil || len(pvcList.Items) == 0 {
+		return err
+	}
+
+	capacity, ok := pvcList.Items[0].Status.Capacity[corev1.ResourceStorage]
+	if !ok || capacity.Cmp(*persistentVolume.Spec.Capacity.Storage()) == 0 {
+		return nil
+	}
+
+	ctx.Log.Infof("Update fake persistent volume %s capacity to %s", persistentVolume.Name, capacity.String())
+	orig := persistentVolume.DeepCopy()
+	if persistentVolume.Spec.Capacity == nil {
+		persistentVolume.Spec.Capacity = corev1.ResourceList{}
+	}
+	persistentVolume.Spec.Capacity[corev1.ResourceStorage] = capacity
+	return ctx.VirtualClient.Patch(ctx.Context, persistentVolume, client.MergeFrom(orig))
+}
+
 func CreateFakePersistentVolume(ctx context.Context, virtualClient client.Client, name types.NamespacedName, vPvc *corev1.PersistentVolumeClaim) error {
 	storageClass := ""
 	if vPvc.Spec.StorageClassName != nil {
@@ -11,6 +11,7 @@ import (
 	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
 	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
 
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/nodepools"
 	translatepods "github.com/loft-sh/vcluster/pkg/controllers/resources/pods/translate"
 	"github.com/loft-sh/vcluster/pkg/util/loghelper"
 	"github.com/loft-sh/vcluster/pkg/util/toleration"
@@ -84,12 +85,15 @@ func New(ctx *synccontext.RegisterContext) (syncer.Object, error) {
 
 		serviceName:     ctx.Options.ServiceName,
 		enableScheduler: ctx.Options.EnableScheduler,
+		serveDNS:        ctx.Options.ServeDNS,
+		clusterDomain:   ctx.Options.ClusterDomain,
 
 		virtualClusterClient:  virtualClusterClient,
 		physicalClusterClient: physicalClusterClient,
 		podTranslator:         podTranslator,
 		nodeSelector:          nodeSelector,
 		tolerations:           tolerations,
+		nodePools:             ctx.NodePools,
 
 		podSecurityStandard: ctx.Options.EnforcePodSecurityStandard,
 	}, nil
@@ -100,12 +104,15 @@ type podSyncer struct {
 
 	serviceName     string
 	enableScheduler bool
+	serveDNS        bool
+	clusterDomain   string
 
 	podTranslator         translatepods.Translator
 	virtualClusterClient  kubernetes.Interface
 	physicalClusterClient kubernetes.Interface
 	nodeSelector          *metav1.LabelSelector
 	tolerations           []*corev1.Toleration
+	nodePools             []nodepools.NodePool
 
 	podSecurityStandard string
 }
@@ -143,9 +150,50 @@ func (s *podSyncer) ModifyController(ctx *synccontext.RegisterContext, builder *
 		},
 	}
 
-	return builder.Watches(&source.Kind{Type: &corev1.Namespace{}}, eventHandler), nil
+	// reconcile the pod of a token secret, so that secrets of pods that were deleted before their
+	// physical pod was created are cleaned up
+	tokenSecretHandler := handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		if obj.GetLabels()[translatepods.ServiceAccountTokenSecretLabel] == "" || obj.GetAnnotations()[translator.NameAnnotation] == "" {
+			return nil
+		}
+
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{
+			Namespace: obj.GetAnnotations()[translator.NamespaceAnnotation],
+			Name:      obj.GetAnnotations()[translator.NameAnnotation],
+		}}}
+	})
+
+	return builder.
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, eventHandler).
+		Watches(source.NewKindWithCache(&corev1.Secret{}, ctx.PhysicalManager.GetCache()), tokenSecretHandler), nil
+}
+
+var _ syncer.Starter = &podSyncer{}
+
+func (s *podSyncer) ReconcileStart(ctx *synccontext.SyncContext, req ctrl.Request) (bool, error) {
+	// the token secret is owned by the physical pod, so if neither the virtual nor the physical pod
+	// exists anymore we have to delete it ourselves
+	err := ctx.VirtualClient.Get(ctx.Context, req.NamespacedName, &corev1.Pod{})
+	if err == nil || !kerrors.IsNotFound(err) {
+		return false, nil
+	}
+
+	vPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: req.Name, Namespace: req.Namespace}}
+	err = ctx.PhysicalClient.Get(ctx.Context, s.VirtualToPhysical(req.NamespacedName, nil), &corev1.Pod{})
+	if err == nil || !kerrors.IsNotFound(err) {
+		return false, nil
+	}
+
+	err = s.deleteServiceAccountTokens(ctx, vPod)
+	if err != nil {
+		return false, errors.Wrap(err, "delete token secret")
+	}
+
+	return false, nil
 }
 
+func (s *podSyncer) ReconcileEnd() {}
+
 var _ syncer.Syncer = &podSyncer{}
 
 func (s *podSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
@@ -156,7 +204,11 @@ func (s *podSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (
 	if vPod.DeletionTimestamp != nil || vPod.Status.StartTime != nil {
 		// delete pod immediately
 		ctx.Log.Infof("delete pod %s/%s immediately, because it is being deleted & there is no physical pod", vPod.Namespace, vPod.Name)
-		err := ctx.VirtualClient.Delete(ctx.Context, vPod, &client.DeleteOptions{
+		err := s.deleteServiceAccountTokens(ctx, vPod)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "delete token secret")
+		}
+		err = ctx.VirtualClient.Delete(ctx.Context, vPod, &client.DeleteOptions{
 			GracePeriodSeconds: &zero,
 		})
 		if kerrors.IsNotFound(err) {
@@ -178,7 +230,7 @@ func (s *podSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (
 	// translate the pod
 	pPod, err := s.translate(ctx, vPod)
 	if err != nil {
-		return ctrl.Result{}, err
+		return s.syncDownFailed(ctx, vPod, err)
 	}
 
 	// ensure tolerations
@@ -213,11 +265,77 @@ func (s *podSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (
 	}
 
 	// if scheduler is enabled we only sync if the pod has a node name
-	if s.enableScheduler && pPod.Spec.NodeName == "" {
+	if s.enableScheduler && vPod.Spec.NodeName == "" {
 		return ctrl.Result{}, nil
 	}
 
-	return s.SyncDownCreate(ctx, vPod, pPod)
+	// ensure the service account tokens of the pod
+	refreshAfter, err := s.ensureServiceAccountTokens(ctx, vPod, nil)
+	if err != nil {
+		return s.syncDownFailed(ctx, vPod, err)
+	}
+
+	result, err := s.SyncDownCreate(ctx, vPod, pPod)
+	if err != nil {
+		return s.syncDownFailed(ctx, vPod, err)
+	}
+
+	return requeueForTokenRefresh(result, refreshAfter), nil
+}
+
+// syncDownFailed handles an error while creating the physical pod. Transient errors, e.g. while the
+// DNS service is not available yet, are requeued quietly and temporary errors of the api server are
+// retried, while all other errors mark the virtual pod as unschedulable.
+func (s *podSyncer) syncDownFailed(ctx *synccontext.SyncContext, vPod *corev1.Pod, err error) (ctrl.Result, error) {
+	if translator.IsTransient(err) {
+		ctx.Log.Debugf("requeue pod %s/%s: %v", vPod.Namespace, vPod.Name, err)
+		return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+	} else if isTemporaryError(err) {
+		return ctrl.Result{}, err
+	}
+
+	s.setSyncFailedCondition(ctx, vPod, err)
+	return ctrl.Result{}, err
+}
+
+// isTemporaryError checks if the api server will likely accept the request when retried
+func isTemporaryError(err error) bool {
+	return kerrors.IsConflict(err) || kerrors.IsAlreadyExists(err) || kerrors.IsServerTimeout(err) || kerrors.IsTimeout(err) ||
+		kerrors.IsTooManyRequests(err) || kerrors.IsServiceUnavailable(err) || kerrors.IsInternalError(err)
+}
+
+// setSyncFailedCondition sets the PodScheduled condition of the virtual pod to false with the error
+// of the host cluster, as the pod would otherwise stay pending without any explanation
+func (s *podSyncer) setSyncFailedCondition(ctx *synccontext.SyncContext, vPod *corev1.Pod, syncErr error) {
+	newPod := vPod.DeepCopy()
+	condition := corev1.PodCondition{
+		Type:               corev1.PodScheduled,
+		Status:             corev1.ConditionFalse,
+		Reason:             "SyncError",
+		Message:            syncErr.Error(),
+		LastTransitionTime: metav1.Now(),
+	}
+
+	found := false
+	for i, c := range newPod.Status.Conditions {
+		if c.Type != corev1.PodScheduled {
+			continue
+		} else if c.Status == condition.Status && c.Reason == condition.Reason && c.Message == condition.Message {
+			return
+		}
+
+		newPod.Status.Conditions[i] = condition
+		found = true
+		break
+	}
+	if !found {
+		newPod.Status.Conditions = append(newPod.Status.Conditions, condition)
+	}
+
+	err := ctx.VirtualClient.Status().Update(ctx.Context, newPod)
+	if err != nil && !kerrors.IsConflict(err) {
+		ctx.Log.Infof("error updating status of virtual pod %s/%s: %v", vPod.Namespace, vPod.Name, err)
+	}
 }
 
 func (s *podSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
@@ -256,27 +374,42 @@ func (s *podSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj
 		return ctrl.Result{}, err
 	}
 
-	// make sure node exists for pod
-	if pPod.Spec.NodeName != "" {
-		requeue, err := s.ensureNode(ctx, pPod, vPod)
-		if err != nil {
-			return ctrl.Result{}, err
-		} else if requeue {
-			return ctrl.Result{Requeue: true}, nil
-		}
-	} else if pPod.Spec.NodeName != "" && vPod.Spec.NodeName != "" && pPod.Spec.NodeName != vPod.Spec.NodeName {
-		// if physical pod nodeName is different from virtual pod nodeName, we delete the virtual one
-		ctx.Log.Infof("delete virtual pod %s/%s, because node name is different between the two", vPod.Namespace, vPod.Name)
-		err := ctx.VirtualClient.Delete(ctx.Context, vPod, &client.DeleteOptions{GracePeriodSeconds: &minimumGracePeriodInSeconds})
-		if err != nil {
-			return ctrl.Result{}, err
-		}
+	// refresh the service account tokens of the pod
+	refreshAfter, err := s.ensureServiceAccountTokens(ctx, vPod, pPod)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
-		return ctrl.Result{}, nil
+	// make sure node exists for pod. Pods of a node pool stay on the virtual pool node, so the host
+	// node is neither synced nor a reason to recreate the pod.
+	if !s.isNodePoolPod(vPod) {
+		if pPod.Spec.NodeName != "" {
+			requeue, err := s.ensureNode(ctx, pPod, vPod)
+			if err != nil {
+				return ctrl.Result{}, err
+			} else if requeue {
+				return ctrl.Result{Requeue: true}, nil
+			}
+		} else if pPod.Spec.NodeName != "" && vPod.Spec.NodeName != "" && pPod.Spec.NodeName != vPod.Spec.NodeName {
+			// if physical pod nodeName is different from virtual pod nodeName, we delete the virtual one
+			ctx.Log.Infof("delete virtual pod %s/%s, because node name is different between the two", vPod.Namespace, vPod.Name)
+			err := ctx.VirtualClient.Delete(ctx.Context, vPod, &client.DeleteOptions{GracePeriodSeconds: &minimumGracePeriodInSeconds})
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{}, nil
+		}
 	}
 
 	// has status changed?
 	strippedPod := stripHostRewriteContainer(pPod)
+	if s.isNodePoolPod(vPod) {
+		strippedPod, err = s.translateNodePoolStatus(ctx, strippedPod, vPod)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
 
 	// update readiness gates & sync status virtual -> physical
 	updated, err := UpdateConditions(ctx, strippedPod, vPod)
@@ -298,13 +431,13 @@ func (s *podSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj
 				s.EventRecorder().Eventf(vObj, "Warning", "SyncError", "Error updating pod: %v", err)
 			}
 
-			return ctrl.Result{}, err
+			return ctrl.Result{}, translator.NewReportedError(err)
 		}
 		return ctrl.Result{}, nil
 	}
 
 	// sync ephemeral containers
-	if syncEphemeralContainers(vPod, strippedPod) {
+	if s.syncEphemeralContainers(vPod, strippedPod) {
 		kubeIP, _, ptrServiceList, err := s.getK8sIPDNSIPServiceList(ctx, vPod)
 		if err != nil {
 			return ctrl.Result{}, err
@@ -312,16 +445,25 @@ func (s *podSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj
 
 		// translate services to environment variables
 		serviceEnv := translatepods.TranslateServicesToEnvironmentVariables(vPod.Spec.EnableServiceLinks, ptrServiceList, kubeIP)
+		rejected := false
 		for i := range vPod.Spec.EphemeralContainers {
 			envVar, envFrom := translatepods.TranslateContainerEnv(vPod.Spec.EphemeralContainers[i].Env, vPod.Spec.EphemeralContainers[i].EnvFrom, vPod, serviceEnv)
 			vPod.Spec.EphemeralContainers[i].Env = envVar
 			vPod.Spec.EphemeralContainers[i].EnvFrom = envFrom
+			vPod.Spec.EphemeralContainers[i].Image, err = s.podTranslator.TranslateImage(vPod, vPod.Spec.EphemeralContainers[i].Image)
+			if err != nil {
+				// the rejection was already reported as event, we don't want to block the sync
+				// of the running pod because of it
+				rejected = true
+			}
 		}
 
 		// add ephemeralContainers subresource to physical pod
-		err = AddEphemeralContainer(ctx, s.physicalClusterClient, pPod, vPod)
-		if err != nil {
-			return ctrl.Result{}, err
+		if !rejected {
+			err = AddEphemeralContainer(ctx, s.physicalClusterClient, pPod, vPod)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
 		}
 	}
 
@@ -343,10 +485,11 @@ func (s *podSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj
 		translator.PrintChanges(pPod, updatedPod, ctx.Log)
 	}
 
-	return s.SyncDownUpdate(ctx, vPod, updatedPod)
+	result, err := s.SyncDownUpdate(ctx, vPod, updatedPod)
+	return requeueForTokenRefresh(result, refreshAfter), err
 }
 
-func syncEphemeralContainers(vPod *corev1.Pod, pPod *corev1.Pod) bool {
+func (s *podSyncer) syncEphemeralContainers(vPod *corev1.Pod, pPod *corev1.Pod) bool {
 	if vPod.Spec.EphemeralContainers == nil {
 		return false
 	}
@@ -354,7 +497,10 @@ func syncEphemeralContainers(vPod *corev1.Pod, pPod *corev1.Pod) bool {
 		return true
 	}
 	for i := range vPod.Spec.EphemeralContainers {
-		if vPod.Spec.EphemeralContainers[i].Image != pPod.Spec.EphemeralContainers[i].Image {
+		// a rejected image is reported as event by the translator and can't be synced anyways, so it is
+		// not treated as change
+		imageChanged, err := s.podTranslator.ImageChanged(vPod, vPod.Spec.EphemeralContainers[i].Image, pPod.Spec.EphemeralContainers[i].Image)
+		if err == nil && imageChanged {
 			return true
 		}
 		if vPod.Spec.EphemeralContainers[i].Name != pPod.Spec.EphemeralContainers[i].Name {
@@ -401,6 +547,36 @@ func (s *podSyncer) ensureNode(ctx *synccontext.SyncContext, pObj *corev1.Pod, v
 	return false, nil
 }
 
+func (s *podSyncer) isNodePoolPod(vPod *corev1.Pod) bool {
+	return nodepools.Find(s.nodePools, vPod.Spec.NodeName) != nil
+}
+
+// translateNodePoolStatus replaces the host ip of the physical pod with the ip of the virtual pool node,
+// as the host node of a pod that runs in a node pool shouldn't be visible in the virtual cluster
+func (s *podSyncer) translateNodePoolStatus(ctx *synccontext.SyncContext, pPod *corev1.Pod, vPod *corev1.Pod) (*corev1.Pod, error) {
+	if pPod.Status.HostIP == "" {
+		return pPod, nil
+	}
+
+	vNode := &corev1.Node{}
+	err := ctx.VirtualClient.Get(ctx.Context, types.NamespacedName{Name: vPod.Spec.NodeName}, vNode)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	hostIP := ""
+	for _, address := range vNode.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			hostIP = address.Address
+			break
+		}
+	}
+
+	newPod := pPod.DeepCopy()
+	newPod.Status.HostIP = hostIP
+	return newPod, nil
+}
+
 func (s *podSyncer) assignNodeToPod(ctx *synccontext.SyncContext, pObj *corev1.Pod, vObj *corev1.Pod) error {
 	ctx.Log.Infof("bind virtual pod %s/%s to node %s, because node name between physical and virtual is different", vObj.Namespace, vObj.Name, pObj.Spec.NodeName)
 	err := s.virtualClusterClient.CoreV1().Pods(vObj.Namespace).Bind(ctx.Context, &corev1.Binding{
@@ -416,7 +592,7 @@ func (s *podSyncer) assignNodeToPod(ctx *synccontext.SyncContext, pObj *corev1.P
 	}, metav1.CreateOptions{})
 	if err != nil {
 		s.EventRecorder().Eventf(vObj, "Warning", "SyncError", "Error binding pod: %v", err)
-		return err
+		return translator.NewReportedError(err)
 	}
 
 	// wait until cache is updated
@@ -1,18 +1,24 @@
 package pods
 
 import (
+	"context"
+	"errors"
 	"testing"
 
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/nodepools"
 	podtranslate "github.com/loft-sh/vcluster/pkg/controllers/resources/pods/translate"
 	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
 	generictesting "github.com/loft-sh/vcluster/pkg/controllers/syncer/testing"
 	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	testingutil "github.com/loft-sh/vcluster/pkg/util/testing"
 	"github.com/loft-sh/vcluster/pkg/util/translate"
 	"gotest.tools/assert"
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/pod-security-admission/api"
 	"k8s.io/utils/pointer"
 )
@@ -85,6 +91,10 @@ func TestSync(t *testing.T) {
 	pPodWithNodeName := pPodBase.DeepCopy()
 	pPodWithNodeName.Spec.NodeName = "test456"
 
+	// pods of a node pool run on a host node that differs from the virtual pool node
+	vPodInNodePool := vPodWithNodeName.DeepCopy()
+	vPodInNodePool.Spec.NodeName = "pool-0"
+
 	vPodWithNodeSelector := &corev1.Pod{
 		ObjectMeta: vObjectMeta,
 		Spec: corev1.PodSpec{
@@ -142,6 +152,25 @@ func TestSync(t *testing.T) {
 				assert.NilError(t, err)
 			},
 		},
+		{
+			Name:                 "Keep node pool pod on a different host node",
+			InitialVirtualState:  []runtime.Object{vPodInNodePool.DeepCopy(), vNamespace.DeepCopy()},
+			InitialPhysicalState: []runtime.Object{pPodWithNodeName.DeepCopy(), pVclusterService.DeepCopy(), pDNSService.DeepCopy()},
+			Sync: func(ctx *synccontext.RegisterContext) {
+				ctx.NodePools = []nodepools.NodePool{{Name: "pool"}}
+				syncCtx, syncer := generictesting.FakeStartSyncer(t, ctx, New)
+				_, err := syncer.(*podSyncer).Sync(syncCtx, pPodWithNodeName.DeepCopy(), vPodInNodePool.DeepCopy())
+				assert.NilError(t, err)
+
+				// neither the virtual pod is deleted nor the host node synced
+				vPod := &corev1.Pod{}
+				err = syncCtx.VirtualClient.Get(syncCtx.Context, types.NamespacedName{Name: vPodInNodePool.Name, Namespace: vPodInNodePool.Namespace}, vPod)
+				assert.NilError(t, err)
+				assert.Equal(t, vPod.Spec.NodeName, "pool-0")
+				err = syncCtx.VirtualClient.Get(syncCtx.Context, types.NamespacedName{Name: pPodWithNodeName.Spec.NodeName}, &corev1.Node{})
+				assert.Assert(t, kerrors.IsNotFound(err), "expected host node not to be synced")
+			},
+		},
 		{
 			Name:                 "Sync and enforce NodeSelector",
 			InitialVirtualState:  []runtime.Object{vPodWithNodeSelector.DeepCopy(), vNamespace.DeepCopy()},
@@ -214,3 +243,79 @@ func TestSync(t *testing.T) {
 		},
 	})
 }
+
+func TestSetSyncFailedCondition(t *testing.T) {
+	vPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}},
+		},
+	}
+	scheme := testingutil.NewScheme()
+	vClient := testingutil.NewFakeClient(scheme, vPod.DeepCopy())
+	syncCtx, syncer := generictesting.FakeStartSyncer(t, generictesting.NewFakeRegisterContext(testingutil.NewFakeClient(scheme), vClient), New)
+	podSyncer := syncer.(*podSyncer)
+
+	getPod := func() *corev1.Pod {
+		pod := &corev1.Pod{}
+		err := vClient.Get(context.TODO(), types.NamespacedName{Name: vPod.Name, Namespace: vPod.Namespace}, pod)
+		assert.NilError(t, err)
+		return pod
+	}
+	assertCondition := func(pod *corev1.Pod, message string) {
+		assert.Equal(t, len(pod.Status.Conditions), 2, "expected other conditions to be kept")
+		assert.Equal(t, pod.Status.Conditions[1].Type, corev1.PodScheduled)
+		assert.Equal(t, pod.Status.Conditions[1].Status, corev1.ConditionFalse)
+		assert.Equal(t, pod.Status.Conditions[1].Reason, "SyncError")
+		assert.Equal(t, pod.Status.Conditions[1].Message, message)
+	}
+
+	podSyncer.setSyncFailedCondition(syncCtx, vPod, errors.New("exceeded quota"))
+	updatedPod := getPod()
+	assertCondition(updatedPod, "exceeded quota")
+
+	// the same error doesn't update the pod again
+	podSyncer.setSyncFailedCondition(syncCtx, updatedPod, errors.New("exceeded quota"))
+	assert.Equal(t, getPod().ResourceVersion, updatedPod.ResourceVersion)
+
+	// a new error replaces the existing condition
+	podSyncer.setSyncFailedCondition(syncCtx, updatedPod, errors.New("invalid volume"))
+	assertCondition(getPod(), "invalid volume")
+}
+
+func TestSyncDownFailedTransientErrors(t *testing.T) {
+	vPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+	}
+	pVclusterService := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      generictesting.DefaultTestVclusterServiceName,
+			Namespace: generictesting.DefaultTestCurrentNamespace,
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "1.2.3.4",
+		},
+	}
+	scheme := testingutil.NewScheme()
+	vClient := testingutil.NewFakeClient(scheme, vPod.DeepCopy())
+	syncCtx, syncer := generictesting.FakeStartSyncer(t, generictesting.NewFakeRegisterContext(testingutil.NewFakeClient(scheme, pVclusterService), vClient), New)
+	podSyncer := syncer.(*podSyncer)
+
+	assertNoCondition := func() {
+		pod := &corev1.Pod{}
+		err := vClient.Get(context.TODO(), types.NamespacedName{Name: vPod.Name, Namespace: vPod.Namespace}, pod)
+		assert.NilError(t, err)
+		assert.Equal(t, len(pod.Status.Conditions), 0)
+	}
+
+	// the DNS service doesn't exist yet, so the pod is requeued quietly
+	result, err := podSyncer.SyncDown(syncCtx, vPod.DeepCopy())
+	assert.NilError(t, err)
+	assert.Assert(t, result.RequeueAfter > 0)
+	assertNoCondition()
+
+	// temporary errors of the api server are retried without marking the pod
+	_, err = podSyncer.syncDownFailed(syncCtx, vPod.DeepCopy(), kerrors.NewConflict(schema.GroupResource{Resource: "pods"}, vPod.Name, errors.New("conflict")))
+	assert.Assert(t, kerrors.IsConflict(err))
+	assertNoCondition()
+}
@@ -0,0 +1,172 @@
+package pods
+
+import (
+	"time"
+
+	translatepods "github.com/loft-sh/vcluster/pkg/controllers/resources/pods/translate"
+	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// TokenRefreshAnnotation holds the time the tokens within a token secret need to be refreshed
+const TokenRefreshAnnotation = "vcluster.loft.sh/token-refresh"
+
+// ensureServiceAccountTokens makes sure the token secret of the pod holds a valid token for each service account
+// token projection of the virtual pod. Tokens are requested with the expiration of the projection and are refreshed
+// after 80% of their lifetime, the same as the kubelet does. Once the physical pod exists, it becomes the owner of
+// the secret, so the secret is garbage collected together with the pod. Before that, the secret is deleted by the
+// syncer if the virtual pod is deleted. Returns the duration until the next refresh.
+func (s *podSyncer) ensureServiceAccountTokens(ctx *synccontext.SyncContext, vPod *corev1.Pod, pPod *corev1.Pod) (time.Duration, error) {
+	requests := translatepods.ServiceAccountTokenRequests(vPod, s.clusterDomain)
+	if len(requests) == 0 {
+		return 0, nil
+	}
+
+	secretName := translatepods.ServiceAccountTokenSecretName(vPod)
+	secret := &corev1.Secret{}
+	err := ctx.PhysicalClient.Get(ctx.Context, types.NamespacedName{Namespace: ctx.TargetNamespace, Name: secretName}, secret)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return 0, errors.Wrap(err, "get token secret")
+		}
+
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: ctx.TargetNamespace,
+				Labels: map[string]string{
+					translatepods.ServiceAccountTokenSecretLabel: translate.Suffix,
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+		}
+	}
+
+	updated := secret.DeepCopy()
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	updated.Annotations[translator.NameAnnotation] = vPod.Name
+	updated.Annotations[translator.NamespaceAnnotation] = vPod.Namespace
+	if pPod != nil && pPod.UID != "" {
+		updated.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Pod",
+			Name:       pPod.Name,
+			UID:        pPod.UID,
+		}}
+	} else if len(updated.OwnerReferences) == 0 {
+		updated.OwnerReferences = translate.GetOwnerReference(nil)
+	}
+
+	refreshAt, err := time.Parse(time.RFC3339, secret.Annotations[TokenRefreshAnnotation])
+	if err != nil || !time.Now().Before(refreshAt) || !hasServiceAccountTokens(secret, requests) {
+		updated.Data, refreshAt, err = s.requestServiceAccountTokens(ctx, vPod, requests)
+		if err != nil {
+			return 0, err
+		}
+		updated.Annotations[TokenRefreshAnnotation] = refreshAt.Format(time.RFC3339)
+	}
+
+	if secret.ResourceVersion == "" {
+		ctx.Log.Infof("create token secret %s for pod %s/%s", secretName, vPod.Namespace, vPod.Name)
+		err = ctx.PhysicalClient.Create(ctx.Context, updated)
+		if kerrors.IsAlreadyExists(err) {
+			// the cache is not up to date yet, we retry shortly
+			return time.Second, nil
+		}
+	} else if !equality.Semantic.DeepEqual(secret, updated) {
+		ctx.Log.Debugf("update token secret %s for pod %s/%s", secretName, vPod.Namespace, vPod.Name)
+		err = ctx.PhysicalClient.Update(ctx.Context, updated)
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "save token secret")
+	}
+
+	return time.Until(refreshAt), nil
+}
+
+func (s *podSyncer) requestServiceAccountTokens(ctx *synccontext.SyncContext, vPod *corev1.Pod, requests []translatepods.ServiceAccountTokenRequest) (map[string][]byte, time.Time, error) {
+	data := map[string][]byte{}
+	var refreshAt time.Time
+	for _, request := range requests {
+		expirationSeconds := request.ExpirationSeconds
+		issuedAt := time.Now()
+		token, err := s.virtualClusterClient.CoreV1().ServiceAccounts(vPod.Namespace).CreateToken(ctx.Context, translatepods.ServiceAccountName(vPod), &authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				Audiences: request.Audiences,
+				BoundObjectRef: &authenticationv1.BoundObjectReference{
+					APIVersion: corev1.SchemeGroupVersion.String(),
+					Kind:       "Pod",
+					Name:       vPod.Name,
+					UID:        vPod.UID,
+				},
+				ExpirationSeconds: &expirationSeconds,
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, time.Time{}, errors.Wrap(err, "create token")
+		} else if token.Status.Token == "" {
+			return nil, time.Time{}, errors.New("received empty token")
+		}
+
+		// the api server might shorten or extend the requested expiration
+		lifetime := token.Status.ExpirationTimestamp.Sub(issuedAt)
+		if lifetime <= 0 {
+			lifetime = time.Duration(expirationSeconds) * time.Second
+		}
+		tokenRefreshAt := issuedAt.Add(lifetime * 8 / 10)
+		if refreshAt.IsZero() || tokenRefreshAt.Before(refreshAt) {
+			refreshAt = tokenRefreshAt
+		}
+
+		data[request.Key] = []byte(token.Status.Token)
+	}
+
+	return data, refreshAt, nil
+}
+
+func hasServiceAccountTokens(secret *corev1.Secret, requests []translatepods.ServiceAccountTokenRequest) bool {
+	for _, request := range requests {
+		if len(secret.Data[request.Key]) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// deleteServiceAccountTokens deletes the token secret of the pod, which is otherwise garbage collected with the physical pod
+func (s *podSyncer) deleteServiceAccountTokens(ctx *synccontext.SyncContext, vPod *corev1.Pod) error {
+	err := ctx.PhysicalClient.Delete(ctx.Context, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      translatepods.ServiceAccountTokenSecretName(vPod),
+			Namespace: ctx.TargetNamespace,
+		},
+	})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// requeueForTokenRefresh makes sure the pod is reconciled again before its tokens need to be refreshed
+func requeueForTokenRefresh(result ctrl.Result, refreshAfter time.Duration) ctrl.Result {
+	if refreshAfter <= 0 || result.Requeue {
+		return result
+	} else if result.RequeueAfter == 0 || refreshAfter < result.RequeueAfter {
+		result.RequeueAfter = refreshAfter
+	}
+
+	return result
+}
@@ -0,0 +1,140 @@
+package pods
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	podtranslate "github.com/loft-sh/vcluster/pkg/controllers/resources/pods/translate"
+	generictesting "github.com/loft-sh/vcluster/pkg/controllers/syncer/testing"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	testingutil "github.com/loft-sh/vcluster/pkg/util/testing"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"gotest.tools/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// fakeTokenClient returns a clientset that issues tokens with the given lifetime and records the token requests
+func fakeTokenClient(lifetime time.Duration, requests *[]*authenticationv1.TokenRequest) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "serviceaccounts", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		request := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenRequest).DeepCopy()
+		*requests = append(*requests, request)
+		request.Status = authenticationv1.TokenRequestStatus{
+			Token:               "token-" + request.Spec.Audiences[0],
+			ExpirationTimestamp: metav1.NewTime(time.Now().Add(lifetime)),
+		}
+		return true, request, nil
+	})
+	return client
+}
+
+func TestServiceAccountTokens(t *testing.T) {
+	translate.Suffix = generictesting.DefaultTestVclusterName
+	expirationSeconds := int64(600)
+	vPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "123"},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: "sa",
+			Volumes: []corev1.Volume{{
+				Name: "tokens",
+				VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: []corev1.VolumeProjection{
+					{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{Audience: "vault", ExpirationSeconds: &expirationSeconds}},
+					{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{Audience: "api"}},
+				}}},
+			}},
+		},
+	}
+	secretName := types.NamespacedName{Namespace: generictesting.DefaultTestTargetNamespace, Name: podtranslate.ServiceAccountTokenSecretName(vPod)}
+
+	scheme := testingutil.NewScheme()
+	pClient := testingutil.NewFakeClient(scheme)
+	syncCtx, syncer := generictesting.FakeStartSyncer(t, generictesting.NewFakeRegisterContext(pClient, testingutil.NewFakeClient(scheme)), New)
+	podSyncer := syncer.(*podSyncer)
+	tokenRequests := []*authenticationv1.TokenRequest{}
+	podSyncer.virtualClusterClient = fakeTokenClient(time.Duration(expirationSeconds)*time.Second, &tokenRequests)
+
+	// request the tokens bound to the virtual pod with the expiration of the projection
+	data, refreshAt, err := podSyncer.requestServiceAccountTokens(syncCtx, vPod, podtranslate.ServiceAccountTokenRequests(vPod, "cluster.local"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, data, map[string][]byte{"tokens.0": []byte("token-vault"), "tokens.1": []byte("token-api")})
+	assert.Equal(t, len(tokenRequests), 2)
+	assert.Equal(t, tokenRequests[0].Spec.BoundObjectRef.UID, vPod.UID)
+	assert.Equal(t, *tokenRequests[0].Spec.ExpirationSeconds, expirationSeconds)
+	assert.Equal(t, *tokenRequests[1].Spec.ExpirationSeconds, int64(3600))
+	assert.Assert(t, time.Until(refreshAt) > 7*time.Minute && time.Until(refreshAt) <= 8*time.Minute, "expected refresh after 80%% of the lifetime, got %v", time.Until(refreshAt))
+
+	// create the secret owned by the vcluster while there is no physical pod
+	tokenRequests = nil
+	refreshAfter, err := podSyncer.ensureServiceAccountTokens(syncCtx, vPod, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, refreshAfter > 7*time.Minute && refreshAfter <= 8*time.Minute)
+	assert.Equal(t, len(tokenRequests), 2)
+	secret := &corev1.Secret{}
+	assert.NilError(t, pClient.Get(context.TODO(), secretName, secret))
+	assert.Equal(t, secret.Labels[podtranslate.ServiceAccountTokenSecretLabel], translate.Suffix)
+	assert.Equal(t, secret.Annotations[translator.NameAnnotation], vPod.Name)
+	assert.Equal(t, secret.Annotations[translator.NamespaceAnnotation], vPod.Namespace)
+	assert.Equal(t, string(secret.Data["tokens.1"]), "token-api")
+
+	// valid tokens are not requested again, but the physical pod becomes the owner
+	tokenRequests = nil
+	pPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: translate.PhysicalName(vPod.Name, vPod.Namespace), Namespace: generictesting.DefaultTestTargetNamespace, UID: "456"}}
+	_, err = podSyncer.ensureServiceAccountTokens(syncCtx, vPod, pPod)
+	assert.NilError(t, err)
+	assert.Equal(t, len(tokenRequests), 0)
+	assert.NilError(t, pClient.Get(context.TODO(), secretName, secret))
+	assert.Equal(t, len(secret.OwnerReferences), 1)
+	assert.Equal(t, secret.OwnerReferences[0].UID, pPod.UID)
+
+	// tokens are refreshed once the refresh time passed
+	secret.Annotations[TokenRefreshAnnotation] = time.Now().Add(-time.Minute).Format(time.RFC3339)
+	assert.NilError(t, pClient.Update(context.TODO(), secret))
+	_, err = podSyncer.ensureServiceAccountTokens(syncCtx, vPod, pPod)
+	assert.NilError(t, err)
+	assert.Equal(t, len(tokenRequests), 2)
+
+	// the secret is deleted with the pod
+	assert.NilError(t, podSyncer.deleteServiceAccountTokens(syncCtx, vPod))
+	assert.Assert(t, kerrors.IsNotFound(pClient.Get(context.TODO(), secretName, secret)))
+	assert.NilError(t, podSyncer.deleteServiceAccountTokens(syncCtx, vPod))
+}
+
+func TestDeleteOrphanedServiceAccountTokens(t *testing.T) {
+	translate.Suffix = generictesting.DefaultTestVclusterName
+	vPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        podtranslate.ServiceAccountTokenSecretName(vPod),
+			Namespace:   generictesting.DefaultTestTargetNamespace,
+			Labels:      map[string]string{podtranslate.ServiceAccountTokenSecretLabel: translate.Suffix},
+			Annotations: map[string]string{translator.NameAnnotation: vPod.Name, translator.NamespaceAnnotation: vPod.Namespace},
+		},
+	}
+	secretName := types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: vPod.Namespace, Name: vPod.Name}}
+
+	// keep the secret while the virtual pod exists
+	scheme := testingutil.NewScheme()
+	pClient := testingutil.NewFakeClient(scheme, secret.DeepCopy())
+	vClient := testingutil.NewFakeClient(scheme, vPod.DeepCopy())
+	syncCtx, syncer := generictesting.FakeStartSyncer(t, generictesting.NewFakeRegisterContext(pClient, vClient), New)
+	skip, err := syncer.(*podSyncer).ReconcileStart(syncCtx, req)
+	assert.NilError(t, err)
+	assert.Equal(t, skip, false)
+	assert.NilError(t, pClient.Get(context.TODO(), secretName, &corev1.Secret{}))
+
+	// delete the secret if the virtual pod was deleted before the physical pod was created
+	assert.NilError(t, vClient.Delete(context.TODO(), vPod.DeepCopy()))
+	_, err = syncer.(*podSyncer).ReconcileStart(syncCtx, req)
+	assert.NilError(t, err)
+	assert.Assert(t, kerrors.IsNotFound(pClient.Get(context.TODO(), secretName, &corev1.Secret{})))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
 	"github.com/loft-sh/vcluster/pkg/util/translate"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -17,7 +18,7 @@ func (s *podSyncer) translate(ctx *synccontext.SyncContext, vPod *corev1.Pod) (*
 		return nil, err
 	}
 
-	pPod, err := s.podTranslator.Translate(vPod, ptrServiceList, dnsIP, kubeIP)
+	pPod, err := s.podTranslator.Translate(ctx.Context, vPod, ptrServiceList, dnsIP, kubeIP)
 	if err != nil {
 		return nil, err
 	}
@@ -69,9 +70,14 @@ func (s *podSyncer) findKubernetesIP(ctx *synccontext.SyncContext) (string, erro
 }
 
 func (s *podSyncer) findKubernetesDNSIP(ctx *synccontext.SyncContext) (string, error) {
+	// the syncer serves DNS itself and is reachable through the vcluster service
+	if s.serveDNS {
+		return s.findKubernetesIP(ctx)
+	}
+
 	ip := s.translateAndFindService(ctx, "kube-system", "kube-dns")
 	if ip == "" {
-		return "", fmt.Errorf("waiting for DNS service IP")
+		return "", translator.NewTransientError(fmt.Errorf("waiting for DNS service IP"))
 	}
 
 	return ip, nil
@@ -0,0 +1,217 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultRegistryHost = "registry-1.docker.io"
+
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// DigestResolver resolves image tags to digests
+type DigestResolver interface {
+	// Resolve returns the given image pinned to its current digest
+	Resolve(ctx context.Context, image string) (string, error)
+}
+
+type digestCacheEntry struct {
+	digest  string
+	expires time.Time
+}
+
+type digestResolver struct {
+	client   *http.Client
+	cacheTTL time.Duration
+
+	cacheMutex sync.Mutex
+	cache      map[string]digestCacheEntry
+}
+
+// NewDigestResolver creates a resolver that looks up digests anonymously through the
+// registry v2 api and caches the results for cacheTTL
+func NewDigestResolver(cacheTTL time.Duration) DigestResolver {
+	return newDigestResolver(&http.Client{Timeout: time.Second * 10}, cacheTTL)
+}
+
+func newDigestResolver(client *http.Client, cacheTTL time.Duration) *digestResolver {
+	return &digestResolver{
+		client:   client,
+		cacheTTL: cacheTTL,
+		cache:    map[string]digestCacheEntry{},
+	}
+}
+
+func (d *digestResolver) Resolve(ctx context.Context, image string) (string, error) {
+	// already pinned
+	if strings.Contains(image, "@") {
+		return image, nil
+	}
+
+	normalized := NormalizeImage(image)
+	d.cacheMutex.Lock()
+	entry, ok := d.cache[normalized]
+	d.cacheMutex.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return image + "@" + entry.digest, nil
+	}
+
+	digest, err := d.lookup(ctx, normalized)
+	if err != nil {
+		return "", err
+	}
+
+	d.cacheMutex.Lock()
+	d.cache[normalized] = digestCacheEntry{digest: digest, expires: time.Now().Add(d.cacheTTL)}
+	d.cacheMutex.Unlock()
+	return image + "@" + digest, nil
+}
+
+func (d *digestResolver) lookup(ctx context.Context, normalized string) (string, error) {
+	domain, remainder := splitImageDomain(normalized)
+	tagIdx := strings.LastIndex(remainder, ":")
+	repository, tag := remainder[:tagIdx], remainder[tagIdx+1:]
+	if domain == defaultImageDomain {
+		domain = defaultRegistryHost
+	}
+
+	manifestURL := "https://" + domain + "/v2/" + repository + "/manifests/" + tag
+	resp, err := d.headManifest(ctx, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err := d.token(ctx, resp.Header.Get("Www-Authenticate"))
+		if err != nil {
+			return "", fmt.Errorf("authenticate to registry %s: %v", domain, err)
+		}
+
+		resp, err = d.headManifest(ctx, manifestURL, token)
+		if err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d while resolving %s", resp.StatusCode, normalized)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry %s didn't return a digest for %s", domain, normalized)
+	}
+	return digest, nil
+}
+
+func (d *digestResolver) headManifest(ctx context.Context, manifestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	return resp, nil
+}
+
+// token retrieves an anonymous bearer token for the given challenge
+func (d *digestResolver) token(ctx context.Context, challenge string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return "", fmt.Errorf("unsupported authentication challenge '%s'", challenge)
+	}
+
+	params := parseChallengeParams(challenge[len("bearer "):])
+	if params["realm"] == "" {
+		return "", fmt.Errorf("authentication challenge '%s' is missing a realm", challenge)
+	}
+
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", err
+	}
+	query := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, params["realm"])
+	}
+
+	tokenResponse := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&tokenResponse)
+	if err != nil {
+		return "", err
+	}
+	if tokenResponse.Token != "" {
+		return tokenResponse.Token, nil
+	}
+	return tokenResponse.AccessToken, nil
+}
+
+// parseChallengeParams parses the key="value" pairs of a Www-Authenticate header
+func parseChallengeParams(params string) map[string]string {
+	out := map[string]string{}
+	for params != "" {
+		eq := strings.Index(params, "=")
+		if eq == -1 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(params[:eq]))
+		params = strings.TrimSpace(params[eq+1:])
+
+		value := ""
+		if strings.HasPrefix(params, `"`) {
+			end := strings.Index(params[1:], `"`)
+			if end == -1 {
+				value, params = params[1:], ""
+			} else {
+				value, params = params[1:end+1], params[end+2:]
+			}
+		} else {
+			end := strings.Index(params, ",")
+			if end == -1 {
+				value, params = params, ""
+			} else {
+				value, params = params[:end], params[end:]
+			}
+		}
+
+		out[key] = value
+		params = strings.TrimLeft(params, ", ")
+	}
+
+	return out
+}
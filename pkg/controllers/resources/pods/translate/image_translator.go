@@ -2,38 +2,177 @@ package translate
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
+const (
+	defaultImageDomain = "docker.io"
+	officialRepoPrefix = "library/"
+	defaultImageTag    = "latest"
+)
+
 type ImageTranslator interface {
-	Translate(image string) string
+	// Translate rewrites the given image according to the translate rules or
+	// returns an error if the image was rejected
+	Translate(image string) (string, error)
+}
+
+type imageRule struct {
+	from    *regexp.Regexp
+	to      string
+	pattern string
 }
 
 type imageTranslator struct {
 	translateImages map[string]string
+	translateRules  []imageRule
+	rejectRules     []imageRule
 }
 
-func NewImageTranslator(translateImages []string) (ImageTranslator, error) {
+// NewImageTranslator creates a new image translator from the given translate and reject rules. Translate
+// rules have the form image1=image2 and may contain * wildcards, e.g. docker.io/*=mirror.corp/dockerhub/*,
+// where each * in the target is replaced by the part matched by the corresponding * in the source. Reject
+// rules are patterns such as *:latest. Patterns are matched against the image as written in the pod as
+// well as its fully qualified form, so docker.io/* also matches nginx.
+func NewImageTranslator(translateImages []string, rejectImages []string) (ImageTranslator, error) {
 	translateImagesMap := map[string]string{}
+	translateRules := []imageRule{}
 	for _, t := range translateImages {
 		i := strings.Split(strings.TrimSpace(t), "=")
-		if len(i) != 2 {
+		if len(i) != 2 || i[0] == "" || i[1] == "" {
 			return nil, fmt.Errorf("error parsing translate image '%s': bad format expected image1=image2", t)
 		}
 
-		translateImagesMap[i[0]] = i[1]
+		from, to := strings.TrimSpace(i[0]), strings.TrimSpace(i[1])
+		if !strings.Contains(from, "*") {
+			if strings.Contains(to, "*") {
+				return nil, fmt.Errorf("error parsing translate image '%s': target contains a wildcard, but source doesn't", t)
+			}
+
+			translateImagesMap[from] = to
+			continue
+		}
+		if strings.Count(to, "*") > strings.Count(from, "*") {
+			return nil, fmt.Errorf("error parsing translate image '%s': target contains more wildcards than source", t)
+		}
+
+		translateRules = append(translateRules, imageRule{
+			from:    compileImagePattern(from),
+			to:      to,
+			pattern: t,
+		})
+	}
+
+	rejectRules := []imageRule{}
+	for _, r := range rejectImages {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			return nil, fmt.Errorf("error parsing reject image: empty pattern")
+		}
+
+		rejectRules = append(rejectRules, imageRule{
+			from:    compileImagePattern(r),
+			pattern: r,
+		})
 	}
 
 	return &imageTranslator{
 		translateImages: translateImagesMap,
+		translateRules:  translateRules,
+		rejectRules:     rejectRules,
 	}, nil
 }
 
-func (i *imageTranslator) Translate(image string) string {
+func (i *imageTranslator) Translate(image string) (string, error) {
+	normalized := NormalizeImage(image)
+	for _, rule := range i.rejectRules {
+		if rule.from.MatchString(image) || rule.from.MatchString(normalized) {
+			return "", fmt.Errorf("image %s is not allowed by the image policy (rejected by pattern %s)", image, rule.pattern)
+		}
+	}
+
+	// exact matches take precedence over patterns
 	out, ok := i.translateImages[image]
 	if ok {
-		return out
+		return out, nil
+	}
+
+	for _, rule := range i.translateRules {
+		for _, candidate := range []string{image, normalized} {
+			matches := rule.from.FindStringSubmatch(candidate)
+			if matches != nil {
+				return expandImagePattern(rule.to, matches[1:]), nil
+			}
+		}
+	}
+
+	return image, nil
+}
+
+// NormalizeImage returns the fully qualified form of the given image, e.g. nginx becomes
+// docker.io/library/nginx:latest
+func NormalizeImage(image string) string {
+	name, digest := image, ""
+	if idx := strings.Index(name, "@"); idx >= 0 {
+		name, digest = name[:idx], name[idx:]
+	}
+
+	domain, remainder := splitImageDomain(name)
+	if domain == defaultImageDomain && !strings.Contains(remainder, "/") {
+		remainder = officialRepoPrefix + remainder
+	}
+	if digest == "" && !strings.Contains(remainder[strings.LastIndex(remainder, "/")+1:], ":") {
+		remainder += ":" + defaultImageTag
+	}
+
+	return domain + "/" + remainder + digest
+}
+
+// splitImageDomain splits the registry domain from the image name
+func splitImageDomain(name string) (string, string) {
+	i := strings.IndexRune(name, '/')
+	if i == -1 || (!strings.ContainsAny(name[:i], ".:") && name[:i] != "localhost") {
+		return defaultImageDomain, name
+	}
+
+	domain := name[:i]
+	if domain == "index.docker.io" {
+		domain = defaultImageDomain
+	}
+	return domain, name[i+1:]
+}
+
+// stripImageDigest removes a digest pinned by the syncer from the given image
+func stripImageDigest(image string) string {
+	if idx := strings.Index(image, "@"); idx >= 0 {
+		return image[:idx]
 	}
 
 	return image
 }
+
+func compileImagePattern(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+
+	return regexp.MustCompile("^" + strings.Join(parts, "(.*)") + "$")
+}
+
+func expandImagePattern(to string, matches []string) string {
+	out := strings.Builder{}
+	match := 0
+	for _, c := range to {
+		if c == '*' {
+			out.WriteString(matches[match])
+			match++
+			continue
+		}
+
+		out.WriteRune(c)
+	}
+
+	return out.String()
+}
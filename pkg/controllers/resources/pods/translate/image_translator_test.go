@@ -0,0 +1,190 @@
+package translate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	translator2 "github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestImageTranslator(t *testing.T) {
+	imageTranslator, err := NewImageTranslator([]string{
+		"coredns/coredns:1.8.7=mirror.io/coredns/coredns:1.8.7",
+		"docker.io/*=mirror.corp/dockerhub/*",
+		"quay.io/*:*=mirror.corp/quay/*:*",
+	}, []string{"*:latest"})
+	assert.NilError(t, err)
+
+	testCases := []struct {
+		image    string
+		expected string
+		rejected bool
+	}{
+		{image: "coredns/coredns:1.8.7", expected: "mirror.io/coredns/coredns:1.8.7"},
+		{image: "nginx:1.21", expected: "mirror.corp/dockerhub/library/nginx:1.21"},
+		{image: "docker.io/bitnami/redis:6", expected: "mirror.corp/dockerhub/bitnami/redis:6"},
+		{image: "quay.io/prometheus/prometheus:v2.36.0", expected: "mirror.corp/quay/prometheus/prometheus:v2.36.0"},
+		{image: "gcr.io/distroless/static:nonroot", expected: "gcr.io/distroless/static:nonroot"},
+		{image: "nginx@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31", expected: "mirror.corp/dockerhub/library/nginx@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31"},
+		{image: "nginx", rejected: true},
+		{image: "nginx:latest", rejected: true},
+		{image: "localhost:5000/app", rejected: true},
+	}
+	for _, testCase := range testCases {
+		translated, err := imageTranslator.Translate(testCase.image)
+		if testCase.rejected {
+			assert.Assert(t, err != nil, "expected image %s to be rejected", testCase.image)
+			continue
+		}
+
+		assert.NilError(t, err, "unexpected error for image %s", testCase.image)
+		assert.Equal(t, translated, testCase.expected, "unexpected translation for image %s", testCase.image)
+	}
+
+	for _, invalid := range [][]string{{"nginx"}, {"nginx=*"}, {"docker.io/*=mirror/*/*"}} {
+		_, err := NewImageTranslator(invalid, nil)
+		assert.Assert(t, err != nil, "expected %v to be invalid", invalid)
+	}
+}
+
+func TestNormalizeImage(t *testing.T) {
+	for image, expected := range map[string]string{
+		"nginx":                       "docker.io/library/nginx:latest",
+		"bitnami/redis:6":             "docker.io/bitnami/redis:6",
+		"index.docker.io/nginx":       "docker.io/library/nginx:latest",
+		"localhost/app":               "localhost/app:latest",
+		"registry.io:5000/team/app:1": "registry.io:5000/team/app:1",
+		"nginx@sha256:abc":            "docker.io/library/nginx@sha256:abc",
+	} {
+		assert.Equal(t, NormalizeImage(image), expected)
+	}
+}
+
+func TestDigestResolver(t *testing.T) {
+	const digest = "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31"
+	requests := 0
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			assert.Equal(t, r.URL.Query().Get("scope"), "repository:team/app:pull")
+			_, _ = w.Write([]byte(`{"token":"secret"}`))
+		case "/v2/team/app/manifests/1.0":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.Header().Set("Www-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry",scope="repository:team/app:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			requests++
+			w.Header().Set("Docker-Content-Digest", digest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	resolver := newDigestResolver(server.Client(), time.Hour)
+	image := strings.TrimPrefix(server.URL, "https://") + "/team/app:1.0"
+	for i := 0; i < 2; i++ {
+		pinned, err := resolver.Resolve(context.TODO(), image)
+		assert.NilError(t, err)
+		assert.Equal(t, pinned, image+"@"+digest)
+	}
+	assert.Equal(t, requests, 1, "expected resolved digest to be cached")
+
+	_, err := resolver.Resolve(context.TODO(), strings.TrimPrefix(server.URL, "https://")+"/team/missing:1.0")
+	assert.Assert(t, err != nil)
+}
+
+type fakeDigestResolver struct {
+	calls       int
+	hadDeadline bool
+}
+
+func (f *fakeDigestResolver) Resolve(ctx context.Context, image string) (string, error) {
+	f.calls++
+	_, f.hadDeadline = ctx.Deadline()
+	return image + "@sha256:abc", nil
+}
+
+func TestImageDigestOnlyOnCreate(t *testing.T) {
+	imageTranslator, err := NewImageTranslator(nil, []string{"*:latest"})
+	assert.NilError(t, err)
+	resolver := &fakeDigestResolver{}
+	tr := &translator{
+		imageTranslator: imageTranslator,
+		digestResolver:  resolver,
+		eventRecorder:   record.NewFakeRecorder(10),
+	}
+	vPod := &corev1.Pod{}
+
+	// creation pins the image within a bounded time
+	assert.Equal(t, tr.pinImage(context.Background(), vPod, "nginx:1.21"), "nginx:1.21@sha256:abc")
+	assert.Equal(t, resolver.calls, 1)
+	assert.Assert(t, resolver.hadDeadline, "expected digest resolution to have a timeout")
+
+	// a pinned image is not changed as long as the virtual image stays the same
+	pContainers := []corev1.Container{{Name: "web", Image: "nginx:1.21@sha256:abc"}}
+	updated, err := tr.calcContainerImageDiff(vPod, pContainers, []corev1.Container{{Name: "web", Image: "nginx:1.21"}}, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, updated == nil)
+
+	// updates of running pods don't resolve digests
+	updated, err = tr.calcContainerImageDiff(vPod, pContainers, []corev1.Container{{Name: "web", Image: "nginx:1.22"}}, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, updated[0].Image, "nginx:1.22")
+	assert.Equal(t, resolver.calls, 1)
+
+	// a rejected image keeps the running container as is instead of failing every sync
+	updated, err = tr.calcContainerImageDiff(vPod, pContainers, []corev1.Container{{Name: "web", Image: "nginx:latest"}}, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, updated == nil)
+}
+
+func TestRejectedImageReportedOnce(t *testing.T) {
+	imageTranslator, err := NewImageTranslator(nil, []string{"*:latest"})
+	assert.NilError(t, err)
+	recorder := record.NewFakeRecorder(10)
+	tr := &translator{
+		imageTranslator: imageTranslator,
+		digestResolver:  &fakeDigestResolver{},
+		eventRecorder:   recorder,
+		events:          translator2.NewEventCache(),
+	}
+	vPod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "web", Image: "nginx:latest"}},
+		},
+	}
+	pContainers := []corev1.Container{{Name: "web", Image: "nginx:1.21"}}
+
+	// a rejected image is only reported on the first sync
+	for i := 0; i < 3; i++ {
+		updated, err := tr.calcContainerImageDiff(vPod, pContainers, vPod.Spec.Containers, nil)
+		assert.NilError(t, err)
+		assert.Assert(t, updated == nil)
+	}
+	assert.Equal(t, len(recorder.Events), 1)
+
+	// rejected ephemeral container images are reported the same way
+	vPod.Spec.EphemeralContainers = []corev1.EphemeralContainer{{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debug", Image: "busybox:latest"}}}
+	for i := 0; i < 3; i++ {
+		_, err := tr.ImageChanged(vPod, "busybox:latest", "busybox:1.36")
+		assert.ErrorContains(t, err, "")
+	}
+	assert.Equal(t, len(recorder.Events), 2)
+
+	// changing the images of the pod reports the rejection again
+	vPod.Spec.Containers[0].Image = "nginx:stable"
+	_, err = tr.ImageChanged(vPod, "busybox:latest", "busybox:1.36")
+	assert.ErrorContains(t, err, "")
+	assert.Equal(t, len(recorder.Events), 3)
+}
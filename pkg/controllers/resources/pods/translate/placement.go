@@ -0,0 +1,210 @@
+package translate
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/ghodss/yaml"
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/nodepools"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PlacementRule defines the host placement of virtual pods that match the rule. A rule matches
+// if the pod's virtual namespace and labels match all of the given selectors.
+type PlacementRule struct {
+	// Name identifies the rule in events and logs
+	Name string `json:"name"`
+
+	// Namespaces are virtual namespace names the rule applies to, * wildcards are allowed (e.g. ml-*)
+	Namespaces []string `json:"namespaces,omitempty"`
+	// NamespaceSelector selects virtual namespaces by their labels
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// PodSelector selects virtual pods by their labels
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+
+	// ServiceAccount is the host service account the physical pod should use
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	// NodeSelector is added to the node selector of the physical pod
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations are added to the physical pod
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// RuntimeClassName is the runtime class of the physical pod
+	RuntimeClassName string `json:"runtimeClassName,omitempty"`
+
+	namespaceSelector labels.Selector
+	podSelector       labels.Selector
+}
+
+// Validate checks the rule and parses its selectors
+func (r *PlacementRule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("placement rule is missing a name")
+	}
+	for _, namespace := range r.Namespaces {
+		if _, err := path.Match(namespace, ""); err != nil {
+			return fmt.Errorf("placement rule %s: invalid namespace pattern %s: %v", r.Name, namespace, err)
+		}
+	}
+
+	var err error
+	r.namespaceSelector, err = parseSelector(r.NamespaceSelector)
+	if err != nil {
+		return errors.Wrapf(err, "placement rule %s: parse namespace selector", r.Name)
+	}
+	r.podSelector, err = parseSelector(r.PodSelector)
+	if err != nil {
+		return errors.Wrapf(err, "placement rule %s: parse pod selector", r.Name)
+	}
+
+	return nil
+}
+
+// Matches checks if the rule applies to the given virtual pod
+func (r *PlacementRule) Matches(vNamespace *corev1.Namespace, vPod *corev1.Pod) bool {
+	if len(r.Namespaces) > 0 {
+		found := false
+		for _, namespace := range r.Namespaces {
+			if ok, _ := path.Match(namespace, vPod.Namespace); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return r.namespaceSelector.Matches(labels.Set(vNamespace.Labels)) && r.podSelector.Matches(labels.Set(vPod.Labels))
+}
+
+func parseSelector(selector *metav1.LabelSelector) (labels.Selector, error) {
+	if selector == nil {
+		return labels.Everything(), nil
+	}
+
+	return metav1.LabelSelectorAsSelector(selector)
+}
+
+// Placement is the merged placement of all rules that match a pod
+type Placement struct {
+	ServiceAccount   string
+	NodeSelector     map[string]string
+	Tolerations      []corev1.Toleration
+	RuntimeClassName string
+}
+
+// LoadPlacementRules reads and validates the placement rules from the given yaml file
+func LoadPlacementRules(path string) ([]PlacementRule, error) {
+	out, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []PlacementRule{}
+	err = yaml.Unmarshal(out, &rules)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse placement rules %s", path)
+	}
+
+	for i := range rules {
+		err = rules[i].Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rules, nil
+}
+
+// PlacementFor merges all rules that match the given pod in order. Later rules override the service
+// account, runtime class and node selector values of earlier rules, tolerations are accumulated.
+func PlacementFor(rules []PlacementRule, vNamespace *corev1.Namespace, vPod *corev1.Pod) *Placement {
+	var placement *Placement
+	for i := range rules {
+		if !rules[i].Matches(vNamespace, vPod) {
+			continue
+		}
+		if placement == nil {
+			placement = &Placement{}
+		}
+
+		rule := rules[i]
+		if rule.ServiceAccount != "" {
+			placement.ServiceAccount = rule.ServiceAccount
+		}
+		if rule.RuntimeClassName != "" {
+			placement.RuntimeClassName = rule.RuntimeClassName
+		}
+		for k, v := range rule.NodeSelector {
+			if placement.NodeSelector == nil {
+				placement.NodeSelector = map[string]string{}
+			}
+			placement.NodeSelector[k] = v
+		}
+		placement.Tolerations = append(placement.Tolerations, rule.Tolerations...)
+	}
+
+	return placement
+}
+
+// applyPlacement applies the given placement to the physical pod
+func applyPlacement(placement *Placement, pPod *corev1.Pod) {
+	if placement == nil {
+		return
+	}
+
+	if placement.ServiceAccount != "" {
+		pPod.Spec.ServiceAccountName = placement.ServiceAccount
+	}
+	if placement.RuntimeClassName != "" {
+		runtimeClassName := placement.RuntimeClassName
+		pPod.Spec.RuntimeClassName = &runtimeClassName
+	}
+	for k, v := range placement.NodeSelector {
+		if pPod.Spec.NodeSelector == nil {
+			pPod.Spec.NodeSelector = map[string]string{}
+		}
+		pPod.Spec.NodeSelector[k] = v
+	}
+	pPod.Spec.Tolerations = append(pPod.Spec.Tolerations, missingTolerations(pPod.Spec.Tolerations, placement.Tolerations)...)
+}
+
+// applyNodePool adds the host node selector and tolerations of the node pool to the physical pod
+func applyNodePool(pool *nodepools.NodePool, pPod *corev1.Pod) {
+	for k, v := range pool.HostNodeSelector {
+		if pPod.Spec.NodeSelector == nil {
+			pPod.Spec.NodeSelector = map[string]string{}
+		}
+		pPod.Spec.NodeSelector[k] = v
+	}
+	pPod.Spec.Tolerations = append(pPod.Spec.Tolerations, missingTolerations(pPod.Spec.Tolerations, pool.HostTolerations)...)
+}
+
+// missingTolerations returns the tolerations that are not yet part of existing
+func missingTolerations(existing, tolerations []corev1.Toleration) []corev1.Toleration {
+	missing := []corev1.Toleration{}
+	for _, toleration := range tolerations {
+		found := false
+		for i := range existing {
+			if existing[i].MatchToleration(&toleration) {
+				found = true
+				break
+			}
+		}
+		for i := range missing {
+			if missing[i].MatchToleration(&toleration) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, toleration)
+		}
+	}
+
+	return missing
+}
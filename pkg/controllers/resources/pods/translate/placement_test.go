@@ -0,0 +1,82 @@
+package translate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPlacementRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	err := os.WriteFile(path, []byte(`
+- name: gpu
+  namespaces: ["ml-*"]
+  nodeSelector:
+    pool: gpu
+  tolerations:
+  - key: nvidia.com/gpu
+    operator: Exists
+    effect: NoSchedule
+- name: data
+  podSelector:
+    matchLabels:
+      team: data
+  serviceAccount: data-jobs
+- name: untrusted
+  namespaceSelector:
+    matchExpressions:
+    - key: trust
+      operator: In
+      values: ["untrusted"]
+  runtimeClassName: gvisor
+  nodeSelector:
+    pool: sandbox`), 0600)
+	assert.NilError(t, err)
+
+	rules, err := LoadPlacementRules(path)
+	assert.NilError(t, err)
+
+	namespace := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	pod := func(namespace string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: namespace, Labels: labels}}
+	}
+
+	// no rule matches
+	assert.Assert(t, PlacementFor(rules, namespace("default", nil), pod("default", nil)) == nil)
+
+	// gpu rule
+	placement := PlacementFor(rules, namespace("ml-training", nil), pod("ml-training", nil))
+	assert.DeepEqual(t, placement.NodeSelector, map[string]string{"pool": "gpu"})
+	assert.Equal(t, len(placement.Tolerations), 1)
+
+	// gpu and data rule
+	placement = PlacementFor(rules, namespace("ml-training", nil), pod("ml-training", map[string]string{"team": "data"}))
+	assert.Equal(t, placement.ServiceAccount, "data-jobs")
+	assert.DeepEqual(t, placement.NodeSelector, map[string]string{"pool": "gpu"})
+
+	// later rules override earlier ones
+	placement = PlacementFor(rules, namespace("ml-builds", map[string]string{"trust": "untrusted"}), pod("ml-builds", nil))
+	assert.Equal(t, placement.RuntimeClassName, "gvisor")
+	assert.DeepEqual(t, placement.NodeSelector, map[string]string{"pool": "sandbox"})
+
+	// applying twice doesn't duplicate tolerations
+	pPod := pod("ml-training", nil)
+	placement = PlacementFor(rules, namespace("ml-training", nil), pPod)
+	applyPlacement(placement, pPod)
+	applyPlacement(placement, pPod)
+	assert.Equal(t, len(pPod.Spec.Tolerations), 1)
+	assert.Equal(t, pPod.Spec.NodeSelector["pool"], "gpu")
+
+	// invalid rules
+	err = os.WriteFile(path, []byte(`
+- namespaces: ["ml-*"]`), 0600)
+	assert.NilError(t, err)
+	_, err = LoadPlacementRules(path)
+	assert.Assert(t, err != nil)
+}
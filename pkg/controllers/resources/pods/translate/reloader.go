@@ -0,0 +1,64 @@
+package translate
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// fileReloader loads a config file and loads it again whenever it changes, so mounted config maps
+// can be updated without restarting the syncer. If the changed file is invalid, the previous value is kept.
+type fileReloader struct {
+	path string
+	load func(path string) (interface{}, error)
+
+	m       sync.Mutex
+	modTime time.Time
+	value   interface{}
+}
+
+func newFileReloader(path string, load func(path string) (interface{}, error)) (*fileReloader, error) {
+	reloader := &fileReloader{path: path, load: load}
+	if path == "" {
+		return reloader, nil
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	reloader.value, err = load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reloader.modTime = stat.ModTime()
+	return reloader, nil
+}
+
+// Get returns the current value or nil if no file was configured
+func (f *fileReloader) Get() interface{} {
+	if f == nil || f.path == "" {
+		return nil
+	}
+
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	stat, err := os.Stat(f.path)
+	if err != nil || stat.ModTime().Equal(f.modTime) {
+		return f.value
+	}
+
+	value, err := f.load(f.path)
+	if err != nil {
+		klog.Errorf("error reloading %s, keeping the previous config: %v", f.path, err)
+	} else {
+		f.value = value
+	}
+
+	f.modTime = stat.ModTime()
+	return f.value
+}
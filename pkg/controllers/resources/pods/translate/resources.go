@@ -0,0 +1,193 @@
+package translate
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	translator2 "github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	"github.com/pkg/errors"
+	"gopkg.in/inf.v0"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// ResourcePolicyActionReject rejects pods that violate the maximum or ratio of the resource policy
+	ResourcePolicyActionReject = "Reject"
+	// ResourcePolicyActionClamp lowers limits to the maximum and raises requests to satisfy the ratio
+	ResourcePolicyActionClamp = "Clamp"
+)
+
+// ResourcePolicy defines defaults and constraints for the resources of the containers of a pod. The
+// semantics are similar to a LimitRange, but the policy is applied during translation, so violations
+// are reported on the virtual pod instead of failing at the host admission.
+type ResourcePolicy struct {
+	// DefaultRequests are set on containers without a request for the resource
+	DefaultRequests corev1.ResourceList `json:"defaultRequests,omitempty"`
+	// DefaultLimits are set on containers without a limit for the resource
+	DefaultLimits corev1.ResourceList `json:"defaultLimits,omitempty"`
+	// Max is the maximum limit (or request, if there is no limit) of a single container
+	Max corev1.ResourceList `json:"max,omitempty"`
+	// MaxLimitRequestRatio is the maximum ratio of limit to request of a single container
+	MaxLimitRequestRatio corev1.ResourceList `json:"maxLimitRequestRatio,omitempty"`
+	// Action defines what happens if a container violates Max or MaxLimitRequestRatio, either Reject (default) or Clamp
+	Action string `json:"action,omitempty"`
+}
+
+// LoadResourcePolicy reads and validates the resource policy from the given yaml file
+func LoadResourcePolicy(path string) (*ResourcePolicy, error) {
+	out, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &ResourcePolicy{}
+	err = yaml.Unmarshal(out, policy)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse resource policy %s", path)
+	}
+	if policy.Action == "" {
+		policy.Action = ResourcePolicyActionReject
+	} else if policy.Action != ResourcePolicyActionReject && policy.Action != ResourcePolicyActionClamp {
+		return nil, fmt.Errorf("resource policy: unknown action %s, expected %s or %s", policy.Action, ResourcePolicyActionReject, ResourcePolicyActionClamp)
+	}
+	for name, ratio := range policy.MaxLimitRequestRatio {
+		if ratio.Cmp(resource.MustParse("1")) < 0 {
+			return nil, fmt.Errorf("resource policy: max limit request ratio of %s has to be at least 1", name)
+		}
+	}
+
+	return policy, nil
+}
+
+// Apply applies the policy to the given containers and returns a description of every change that was made.
+// If the policy action is Reject, an error is returned for the first container that violates the policy.
+func (p *ResourcePolicy) Apply(containers []corev1.Container) ([]string, error) {
+	changes := []string{}
+	for i := range containers {
+		containerChanges, err := p.applyContainer(&containers[i])
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, containerChanges...)
+	}
+
+	return changes, nil
+}
+
+func (p *ResourcePolicy) applyContainer(container *corev1.Container) ([]string, error) {
+	changes := []string{}
+	resources := &container.Resources
+
+	// defaults
+	for name, value := range p.DefaultLimits {
+		if _, ok := resources.Limits[name]; ok {
+			continue
+		}
+
+		// a default limit must not be lower than the request
+		if request, ok := resources.Requests[name]; ok && value.Cmp(request) < 0 {
+			value = request
+		}
+		setResource(&resources.Limits, name, value)
+		changes = append(changes, fmt.Sprintf("container %s: set default %s limit %s", container.Name, name, value.String()))
+	}
+	for name, value := range p.DefaultRequests {
+		if _, ok := resources.Requests[name]; ok {
+			continue
+		}
+
+		// a default request must not exceed the limit
+		if limit, ok := resources.Limits[name]; ok && value.Cmp(limit) > 0 {
+			value = limit
+		}
+		setResource(&resources.Requests, name, value)
+		changes = append(changes, fmt.Sprintf("container %s: set default %s request %s", container.Name, name, value.String()))
+	}
+
+	// maximum
+	for name, max := range p.Max {
+		for _, list := range []*corev1.ResourceList{&resources.Limits, &resources.Requests} {
+			value, ok := (*list)[name]
+			if !ok || value.Cmp(max) <= 0 {
+				continue
+			}
+
+			kind := "limit"
+			if list == &resources.Requests {
+				kind = "request"
+			}
+			if p.Action == ResourcePolicyActionReject {
+				return nil, fmt.Errorf("container %s: %s %s %s exceeds the maximum of %s", container.Name, name, kind, value.String(), max.String())
+			}
+
+			setResource(list, name, max)
+			changes = append(changes, fmt.Sprintf("container %s: lowered %s %s from %s to the maximum of %s", container.Name, name, kind, value.String(), max.String()))
+		}
+	}
+
+	// limit to request ratio
+	for name, ratio := range p.MaxLimitRequestRatio {
+		limit, hasLimit := resources.Limits[name]
+		request, hasRequest := resources.Requests[name]
+		if !hasLimit || limit.IsZero() {
+			continue
+		}
+
+		// round up, so the resulting ratio never exceeds the maximum. This is calculated with
+		// decimals, because large limits would overflow an int64 in milli units.
+		scale := inf.Scale(0)
+		if name == corev1.ResourceCPU {
+			scale = 3
+		}
+		minDec := new(inf.Dec).QuoRound(limit.AsDec(), ratio.AsDec(), scale, inf.RoundCeil)
+		minRequest := resource.NewDecimalQuantity(*minDec, limit.Format)
+		if hasRequest && request.Cmp(*minRequest) >= 0 {
+			continue
+		}
+		if p.Action == ResourcePolicyActionReject {
+			return nil, fmt.Errorf("container %s: %s limit %s is more than %s times the request", container.Name, name, limit.String(), ratio.String())
+		}
+
+		setResource(&resources.Requests, name, *minRequest)
+		changes = append(changes, fmt.Sprintf("container %s: raised %s request to %s to satisfy the maximum limit to request ratio of %s", container.Name, name, minRequest.String(), ratio.String()))
+	}
+
+	return changes, nil
+}
+
+func setResource(list *corev1.ResourceList, name corev1.ResourceName, value resource.Quantity) {
+	if *list == nil {
+		*list = corev1.ResourceList{}
+	}
+
+	(*list)[name] = value.DeepCopy()
+}
+
+// applyResourcePolicy applies the resource policy to the containers and init containers of the physical pod
+// and records an event on the virtual pod if the pod was changed or rejected
+func (t *translator) applyResourcePolicy(vPod, pPod *corev1.Pod) error {
+	policy, _ := t.resourcePolicy.Get().(*ResourcePolicy)
+	if policy == nil {
+		return nil
+	}
+
+	changes, err := policy.Apply(pPod.Spec.Containers)
+	if err == nil {
+		var initChanges []string
+		initChanges, err = policy.Apply(pPod.Spec.InitContainers)
+		changes = append(changes, initChanges...)
+	}
+	if err != nil {
+		t.eventRecorder.Eventf(vPod, "Warning", "ResourcesRejected", "Pod violates the resource policy of the virtual cluster: %v", err)
+		return translator2.NewReportedError(errors.Wrap(err, "resource policy"))
+	}
+	if len(changes) > 0 {
+		t.eventRecorder.Eventf(vPod, "Normal", "ResourcesModified", "Pod resources were adjusted by the resource policy of the virtual cluster: %s", strings.Join(changes, "; "))
+	}
+
+	return nil
+}
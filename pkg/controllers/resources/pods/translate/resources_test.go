@@ -0,0 +1,161 @@
+package translate
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestResourcePolicy(t *testing.T) {
+	policy := &ResourcePolicy{
+		DefaultRequests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		},
+		DefaultLimits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("512Mi"),
+		},
+		Max: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("2"),
+			corev1.ResourceMemory: resource.MustParse("4Gi"),
+		},
+		MaxLimitRequestRatio: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("4"),
+		},
+		Action: ResourcePolicyActionReject,
+	}
+
+	testCases := []struct {
+		name      string
+		resources corev1.ResourceRequirements
+		action    string
+
+		expectedRequests corev1.ResourceList
+		expectedLimits   corev1.ResourceList
+		rejected         bool
+	}{
+		{
+			name: "defaults",
+			expectedRequests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+				corev1.ResourceMemory: resource.MustParse("128Mi"),
+			},
+			expectedLimits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+			},
+		},
+		{
+			name: "default request is capped by limit",
+			resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi"), corev1.ResourceCPU: resource.MustParse("100m")},
+			},
+			expectedRequests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+			},
+			expectedLimits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+			},
+		},
+		{
+			name: "default limit is raised to request",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+			},
+			expectedRequests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+			expectedLimits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+		{
+			name: "reject above max",
+			resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")},
+			},
+			rejected: true,
+		},
+		{
+			name: "reject ratio",
+			resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+			},
+			rejected: true,
+		},
+		{
+			name:   "clamp",
+			action: ResourcePolicyActionClamp,
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4"), corev1.ResourceMemory: resource.MustParse("8Gi")},
+			},
+			expectedRequests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+			expectedLimits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("2"),
+				corev1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		policy.Action = ResourcePolicyActionReject
+		if testCase.action != "" {
+			policy.Action = testCase.action
+		}
+
+		containers := []corev1.Container{{Name: "test", Resources: testCase.resources}}
+		changes, err := policy.Apply(containers)
+		if testCase.rejected {
+			assert.Assert(t, err != nil, "expected test case %s to be rejected", testCase.name)
+			continue
+		}
+
+		assert.NilError(t, err, "unexpected error in test case %s", testCase.name)
+		assert.Assert(t, len(changes) > 0, "expected changes in test case %s", testCase.name)
+		assertResourceList(t, testCase.name, containers[0].Resources.Requests, testCase.expectedRequests)
+		assertResourceList(t, testCase.name, containers[0].Resources.Limits, testCase.expectedLimits)
+	}
+}
+
+func assertResourceList(t *testing.T, name string, actual, expected corev1.ResourceList) {
+	assert.Equal(t, len(actual), len(expected), "unexpected resources in test case %s: %v", name, actual)
+	for resourceName, quantity := range expected {
+		actualQuantity := actual[resourceName]
+		assert.Equal(t, actualQuantity.Cmp(quantity), 0, "unexpected %s in test case %s: %s", resourceName, name, actualQuantity.String())
+	}
+}
+
+func TestResourcePolicyRatioRounding(t *testing.T) {
+	policy := &ResourcePolicy{
+		MaxLimitRequestRatio: corev1.ResourceList{
+			corev1.ResourceCPU:              resource.MustParse("3"),
+			corev1.ResourceMemory:           resource.MustParse("3"),
+			corev1.ResourceEphemeralStorage: resource.MustParse("3"),
+		},
+		Action: ResourcePolicyActionClamp,
+	}
+
+	// large limits must not overflow and requests are rounded up to milli cpus and whole bytes
+	containers := []corev1.Container{{Name: "test", Resources: corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:              resource.MustParse("1"),
+			corev1.ResourceMemory:           resource.MustParse("10"),
+			corev1.ResourceEphemeralStorage: resource.MustParse("30P"),
+		},
+	}}}
+	_, err := policy.Apply(containers)
+	assert.NilError(t, err)
+	assertResourceList(t, "ratio rounding", containers[0].Resources.Requests, corev1.ResourceList{
+		corev1.ResourceCPU:              resource.MustParse("334m"),
+		corev1.ResourceMemory:           resource.MustParse("4"),
+		corev1.ResourceEphemeralStorage: resource.MustParse("10P"),
+	})
+}
@@ -0,0 +1,79 @@
+package translate
+
+import (
+	"fmt"
+
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ServiceAccountTokenSecretLabel marks the host secrets that hold the service account tokens of a pod
+	ServiceAccountTokenSecretLabel = "vcluster.loft.sh/service-account-token"
+
+	// defaultTokenExpirationSeconds is the expiration the api server defaults service account token projections to
+	defaultTokenExpirationSeconds = int64(60 * 60)
+)
+
+// ServiceAccountTokenRequest is a service account token projection of a virtual pod, which
+// is stored in the token secret of the pod under the given key
+type ServiceAccountTokenRequest struct {
+	Key               string
+	Audiences         []string
+	ExpirationSeconds int64
+}
+
+// ServiceAccountTokenSecretName returns the name of the host secret that holds the service account
+// tokens of the given virtual pod
+func ServiceAccountTokenSecretName(vPod *corev1.Pod) string {
+	return translate.SafeConcatName(translate.PhysicalName(vPod.Name, vPod.Namespace), "sa-token")
+}
+
+// ServiceAccountName returns the name of the service account of the given virtual pod
+func ServiceAccountName(vPod *corev1.Pod) string {
+	if vPod.Spec.ServiceAccountName != "" {
+		return vPod.Spec.ServiceAccountName
+	} else if vPod.Spec.DeprecatedServiceAccount != "" {
+		return vPod.Spec.DeprecatedServiceAccount
+	}
+
+	return "default"
+}
+
+// ServiceAccountTokenRequests returns all service account token projections of the virtual pod
+func ServiceAccountTokenRequests(vPod *corev1.Pod, clusterDomain string) []ServiceAccountTokenRequest {
+	requests := []ServiceAccountTokenRequest{}
+	for _, volume := range vPod.Spec.Volumes {
+		if volume.Projected == nil {
+			continue
+		}
+
+		for i, source := range volume.Projected.Sources {
+			if source.ServiceAccountToken == nil {
+				continue
+			}
+
+			audiences := []string{"https://kubernetes.default.svc." + clusterDomain, "https://kubernetes.default.svc", "https://kubernetes.default"}
+			if source.ServiceAccountToken.Audience != "" {
+				audiences = []string{source.ServiceAccountToken.Audience}
+			}
+
+			expirationSeconds := defaultTokenExpirationSeconds
+			if source.ServiceAccountToken.ExpirationSeconds != nil {
+				expirationSeconds = *source.ServiceAccountToken.ExpirationSeconds
+			}
+
+			requests = append(requests, ServiceAccountTokenRequest{
+				Key:               serviceAccountTokenKey(volume.Name, i),
+				Audiences:         audiences,
+				ExpirationSeconds: expirationSeconds,
+			})
+		}
+	}
+
+	return requests
+}
+
+func serviceAccountTokenKey(volumeName string, sourceIndex int) string {
+	return fmt.Sprintf("%s.%d", volumeName, sourceIndex)
+}
@@ -0,0 +1,55 @@
+package translate
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestServiceAccountTokens(t *testing.T) {
+	vPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{
+					Name: "kube-api-access-abcde",
+					VolumeSource: corev1.VolumeSource{
+						Projected: &corev1.ProjectedVolumeSource{
+							Sources: []corev1.VolumeProjection{
+								{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{Path: "token", ExpirationSeconds: pointer.Int64(3607)}},
+								{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "kube-root-ca.crt"}}},
+								{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{Path: "vault-token", Audience: "vault"}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	requests := ServiceAccountTokenRequests(vPod, "cluster.local")
+	assert.DeepEqual(t, requests, []ServiceAccountTokenRequest{
+		{Key: "kube-api-access-abcde.0", Audiences: []string{"https://kubernetes.default.svc.cluster.local", "https://kubernetes.default.svc", "https://kubernetes.default"}, ExpirationSeconds: 3607},
+		{Key: "kube-api-access-abcde.2", Audiences: []string{"vault"}, ExpirationSeconds: 3600},
+	})
+
+	// the projections are rewritten to the token secret of the pod
+	pPod := vPod.DeepCopy()
+	err := (&translator{}).translateVolumes(pPod, vPod)
+	assert.NilError(t, err)
+	sources := pPod.Spec.Volumes[0].Projected.Sources
+	for i, request := range []ServiceAccountTokenRequest{requests[0], {}, requests[1]} {
+		if request.Key == "" {
+			continue
+		}
+
+		assert.Assert(t, sources[i].ServiceAccountToken == nil)
+		assert.Equal(t, sources[i].Secret.Name, ServiceAccountTokenSecretName(vPod))
+		assert.Equal(t, sources[i].Secret.Items[0].Key, request.Key)
+		assert.Equal(t, sources[i].Secret.Items[0].Path, vPod.Spec.Volumes[0].Projected.Sources[i].ServiceAccountToken.Path)
+	}
+	assert.Assert(t, len(pPod.Annotations) == 0, "tokens must not be stored on the pod")
+}
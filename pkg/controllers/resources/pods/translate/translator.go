@@ -8,21 +8,21 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/nodepools"
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/noderules"
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/priorityclasses"
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/serviceaccounts/identitypolicy"
 	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
 	translator2 "github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
 	"github.com/loft-sh/vcluster/pkg/util/loghelper"
-	"github.com/loft-sh/vcluster/pkg/util/random"
 	"github.com/loft-sh/vcluster/pkg/util/translate"
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
-	authenticationv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -38,7 +38,13 @@ const (
 	ClusterAutoScalerAnnotation          = "cluster-autoscaler.kubernetes.io/safe-to-evict"
 	ClusterAutoScalerDaemonSetAnnotation = "cluster-autoscaler.kubernetes.io/daemonset-pod"
 	ServiceAccountNameAnnotation         = "vcluster.loft.sh/service-account-name"
-	ServiceAccountTokenAnnotation        = "vcluster.loft.sh/token-"
+
+	// ServiceAccountTokenAnnotation was used to store service account tokens on the host pod, it is
+	// still excluded from the annotation sync for pods that were created by older versions
+	ServiceAccountTokenAnnotation = "vcluster.loft.sh/token-"
+
+	// imageDigestTimeout is how long a digest lookup may delay the creation of a pod
+	imageDigestTimeout = time.Second * 15
 )
 
 var (
@@ -49,21 +55,61 @@ var (
 )
 
 type Translator interface {
-	Translate(vPod *corev1.Pod, services []*corev1.Service, dnsIP string, kubeIP string) (*corev1.Pod, error)
+	Translate(ctx context.Context, vPod *corev1.Pod, services []*corev1.Service, dnsIP string, kubeIP string) (*corev1.Pod, error)
 	Diff(vPod, pPod *corev1.Pod) (*corev1.Pod, error)
+
+	// TranslateImage rewrites the given container image of the virtual pod
+	TranslateImage(vPod *corev1.Pod, image string) (string, error)
+	// ImageChanged checks if the physical image is outdated compared to the virtual image of the virtual pod
+	ImageChanged(vPod *corev1.Pod, vImage, pImage string) (bool, error)
 }
 
 func NewTranslator(ctx *synccontext.RegisterContext, eventRecorder record.EventRecorder) (Translator, error) {
-	imageTranslator, err := NewImageTranslator(ctx.Options.TranslateImages)
+	imageTranslator, err := NewImageTranslator(ctx.Options.TranslateImages, ctx.Options.RejectImages)
 	if err != nil {
 		return nil, err
 	}
 
+	placementRules, err := newFileReloader(ctx.Options.PodPlacementRules, func(path string) (interface{}, error) {
+		return LoadPlacementRules(path)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "load pod placement rules")
+	}
+
+	resourcePolicy, err := newFileReloader(ctx.Options.PodResourcePolicy, func(path string) (interface{}, error) {
+		return LoadResourcePolicy(path)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "load pod resource policy")
+	}
+
+	nodeRules, err := noderules.Load(ctx.Options.NodeSyncRules)
+	if err != nil {
+		return nil, errors.Wrap(err, "load node sync rules")
+	}
+
+	identityPolicy, err := identitypolicy.Load(ctx.Options.ServiceAccountIdentityPolicy)
+	if err != nil {
+		return nil, errors.Wrap(err, "load service account identity policy")
+	}
+
+	var digestResolver DigestResolver
+	if ctx.Options.PinImageDigests {
+		digestResolver = NewDigestResolver(ctx.Options.ImageDigestCacheTTL)
+	}
+
 	return &translator{
-		vClientConfig:   ctx.VirtualManager.GetConfig(),
 		vClient:         ctx.VirtualManager.GetClient(),
 		imageTranslator: imageTranslator,
+		digestResolver:  digestResolver,
+		placementRules:  placementRules,
+		resourcePolicy:  resourcePolicy,
+		nodePools:       ctx.NodePools,
+		nodeRules:       nodeRules,
+		identityPolicy:  identityPolicy,
 		eventRecorder:   eventRecorder,
+		events:          translator2.NewEventCache(),
 		log:             loghelper.New("pods-syncer-translator"),
 
 		defaultImageRegistry: ctx.Options.DefaultImageRegistry,
@@ -75,16 +121,23 @@ func NewTranslator(ctx *synccontext.RegisterContext, eventRecorder record.EventR
 		overrideHostsImage:     ctx.Options.OverrideHostsContainerImage,
 		serviceAccountsEnabled: ctx.Controllers["serviceaccounts"],
 		priorityClassesEnabled: ctx.Controllers["priorityclasses"],
+		pvcsEnabled:            ctx.Controllers["persistentvolumeclaims"],
 		enableScheduler:        ctx.Options.EnableScheduler,
 		syncedLabels:           ctx.Options.SyncLabels,
 	}, nil
 }
 
 type translator struct {
-	vClientConfig   *rest.Config
 	vClient         client.Client
 	imageTranslator ImageTranslator
+	digestResolver  DigestResolver
+	placementRules  *fileReloader
+	resourcePolicy  *fileReloader
+	nodePools       []nodepools.NodePool
+	nodeRules       *noderules.Rules
+	identityPolicy  *identitypolicy.Policy
 	eventRecorder   record.EventRecorder
+	events          *translator2.EventCache
 	log             loghelper.Logger
 
 	defaultImageRegistry string
@@ -96,11 +149,12 @@ type translator struct {
 	overrideHosts          bool
 	overrideHostsImage     string
 	priorityClassesEnabled bool
+	pvcsEnabled            bool
 	enableScheduler        bool
 	syncedLabels           []string
 }
 
-func (t *translator) Translate(vPod *corev1.Pod, services []*corev1.Service, dnsIP string, kubeIP string) (*corev1.Pod, error) {
+func (t *translator) Translate(ctx context.Context, vPod *corev1.Pod, services []*corev1.Service, dnsIP string, kubeIP string) (*corev1.Pod, error) {
 	// get Namespace resource in order to have access to its labels
 	vNamespace := &corev1.Namespace{}
 	err := t.vClient.Get(context.TODO(), client.ObjectKey{Name: vPod.ObjectMeta.GetNamespace()}, vNamespace)
@@ -125,6 +179,11 @@ func (t *translator) Translate(vPod *corev1.Pod, services []*corev1.Service, dns
 		}
 	}
 
+	// use the host service account the identity policy maps the virtual service account to
+	if hostServiceAccount := t.identityPolicy.HostServiceAccount(vPod.Namespace, ServiceAccountName(vPod)); hostServiceAccount != "" {
+		pPod.Spec.ServiceAccountName = hostServiceAccount
+	}
+
 	pPod.Spec.AutomountServiceAccountToken = &False
 	pPod.Spec.EnableServiceLinks = &False
 
@@ -227,7 +286,11 @@ func (t *translator) Translate(vPod *corev1.Pod, services []*corev1.Service, dns
 		envVar, envFrom := TranslateContainerEnv(pPod.Spec.Containers[i].Env, pPod.Spec.Containers[i].EnvFrom, vPod, serviceEnv)
 		pPod.Spec.Containers[i].Env = envVar
 		pPod.Spec.Containers[i].EnvFrom = envFrom
-		pPod.Spec.Containers[i].Image = t.imageTranslator.Translate(pPod.Spec.Containers[i].Image)
+		pPod.Spec.Containers[i].Image, err = t.TranslateImage(vPod, pPod.Spec.Containers[i].Image)
+		if err != nil {
+			return nil, err
+		}
+		pPod.Spec.Containers[i].Image = t.pinImage(ctx, vPod, pPod.Spec.Containers[i].Image)
 	}
 
 	// translate init containers
@@ -235,7 +298,11 @@ func (t *translator) Translate(vPod *corev1.Pod, services []*corev1.Service, dns
 		envVar, envFrom := TranslateContainerEnv(pPod.Spec.InitContainers[i].Env, pPod.Spec.InitContainers[i].EnvFrom, vPod, serviceEnv)
 		pPod.Spec.InitContainers[i].Env = envVar
 		pPod.Spec.InitContainers[i].EnvFrom = envFrom
-		pPod.Spec.InitContainers[i].Image = t.imageTranslator.Translate(pPod.Spec.InitContainers[i].Image)
+		pPod.Spec.InitContainers[i].Image, err = t.TranslateImage(vPod, pPod.Spec.InitContainers[i].Image)
+		if err != nil {
+			return nil, err
+		}
+		pPod.Spec.InitContainers[i].Image = t.pinImage(ctx, vPod, pPod.Spec.InitContainers[i].Image)
 	}
 
 	// translate ephemeral containers
@@ -243,7 +310,17 @@ func (t *translator) Translate(vPod *corev1.Pod, services []*corev1.Service, dns
 		envVar, envFrom := TranslateContainerEnv(pPod.Spec.EphemeralContainers[i].Env, pPod.Spec.EphemeralContainers[i].EnvFrom, vPod, serviceEnv)
 		pPod.Spec.EphemeralContainers[i].Env = envVar
 		pPod.Spec.EphemeralContainers[i].EnvFrom = envFrom
-		pPod.Spec.EphemeralContainers[i].Image = t.imageTranslator.Translate(pPod.Spec.EphemeralContainers[i].Image)
+		pPod.Spec.EphemeralContainers[i].Image, err = t.TranslateImage(vPod, pPod.Spec.EphemeralContainers[i].Image)
+		if err != nil {
+			return nil, err
+		}
+		pPod.Spec.EphemeralContainers[i].Image = t.pinImage(ctx, vPod, pPod.Spec.EphemeralContainers[i].Image)
+	}
+
+	// apply the resource policy
+	err = t.applyResourcePolicy(vPod, pPod)
+	if err != nil {
+		return nil, err
 	}
 
 	// translate image pull secrets
@@ -293,6 +370,18 @@ func (t *translator) Translate(vPod *corev1.Pod, services []*corev1.Service, dns
 		}
 	}
 
+	// translate virtual node labels and taints back to the host ones
+	t.nodeRules.TranslatePodSpec(&pPod.Spec)
+
+	// pods that are scheduled to a virtual node pool can run on any host node of the pool
+	if pool := nodepools.Find(t.nodePools, vPod.Spec.NodeName); pool != nil {
+		pPod.Spec.NodeName = ""
+		applyNodePool(pool, pPod)
+	}
+
+	// apply the host placement rules
+	applyPlacement(PlacementFor(t.getPlacementRules(), vNamespace, vPod), pPod)
+
 	return pPod, nil
 }
 
@@ -324,14 +413,19 @@ func (t *translator) translateVolumes(pPod *corev1.Pod, vPod *corev1.Pod) error
 		if pPod.Spec.Volumes[i].Secret != nil {
 			pPod.Spec.Volumes[i].Secret.SecretName = translate.PhysicalName(pPod.Spec.Volumes[i].Secret.SecretName, vPod.Namespace)
 		}
+		if pPod.Spec.Volumes[i].Ephemeral != nil && t.pvcsEnabled {
+			// the ephemeral volume controller of the virtual cluster creates the claim owned by the virtual pod,
+			// which is synced to the host, so the physical pod uses that claim instead of creating its own
+			pPod.Spec.Volumes[i].PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: EphemeralVolumeClaimName(vPod, pPod.Spec.Volumes[i].Name),
+			}
+			pPod.Spec.Volumes[i].Ephemeral = nil
+		}
 		if pPod.Spec.Volumes[i].PersistentVolumeClaim != nil {
 			pPod.Spec.Volumes[i].PersistentVolumeClaim.ClaimName = translate.PhysicalName(pPod.Spec.Volumes[i].PersistentVolumeClaim.ClaimName, vPod.Namespace)
 		}
 		if pPod.Spec.Volumes[i].Projected != nil {
-			err := t.translateProjectedVolume(pPod.Spec.Volumes[i].Projected, pPod, vPod)
-			if err != nil {
-				return err
-			}
+			t.translateProjectedVolume(pPod.Spec.Volumes[i].Name, pPod.Spec.Volumes[i].Projected, vPod)
 		}
 		if pPod.Spec.Volumes[i].DownwardAPI != nil {
 			for j := range pPod.Spec.Volumes[i].DownwardAPI.Items {
@@ -373,7 +467,12 @@ func (t *translator) translateVolumes(pPod *corev1.Pod, vPod *corev1.Pod) error
 	return nil
 }
 
-func (t *translator) translateProjectedVolume(projectedVolume *corev1.ProjectedVolumeSource, pPod *corev1.Pod, vPod *corev1.Pod) error {
+// EphemeralVolumeClaimName returns the name of the claim the ephemeral volume controller creates for a generic ephemeral volume
+func EphemeralVolumeClaimName(pod *corev1.Pod, volumeName string) string {
+	return pod.Name + "-" + volumeName
+}
+
+func (t *translator) translateProjectedVolume(volumeName string, projectedVolume *corev1.ProjectedVolumeSource, vPod *corev1.Pod) {
 	for i := range projectedVolume.Sources {
 		if projectedVolume.Sources[i].Secret != nil {
 			projectedVolume.Sources[i].Secret.Name = translate.PhysicalName(projectedVolume.Sources[i].Secret.Name, vPod.Namespace)
@@ -387,66 +486,17 @@ func (t *translator) translateProjectedVolume(projectedVolume *corev1.ProjectedV
 			}
 		}
 		if projectedVolume.Sources[i].ServiceAccountToken != nil {
-			serviceAccountName := "default"
-			if vPod.Spec.ServiceAccountName != "" {
-				serviceAccountName = vPod.Spec.ServiceAccountName
-			} else if vPod.Spec.DeprecatedServiceAccount != "" {
-				serviceAccountName = vPod.Spec.DeprecatedServiceAccount
-			}
-
-			// create new client
-			vClient, err := kubernetes.NewForConfig(t.vClientConfig)
-			if err != nil {
-				return errors.Wrap(err, "create client")
-			}
-
-			audiences := []string{"https://kubernetes.default.svc." + t.clusterDomain, "https://kubernetes.default.svc", "https://kubernetes.default"}
-			if projectedVolume.Sources[i].ServiceAccountToken.Audience != "" {
-				audiences = []string{projectedVolume.Sources[i].ServiceAccountToken.Audience}
-			}
-
-			expirationSeconds := int64(10 * 365 * 24 * 60 * 60)
-			token, err := vClient.CoreV1().ServiceAccounts(vPod.Namespace).CreateToken(context.Background(), serviceAccountName, &authenticationv1.TokenRequest{
-				Spec: authenticationv1.TokenRequestSpec{
-					Audiences: audiences,
-					BoundObjectRef: &authenticationv1.BoundObjectReference{
-						APIVersion: corev1.SchemeGroupVersion.String(),
-						Kind:       "Pod",
-						Name:       vPod.Name,
-						UID:        vPod.UID,
-					},
-					ExpirationSeconds: &expirationSeconds,
-				},
-			}, metav1.CreateOptions{})
-			if err != nil {
-				return errors.Wrap(err, "create token")
-			} else if token.Status.Token == "" {
-				return errors.New("received empty token")
-			}
-
-			// set the token as annotation
-			if pPod.Annotations == nil {
-				pPod.Annotations = map[string]string{}
-			}
-			var annotation string
-			for {
-				annotation = ServiceAccountTokenAnnotation + random.RandomString(8)
-				if pPod.Annotations[annotation] == "" {
-					pPod.Annotations[annotation] = token.Status.Token
-					break
-				}
-			}
-
-			// rewrite projected volume
+			// the token is requested from the virtual cluster and kept up to date by the syncer
+			// within the token secret of the pod
 			allRights := int32(0644)
-			projectedVolume.Sources[i].DownwardAPI = &corev1.DownwardAPIProjection{
-				Items: []corev1.DownwardAPIVolumeFile{
+			projectedVolume.Sources[i].Secret = &corev1.SecretProjection{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: ServiceAccountTokenSecretName(vPod),
+				},
+				Items: []corev1.KeyToPath{
 					{
+						Key:  serviceAccountTokenKey(volumeName, i),
 						Path: projectedVolume.Sources[i].ServiceAccountToken.Path,
-						FieldRef: &corev1.ObjectFieldSelector{
-							APIVersion: "v1",
-							FieldPath:  "metadata.annotations['" + annotation + "']",
-						},
 						Mode: &allRights,
 					},
 				},
@@ -454,8 +504,6 @@ func (t *translator) translateProjectedVolume(projectedVolume *corev1.ProjectedV
 			projectedVolume.Sources[i].ServiceAccountToken = nil
 		}
 	}
-
-	return nil
 }
 
 func translateFieldRef(fieldSelector *corev1.ObjectFieldSelector) {
@@ -757,8 +805,10 @@ func (t *translator) Diff(vPod, pPod *corev1.Pod) (*corev1.Pod, error) {
 	}
 
 	var updatedPod *corev1.Pod
-	updatedPodSpec := t.calcSpecDiff(pPod, vPod)
-	if updatedPodSpec != nil {
+	updatedPodSpec, err := t.calcSpecDiff(pPod, vPod, vNamespace)
+	if err != nil {
+		return nil, err
+	} else if updatedPodSpec != nil {
 		updatedPod = pPod.DeepCopy()
 		updatedPod.Spec = *updatedPodSpec
 	}
@@ -814,13 +864,86 @@ func getExcludedAnnotations(pPod *corev1.Pod) []string {
 	return annotations
 }
 
+func (t *translator) getPlacementRules() []PlacementRule {
+	rules, _ := t.placementRules.Get().([]PlacementRule)
+	return rules
+}
+
+// TranslateImage rewrites the given image of the virtual pod according to the image translate rules.
+// Rejected images are reported as an event on the virtual pod.
+func (t *translator) TranslateImage(vPod *corev1.Pod, image string) (string, error) {
+	translated, err := t.imageTranslator.Translate(image)
+	if err != nil {
+		t.reportRejectedImage(vPod, image, err)
+		return "", translator2.NewReportedError(err)
+	}
+
+	return translated, nil
+}
+
+// reportRejectedImage records an event for a rejected image of the virtual pod. The event is only recorded
+// again after the images of the virtual pod have changed, as the pod is synced over and over again.
+func (t *translator) reportRejectedImage(vPod *corev1.Pod, image string, err error) {
+	images := []string{}
+	for _, container := range vPod.Spec.InitContainers {
+		images = append(images, container.Image)
+	}
+	for _, container := range vPod.Spec.Containers {
+		images = append(images, container.Image)
+	}
+	for _, container := range vPod.Spec.EphemeralContainers {
+		images = append(images, container.Image)
+	}
+	if !t.events.ShouldRecord(vPod, "ImageRejected/"+image, images) {
+		return
+	}
+
+	t.eventRecorder.Eventf(vPod, "Warning", "ImageRejected", "%v", err)
+}
+
+// pinImage pins the given image to its digest if enabled. Digests are only resolved when the
+// physical pod is created, so an unreachable registry never blocks the sync of a running pod.
+func (t *translator) pinImage(ctx context.Context, vPod *corev1.Pod, image string) string {
+	if t.digestResolver == nil {
+		return image
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, imageDigestTimeout)
+	defer cancel()
+	pinned, err := t.digestResolver.Resolve(ctx, image)
+	if err != nil {
+		// we don't want to block the pod if the registry is not reachable
+		t.eventRecorder.Eventf(vPod, "Warning", "ImageDigestResolution", "Couldn't pin image %s to its digest, using the tag instead: %v", image, err)
+		return image
+	}
+
+	return pinned
+}
+
+// ImageChanged checks if the physical image differs from the translated virtual image. A digest
+// that was pinned by the syncer is ignored, so pinned images are not updated on every sync. A rejected
+// virtual image is reported as an event on the virtual pod.
+func (t *translator) ImageChanged(vPod *corev1.Pod, vImage, pImage string) (bool, error) {
+	translated, err := t.imageTranslator.Translate(vImage)
+	if err != nil {
+		t.reportRejectedImage(vPod, vImage, err)
+		return false, err
+	}
+	if pImage == translated {
+		return false, nil
+	}
+
+	return t.digestResolver == nil || strings.Contains(translated, "@") || stripImageDigest(pImage) != translated, nil
+}
+
 // Changeable fields within the pod:
 // - spec.containers[*].image
 // - spec.initContainers[*].image
 // - spec.activeDeadlineSeconds
+// - spec.tolerations (only additions from the placement rules)
 //
 // TODO: check for ephemereal containers
-func (t *translator) calcSpecDiff(pObj, vObj *corev1.Pod) *corev1.PodSpec {
+func (t *translator) calcSpecDiff(pObj, vObj *corev1.Pod, vNamespace *corev1.Namespace) (*corev1.PodSpec, error) {
 	var updatedPodSpec *corev1.PodSpec
 
 	// active deadlines different?
@@ -831,8 +954,10 @@ func (t *translator) calcSpecDiff(pObj, vObj *corev1.Pod) *corev1.PodSpec {
 	}
 
 	// is image different?
-	updatedContainer := calcContainerImageDiff(pObj.Spec.Containers, vObj.Spec.Containers, t.imageTranslator, nil)
-	if len(updatedContainer) != 0 {
+	updatedContainer, err := t.calcContainerImageDiff(vObj, pObj.Spec.Containers, vObj.Spec.Containers, nil)
+	if err != nil {
+		return nil, err
+	} else if len(updatedContainer) != 0 {
 		if updatedPodSpec == nil {
 			updatedPodSpec = pObj.Spec.DeepCopy()
 		}
@@ -847,18 +972,33 @@ func (t *translator) calcSpecDiff(pObj, vObj *corev1.Pod) *corev1.PodSpec {
 		}
 	}
 
-	updatedContainer = calcContainerImageDiff(pObj.Spec.InitContainers, vObj.Spec.InitContainers, t.imageTranslator, skipContainers)
-	if len(updatedContainer) != 0 {
+	updatedContainer, err = t.calcContainerImageDiff(vObj, pObj.Spec.InitContainers, vObj.Spec.InitContainers, skipContainers)
+	if err != nil {
+		return nil, err
+	} else if len(updatedContainer) != 0 {
 		if updatedPodSpec == nil {
 			updatedPodSpec = pObj.Spec.DeepCopy()
 		}
 		updatedPodSpec.InitContainers = updatedContainer
 	}
 
-	return updatedPodSpec
+	// tolerations can be added to a running pod, so new tolerations of the placement rules
+	// are applied. All other placement fields are immutable and only apply to new pods.
+	placement := PlacementFor(t.getPlacementRules(), vNamespace, vObj)
+	if placement != nil {
+		missing := missingTolerations(pObj.Spec.Tolerations, placement.Tolerations)
+		if len(missing) > 0 {
+			if updatedPodSpec == nil {
+				updatedPodSpec = pObj.Spec.DeepCopy()
+			}
+			updatedPodSpec.Tolerations = append(updatedPodSpec.Tolerations, missing...)
+		}
+	}
+
+	return updatedPodSpec, nil
 }
 
-func calcContainerImageDiff(pContainers, vContainers []corev1.Container, translateImages ImageTranslator, skipContainers map[string]bool) []corev1.Container {
+func (t *translator) calcContainerImageDiff(vPod *corev1.Pod, pContainers, vContainers []corev1.Container, skipContainers map[string]bool) ([]corev1.Container, error) {
 	newContainers := []corev1.Container{}
 	changed := false
 	for _, p := range pContainers {
@@ -869,9 +1009,16 @@ func calcContainerImageDiff(pContainers, vContainers []corev1.Container, transla
 
 		for _, v := range vContainers {
 			if p.Name == v.Name {
-				if p.Image != translateImages.Translate(v.Image) {
+				imageChanged, err := t.ImageChanged(vPod, v.Image, p.Image)
+				if err != nil {
+					// the running container keeps its current image, because the new one is rejected
+					newContainers = append(newContainers, p)
+				} else if imageChanged {
 					newContainer := *p.DeepCopy()
-					newContainer.Image = translateImages.Translate(v.Image)
+					newContainer.Image, err = t.TranslateImage(vPod, v.Image)
+					if err != nil {
+						return nil, err
+					}
 					newContainers = append(newContainers, newContainer)
 					changed = true
 				} else {
@@ -884,9 +1031,9 @@ func calcContainerImageDiff(pContainers, vContainers []corev1.Container, transla
 	}
 
 	if !changed {
-		return nil
+		return nil, nil
 	}
-	return newContainers
+	return newContainers, nil
 }
 
 func isInt64Different(i1, i2 *int64) (*int64, bool) {
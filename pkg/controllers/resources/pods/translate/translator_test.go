@@ -176,3 +176,45 @@ func appendNamespacesToMatchExpressions(source *metav1.LabelSelector, namespaces
 	})
 	return ls
 }
+
+func TestTranslateStorageVolumes(t *testing.T) {
+	vPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{
+					Name: "scratch",
+					VolumeSource: corev1.VolumeSource{
+						Ephemeral: &corev1.EphemeralVolumeSource{
+							VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{},
+						},
+					},
+				},
+				{
+					Name: "secrets-store",
+					VolumeSource: corev1.VolumeSource{
+						CSI: &corev1.CSIVolumeSource{
+							Driver:               "secrets-store.csi.k8s.io",
+							NodePublishSecretRef: &corev1.LocalObjectReference{Name: "store-creds"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// generic ephemeral volumes use the synced claim of the virtual cluster
+	pPod := vPod.DeepCopy()
+	err := (&translator{pvcsEnabled: true}).translateVolumes(pPod, vPod)
+	assert.NilError(t, err)
+	assert.Assert(t, pPod.Spec.Volumes[0].Ephemeral == nil)
+	assert.Equal(t, pPod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName, translate.PhysicalName("web-scratch", "default"))
+	assert.Equal(t, pPod.Spec.Volumes[1].CSI.NodePublishSecretRef.Name, translate.PhysicalName("store-creds", "default"))
+
+	// without synced claims the host creates the claim
+	pPod = vPod.DeepCopy()
+	err = (&translator{}).translateVolumes(pPod, vPod)
+	assert.NilError(t, err)
+	assert.Assert(t, pPod.Spec.Volumes[0].Ephemeral != nil)
+	assert.Assert(t, pPod.Spec.Volumes[0].PersistentVolumeClaim == nil)
+}
@@ -0,0 +1,196 @@
+package identitypolicy
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// DefaultIdentityAnnotations are the service account annotations the cloud providers use to grant
+// workload identities, e.g. IRSA, GKE Workload Identity and Azure Workload Identity
+var DefaultIdentityAnnotations = []string{
+	"eks.amazonaws.com/role-arn",
+	"iam.gke.io/gcp-service-account",
+	"azure.workload.identity/client-id",
+	"azure.workload.identity/tenant-id",
+}
+
+// Policy defines which virtual service accounts may use which host identities. If a policy is configured,
+// identity annotations of virtual service accounts are only synced to the host if a rule allows them.
+type Policy struct {
+	// IdentityAnnotations are the annotations that grant an identity, defaults to DefaultIdentityAnnotations
+	IdentityAnnotations []string `json:"identityAnnotations,omitempty"`
+	// Rules are evaluated in order, the first rule that matches a service account and defines a
+	// host service account is used for its pods
+	Rules []Rule `json:"rules,omitempty"`
+
+	identityAnnotations map[string]bool
+}
+
+// Rule maps virtual service accounts to a host service account or allows identity annotations for them
+type Rule struct {
+	Name string `json:"name,omitempty"`
+	// Namespaces are the virtual namespaces the rule applies to, supports * wildcards. Empty matches all namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// ServiceAccounts are the virtual service account names the rule applies to, supports * wildcards. Empty matches all.
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
+	// HostServiceAccount is an existing service account in the host namespace the pods of the matching service accounts run with
+	HostServiceAccount string `json:"hostServiceAccount,omitempty"`
+	// Annotations are the identity annotations the matching service accounts may set, with the allowed values that support * wildcards
+	Annotations map[string][]string `json:"annotations,omitempty"`
+
+	namespaces      []*regexp.Regexp
+	serviceAccounts []*regexp.Regexp
+	annotations     map[string][]*regexp.Regexp
+}
+
+// Load reads and validates the identity policy from the given yaml file. If path is empty,
+// nil is returned, which keeps all service account annotations as is.
+func Load(path string) (*Policy, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	out, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{}
+	err = yaml.Unmarshal(out, policy)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse identity policy %s", path)
+	}
+
+	err = policy.compile()
+	if err != nil {
+		return nil, errors.Wrap(err, "identity policy")
+	}
+
+	return policy, nil
+}
+
+func (p *Policy) compile() error {
+	identityAnnotations := p.IdentityAnnotations
+	if len(identityAnnotations) == 0 {
+		identityAnnotations = DefaultIdentityAnnotations
+	}
+	p.identityAnnotations = map[string]bool{}
+	for _, annotation := range identityAnnotations {
+		p.identityAnnotations[annotation] = true
+	}
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.HostServiceAccount == "" && len(rule.Annotations) == 0 {
+			return fmt.Errorf("rule %s: either hostServiceAccount or annotations is required", rule.displayName(i))
+		}
+
+		rule.namespaces = compilePatterns(rule.Namespaces)
+		rule.serviceAccounts = compilePatterns(rule.ServiceAccounts)
+		rule.annotations = map[string][]*regexp.Regexp{}
+		for annotation, values := range rule.Annotations {
+			if !p.identityAnnotations[annotation] {
+				return fmt.Errorf("rule %s: %s is not an identity annotation", rule.displayName(i), annotation)
+			}
+
+			rule.annotations[annotation] = compilePatterns(values)
+		}
+	}
+
+	return nil
+}
+
+func (r *Rule) displayName(index int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	return fmt.Sprintf("#%d", index)
+}
+
+func compilePatterns(patterns []string) []*regexp.Regexp {
+	compiled := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		compiled = append(compiled, regexp.MustCompile("^"+strings.ReplaceAll(regexp.QuoteMeta(pattern), "\\*", ".*")+"$"))
+	}
+
+	return compiled
+}
+
+func matchesAny(patterns []*regexp.Regexp, value string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Rule) matches(namespace, serviceAccount string) bool {
+	if len(r.namespaces) > 0 && !matchesAny(r.namespaces, namespace) {
+		return false
+	} else if len(r.serviceAccounts) > 0 && !matchesAny(r.serviceAccounts, serviceAccount) {
+		return false
+	}
+
+	return true
+}
+
+// HostServiceAccount returns the host service account the pods of the given virtual service account
+// should run with or an empty string if there is no matching rule
+func (p *Policy) HostServiceAccount(namespace, serviceAccount string) string {
+	if p == nil {
+		return ""
+	}
+
+	for i := range p.Rules {
+		if p.Rules[i].HostServiceAccount != "" && p.Rules[i].matches(namespace, serviceAccount) {
+			return p.Rules[i].HostServiceAccount
+		}
+	}
+
+	return ""
+}
+
+// FilterAnnotations removes the identity annotations of the given virtual service account that are not
+// allowed by any rule. Returns the filtered annotations and the keys of the removed annotations.
+func (p *Policy) FilterAnnotations(namespace, serviceAccount string, annotations map[string]string) (map[string]string, []string) {
+	if p == nil || annotations == nil {
+		return annotations, nil
+	}
+
+	filtered := map[string]string{}
+	denied := []string{}
+	for k, v := range annotations {
+		if p.identityAnnotations[k] && !p.allowed(namespace, serviceAccount, k, v) {
+			denied = append(denied, k)
+			continue
+		}
+
+		filtered[k] = v
+	}
+
+	sort.Strings(denied)
+	return filtered, denied
+}
+
+func (p *Policy) allowed(namespace, serviceAccount, annotation, value string) bool {
+	for i := range p.Rules {
+		if !p.Rules[i].matches(namespace, serviceAccount) {
+			continue
+		}
+
+		if matchesAny(p.Rules[i].annotations[annotation], value) {
+			return true
+		}
+	}
+
+	return false
+}
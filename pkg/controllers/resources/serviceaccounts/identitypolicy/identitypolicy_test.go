@@ -0,0 +1,61 @@
+package identitypolicy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	err := os.WriteFile(path, []byte(`
+rules:
+- name: team-a-s3
+  namespaces: ["team-a"]
+  serviceAccounts: ["s3-*"]
+  hostServiceAccount: team-a-s3
+- name: team-a-roles
+  namespaces: ["team-a*"]
+  annotations:
+    eks.amazonaws.com/role-arn: ["arn:aws:iam::123456789012:role/team-a-*"]`), 0600)
+	assert.NilError(t, err)
+
+	policy, err := Load(path)
+	assert.NilError(t, err)
+
+	assert.Equal(t, policy.HostServiceAccount("team-a", "s3-reader"), "team-a-s3")
+	assert.Equal(t, policy.HostServiceAccount("team-a", "default"), "")
+	assert.Equal(t, policy.HostServiceAccount("team-b", "s3-reader"), "")
+
+	annotations, denied := policy.FilterAnnotations("team-a-dev", "app", map[string]string{
+		"eks.amazonaws.com/role-arn":     "arn:aws:iam::123456789012:role/team-a-app",
+		"iam.gke.io/gcp-service-account": "app@project.iam.gserviceaccount.com",
+		"other":                          "value",
+	})
+	assert.DeepEqual(t, annotations, map[string]string{
+		"eks.amazonaws.com/role-arn": "arn:aws:iam::123456789012:role/team-a-app",
+		"other":                      "value",
+	})
+	assert.DeepEqual(t, denied, []string{"iam.gke.io/gcp-service-account"})
+
+	_, denied = policy.FilterAnnotations("team-b", "app", map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::123456789012:role/team-a-app"})
+	assert.DeepEqual(t, denied, []string{"eks.amazonaws.com/role-arn"})
+
+	// without a policy all annotations are kept
+	var noPolicy *Policy
+	_, denied = noPolicy.FilterAnnotations("team-b", "app", map[string]string{"eks.amazonaws.com/role-arn": "arn"})
+	assert.Equal(t, len(denied), 0)
+
+	// invalid policies
+	for _, invalid := range []string{
+		`rules: [{name: empty, namespaces: ["team-a"]}]`,
+		`rules: [{name: unknown, annotations: {"example.com/role": ["*"]}}]`,
+	} {
+		err = os.WriteFile(path, []byte(invalid), 0600)
+		assert.NilError(t, err)
+		_, err = Load(path)
+		assert.Assert(t, err != nil, "expected error for %s", invalid)
+	}
+}
@@ -1,7 +1,11 @@
 package serviceaccounts
 
 import (
+	"strings"
+
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/serviceaccounts/identitypolicy"
 	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	"github.com/pkg/errors"
 
 	"github.com/loft-sh/vcluster/pkg/controllers/syncer"
 	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
@@ -11,25 +15,62 @@ import (
 )
 
 func New(ctx *synccontext.RegisterContext) (syncer.Object, error) {
+	identityPolicy, err := identitypolicy.Load(ctx.Options.ServiceAccountIdentityPolicy)
+	if err != nil {
+		return nil, errors.Wrap(err, "load service account identity policy")
+	}
+
 	return &serviceAccountSyncer{
 		NamespacedTranslator: translator.NewNamespacedTranslator(ctx, "serviceaccount", &corev1.ServiceAccount{}),
+
+		identityPolicy: identityPolicy,
 	}, nil
 }
 
 type serviceAccountSyncer struct {
 	translator.NamespacedTranslator
+
+	identityPolicy *identitypolicy.Policy
 }
 
 func (s *serviceAccountSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
-	return s.SyncDownCreate(ctx, vObj, s.translate(vObj.(*corev1.ServiceAccount)))
+	vServiceAccount, denied := s.filterIdentityAnnotations(vObj.(*corev1.ServiceAccount))
+	s.recordDeniedAnnotations(vServiceAccount, denied)
+	return s.SyncDownCreate(ctx, vObj, s.translate(vServiceAccount))
 }
 
 func (s *serviceAccountSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
 	// did the service account change?
-	newServiceAccount := s.translateUpdate(pObj.(*corev1.ServiceAccount), vObj.(*corev1.ServiceAccount))
+	vServiceAccount, denied := s.filterIdentityAnnotations(vObj.(*corev1.ServiceAccount))
+	newServiceAccount := s.translateUpdate(pObj.(*corev1.ServiceAccount), vServiceAccount)
 	if newServiceAccount != nil {
 		translator.PrintChanges(pObj, newServiceAccount, ctx.Log)
+		s.recordDeniedAnnotations(vServiceAccount, denied)
 	}
 
 	return s.SyncDownUpdate(ctx, vObj, newServiceAccount)
 }
+
+// filterIdentityAnnotations returns a copy of the virtual service account without the identity annotations
+// the identity policy does not allow, as otherwise tenants could assume arbitrary cloud identities of the host.
+// The denied annotations are returned as well.
+func (s *serviceAccountSyncer) filterIdentityAnnotations(vServiceAccount *corev1.ServiceAccount) (*corev1.ServiceAccount, []string) {
+	annotations, denied := s.identityPolicy.FilterAnnotations(vServiceAccount.Namespace, vServiceAccount.Name, vServiceAccount.Annotations)
+	if len(denied) == 0 {
+		return vServiceAccount, nil
+	}
+
+	filtered := vServiceAccount.DeepCopy()
+	filtered.Annotations = annotations
+	return filtered, denied
+}
+
+// recordDeniedAnnotations records an event for the denied annotations, which is only done when the host
+// service account is created or updated to not record the same event on every sync
+func (s *serviceAccountSyncer) recordDeniedAnnotations(vServiceAccount *corev1.ServiceAccount, denied []string) {
+	if len(denied) == 0 {
+		return
+	}
+
+	s.EventRecorder().Eventf(vServiceAccount, "Warning", "IdentityDenied", "Annotations %s are not allowed by the identity policy and are not synced to the host cluster", strings.Join(denied, ", "))
+}
@@ -0,0 +1,200 @@
+package servicesync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	"github.com/loft-sh/vcluster/pkg/util/loghelper"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// ExportToVClustersAnnotation on a virtual service allows other vclusters on the same host to import it.
+	// The value is either "*" for all vclusters or a comma separated list of host-namespace/vcluster-name.
+	ExportToVClustersAnnotation = "vcluster.loft.sh/export-to-vclusters"
+
+	// importsSource is the source of the imported services within the host service syncer
+	importsSource = "a service import"
+)
+
+// ServiceImport imports a virtual service of another vcluster on the same host cluster
+type ServiceImport struct {
+	// HostNamespace is the host namespace the exporting vcluster syncs its services to
+	HostNamespace string
+	// VCluster is the name of the exporting vcluster
+	VCluster string
+	// Service is the service within the exporting vcluster
+	Service types.NamespacedName
+	// To is the service within this vcluster
+	To types.NamespacedName
+}
+
+// ParseImports parses imports in the form host-namespace/vcluster-name/namespace/service=namespace/service
+func ParseImports(imports []string) ([]ServiceImport, error) {
+	ret := []ServiceImport{}
+	for _, i := range imports {
+		splitted := strings.Split(i, "=")
+		if len(splitted) != 2 {
+			return nil, fmt.Errorf("invalid service import %s, please use host-namespace/vcluster-name/namespace/service=namespace/service", i)
+		}
+
+		from := strings.Split(splitted[0], "/")
+		to := strings.Split(splitted[1], "/")
+		if len(from) != 4 || len(to) != 2 || hasEmpty(from) || hasEmpty(to) {
+			return nil, fmt.Errorf("invalid service import %s, please use host-namespace/vcluster-name/namespace/service=namespace/service", i)
+		}
+
+		ret = append(ret, ServiceImport{
+			HostNamespace: from[0],
+			VCluster:      from[1],
+			Service:       types.NamespacedName{Namespace: from[2], Name: from[3]},
+			To:            types.NamespacedName{Namespace: to[0], Name: to[1]},
+		})
+	}
+
+	return ret, nil
+}
+
+func hasEmpty(values []string) bool {
+	for _, v := range values {
+		if v == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ImportReconciler resolves the physical services of imported services through the translator annotations
+// of the exporting vcluster and maps them into this vcluster with the host service syncer. As the host
+// service syncer creates endpoints, the imported services stay reachable when they are recreated.
+type ImportReconciler struct {
+	Imports []ServiceImport
+
+	// HostNamespace and VCluster identify this vcluster for the export annotation
+	HostNamespace string
+	VCluster      string
+
+	// HostClient is a client for all host namespaces
+	HostClient client.Client
+	// HostServiceSyncer syncs host services into the virtual cluster
+	HostServiceSyncer *ServiceSyncer
+
+	Log loghelper.Logger
+
+	// lastStatus holds the last status of each import, so changes are only logged once
+	lastStatus map[string]string
+}
+
+// SetupWithManager registers the reconciler with a manager that watches the services of all host namespaces
+func (r *ImportReconciler) SetupWithManager(hostManager ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(hostManager).
+		Named("serviceimport").
+		For(&corev1.Service{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(object client.Object) bool {
+			for _, i := range r.Imports {
+				if object.GetNamespace() == i.HostNamespace && object.GetLabels()[translate.MarkerLabel] == i.VCluster {
+					return true
+				}
+			}
+
+			return false
+		}))).
+		Complete(r)
+}
+
+// Reconcile resolves all imports, regardless of the service that triggered it
+func (r *ImportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	mappings := map[string]types.NamespacedName{}
+	status := map[string]string{}
+	for _, i := range r.Imports {
+		key := importKey(i)
+		physicalName, err := r.resolve(ctx, i)
+		if err != nil {
+			return ctrl.Result{}, err
+		} else if physicalName == "" {
+			status[key] = "exported service not found"
+			continue
+		}
+
+		mappings[i.HostNamespace+"/"+physicalName] = i.To
+		status[key] = fmt.Sprintf("imported from host service %s/%s", i.HostNamespace, physicalName)
+	}
+
+	conflicts, err := r.HostServiceSyncer.SetDynamicMappings(ctx, importsSource, mappings)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	for _, i := range r.Imports {
+		for from, to := range mappings {
+			if to == i.To && conflicts[from] != "" {
+				status[importKey(i)] = "conflict: " + conflicts[from]
+			}
+		}
+	}
+
+	r.logStatus(status)
+	return ctrl.Result{}, nil
+}
+
+// resolve returns the name of the host service the exporting vcluster synced the imported service to or
+// an empty string if there is no such service or this vcluster is not allowed to import it
+func (r *ImportReconciler) resolve(ctx context.Context, i ServiceImport) (string, error) {
+	serviceList := &corev1.ServiceList{}
+	err := r.HostClient.List(ctx, serviceList, client.InNamespace(i.HostNamespace), client.MatchingLabels{translate.MarkerLabel: i.VCluster})
+	if err != nil {
+		return "", err
+	}
+
+	for _, pService := range serviceList.Items {
+		if pService.Annotations[translator.NamespaceAnnotation] != i.Service.Namespace || pService.Annotations[translator.NameAnnotation] != i.Service.Name {
+			continue
+		} else if !exportedTo(pService.Annotations[ExportToVClustersAnnotation], r.HostNamespace, r.VCluster) {
+			return "", nil
+		}
+
+		return pService.Name, nil
+	}
+
+	return "", nil
+}
+
+// exportedTo checks if the given export annotation value allows the vcluster to import the service
+func exportedTo(exportTo, hostNamespace, vcluster string) bool {
+	for _, allowed := range strings.Split(exportTo, ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || allowed == hostNamespace+"/"+vcluster {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *ImportReconciler) logStatus(status map[string]string) {
+	keys := []string{}
+	for key := range status {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if r.lastStatus[key] != status[key] {
+			r.Log.Infof("Service import %s: %s", key, status[key])
+		}
+	}
+
+	r.lastStatus = status
+}
+
+func importKey(i ServiceImport) string {
+	return fmt.Sprintf("%s/%s/%s/%s=%s/%s", i.HostNamespace, i.VCluster, i.Service.Namespace, i.Service.Name, i.To.Namespace, i.To.Name)
+}
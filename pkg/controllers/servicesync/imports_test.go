@@ -0,0 +1,132 @@
+package servicesync
+
+import (
+	"context"
+	"testing"
+
+	generictesting "github.com/loft-sh/vcluster/pkg/controllers/syncer/testing"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	"github.com/loft-sh/vcluster/pkg/util/loghelper"
+	testingutil "github.com/loft-sh/vcluster/pkg/util/testing"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestImports(t *testing.T) {
+	imports, err := ParseImports([]string{"team-a/vcluster-a/default/api=imports/api-a", "team-a/vcluster-a/default/internal=imports/internal"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, imports[0], ServiceImport{
+		HostNamespace: "team-a",
+		VCluster:      "vcluster-a",
+		Service:       types.NamespacedName{Namespace: "default", Name: "api"},
+		To:            types.NamespacedName{Namespace: "imports", Name: "api-a"},
+	})
+	for _, invalid := range []string{"team-a/vcluster-a/api=imports/api", "team-a/vcluster-a/default/api=api", "team-a//default/api=imports/api"} {
+		_, err = ParseImports([]string{invalid})
+		assert.Assert(t, err != nil, "expected error for %s", invalid)
+	}
+
+	exportedService := func(name, namespace, physicalName, exportTo string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      physicalName,
+				Namespace: "team-a",
+				Labels:    map[string]string{translate.MarkerLabel: "vcluster-a"},
+				Annotations: map[string]string{
+					translator.NameAnnotation:      name,
+					translator.NamespaceAnnotation: namespace,
+					ExportToVClustersAnnotation:    exportTo,
+				},
+			},
+		}
+	}
+
+	hostServiceSyncer := &ServiceSyncer{}
+	reconciler := &ImportReconciler{
+		Imports:       imports,
+		HostNamespace: "team-b",
+		VCluster:      "vcluster-b",
+		HostClient: testingutil.NewFakeClient(testingutil.NewScheme(),
+			exportedService("api", "default", "api-x-default-x-vcluster-a", "team-c/vcluster-c, team-b/vcluster-b"),
+			exportedService("internal", "default", "internal-x-default-x-vcluster-a", "team-c/vcluster-c"),
+		),
+		HostServiceSyncer: hostServiceSyncer,
+		Log:               loghelper.New("service-import-test"),
+	}
+	_, err = reconciler.Reconcile(context.Background(), ctrl.Request{})
+	assert.NilError(t, err)
+
+	to, ok := hostServiceSyncer.mapping("team-a/api-x-default-x-vcluster-a")
+	assert.Assert(t, ok)
+	assert.Equal(t, to, types.NamespacedName{Namespace: "imports", Name: "api-a"})
+	_, ok = hostServiceSyncer.mapping("team-a/internal-x-default-x-vcluster-a")
+	assert.Assert(t, !ok, "service is not exported to this vcluster")
+}
+
+func TestCopyImportedEndpoints(t *testing.T) {
+	headlessService := func(namespace, name string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: corev1.ServiceSpec{
+				ClusterIP: corev1.ClusterIPNone,
+				Ports:     []corev1.ServicePort{{Name: "http", Port: 80}},
+			},
+		}
+	}
+	endpoints := func(namespace, name string) *corev1.Endpoints {
+		return &corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Subsets: []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1", NodeName: pointer.String("node-a")}},
+				Ports:     []corev1.EndpointPort{{Name: "http", Port: 8080}},
+			}},
+		}
+	}
+	scheme := testingutil.NewScheme()
+	registerContext := generictesting.NewFakeRegisterContext(testingutil.NewFakeClient(scheme,
+		headlessService("team-a", "imported"), endpoints("team-a", "imported"),
+		headlessService("default", "static"), endpoints("default", "static"),
+	), testingutil.NewFakeClient(scheme))
+	syncer := &ServiceSyncer{
+		SyncServices: map[string]types.NamespacedName{
+			"default/static": {Namespace: "mapped", Name: "static"},
+		},
+		CreateEndpoints:       true,
+		CopyImportedEndpoints: true,
+		From:                  registerContext.PhysicalManager,
+		To:                    registerContext.VirtualManager,
+		Log:                   loghelper.New("service-import-test"),
+	}
+	_, err := syncer.SetDynamicMappings(context.Background(), importsSource, map[string]types.NamespacedName{
+		"team-a/imported": {Namespace: "imports", Name: "imported"},
+	})
+	assert.NilError(t, err)
+
+	getEndpoints := func(namespace, name string) *corev1.Endpoints {
+		for i := 0; i < 3; i++ {
+			_, err := syncer.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}})
+			assert.NilError(t, err)
+		}
+
+		to, _ := syncer.mapping(namespace + "/" + name)
+		toEndpoints := &corev1.Endpoints{}
+		assert.NilError(t, registerContext.VirtualManager.GetClient().Get(context.Background(), to, toEndpoints))
+		return toEndpoints
+	}
+
+	// the endpoints of imported headless services are copied without the node references
+	imported := getEndpoints("team-a", "imported")
+	assert.Equal(t, len(imported.Subsets), 1)
+	assert.Equal(t, imported.Subsets[0].Addresses[0].IP, "10.0.0.1")
+	assert.Assert(t, imported.Subsets[0].Addresses[0].NodeName == nil)
+
+	// statically mapped headless services keep pointing to the service ip
+	static := getEndpoints("default", "static")
+	assert.Equal(t, len(static.Subsets), 1)
+	assert.Equal(t, static.Subsets[0].Addresses[0].IP, corev1.ClusterIPNone)
+}
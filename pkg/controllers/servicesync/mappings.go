@@ -0,0 +1,365 @@
+package servicesync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/loft-sh/vcluster/pkg/util/loghelper"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// ExportAnnotation on a virtual service requests a mapping to the given host service in the vcluster namespace
+	ExportAnnotation = "vcluster.loft.sh/export-to-host"
+
+	// StatusAnnotation on the service mappings ConfigMap holds the status of all dynamic mappings
+	StatusAnnotation = "vcluster.loft.sh/service-mapping-status"
+
+	// MappingsKey is the key of the mappings within the service mappings ConfigMap
+	MappingsKey = "mappings.yaml"
+
+	StatusActive   = "Active"
+	StatusConflict = "Conflict"
+	StatusDenied   = "Denied"
+	StatusInvalid  = "Invalid"
+
+	// mappingsSource is the source of the dynamic mappings within the service syncers
+	mappingsSource = "the service mappings ConfigMap"
+)
+
+// MappingConfig are the service mappings that are declared at runtime within the service mappings ConfigMap
+type MappingConfig struct {
+	// HostServices map host services (namespace/name) to virtual services (namespace/name)
+	HostServices []Mapping `json:"hostServices,omitempty"`
+	// VirtualServices map virtual services (namespace/name) to host services (name) in the vcluster namespace
+	VirtualServices []Mapping `json:"virtualServices,omitempty"`
+	// AllowedExports define which virtual services may request a host mapping with the export annotation
+	AllowedExports []AllowedExport `json:"allowedExports,omitempty"`
+}
+
+type Mapping struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// AllowedExport allows the matching virtual services to be exported under the matching host service names.
+// All fields support * wildcards and empty fields match everything.
+type AllowedExport struct {
+	Namespaces   []string `json:"namespaces,omitempty"`
+	Services     []string `json:"services,omitempty"`
+	HostServices []string `json:"hostServices,omitempty"`
+}
+
+// MappingStatus is the status of a single dynamic mapping
+type MappingStatus struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// MappingReconciler applies the mappings of the service mappings ConfigMap and the export annotations of
+// virtual services to the service syncers, so mappings can be added and removed without a restart.
+type MappingReconciler struct {
+	// ConfigMap is the service mappings ConfigMap within the vcluster namespace
+	ConfigMap types.NamespacedName
+	// ConfigMapClient is a client for the namespace of the ConfigMap
+	ConfigMapClient client.Client
+
+	VirtualClient   client.Client
+	TargetNamespace string
+
+	// HostServiceSyncer syncs host services into the virtual cluster
+	HostServiceSyncer *ServiceSyncer
+	// VirtualServiceSyncer syncs virtual services into the host cluster
+	VirtualServiceSyncer *ServiceSyncer
+
+	EventRecorder record.EventRecorder
+	Log           loghelper.Logger
+
+	// exportStatus holds the last status of the export annotations, so events are only sent on changes
+	exportStatus map[string]string
+}
+
+// SetupWithManager registers the reconciler with the virtual manager. Changes to the ConfigMap are
+// watched through the given cache of the vcluster namespace.
+func (r *MappingReconciler) SetupWithManager(virtualManager ctrl.Manager, configMapCache cache.Cache) error {
+	hasExportAnnotation := func(obj client.Object) bool {
+		_, ok := obj.GetAnnotations()[ExportAnnotation]
+		return ok
+	}
+
+	configMapRequest := reconcile.Request{NamespacedName: r.ConfigMap}
+	return ctrl.NewControllerManagedBy(virtualManager).
+		Named("servicemapping").
+		For(&corev1.Service{}, builder.WithPredicates(predicate.Funcs{
+			CreateFunc: func(e event.CreateEvent) bool {
+				return hasExportAnnotation(e.Object)
+			},
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				return hasExportAnnotation(e.ObjectOld) || hasExportAnnotation(e.ObjectNew)
+			},
+			DeleteFunc: func(e event.DeleteEvent) bool {
+				return hasExportAnnotation(e.Object)
+			},
+			GenericFunc: func(e event.GenericEvent) bool {
+				return false
+			},
+		})).
+		Watches(source.NewKindWithCache(&corev1.ConfigMap{}, configMapCache), handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+			if object == nil || object.GetNamespace() != r.ConfigMap.Namespace || object.GetName() != r.ConfigMap.Name {
+				return nil
+			}
+
+			return []reconcile.Request{configMapRequest}
+		})).
+		Complete(r)
+}
+
+// Reconcile recalculates all dynamic mappings, regardless of the object that triggered it
+func (r *MappingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	configMap := &corev1.ConfigMap{}
+	err := r.ConfigMapClient.Get(ctx, r.ConfigMap, configMap)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		configMap = nil
+	}
+
+	config := &MappingConfig{}
+	if configMap != nil && configMap.Data[MappingsKey] != "" {
+		err = yaml.Unmarshal([]byte(configMap.Data[MappingsKey]), config)
+		if err != nil {
+			// we keep the current mappings, as removing them would interrupt running workloads
+			r.Log.Infof("Error parsing service mappings %s/%s: %v", r.ConfigMap.Namespace, r.ConfigMap.Name, err)
+			return ctrl.Result{}, r.updateStatus(ctx, configMap, []MappingStatus{{Status: StatusInvalid, Message: err.Error()}})
+		}
+	}
+
+	statuses := []MappingStatus{}
+	hostMappings := map[string]types.NamespacedName{}
+	for _, mapping := range config.HostServices {
+		from, fromErr := parseServiceName(mapping.From, "")
+		to, toErr := parseServiceName(mapping.To, "")
+		if fromErr != nil || toErr != nil {
+			statuses = append(statuses, MappingStatus{From: mapping.From, To: mapping.To, Status: StatusInvalid, Message: "expected namespace/name for from and to"})
+			continue
+		}
+
+		hostMappings[from.String()] = to
+	}
+
+	virtualMappings := map[string]types.NamespacedName{}
+	for _, mapping := range config.VirtualServices {
+		from, fromErr := parseServiceName(mapping.From, "")
+		to, toErr := parseServiceName(mapping.To, r.TargetNamespace)
+		if fromErr != nil || toErr != nil || to.Namespace != r.TargetNamespace {
+			statuses = append(statuses, MappingStatus{From: mapping.From, To: mapping.To, Status: StatusInvalid, Message: "expected namespace/name for from and a name for to"})
+			continue
+		}
+
+		virtualMappings[from.String()] = to
+	}
+
+	// add the mappings requested by virtual services
+	exportStatuses, err := r.exportMappings(ctx, config.AllowedExports, virtualMappings)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	hostConflicts, err := r.setMappings(ctx, r.HostServiceSyncer, hostMappings)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	virtualConflicts, err := r.setMappings(ctx, r.VirtualServiceSyncer, virtualMappings)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	statuses = append(statuses, mappingStatuses(hostMappings, hostConflicts, nil)...)
+	statuses = append(statuses, mappingStatuses(virtualMappings, virtualConflicts, exportStatuses)...)
+	r.reportExportStatus(ctx, virtualMappings, virtualConflicts, exportStatuses)
+	r.Log.Debugf("Applied %d host and %d virtual service mappings", len(hostMappings)-len(hostConflicts), len(virtualMappings)-len(virtualConflicts))
+	return ctrl.Result{}, r.updateStatus(ctx, configMap, statuses)
+}
+
+func (r *MappingReconciler) setMappings(ctx context.Context, serviceSyncer *ServiceSyncer, mappings map[string]types.NamespacedName) (map[string]string, error) {
+	if serviceSyncer == nil {
+		conflicts := map[string]string{}
+		for from := range mappings {
+			conflicts[from] = "service mapping in this direction is not enabled"
+		}
+		return conflicts, nil
+	}
+
+	return serviceSyncer.SetDynamicMappings(ctx, mappingsSource, mappings)
+}
+
+// exportMappings adds the mappings of virtual services with the export annotation that are allowed and
+// returns the status of all requested exports that are not applied
+func (r *MappingReconciler) exportMappings(ctx context.Context, allowedExports []AllowedExport, mappings map[string]types.NamespacedName) (map[string]MappingStatus, error) {
+	serviceList := &corev1.ServiceList{}
+	err := r.VirtualClient.List(ctx, serviceList)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := map[string]MappingStatus{}
+	for _, vService := range serviceList.Items {
+		hostName, ok := vService.Annotations[ExportAnnotation]
+		if !ok {
+			continue
+		}
+
+		from := vService.Namespace + "/" + vService.Name
+		if len(validation.IsDNS1035Label(hostName)) > 0 {
+			statuses[from] = MappingStatus{From: from, To: hostName, Status: StatusInvalid, Message: fmt.Sprintf("%s is not a valid service name", hostName)}
+		} else if _, ok := mappings[from]; ok {
+			statuses[from] = MappingStatus{From: from, To: hostName, Status: StatusConflict, Message: "service is already mapped by the service mappings ConfigMap"}
+		} else if !exportAllowed(allowedExports, vService.Namespace, vService.Name, hostName) {
+			statuses[from] = MappingStatus{From: from, To: hostName, Status: StatusDenied, Message: "export is not allowed by the service mappings ConfigMap"}
+		} else {
+			mappings[from] = types.NamespacedName{Namespace: r.TargetNamespace, Name: hostName}
+			statuses[from] = MappingStatus{From: from, To: hostName, Status: StatusActive}
+		}
+	}
+
+	return statuses, nil
+}
+
+func exportAllowed(allowedExports []AllowedExport, namespace, name, hostName string) bool {
+	for _, allowed := range allowedExports {
+		if matchesPattern(allowed.Namespaces, namespace) && matchesPattern(allowed.Services, name) && matchesPattern(allowed.HostServices, hostName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesPattern(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		if regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), "\\*", ".*") + "$").MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reportExportStatus sends an event to the virtual services with the export annotation if their status changed
+func (r *MappingReconciler) reportExportStatus(ctx context.Context, mappings map[string]types.NamespacedName, conflicts map[string]string, exportStatuses map[string]MappingStatus) {
+	newExportStatus := map[string]string{}
+	for from, status := range exportStatuses {
+		if conflict, ok := conflicts[from]; ok && status.Status == StatusActive {
+			status = MappingStatus{From: from, To: mappings[from].Name, Status: StatusConflict, Message: conflict}
+		}
+
+		newExportStatus[from] = status.Status + ": " + status.Message
+		if r.exportStatus[from] == newExportStatus[from] || r.EventRecorder == nil {
+			continue
+		}
+
+		vService := &corev1.Service{}
+		err := r.VirtualClient.Get(ctx, splitServiceName(from), vService)
+		if err != nil {
+			continue
+		}
+
+		if status.Status == StatusActive {
+			r.EventRecorder.Eventf(vService, "Normal", "ServiceExported", "Service is mapped to host service %s/%s", r.TargetNamespace, status.To)
+		} else {
+			r.EventRecorder.Eventf(vService, "Warning", "ServiceExport"+status.Status, "Service cannot be mapped to host service %s: %s", status.To, status.Message)
+		}
+	}
+
+	r.exportStatus = newExportStatus
+}
+
+func mappingStatuses(mappings map[string]types.NamespacedName, conflicts map[string]string, exportStatuses map[string]MappingStatus) []MappingStatus {
+	statuses := []MappingStatus{}
+	for from, to := range mappings {
+		if _, ok := exportStatuses[from]; ok {
+			continue
+		}
+
+		status := MappingStatus{From: from, To: to.String(), Status: StatusActive}
+		if conflict, ok := conflicts[from]; ok {
+			status.Status = StatusConflict
+			status.Message = conflict
+		}
+		statuses = append(statuses, status)
+	}
+	for from, status := range exportStatuses {
+		if conflict, ok := conflicts[from]; ok && status.Status == StatusActive {
+			status.Status = StatusConflict
+			status.Message = conflict
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].From == statuses[j].From {
+			return statuses[i].To < statuses[j].To
+		}
+		return statuses[i].From < statuses[j].From
+	})
+	return statuses
+}
+
+// updateStatus writes the status of the mappings as annotation to the ConfigMap
+func (r *MappingReconciler) updateStatus(ctx context.Context, configMap *corev1.ConfigMap, statuses []MappingStatus) error {
+	if configMap == nil {
+		return nil
+	}
+
+	out, err := json.Marshal(statuses)
+	if err != nil {
+		return err
+	} else if configMap.Annotations[StatusAnnotation] == string(out) {
+		return nil
+	}
+
+	updated := configMap.DeepCopy()
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	updated.Annotations[StatusAnnotation] = string(out)
+	return r.ConfigMapClient.Update(ctx, updated)
+}
+
+// parseServiceName parses namespace/name, if the namespace is missing the default namespace is used
+func parseServiceName(name, defaultNamespace string) (types.NamespacedName, error) {
+	splitted := strings.Split(name, "/")
+	if len(splitted) == 1 && defaultNamespace != "" && splitted[0] != "" {
+		return types.NamespacedName{Namespace: defaultNamespace, Name: splitted[0]}, nil
+	} else if len(splitted) != 2 || splitted[0] == "" || splitted[1] == "" {
+		return types.NamespacedName{}, fmt.Errorf("invalid service name %s, expected namespace/name", name)
+	}
+
+	return types.NamespacedName{Namespace: splitted[0], Name: splitted[1]}, nil
+}
@@ -0,0 +1,80 @@
+package servicesync
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestSetDynamicMappings(t *testing.T) {
+	syncer := &ServiceSyncer{
+		SyncServices: map[string]types.NamespacedName{
+			"default/static": {Namespace: "vcluster", Name: "static"},
+		},
+	}
+
+	conflicts, err := syncer.SetDynamicMappings(context.Background(), mappingsSource, map[string]types.NamespacedName{
+		"default/static": {Namespace: "vcluster", Name: "other"},
+		"default/a":      {Namespace: "vcluster", Name: "static"},
+		"default/b":      {Namespace: "vcluster", Name: "shared"},
+		"default/c":      {Namespace: "vcluster", Name: "shared"},
+		"default/d":      {Namespace: "vcluster", Name: "d"},
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, conflicts, map[string]string{
+		"default/static": "service is already mapped by the vcluster configuration",
+		"default/a":      "target vcluster/static is already used by default/static",
+		"default/c":      "target vcluster/shared is already used by default/b",
+	})
+
+	to, ok := syncer.mapping("default/static")
+	assert.Assert(t, ok)
+	assert.Equal(t, to.Name, "static")
+	to, ok = syncer.mapping("default/d")
+	assert.Assert(t, ok)
+	assert.Equal(t, to.Name, "d")
+	_, ok = syncer.mapping("default/c")
+	assert.Assert(t, !ok)
+
+	from, ok := syncer.reverseMapping(types.NamespacedName{Namespace: "vcluster", Name: "shared"})
+	assert.Assert(t, ok)
+	assert.Equal(t, from.Name, "b")
+
+	// mappings of other sources conflict as well
+	conflicts, err = syncer.SetDynamicMappings(context.Background(), importsSource, map[string]types.NamespacedName{
+		"default/d": {Namespace: "vcluster", Name: "e"},
+		"default/e": {Namespace: "vcluster", Name: "shared"},
+		"default/f": {Namespace: "vcluster", Name: "f"},
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, conflicts, map[string]string{
+		"default/d": "service is already mapped by the service mappings ConfigMap",
+		"default/e": "target vcluster/shared is already used by default/b",
+	})
+	to, ok = syncer.mapping("default/f")
+	assert.Assert(t, ok)
+	assert.Equal(t, to.Name, "f")
+}
+
+func TestExportAllowed(t *testing.T) {
+	allowedExports := []AllowedExport{
+		{Namespaces: []string{"team-*"}, HostServices: []string{"team-*"}},
+		{Namespaces: []string{"default"}, Services: []string{"api"}},
+	}
+
+	assert.Assert(t, exportAllowed(allowedExports, "team-a", "web", "team-a-web"))
+	assert.Assert(t, !exportAllowed(allowedExports, "team-a", "web", "web"))
+	assert.Assert(t, exportAllowed(allowedExports, "default", "api", "public-api"))
+	assert.Assert(t, !exportAllowed(allowedExports, "default", "web", "public-web"))
+	assert.Assert(t, !exportAllowed(nil, "default", "api", "api"))
+
+	name, err := parseServiceName("web", "vcluster")
+	assert.NilError(t, err)
+	assert.Equal(t, name, types.NamespacedName{Namespace: "vcluster", Name: "web"})
+	_, err = parseServiceName("web", "")
+	assert.Assert(t, err != nil)
+	_, err = parseServiceName("a/b/c", "")
+	assert.Assert(t, err != nil)
+}
@@ -2,7 +2,10 @@ package servicesync
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/services"
 	"github.com/loft-sh/vcluster/pkg/util/loghelper"
@@ -12,8 +15,10 @@ import (
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
@@ -25,43 +30,236 @@ type ServiceSyncer struct {
 	CreateNamespace bool
 	CreateEndpoints bool
 
+	// CopyImportedEndpoints copies the endpoints of imported headless services instead of pointing the target
+	// endpoints to the service ip, which requires a watch on all endpoints of the source cluster
+	CopyImportedEndpoints bool
+
 	From ctrl.Manager
 	To   ctrl.Manager
 
 	Log loghelper.Logger
+
+	// dynamicServices are the mappings by source that are added and removed at runtime in addition to SyncServices
+	dynamicServices map[string]map[string]types.NamespacedName
+	mappingsLock    sync.RWMutex
+
+	queue    chan event.GenericEvent
+	recorder record.EventRecorder
 }
 
 func (e *ServiceSyncer) Register() error {
-	reverseMapping := map[string]types.NamespacedName{}
-	for k, v := range e.SyncServices {
-		splitted := strings.Split(k, "/")
-		reverseMapping[v.Namespace+"/"+v.Name] = types.NamespacedName{
-			Namespace: splitted[0],
-			Name:      splitted[1],
-		}
+	e.queue = make(chan event.GenericEvent, 100)
+	e.recorder = e.From.GetEventRecorderFor("servicesync")
+	controllerBuilder := ctrl.NewControllerManagedBy(e.From).
+		Named("servicesync").
+		For(&corev1.Service{})
+	if e.CreateEndpoints && e.CopyImportedEndpoints {
+		// endpoints of imported headless services are copied, so we need to watch them
+		controllerBuilder = controllerBuilder.Watches(&source.Kind{Type: &corev1.Endpoints{}}, handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+			if object == nil || !e.imported(object.GetNamespace()+"/"+object.GetName()) {
+				return nil
+			}
+
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: object.GetNamespace(), Name: object.GetName()}}}
+		}))
 	}
 
-	return ctrl.NewControllerManagedBy(e.From).
-		Named("servicesync").
-		For(&corev1.Service{}).
+	return controllerBuilder.
 		Watches(source.NewKindWithCache(&corev1.Service{}, e.To.GetCache()), handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
 			if object == nil {
 				return nil
 			}
 
-			from, ok := reverseMapping[object.GetNamespace()+"/"+object.GetName()]
+			from, ok := e.reverseMapping(types.NamespacedName{Namespace: object.GetNamespace(), Name: object.GetName()})
 			if !ok {
 				return nil
 			}
 
 			return []reconcile.Request{{NamespacedName: from}}
 		})).
+		Watches(&source.Channel{Source: e.queue}, &handler.EnqueueRequestForObject{}).
 		Complete(e)
 }
 
+// mapping returns the target of the given service, static mappings take precedence over dynamic ones
+func (e *ServiceSyncer) mapping(from string) (types.NamespacedName, bool) {
+	if to, ok := e.SyncServices[from]; ok {
+		return to, true
+	}
+
+	e.mappingsLock.RLock()
+	defer e.mappingsLock.RUnlock()
+
+	for _, mappings := range e.dynamicServices {
+		if to, ok := mappings[from]; ok {
+			return to, true
+		}
+	}
+
+	return types.NamespacedName{}, false
+}
+
+// imported checks if the given service is mapped by a service import and its endpoints should be copied
+func (e *ServiceSyncer) imported(from string) bool {
+	if !e.CopyImportedEndpoints {
+		return false
+	} else if _, ok := e.SyncServices[from]; ok {
+		return false
+	}
+
+	e.mappingsLock.RLock()
+	defer e.mappingsLock.RUnlock()
+
+	_, ok := e.dynamicServices[importsSource][from]
+	return ok
+}
+
+func (e *ServiceSyncer) reverseMapping(to types.NamespacedName) (types.NamespacedName, bool) {
+	for from, target := range e.SyncServices {
+		if target == to {
+			return splitServiceName(from), true
+		}
+	}
+
+	e.mappingsLock.RLock()
+	defer e.mappingsLock.RUnlock()
+
+	for _, mappings := range e.dynamicServices {
+		for from, target := range mappings {
+			if target == to {
+				return splitServiceName(from), true
+			}
+		}
+	}
+
+	return types.NamespacedName{}, false
+}
+
+func splitServiceName(name string) types.NamespacedName {
+	splitted := strings.Split(name, "/")
+	return types.NamespacedName{
+		Namespace: splitted[0],
+		Name:      splitted[1],
+	}
+}
+
+// SetDynamicMappings replaces the dynamic mappings of the given source, e.g. the service mappings ConfigMap.
+// Mappings that conflict with a static mapping, a mapping of another source or another dynamic mapping to
+// the same target are not applied and are returned with the reason. Target services of removed mappings
+// are deleted, new and changed mappings are synced.
+func (e *ServiceSyncer) SetDynamicMappings(ctx context.Context, source string, mappings map[string]types.NamespacedName) (map[string]string, error) {
+	e.mappingsLock.Lock()
+	conflicts := map[string]string{}
+	targets := map[types.NamespacedName]string{}
+	for from, to := range e.SyncServices {
+		targets[to] = from
+	}
+	mappedBy := map[string]string{}
+	for otherSource, otherMappings := range e.dynamicServices {
+		if otherSource == source {
+			continue
+		}
+
+		for from, to := range otherMappings {
+			targets[to] = from
+			mappedBy[from] = otherSource
+		}
+	}
+
+	// sort the mappings, so conflicts between dynamic mappings are resolved the same way every time
+	froms := []string{}
+	for from := range mappings {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+
+	applied := map[string]types.NamespacedName{}
+	for _, from := range froms {
+		to := mappings[from]
+		if _, ok := e.SyncServices[from]; ok {
+			conflicts[from] = "service is already mapped by the vcluster configuration"
+			continue
+		} else if otherSource, ok := mappedBy[from]; ok {
+			conflicts[from] = fmt.Sprintf("service is already mapped by %s", otherSource)
+			continue
+		} else if other, ok := targets[to]; ok {
+			conflicts[from] = fmt.Sprintf("target %s/%s is already used by %s", to.Namespace, to.Name, other)
+			continue
+		}
+
+		targets[to] = from
+		applied[from] = to
+	}
+
+	if e.dynamicServices == nil {
+		e.dynamicServices = map[string]map[string]types.NamespacedName{}
+	}
+	old := e.dynamicServices[source]
+	e.dynamicServices[source] = applied
+	e.mappingsLock.Unlock()
+
+	// delete the targets of removed mappings
+	for from, to := range old {
+		if newTo, ok := applied[from]; ok && newTo == to {
+			continue
+		} else if _, ok := targets[to]; ok {
+			continue
+		}
+
+		err := e.deleteTarget(ctx, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// sync new and changed mappings
+	for from, to := range applied {
+		if oldTo, ok := old[from]; ok && oldTo == to {
+			continue
+		}
+
+		e.enqueue(splitServiceName(from))
+	}
+
+	return conflicts, nil
+}
+
+func (e *ServiceSyncer) enqueue(from types.NamespacedName) {
+	if e.queue == nil {
+		return
+	}
+
+	go func() {
+		e.queue <- event.GenericEvent{Object: &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: from.Namespace, Name: from.Name}}}
+	}()
+}
+
+// deleteTarget deletes the target service if it was created by vcluster
+func (e *ServiceSyncer) deleteTarget(ctx context.Context, to types.NamespacedName) error {
+	toService := &corev1.Service{}
+	err := e.To.GetClient().Get(ctx, to, toService)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+
+		return err
+	} else if toService.Labels == nil || toService.Labels[translate.ControllerLabel] != "vcluster" {
+		return nil
+	}
+
+	e.Log.Infof("Delete target service %s/%s because its mapping was removed", to.Namespace, to.Name)
+	err = e.To.GetClient().Delete(ctx, toService)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
 func (e *ServiceSyncer) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	from := req.Namespace + "/" + req.Name
-	to, ok := e.SyncServices[from]
+	to, ok := e.mapping(from)
 	if !ok {
 		return ctrl.Result{}, nil
 	}
@@ -101,6 +299,14 @@ func (e *ServiceSyncer) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	return e.syncServiceWithSelector(ctx, fromService, to)
 }
 
+// reportConflict is called if the target service exists, but was not created by vcluster
+func (e *ServiceSyncer) reportConflict(fromService *corev1.Service, to types.NamespacedName) {
+	e.Log.Infof("Skip target service %s/%s, because it was not created by vcluster", to.Namespace, to.Name)
+	if e.recorder != nil {
+		e.recorder.Eventf(fromService, "Warning", "ServiceMappingConflict", "Target service %s/%s already exists and is not managed by vcluster", to.Namespace, to.Name)
+	}
+}
+
 func (e *ServiceSyncer) syncServiceWithSelector(ctx context.Context, fromService *corev1.Service, to types.NamespacedName) (ctrl.Result, error) {
 	// compare to endpoint and service
 	toService := &corev1.Service{}
@@ -128,6 +334,7 @@ func (e *ServiceSyncer) syncServiceWithSelector(ctx context.Context, fromService
 		return ctrl.Result{}, e.To.GetClient().Create(ctx, toService)
 	} else if toService.Labels == nil || toService.Labels[translate.ControllerLabel] != "vcluster" {
 		// skip as it seems the service was user created
+		e.reportConflict(fromService, to)
 		return ctrl.Result{}, nil
 	}
 
@@ -193,6 +400,7 @@ func (e *ServiceSyncer) syncServiceAndEndpoints(ctx context.Context, fromService
 		return ctrl.Result{}, e.To.GetClient().Create(ctx, toService)
 	} else if toService.Labels == nil || toService.Labels[translate.ControllerLabel] != "vcluster" {
 		// skip as it seems the service was user created
+		e.reportConflict(fromService, to)
 		return ctrl.Result{}, nil
 	}
 
@@ -209,6 +417,24 @@ func (e *ServiceSyncer) syncServiceAndEndpoints(ctx context.Context, fromService
 		return ctrl.Result{}, e.To.GetClient().Update(ctx, toService)
 	}
 
+	// imported headless services have no cluster ip, so we copy their endpoints instead
+	expectedSubsets := []corev1.EndpointSubset{
+		{
+			Addresses: []corev1.EndpointAddress{
+				{
+					IP: fromService.Spec.ClusterIP,
+				},
+			},
+			Ports: convertPorts(toService.Spec.Ports),
+		},
+	}
+	if fromService.Spec.ClusterIP == corev1.ClusterIPNone && e.imported(fromService.Namespace+"/"+fromService.Name) {
+		expectedSubsets, err = e.headlessSubsets(ctx, fromService)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	// check target endpoints
 	toEndpoints := &corev1.Endpoints{}
 	err = e.To.GetClient().Get(ctx, to, toEndpoints)
@@ -226,32 +452,13 @@ func (e *ServiceSyncer) syncServiceAndEndpoints(ctx context.Context, fromService
 					translate.ControllerLabel: "vcluster",
 				},
 			},
-			Subsets: []corev1.EndpointSubset{
-				{
-					Addresses: []corev1.EndpointAddress{
-						{
-							IP: fromService.Spec.ClusterIP,
-						},
-					},
-					Ports: convertPorts(toService.Spec.Ports),
-				},
-			},
+			Subsets: expectedSubsets,
 		}
 		e.Log.Infof("Create target endpoints %s/%s because they are missing", to.Namespace, to.Name)
 		return ctrl.Result{}, e.To.GetClient().Create(ctx, toEndpoints)
 	}
 
 	// check if update is needed
-	expectedSubsets := []corev1.EndpointSubset{
-		{
-			Addresses: []corev1.EndpointAddress{
-				{
-					IP: fromService.Spec.ClusterIP,
-				},
-			},
-			Ports: convertPorts(toService.Spec.Ports),
-		},
-	}
 	if !apiequality.Semantic.DeepEqual(toEndpoints.Subsets, expectedSubsets) {
 		e.Log.Infof("Update target endpoints %s/%s because subsets are different", to.Namespace, to.Name)
 		toEndpoints.Subsets = expectedSubsets
@@ -261,6 +468,36 @@ func (e *ServiceSyncer) syncServiceAndEndpoints(ctx context.Context, fromService
 	return ctrl.Result{}, nil
 }
 
+// headlessSubsets returns the subsets of the endpoints of the given headless service without the references
+// to pods and nodes, as those don't exist in the target cluster
+func (e *ServiceSyncer) headlessSubsets(ctx context.Context, fromService *corev1.Service) ([]corev1.EndpointSubset, error) {
+	fromEndpoints := &corev1.Endpoints{}
+	err := e.From.GetClient().Get(ctx, types.NamespacedName{Namespace: fromService.Namespace, Name: fromService.Name}, fromEndpoints)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	subsets := []corev1.EndpointSubset{}
+	for _, subset := range fromEndpoints.Subsets {
+		subset = *subset.DeepCopy()
+		for i := range subset.Addresses {
+			subset.Addresses[i].NodeName = nil
+			subset.Addresses[i].TargetRef = nil
+		}
+		for i := range subset.NotReadyAddresses {
+			subset.NotReadyAddresses[i].NodeName = nil
+			subset.NotReadyAddresses[i].TargetRef = nil
+		}
+		subsets = append(subsets, subset)
+	}
+
+	return subsets, nil
+}
+
 func convertPorts(servicePorts []corev1.ServicePort) []corev1.EndpointPort {
 	endpointPorts := []corev1.EndpointPort{}
 	for _, p := range servicePorts {
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	controllercontext "github.com/loft-sh/vcluster/cmd/vcluster/context"
+	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/nodepools"
 	"github.com/loft-sh/vcluster/pkg/util/loghelper"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -27,6 +28,7 @@ type RegisterContext struct {
 
 	Options     *controllercontext.VirtualClusterOptions
 	Controllers map[string]bool
+	NodePools   []nodepools.NodePool
 
 	TargetNamespace        string
 	CurrentNamespace       string
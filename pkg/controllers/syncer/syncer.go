@@ -5,9 +5,11 @@ import (
 	"github.com/loft-sh/vcluster/pkg/util/translate"
 
 	synccontext "github.com/loft-sh/vcluster/pkg/controllers/syncer/context"
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
 	"github.com/loft-sh/vcluster/pkg/util/loghelper"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -29,6 +31,7 @@ func RegisterSyncer(ctx *synccontext.RegisterContext, syncer Syncer) error {
 		currentNamespaceClient: ctx.CurrentNamespaceClient,
 
 		virtualClient: ctx.VirtualManager.GetClient(),
+		eventRecorder: ctx.VirtualManager.GetEventRecorderFor(syncer.Name() + "-syncer"),
 	}
 
 	return controller.Register(ctx)
@@ -46,6 +49,7 @@ type syncerController struct {
 	currentNamespaceClient client.Client
 
 	virtualClient client.Client
+	eventRecorder record.EventRecorder
 }
 
 func (r *syncerController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -57,12 +61,17 @@ func (r *syncerController) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		PhysicalClient:         r.physicalClient,
 		CurrentNamespace:       r.currentNamespace,
 		CurrentNamespaceClient: r.currentNamespaceClient,
-		VirtualClient:          r.virtualClient,
+		VirtualClient:          &virtualClient{Client: r.virtualClient},
 	}
 
 	// check if we should skip reconcile
-	lifecycle, ok := r.syncer.(Starter)
-	if ok {
+	if lifecycle, ok := r.syncer.(ResultStarter); ok {
+		result, skip, err := lifecycle.ReconcileStartWithResult(syncContext, req)
+		defer lifecycle.ReconcileEnd()
+		if skip || err != nil {
+			return result, err
+		}
+	} else if lifecycle, ok := r.syncer.(Starter); ok {
 		skip, err := lifecycle.ReconcileStart(syncContext, req)
 		defer lifecycle.ReconcileEnd()
 		if skip || err != nil {
@@ -104,9 +113,13 @@ func (r *syncerController) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 
 	// check what function we should call
 	if vObj != nil && pObj == nil {
-		return r.syncer.SyncDown(syncContext, vObj)
+		result, err := r.syncer.SyncDown(syncContext, vObj)
+		r.reportError(vObj, err)
+		return result, err
 	} else if vObj != nil && pObj != nil {
-		return r.syncer.Sync(syncContext, pObj, vObj)
+		result, err := r.syncer.Sync(syncContext, pObj, vObj)
+		r.reportError(vObj, err)
+		return result, err
 	} else if vObj == nil && pObj != nil {
 		// check if up syncer
 		upSyncer, ok := r.syncer.(UpSyncer)
@@ -127,6 +140,22 @@ func (r *syncerController) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	return ctrl.Result{}, nil
 }
 
+// reportError records an error of a syncer as event on the virtual object, so that users of the virtual
+// cluster can see why an object was not synced without access to the syncer logs. Conflicts are
+// ignored as they are retried anyways.
+func (r *syncerController) reportError(vObj client.Object, err error) {
+	if err == nil || kerrors.IsConflict(err) || translator.IsReported(err) || translator.IsTransient(err) {
+		return
+	}
+
+	// errors while writing to the virtual cluster happen when syncing changes back from the physical cluster
+	direction := "to"
+	if translator.IsVirtual(err) {
+		direction = "from"
+	}
+	r.eventRecorder.Eventf(vObj, "Warning", "SyncError", "Error syncing %s physical cluster: %v", direction, err)
+}
+
 // Create is called in response to an create event - e.g. Pod Creation.
 func (r *syncerController) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
 	r.enqueuePhysical(evt.Object, q)
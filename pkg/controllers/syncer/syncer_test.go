@@ -0,0 +1,58 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	testingutil "github.com/loft-sh/vcluster/pkg/util/testing"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestReportError(t *testing.T) {
+	vObj := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+	testCases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{name: "no error"},
+		{name: "conflict", err: kerrors.NewConflict(schema.GroupResource{Resource: "pods"}, "test", errors.New("changed"))},
+		{name: "already reported", err: translator.NewReportedError(errors.New("rejected"))},
+		{name: "physical error", err: errors.New("quota exceeded"), expected: "Warning SyncError Error syncing to physical cluster: quota exceeded"},
+		{name: "virtual error", err: translator.NewVirtualError(errors.New("invalid status")), expected: "Warning SyncError Error syncing from physical cluster: invalid status"},
+	}
+
+	for _, testCase := range testCases {
+		recorder := record.NewFakeRecorder(1)
+		(&syncerController{eventRecorder: recorder}).reportError(vObj, testCase.err)
+		select {
+		case event := <-recorder.Events:
+			assert.Equal(t, event, testCase.expected, "unexpected event in test case %s", testCase.name)
+		default:
+			assert.Equal(t, "", testCase.expected, "expected an event in test case %s", testCase.name)
+		}
+	}
+}
+
+func TestVirtualClientMarksErrors(t *testing.T) {
+	vClient := &virtualClient{Client: testingutil.NewFakeClient(testingutil.NewScheme())}
+	vObj := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+
+	// updating an object that doesn't exist fails on the virtual side
+	err := vClient.Update(context.TODO(), vObj)
+	assert.Assert(t, translator.IsVirtual(err))
+	assert.Assert(t, kerrors.IsNotFound(err), "expected the original error to be preserved")
+	err = vClient.Status().Update(context.TODO(), vObj)
+	assert.Assert(t, translator.IsVirtual(err))
+
+	// successful writes don't return an error
+	assert.NilError(t, vClient.Create(context.TODO(), vObj))
+	assert.Assert(t, !translator.IsVirtual(errors.New("physical")))
+}
@@ -0,0 +1,90 @@
+package translator
+
+import "errors"
+
+// ReportedError wraps an error that was already recorded as an event on the virtual object, so
+// the syncer controller doesn't record it a second time
+type ReportedError struct {
+	Err error
+}
+
+func (e *ReportedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ReportedError) Unwrap() error {
+	return e.Err
+}
+
+// NewReportedError marks the given error as already reported
+func NewReportedError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &ReportedError{Err: err}
+}
+
+// IsReported checks if the given error was already recorded as an event
+func IsReported(err error) bool {
+	reportedError := &ReportedError{}
+	return errors.As(err, &reportedError)
+}
+
+// TransientError wraps an error that resolves by itself, e.g. while a dependency of the object is not
+// available yet, so it is neither reported to the user nor treated as sync failure
+type TransientError struct {
+	Err error
+}
+
+func (e *TransientError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// NewTransientError marks the given error as transient
+func NewTransientError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &TransientError{Err: err}
+}
+
+// IsTransient checks if the given error is transient
+func IsTransient(err error) bool {
+	transientError := &TransientError{}
+	return errors.As(err, &transientError)
+}
+
+// VirtualError wraps an error that occurred while writing to the virtual cluster, so the syncer
+// controller can report it as an error syncing from the physical cluster
+type VirtualError struct {
+	Err error
+}
+
+func (e *VirtualError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *VirtualError) Unwrap() error {
+	return e.Err
+}
+
+// NewVirtualError marks the given error as an error of the virtual cluster
+func NewVirtualError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &VirtualError{Err: err}
+}
+
+// IsVirtual checks if the given error occurred while writing to the virtual cluster
+func IsVirtual(err error) bool {
+	virtualError := &VirtualError{}
+	return errors.As(err, &virtualError)
+}
@@ -0,0 +1,63 @@
+package translator
+
+import (
+	"encoding/json"
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EventCache remembers the state of the objects a warning was recorded for, so warnings that would
+// otherwise be recorded on every reconcile are only recorded again after the object has changed. A nil
+// cache records every event.
+type EventCache struct {
+	lock     sync.Mutex
+	recorded map[string]string
+}
+
+// NewEventCache creates a new empty event cache
+func NewEventCache() *EventCache {
+	return &EventCache{
+		recorded: map[string]string{},
+	}
+}
+
+// ShouldRecord returns true if no event with the given reason was recorded for the object in the given
+// state yet and remembers the state
+func (c *EventCache) ShouldRecord(obj client.Object, reason string, state interface{}) bool {
+	if c == nil {
+		return true
+	}
+
+	out, err := json.Marshal(state)
+	if err != nil {
+		return true
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := eventCacheKey(obj, reason)
+	if c.recorded[key] == string(out) {
+		return false
+	}
+
+	c.recorded[key] = string(out)
+	return true
+}
+
+// Forget removes the remembered state of the object, so the next event with the given reason is recorded
+func (c *EventCache) Forget(obj client.Object, reason string) {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.recorded, eventCacheKey(obj, reason))
+}
+
+func eventCacheKey(obj client.Object, reason string) string {
+	return obj.GetNamespace() + "/" + obj.GetName() + "/" + string(obj.GetUID()) + "/" + reason
+}
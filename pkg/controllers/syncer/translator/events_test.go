@@ -0,0 +1,24 @@
+package translator
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEventCache(t *testing.T) {
+	cache := NewEventCache()
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "123"}}
+
+	// an event is only recorded again after the state changed
+	assert.Assert(t, cache.ShouldRecord(pod, "Rejected", "a"))
+	assert.Assert(t, !cache.ShouldRecord(pod, "Rejected", "a"))
+	assert.Assert(t, cache.ShouldRecord(pod, "Other", "a"))
+	assert.Assert(t, cache.ShouldRecord(pod, "Rejected", "b"))
+
+	// forgotten objects are recorded again
+	cache.Forget(pod, "Rejected")
+	assert.Assert(t, cache.ShouldRecord(pod, "Rejected", "b"))
+}
@@ -85,7 +85,7 @@ func (n *namespacedTranslator) SyncDownCreate(ctx *context.SyncContext, vObj, pO
 	if err != nil {
 		ctx.Log.Infof("error syncing %s %s/%s to physical cluster: %v", n.name, vObj.GetNamespace(), vObj.GetName(), err)
 		n.eventRecorder.Eventf(vObj, "Warning", "SyncError", "Error syncing to physical cluster: %v", err)
-		return ctrl.Result{}, err
+		return ctrl.Result{}, NewReportedError(err)
 	}
 
 	return ctrl.Result{}, nil
@@ -98,7 +98,7 @@ func (n *namespacedTranslator) SyncDownUpdate(ctx *context.SyncContext, vObj, pO
 		err := ctx.PhysicalClient.Update(ctx.Context, pObj)
 		if err != nil {
 			n.eventRecorder.Eventf(vObj, "Warning", "SyncError", "Error syncing to physical cluster: %v", err)
-			return ctrl.Result{}, err
+			return ctrl.Result{}, NewReportedError(err)
 		}
 	}
 
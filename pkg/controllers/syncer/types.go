@@ -38,6 +38,13 @@ type Starter interface {
 	ReconcileEnd()
 }
 
+// ResultStarter is like Starter, but also returns the result of a request that was handled completely
+// in ReconcileStartWithResult, e.g. to requeue it after a delay
+type ResultStarter interface {
+	ReconcileStartWithResult(ctx *synccontext.SyncContext, req ctrl.Request) (ctrl.Result, bool, error)
+	ReconcileEnd()
+}
+
 // IndicesRegisterer registers additional indices for the controller
 type IndicesRegisterer interface {
 	RegisterIndices(ctx *synccontext.RegisterContext) error
@@ -0,0 +1,50 @@
+package syncer
+
+import (
+	"context"
+
+	"github.com/loft-sh/vcluster/pkg/controllers/syncer/translator"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// virtualClient marks the errors of all writes to the virtual cluster, so that the syncer controller
+// knows in which direction a sync failed
+type virtualClient struct {
+	client.Client
+}
+
+func (c *virtualClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return translator.NewVirtualError(c.Client.Create(ctx, obj, opts...))
+}
+
+func (c *virtualClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	return translator.NewVirtualError(c.Client.Delete(ctx, obj, opts...))
+}
+
+func (c *virtualClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return translator.NewVirtualError(c.Client.Update(ctx, obj, opts...))
+}
+
+func (c *virtualClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return translator.NewVirtualError(c.Client.Patch(ctx, obj, patch, opts...))
+}
+
+func (c *virtualClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	return translator.NewVirtualError(c.Client.DeleteAllOf(ctx, obj, opts...))
+}
+
+func (c *virtualClient) Status() client.StatusWriter {
+	return &virtualStatusWriter{StatusWriter: c.Client.Status()}
+}
+
+type virtualStatusWriter struct {
+	client.StatusWriter
+}
+
+func (w *virtualStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return translator.NewVirtualError(w.StatusWriter.Update(ctx, obj, opts...))
+}
+
+func (w *virtualStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return translator.NewVirtualError(w.StatusWriter.Patch(ctx, obj, patch, opts...))
+}
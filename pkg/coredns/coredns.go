@@ -3,12 +3,15 @@ package coredns
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"text/template"
 
 	"github.com/loft-sh/vcluster/pkg/constants"
 	"github.com/loft-sh/vcluster/pkg/util/applier"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/rest"
 )
@@ -46,6 +49,36 @@ func ApplyManifest(defaultImageRegistry string, inClusterConfig *rest.Config, se
 	return applier.ApplyManifest(inClusterConfig, output)
 }
 
+// Corefile returns the Corefile of the CoreDNS manifest, which is rendered by the chart and
+// contains a custom coredns.config as well
+func Corefile() (string, error) {
+	output, err := processManifestTemplate(getManifestVariables("", &version.Info{}))
+	if err != nil {
+		return "", err
+	}
+
+	return findCorefile(output)
+}
+
+func findCorefile(manifest []byte) (string, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifest), 4096)
+	for {
+		configMap := &corev1.ConfigMap{}
+		err := decoder.Decode(configMap)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("parse coredns manifest: %v", err)
+		}
+
+		if configMap.Kind == "ConfigMap" && configMap.Namespace == "kube-system" && configMap.Name == "coredns" && configMap.Data["Corefile"] != "" {
+			return configMap.Data["Corefile"], nil
+		}
+	}
+
+	return "", fmt.Errorf("coredns manifest doesn't contain a Corefile")
+}
+
 func prepareManifestOutput() (*os.File, error) {
 	manifestOutputPath := path.Join(ManifestsOutputFolder, ManifestRelativePath)
 	err := os.MkdirAll(path.Dir(manifestOutputPath), 0755)
@@ -0,0 +1,40 @@
+package coredns
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestFindCorefile(t *testing.T) {
+	corefile, err := findCorefile([]byte(`apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: coredns
+  namespace: kube-system
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: coredns
+  namespace: kube-system
+data:
+  Corefile: |
+    .:1053 {
+        errors
+        forward . /etc/resolv.conf
+    }
+  NodeHosts: ""
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: coredns
+  namespace: kube-system
+`))
+	assert.NilError(t, err)
+	assert.Equal(t, corefile, ".:1053 {\n    errors\n    forward . /etc/resolv.conf\n}\n")
+
+	_, err = findCorefile([]byte("apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: coredns\n"))
+	assert.Assert(t, err != nil)
+}
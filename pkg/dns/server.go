@@ -0,0 +1,602 @@
+package dns
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"golang.org/x/net/dns/dnsmessage"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ResolvConf is the resolv.conf of the syncer, which points to the host cluster DNS
+	ResolvConf = "/etc/resolv.conf"
+
+	// ttl of the answers for the virtual cluster domain, which is the same as the CoreDNS kubernetes plugin uses
+	ttl = 5
+
+	// maxUDPSize is the maximum size of an udp response if the client does not announce a bigger size
+	maxUDPSize = 512
+
+	forwardTimeout = 5 * time.Second
+
+	// udpWorkers is the number of udp requests that are handled concurrently
+	udpWorkers = 64
+	// udpQueueSize is the number of udp requests that can wait for a worker, further requests are
+	// dropped and retried by the clients
+	udpQueueSize = 1024
+)
+
+// Server serves DNS for the virtual cluster domain. Virtual service names resolve to the cluster ips of
+// the synced physical services and all other queries are forwarded to the upstream server, which makes
+// CoreDNS within the virtual cluster optional.
+type Server struct {
+	// VirtualClient is used to look up virtual services and endpoints, which should be a cached reader
+	VirtualClient client.Reader
+	// PhysicalClient is used to look up the synced physical services, which should be a cached reader
+	PhysicalClient client.Reader
+	// TargetNamespace is the host namespace the services are synced to
+	TargetNamespace string
+	// ClusterDomain is the virtual cluster domain, e.g. cluster.local
+	ClusterDomain string
+	// Upstream is the address queries outside of the cluster domain are forwarded to
+	Upstream string
+}
+
+// ListenAndServe serves DNS over udp and tcp on the given address until the context is done
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	packetConn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		_ = packetConn.Close()
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		_ = packetConn.Close()
+		_ = listener.Close()
+	}()
+
+	errChan := make(chan error, 2)
+	go func() {
+		errChan <- s.serveUDP(ctx, packetConn)
+	}()
+	go func() {
+		errChan <- s.serveTCP(ctx, listener)
+	}()
+
+	err = <-errChan
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+type udpRequest struct {
+	request []byte
+	addr    net.Addr
+}
+
+func (s *Server) serveUDP(ctx context.Context, conn net.PacketConn) error {
+	requests := make(chan udpRequest, udpQueueSize)
+	defer close(requests)
+	for i := 0; i < udpWorkers; i++ {
+		go func() {
+			for request := range requests {
+				response := s.Handle(ctx, request.request, "udp")
+				if response != nil {
+					_, err := conn.WriteTo(response, request.addr)
+					if err != nil {
+						klog.V(1).Infof("error writing dns response to %s: %v", request.addr.String(), err)
+					}
+				}
+			}
+		}()
+	}
+
+	buffer := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return err
+		}
+
+		request := make([]byte, n)
+		copy(request, buffer[:n])
+		select {
+		case requests <- udpRequest{request: request, addr: addr}:
+		default:
+			klog.V(1).Infof("drop dns request from %s, because all workers are busy", addr.String())
+		}
+	}
+}
+
+func (s *Server) serveTCP(ctx context.Context, listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+
+			for {
+				_ = conn.SetDeadline(time.Now().Add(forwardTimeout * 2))
+				request, err := readTCPMessage(conn)
+				if err != nil {
+					return
+				}
+
+				response := s.Handle(ctx, request, "tcp")
+				if response == nil {
+					return
+				}
+				err = writeTCPMessage(conn, response)
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func readTCPMessage(reader io.Reader) ([]byte, error) {
+	length := make([]byte, 2)
+	_, err := io.ReadFull(reader, length)
+	if err != nil {
+		return nil, err
+	}
+
+	message := make([]byte, binary.BigEndian.Uint16(length))
+	_, err = io.ReadFull(reader, message)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func writeTCPMessage(writer io.Writer, message []byte) error {
+	out := make([]byte, 2, len(message)+2)
+	binary.BigEndian.PutUint16(out, uint16(len(message)))
+	_, err := writer.Write(append(out, message...))
+	return err
+}
+
+// Handle answers a single raw dns request that was received over the given network. If the request
+// cannot be parsed at all, nil is returned and the request should be dropped.
+func (s *Server) Handle(ctx context.Context, request []byte, network string) []byte {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(request)
+	if err != nil {
+		return nil
+	}
+
+	question, err := parser.Question()
+	if err != nil {
+		return buildResponse(header, nil, nil, dnsmessage.RCodeFormatError, 0)
+	}
+
+	name := strings.ToLower(question.Name.String())
+	if !s.inClusterDomain(name) {
+		response, err := s.forward(ctx, request, network)
+		if err != nil {
+			klog.V(1).Infof("error forwarding dns query for %s: %v", name, err)
+			return buildResponse(header, &question, nil, dnsmessage.RCodeServerFailure, 0)
+		}
+
+		return response
+	}
+
+	answers, rCode, err := s.resolve(ctx, name, question.Type)
+	if err != nil {
+		klog.V(1).Infof("error resolving %s: %v", name, err)
+		return buildResponse(header, &question, nil, dnsmessage.RCodeServerFailure, 0)
+	}
+
+	maxSize := 0
+	if network == "udp" {
+		maxSize = udpSize(&parser)
+	}
+	return buildResponse(header, &question, answers, rCode, maxSize)
+}
+
+func (s *Server) inClusterDomain(name string) bool {
+	return name == s.ClusterDomain+"." || strings.HasSuffix(name, "."+s.ClusterDomain+".")
+}
+
+// resolve answers a query within the cluster domain, the supported records are:
+// - <service>.<namespace>.svc.<domain>: A / AAAA of the physical service or the endpoints of headless services, CNAME for external name services
+// - <hostname>.<service>.<namespace>.svc.<domain>: A / AAAA of an endpoint of a headless service
+// - _<port>._<protocol>.<service>.<namespace>.svc.<domain>: SRV of a named service port
+// - <a-b-c-d>.<namespace>.pod.<domain>: A of the pod ip
+func (s *Server) resolve(ctx context.Context, name string, qType dnsmessage.Type) ([]dnsmessage.Resource, dnsmessage.RCode, error) {
+	relative := strings.TrimSuffix(strings.TrimSuffix(name, s.ClusterDomain+"."), ".")
+	labels := []string{}
+	if relative != "" {
+		labels = strings.Split(relative, ".")
+	}
+
+	// the cluster domain itself and the namespace zones exist, but have no records
+	if len(labels) == 0 || (len(labels) <= 2 && (labels[len(labels)-1] == "svc" || labels[len(labels)-1] == "pod")) {
+		return nil, dnsmessage.RCodeSuccess, nil
+	}
+
+	switch {
+	case len(labels) == 3 && labels[2] == "pod":
+		ip := net.ParseIP(strings.ReplaceAll(labels[0], "-", "."))
+		if ip == nil {
+			ip = net.ParseIP(strings.ReplaceAll(labels[0], "-", ":"))
+		}
+		if ip == nil {
+			return nil, dnsmessage.RCodeNameError, nil
+		}
+		return addressAnswers(name, qType, []string{ip.String()}), dnsmessage.RCodeSuccess, nil
+	case len(labels) == 3 && labels[2] == "svc":
+		return s.resolveService(ctx, name, qType, labels[1], labels[0])
+	case len(labels) == 4 && labels[3] == "svc":
+		return s.resolveEndpointHostname(ctx, name, qType, labels[2], labels[1], labels[0])
+	case len(labels) == 5 && labels[4] == "svc" && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_"):
+		return s.resolveServicePort(ctx, name, qType, labels[3], labels[2], labels[0][1:], labels[1][1:])
+	}
+
+	return nil, dnsmessage.RCodeNameError, nil
+}
+
+func (s *Server) resolveService(ctx context.Context, name string, qType dnsmessage.Type, namespace, serviceName string) ([]dnsmessage.Resource, dnsmessage.RCode, error) {
+	vService, err := s.virtualService(ctx, namespace, serviceName)
+	if err != nil {
+		return nil, 0, err
+	} else if vService == nil {
+		return nil, dnsmessage.RCodeNameError, nil
+	}
+
+	if vService.Spec.Type == corev1.ServiceTypeExternalName {
+		if qType != dnsmessage.TypeA && qType != dnsmessage.TypeAAAA && qType != dnsmessage.TypeCNAME && qType != dnsmessage.TypeALL {
+			return nil, dnsmessage.RCodeSuccess, nil
+		}
+
+		target, err := dnsmessage.NewName(strings.TrimSuffix(vService.Spec.ExternalName, ".") + ".")
+		if err != nil {
+			return nil, dnsmessage.RCodeSuccess, nil
+		}
+		return []dnsmessage.Resource{{
+			Header: resourceHeader(name, dnsmessage.TypeCNAME),
+			Body:   &dnsmessage.CNAMEResource{CNAME: target},
+		}}, dnsmessage.RCodeSuccess, nil
+	}
+
+	ips, err := s.serviceIPs(ctx, vService)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return addressAnswers(name, qType, ips), dnsmessage.RCodeSuccess, nil
+}
+
+func (s *Server) resolveEndpointHostname(ctx context.Context, name string, qType dnsmessage.Type, namespace, serviceName, hostname string) ([]dnsmessage.Resource, dnsmessage.RCode, error) {
+	vEndpoints := &corev1.Endpoints{}
+	err := s.VirtualClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: serviceName}, vEndpoints)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, dnsmessage.RCodeNameError, nil
+		}
+		return nil, 0, err
+	}
+
+	ips := []string{}
+	for _, subset := range vEndpoints.Subsets {
+		for _, address := range subset.Addresses {
+			if address.Hostname == hostname || strings.ReplaceAll(address.IP, ".", "-") == hostname {
+				ips = append(ips, address.IP)
+			}
+		}
+	}
+	if len(ips) == 0 {
+		return nil, dnsmessage.RCodeNameError, nil
+	}
+
+	return addressAnswers(name, qType, ips), dnsmessage.RCodeSuccess, nil
+}
+
+func (s *Server) resolveServicePort(ctx context.Context, name string, qType dnsmessage.Type, namespace, serviceName, portName, protocol string) ([]dnsmessage.Resource, dnsmessage.RCode, error) {
+	vService, err := s.virtualService(ctx, namespace, serviceName)
+	if err != nil {
+		return nil, 0, err
+	} else if vService == nil {
+		return nil, dnsmessage.RCodeNameError, nil
+	}
+
+	for _, port := range vService.Spec.Ports {
+		if port.Name != portName || !strings.EqualFold(string(port.Protocol), protocol) {
+			continue
+		} else if qType != dnsmessage.TypeSRV && qType != dnsmessage.TypeALL {
+			return nil, dnsmessage.RCodeSuccess, nil
+		}
+
+		target, err := dnsmessage.NewName(fmt.Sprintf("%s.%s.svc.%s.", serviceName, namespace, s.ClusterDomain))
+		if err != nil {
+			return nil, 0, err
+		}
+		return []dnsmessage.Resource{{
+			Header: resourceHeader(name, dnsmessage.TypeSRV),
+			Body:   &dnsmessage.SRVResource{Priority: 0, Weight: 100, Port: uint16(port.Port), Target: target},
+		}}, dnsmessage.RCodeSuccess, nil
+	}
+
+	return nil, dnsmessage.RCodeNameError, nil
+}
+
+func (s *Server) virtualService(ctx context.Context, namespace, name string) (*corev1.Service, error) {
+	vService := &corev1.Service{}
+	err := s.VirtualClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, vService)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return vService, nil
+}
+
+// serviceIPs returns the cluster ips of the synced physical service or the endpoint ips of a headless service
+func (s *Server) serviceIPs(ctx context.Context, vService *corev1.Service) ([]string, error) {
+	if vService.Spec.ClusterIP == corev1.ClusterIPNone {
+		vEndpoints := &corev1.Endpoints{}
+		err := s.VirtualClient.Get(ctx, types.NamespacedName{Namespace: vService.Namespace, Name: vService.Name}, vEndpoints)
+		if err != nil {
+			if kerrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		ips := []string{}
+		for _, subset := range vEndpoints.Subsets {
+			for _, address := range subset.Addresses {
+				ips = append(ips, address.IP)
+			}
+		}
+		return ips, nil
+	}
+
+	pService := &corev1.Service{}
+	err := s.PhysicalClient.Get(ctx, types.NamespacedName{Namespace: s.TargetNamespace, Name: translate.PhysicalName(vService.Name, vService.Namespace)}, pService)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		// services that are not synced, e.g. the default kubernetes service, have the cluster ip the
+		// syncer set on the virtual service
+		return clusterIPs(vService), nil
+	}
+
+	return clusterIPs(pService), nil
+}
+
+func clusterIPs(service *corev1.Service) []string {
+	if len(service.Spec.ClusterIPs) > 0 {
+		return service.Spec.ClusterIPs
+	} else if service.Spec.ClusterIP != "" && service.Spec.ClusterIP != corev1.ClusterIPNone {
+		return []string{service.Spec.ClusterIP}
+	}
+
+	return nil
+}
+
+func addressAnswers(name string, qType dnsmessage.Type, ips []string) []dnsmessage.Resource {
+	answers := []dnsmessage.Resource{}
+	for _, ip := range ips {
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			continue
+		}
+
+		if ipv4 := parsed.To4(); ipv4 != nil && (qType == dnsmessage.TypeA || qType == dnsmessage.TypeALL) {
+			resource := &dnsmessage.AResource{}
+			copy(resource.A[:], ipv4)
+			answers = append(answers, dnsmessage.Resource{Header: resourceHeader(name, dnsmessage.TypeA), Body: resource})
+		} else if ipv4 == nil && (qType == dnsmessage.TypeAAAA || qType == dnsmessage.TypeALL) {
+			resource := &dnsmessage.AAAAResource{}
+			copy(resource.AAAA[:], parsed.To16())
+			answers = append(answers, dnsmessage.Resource{Header: resourceHeader(name, dnsmessage.TypeAAAA), Body: resource})
+		}
+	}
+
+	return answers
+}
+
+func resourceHeader(name string, rType dnsmessage.Type) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  rType,
+		Class: dnsmessage.ClassINET,
+		TTL:   ttl,
+	}
+}
+
+// udpSize returns the udp payload size the client supports, which is announced in the OPT record
+func udpSize(parser *dnsmessage.Parser) int {
+	if parser.SkipAllQuestions() != nil || parser.SkipAllAnswers() != nil || parser.SkipAllAuthorities() != nil {
+		return maxUDPSize
+	}
+
+	for {
+		header, err := parser.AdditionalHeader()
+		if err != nil {
+			return maxUDPSize
+		} else if header.Type == dnsmessage.TypeOPT {
+			if int(header.Class) > maxUDPSize {
+				return int(header.Class)
+			}
+			return maxUDPSize
+		}
+
+		err = parser.SkipAdditional()
+		if err != nil {
+			return maxUDPSize
+		}
+	}
+}
+
+// buildResponse builds the response to the request. If the response would be larger than maxSize,
+// the answers are dropped and the response is marked as truncated, so the client retries over tcp.
+func buildResponse(request dnsmessage.Header, question *dnsmessage.Question, answers []dnsmessage.Resource, rCode dnsmessage.RCode, maxSize int) []byte {
+	response, err := build(request, question, answers, rCode, false)
+	if err == nil && maxSize > 0 && len(response) > maxSize {
+		response, err = build(request, question, nil, rCode, true)
+	}
+	if err != nil {
+		klog.V(1).Infof("error building dns response: %v", err)
+		return nil
+	}
+
+	return response
+}
+
+func build(request dnsmessage.Header, question *dnsmessage.Question, answers []dnsmessage.Resource, rCode dnsmessage.RCode, truncated bool) ([]byte, error) {
+	builder := dnsmessage.NewBuilder(make([]byte, 0, maxUDPSize), dnsmessage.Header{
+		ID:                 request.ID,
+		Response:           true,
+		OpCode:             request.OpCode,
+		Authoritative:      question != nil && rCode != dnsmessage.RCodeServerFailure,
+		Truncated:          truncated,
+		RecursionDesired:   request.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              rCode,
+	})
+	builder.EnableCompression()
+
+	err := builder.StartQuestions()
+	if err != nil {
+		return nil, err
+	}
+	if question != nil {
+		err = builder.Question(*question)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = builder.StartAnswers()
+	if err != nil {
+		return nil, err
+	}
+	for _, answer := range answers {
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			err = builder.AResource(answer.Header, *body)
+		case *dnsmessage.AAAAResource:
+			err = builder.AAAAResource(answer.Header, *body)
+		case *dnsmessage.CNAMEResource:
+			err = builder.CNAMEResource(answer.Header, *body)
+		case *dnsmessage.SRVResource:
+			err = builder.SRVResource(answer.Header, *body)
+		default:
+			err = fmt.Errorf("unsupported resource %T", answer.Body)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return builder.Finish()
+}
+
+// forward sends the raw request to the upstream server and returns its raw response
+func (s *Server) forward(ctx context.Context, request []byte, network string) ([]byte, error) {
+	dialer := &net.Dialer{Timeout: forwardTimeout}
+	conn, err := dialer.DialContext(ctx, network, s.Upstream)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(forwardTimeout))
+	if err != nil {
+		return nil, err
+	}
+
+	if network == "tcp" {
+		err = writeTCPMessage(conn, request)
+		if err != nil {
+			return nil, err
+		}
+
+		return readTCPMessage(conn)
+	}
+
+	_, err = conn.Write(request)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]byte, 65535)
+	n, err := conn.Read(response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response[:n], nil
+}
+
+// Nameserver returns the address of the first nameserver in the given resolv.conf
+func Nameserver(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return fields[1], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("no nameserver found in %s", path)
+}
+
+// Upstream returns the upstream address for the given server, which may be empty to use the
+// nameserver from resolv.conf, and defaults the port to 53
+func Upstream(server string) (string, error) {
+	if server == "" {
+		var err error
+		server, err = Nameserver(ResolvConf)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server, nil
+	} else if net.ParseIP(server) == nil {
+		return "", fmt.Errorf("invalid dns server %q", server)
+	}
+
+	return net.JoinHostPort(server, strconv.Itoa(53)), nil
+}
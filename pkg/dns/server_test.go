@@ -0,0 +1,166 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	testingutil "github.com/loft-sh/vcluster/pkg/util/testing"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"golang.org/x/net/dns/dnsmessage"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServer(t *testing.T) {
+	scheme := testingutil.NewScheme()
+	server := &Server{
+		VirtualClient: testingutil.NewFakeClient(scheme,
+			&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: corev1.ServiceSpec{
+					ClusterIP: "10.96.0.20",
+					Ports:     []corev1.ServicePort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}},
+				},
+			},
+			&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+				Spec:       corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone},
+			},
+			&corev1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+				Subsets: []corev1.EndpointSubset{{
+					Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1", Hostname: "db-0"}, {IP: "10.0.0.2", Hostname: "db-1"}},
+				}},
+			},
+			&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "external", Namespace: "default"},
+				Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeExternalName, ExternalName: "example.com"},
+			},
+		),
+		PhysicalClient: testingutil.NewFakeClient(scheme, &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: translate.PhysicalName("web", "default"), Namespace: "test"},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.10"},
+		}),
+		TargetNamespace: "test",
+		ClusterDomain:   "cluster.local",
+	}
+
+	// fake upstream that refuses everything, so forwarded queries can be told apart
+	upstream, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer upstream.Close()
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, addr, err := upstream.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+
+			var parser dnsmessage.Parser
+			header, _ := parser.Start(buffer[:n])
+			question, _ := parser.Question()
+			_, _ = upstream.WriteTo(buildResponse(header, &question, nil, dnsmessage.RCodeRefused, 0), addr)
+		}
+	}()
+	server.Upstream = upstream.LocalAddr().String()
+
+	tests := []struct {
+		name    string
+		qType   dnsmessage.Type
+		rCode   dnsmessage.RCode
+		answers []string
+	}{
+		{name: "web.default.svc.cluster.local.", qType: dnsmessage.TypeA, answers: []string{"10.96.0.10"}},
+		{name: "Web.Default.svc.cluster.local.", qType: dnsmessage.TypeA, answers: []string{"10.96.0.10"}},
+		{name: "web.default.svc.cluster.local.", qType: dnsmessage.TypeAAAA},
+		{name: "_http._tcp.web.default.svc.cluster.local.", qType: dnsmessage.TypeSRV, answers: []string{"80 web.default.svc.cluster.local."}},
+		{name: "db.default.svc.cluster.local.", qType: dnsmessage.TypeA, answers: []string{"10.0.0.1", "10.0.0.2"}},
+		{name: "db-1.db.default.svc.cluster.local.", qType: dnsmessage.TypeA, answers: []string{"10.0.0.2"}},
+		{name: "external.default.svc.cluster.local.", qType: dnsmessage.TypeA, answers: []string{"example.com."}},
+		{name: "10-0-0-5.default.pod.cluster.local.", qType: dnsmessage.TypeA, answers: []string{"10.0.0.5"}},
+		{name: "default.svc.cluster.local.", qType: dnsmessage.TypeA},
+		{name: "missing.default.svc.cluster.local.", qType: dnsmessage.TypeA, rCode: dnsmessage.RCodeNameError},
+		{name: "example.com.default.svc.cluster.local.", qType: dnsmessage.TypeA, rCode: dnsmessage.RCodeNameError},
+		{name: "example.com.", qType: dnsmessage.TypeA, rCode: dnsmessage.RCodeRefused},
+	}
+	for _, test := range tests {
+		builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 42, RecursionDesired: true})
+		assert.NilError(t, builder.StartQuestions())
+		assert.NilError(t, builder.Question(dnsmessage.Question{Name: dnsmessage.MustNewName(test.name), Type: test.qType, Class: dnsmessage.ClassINET}))
+		request, err := builder.Finish()
+		assert.NilError(t, err)
+
+		message := &dnsmessage.Message{}
+		assert.NilError(t, message.Unpack(server.Handle(context.Background(), request, "udp")), test.name)
+		assert.Equal(t, message.Header.ID, uint16(42), test.name)
+		assert.Equal(t, message.Header.RCode, test.rCode, test.name)
+
+		answers := []string{}
+		for _, answer := range message.Answers {
+			switch body := answer.Body.(type) {
+			case *dnsmessage.AResource:
+				answers = append(answers, net.IP(body.A[:]).String())
+			case *dnsmessage.CNAMEResource:
+				answers = append(answers, body.CNAME.String())
+			case *dnsmessage.SRVResource:
+				answers = append(answers, fmt.Sprintf("%d %s", body.Port, body.Target.String()))
+			}
+		}
+		if len(test.answers) == 0 {
+			assert.Equal(t, len(answers), 0, test.name)
+		} else {
+			assert.DeepEqual(t, answers, test.answers)
+		}
+	}
+}
+
+func TestServeUDP(t *testing.T) {
+	scheme := testingutil.NewScheme()
+	server := &Server{
+		VirtualClient:   testingutil.NewFakeClient(scheme),
+		PhysicalClient:  testingutil.NewFakeClient(scheme),
+		TargetNamespace: "test",
+		ClusterDomain:   "cluster.local",
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	go func() {
+		_ = server.serveUDP(context.Background(), conn)
+	}()
+	defer conn.Close()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	assert.NilError(t, err)
+	defer client.Close()
+
+	// send more requests than there are workers, all of them should be answered
+	requests := udpWorkers * 2
+	for i := 0; i < requests; i++ {
+		builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: uint16(i)})
+		assert.NilError(t, builder.StartQuestions())
+		assert.NilError(t, builder.Question(dnsmessage.Question{Name: dnsmessage.MustNewName("10-0-0-5.default.pod.cluster.local."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}))
+		request, err := builder.Finish()
+		assert.NilError(t, err)
+		_, err = client.Write(request)
+		assert.NilError(t, err)
+	}
+
+	assert.NilError(t, client.SetReadDeadline(time.Now().Add(time.Second*10)))
+	seen := map[uint16]bool{}
+	buffer := make([]byte, 512)
+	for len(seen) < requests {
+		n, err := client.Read(buffer)
+		assert.NilError(t, err)
+
+		message := &dnsmessage.Message{}
+		assert.NilError(t, message.Unpack(buffer[:n]))
+		assert.Equal(t, len(message.Answers), 1)
+		seen[message.Header.ID] = true
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	ctrlcontext "github.com/loft-sh/vcluster/cmd/vcluster/context"
 	"github.com/loft-sh/vcluster/pkg/controllers/resources/nodes/nodeservice"
+	"github.com/loft-sh/vcluster/pkg/util/gateway"
 	"github.com/loft-sh/vcluster/pkg/util/translate"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -33,8 +34,9 @@ func NewSyncer(currentNamespace string, currentNamespaceClient client.Client, op
 		serverCaKey:  options.ServerCaKey,
 		serverCaCert: options.ServerCaCert,
 
-		addSANs:   options.TLSSANs,
-		listeners: []dynamiccertificates.Listener{},
+		addSANs:           options.TLSSANs,
+		gatewayBaseDomain: options.GatewayBaseDomain,
+		listeners:         []dynamiccertificates.Listener{},
 
 		serviceName:           options.ServiceName,
 		currentNamespace:      currentNamespace,
@@ -50,6 +52,8 @@ type syncer struct {
 
 	addSANs []string
 
+	gatewayBaseDomain string
+
 	serviceName           string
 	currentNamespace      string
 	currentNamespaceCient client.Client
@@ -127,6 +131,11 @@ func (s *syncer) getSANs() ([]string, error) {
 		retSANs = append(retSANs, svc.Spec.ClusterIP)
 	}
 
+	// add the hostname the shared gateway routes to this vcluster
+	if s.gatewayBaseDomain != "" {
+		retSANs = append(retSANs, gateway.Hostname(translate.Suffix, s.currentNamespace, s.gatewayBaseDomain))
+	}
+
 	// make sure other sans are there as well
 	retSANs = append(retSANs, s.addSANs...)
 	sort.Strings(retSANs)
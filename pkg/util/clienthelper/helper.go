@@ -13,6 +13,7 @@ import (
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -73,8 +74,14 @@ func GetByIndex(ctx context.Context, c client.Client, obj runtime.Object, index,
 
 	list, err := c.Scheme().New(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
 	if err != nil {
-		// TODO: handle runtime.IsNotRegisteredError(err)
-		return err
+		if _, ok := obj.(*unstructured.Unstructured); !ok || !runtime.IsNotRegisteredError(err) {
+			return err
+		}
+
+		// kinds that are not in the scheme are listed as unstructured objects
+		unstructuredList := &unstructured.UnstructuredList{}
+		unstructuredList.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		list = unstructuredList
 	}
 
 	err = c.List(ctx, list.(client.ObjectList), client.MatchingFields{index: value})
@@ -6,13 +6,19 @@ import (
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"math"
+	"strings"
 	"time"
 
 	"github.com/loft-sh/vcluster/pkg/util/applier"
+	"github.com/loft-sh/vcluster/pkg/util/clienthelper"
 	"github.com/loft-sh/vcluster/pkg/util/loghelper"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func EnsureCRDFromFile(ctx context.Context, config *rest.Config, crdFilePath string, groupVersionKind schema.GroupVersionKind) error {
@@ -48,6 +54,95 @@ func EnsureCRDFromFile(ctx context.Context, config *rest.Config, crdFilePath str
 	return nil
 }
 
+// EnsureCRDFromPhysicalCluster copies the CRD of the given kind from the physical cluster into the virtual
+// cluster, if the kind doesn't exist there yet. This is used for CRDs that are installed with the software
+// running in the host cluster, e.g. the gateway api, so the virtual cluster serves the same versions.
+func EnsureCRDFromPhysicalCluster(ctx context.Context, pConfig *rest.Config, vConfig *rest.Config, groupVersionKind schema.GroupVersionKind) error {
+	exists, err := KindExists(vConfig, groupVersionKind)
+	if err != nil {
+		return err
+	} else if exists {
+		return nil
+	}
+
+	plural, err := KindPlural(pConfig, groupVersionKind)
+	if err != nil {
+		return err
+	} else if plural == "" {
+		return fmt.Errorf("kind %s does not exist in the host cluster", groupVersionKind.String())
+	}
+
+	pClient, err := client.New(pConfig, client.Options{Scheme: clienthelper.DefaultScheme})
+	if err != nil {
+		return err
+	}
+	pCRD := &apiextensionsv1.CustomResourceDefinition{}
+	err = pClient.Get(ctx, types.NamespacedName{Name: plural + "." + groupVersionKind.Group}, pCRD)
+	if err != nil {
+		return fmt.Errorf("get CRD %s from host cluster: %v", groupVersionKind.String(), err)
+	}
+
+	vClient, err := client.New(vConfig, client.Options{Scheme: clienthelper.DefaultScheme})
+	if err != nil {
+		return err
+	}
+	err = wait.ExponentialBackoffWithContext(ctx, wait.Backoff{Duration: time.Second, Factor: 1.5, Cap: 5 * time.Minute, Steps: math.MaxInt32}, func() (bool, error) {
+		err := vClient.Create(ctx, &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        pCRD.Name,
+				Labels:      pCRD.Labels,
+				Annotations: pCRD.Annotations,
+			},
+			Spec: pCRD.Spec,
+		})
+		if err != nil && !kerrors.IsAlreadyExists(err) {
+			loghelper.Infof("Failed to create CRD %s from the host cluster: %v", groupVersionKind.String(), err)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create CRD %s: %v", groupVersionKind.String(), err)
+	}
+
+	var lastErr error
+	err = wait.ExponentialBackoffWithContext(ctx, wait.Backoff{Duration: time.Second, Factor: 1.5, Cap: time.Minute, Steps: math.MaxInt32}, func() (bool, error) {
+		var found bool
+		found, lastErr = KindExists(vConfig, groupVersionKind)
+		return found, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find CRD %s: %v: %v", groupVersionKind.String(), err, lastErr)
+	}
+
+	return nil
+}
+
+// KindPlural returns the resource name of the given kind or an empty string if the kind doesn't exist
+func KindPlural(config *rest.Config, groupVersionKind schema.GroupVersionKind) (string, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return "", err
+	}
+
+	resources, err := discoveryClient.ServerResourcesForGroupVersion(groupVersionKind.GroupVersion().String())
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	for _, r := range resources.APIResources {
+		if r.Kind == groupVersionKind.Kind && !strings.Contains(r.Name, "/") {
+			return r.Name, nil
+		}
+	}
+
+	return "", nil
+}
+
 // KindExists checks if given CRDs exist in the given group.
 // Returns foundKinds, notFoundKinds, error
 func KindExists(config *rest.Config, groupVersionKind schema.GroupVersionKind) (bool, error) {
@@ -0,0 +1,12 @@
+package gateway
+
+import "strings"
+
+// HostnameAnnotation is set on the vcluster service if the vcluster is reachable through a shared
+// gateway that routes to the vcluster by SNI hostname
+const HostnameAnnotation = "vcluster.loft.sh/gateway-hostname"
+
+// Hostname returns the hostname of the vcluster behind a shared gateway with the given base domain
+func Hostname(name, namespace, baseDomain string) string {
+	return name + "." + namespace + "." + strings.TrimPrefix(baseDomain, ".")
+}
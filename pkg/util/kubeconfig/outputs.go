@@ -0,0 +1,372 @@
+package kubeconfig
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/loft-sh/vcluster/pkg/certs"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/klog/v2"
+)
+
+const (
+	TokenSecretKey = "token"
+
+	// ExpiresAtAnnotation holds the time the credentials of a generated kube config expire
+	ExpiresAtAnnotation = "vcluster.loft.sh/kubeconfig-expires-at"
+	// HashAnnotation holds the hash of the output the kube config was generated from
+	HashAnnotation = "vcluster.loft.sh/kubeconfig-hash"
+
+	// DefaultOutputExpiry is the default lifetime of the credentials of a generated kube config
+	DefaultOutputExpiry = time.Hour * 24 * 7
+)
+
+// Output describes a kube config that should be generated by the syncer and written into a host secret.
+// The credentials are either a client certificate for User and Groups or a token of ServiceAccount.
+type Output struct {
+	// Name is used as cluster, user and context name within the kube config
+	Name string `json:"name"`
+
+	// User is the common name of the client certificate
+	User string `json:"user,omitempty"`
+	// Groups are the organizations of the client certificate
+	Groups []string `json:"groups,omitempty"`
+	// ServiceAccount is a virtual service account in the form namespace/name that a token is requested for
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+
+	// Expiry is the lifetime of the credentials, defaults to 7 days
+	Expiry *metav1.Duration `json:"expiry,omitempty"`
+	// RenewBefore renews the credentials if they expire within this duration, defaults to a third of Expiry
+	RenewBefore *metav1.Duration `json:"renewBefore,omitempty"`
+
+	// Secret is the name of the host secret to write the kube config to
+	Secret string `json:"secret"`
+	// SecretNamespace is the host namespace of the secret, defaults to the vcluster namespace
+	SecretNamespace string `json:"secretNamespace,omitempty"`
+
+	// Server overrides the server of the kube config (e.g. https://my-vcluster.domain.com)
+	Server string `json:"server,omitempty"`
+	// ContextName overrides the cluster, user and context name within the kube config
+	ContextName string `json:"contextName,omitempty"`
+}
+
+// LoadOutputs reads and validates the kube config outputs from the given yaml file. Outputs without
+// a secret namespace write their secret into defaultNamespace.
+func LoadOutputs(path, defaultNamespace string) ([]Output, error) {
+	out, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := []Output{}
+	err = yaml.Unmarshal(out, &outputs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse kube config outputs %s", path)
+	}
+
+	secrets := map[string]bool{}
+	for _, output := range outputs {
+		err = output.Validate()
+		if err != nil {
+			return nil, err
+		}
+
+		secretNamespace := output.SecretNamespace
+		if secretNamespace == "" {
+			secretNamespace = defaultNamespace
+		}
+
+		key := secretNamespace + "/" + output.Secret
+		if secrets[key] {
+			return nil, fmt.Errorf("kube config output %s: secret %s is used by another output", output.Name, key)
+		}
+		secrets[key] = true
+	}
+
+	return outputs, nil
+}
+
+// Validate checks that the output has a name, a target secret and exactly one kind of credentials
+func (o *Output) Validate() error {
+	if o.Name == "" {
+		return fmt.Errorf("kube config output is missing a name")
+	} else if o.Secret == "" {
+		return fmt.Errorf("kube config output %s: secret is missing", o.Name)
+	} else if o.User == "" && o.ServiceAccount == "" {
+		return fmt.Errorf("kube config output %s: either user or serviceAccount is required", o.Name)
+	} else if o.User != "" && o.ServiceAccount != "" {
+		return fmt.Errorf("kube config output %s: user and serviceAccount cannot be used together", o.Name)
+	} else if o.ServiceAccount != "" && len(o.Groups) > 0 {
+		return fmt.Errorf("kube config output %s: groups cannot be used with serviceAccount", o.Name)
+	} else if o.ServiceAccount != "" && len(strings.Split(o.ServiceAccount, "/")) != 2 {
+		return fmt.Errorf("kube config output %s: serviceAccount %s is not in the form namespace/name", o.Name, o.ServiceAccount)
+	} else if o.expiry() <= o.renewBefore() {
+		return fmt.Errorf("kube config output %s: renewBefore has to be shorter than expiry", o.Name)
+	}
+
+	return nil
+}
+
+func (o *Output) expiry() time.Duration {
+	if o.Expiry != nil {
+		return o.Expiry.Duration
+	}
+
+	return DefaultOutputExpiry
+}
+
+func (o *Output) renewBefore() time.Duration {
+	if o.RenewBefore != nil {
+		return o.RenewBefore.Duration
+	}
+
+	return o.expiry() / 3
+}
+
+func (o *Output) contextName() string {
+	if o.ContextName != "" {
+		return o.ContextName
+	}
+
+	return o.Name
+}
+
+// OutputWriter generates the kube config outputs and renews them before their credentials expire
+type OutputWriter struct {
+	// HostClient is used to write the secrets, which might be in other namespaces than the vcluster namespace
+	HostClient kubernetes.Interface
+	// VirtualClient is used to request service account tokens
+	VirtualClient kubernetes.Interface
+
+	// ClientCACert and ClientCAKey are the paths of the ca that signs the client certificates
+	ClientCACert string
+	ClientCAKey  string
+
+	// Namespace is the default namespace for the secrets
+	Namespace string
+}
+
+// Write generates a kube config for each output that points to the given cluster and writes it into the
+// output secret. Secrets that were generated from the same output and are not about to expire are skipped.
+// A failing output doesn't stop the other outputs from being written.
+func (w *OutputWriter) Write(ctx context.Context, cluster *api.Cluster, outputs []Output) error {
+	errs := []error{}
+	for _, output := range outputs {
+		err := w.writeOutput(ctx, cluster, output)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "write kube config output %s", output.Name))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func (w *OutputWriter) writeOutput(ctx context.Context, cluster *api.Cluster, output Output) error {
+	if output.SecretNamespace == "" {
+		output.SecretNamespace = w.Namespace
+	}
+
+	cluster = cluster.DeepCopy()
+	if output.Server != "" {
+		cluster.Server = output.Server
+	}
+
+	hash, err := hashOutput(cluster, output)
+	if err != nil {
+		return err
+	}
+
+	exists := true
+	secret, err := w.HostClient.CoreV1().Secrets(output.SecretNamespace).Get(ctx, output.Secret, metav1.GetOptions{})
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+
+		exists = false
+	} else if _, ok := secret.Annotations[HashAnnotation]; !ok {
+		return fmt.Errorf("secret %s/%s already exists and was not created by vcluster, refusing to overwrite it", secret.Namespace, secret.Name)
+	} else if !needsRenewal(secret, hash, output.renewBefore()) {
+		return nil
+	}
+
+	authInfo, expiresAt, err := w.createAuthInfo(ctx, output)
+	if err != nil {
+		return err
+	}
+
+	name := output.contextName()
+	config := api.NewConfig()
+	config.Clusters[name] = cluster
+	config.AuthInfos[name] = authInfo
+	config.Contexts[name] = &api.Context{
+		Cluster:  name,
+		AuthInfo: name,
+	}
+	config.CurrentContext = name
+	out, err := clientcmd.Write(*config)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      output.Secret,
+				Namespace: output.SecretNamespace,
+			},
+			Type: corev1.SecretTypeOpaque,
+		}
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[ExpiresAtAnnotation] = expiresAt.UTC().Format(time.RFC3339)
+	secret.Annotations[HashAnnotation] = hash
+	secret.Data = map[string][]byte{
+		KubeconfigSecretKey: out,
+		CADataSecretKey:     cluster.CertificateAuthorityData,
+	}
+	if len(authInfo.Token) > 0 {
+		secret.Data[TokenSecretKey] = []byte(authInfo.Token)
+	} else {
+		secret.Data[CertificateSecretKey] = authInfo.ClientCertificateData
+		secret.Data[CertificateKeySecretKey] = authInfo.ClientKeyData
+	}
+
+	// set owner reference
+	if translate.Owner != nil && translate.Owner.GetNamespace() == secret.Namespace {
+		secret.OwnerReferences = translate.GetOwnerReference(nil)
+	}
+
+	if !exists {
+		_, err = w.HostClient.CoreV1().Secrets(secret.Namespace).Create(ctx, secret, metav1.CreateOptions{})
+	} else {
+		_, err = w.HostClient.CoreV1().Secrets(secret.Namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return errors.Wrapf(err, "apply kube config secret %s/%s", secret.Namespace, secret.Name)
+	}
+
+	klog.Infof("Generated kube config secret %s/%s, valid until %s", secret.Namespace, secret.Name, expiresAt.String())
+	return nil
+}
+
+func (w *OutputWriter) createAuthInfo(ctx context.Context, output Output) (*api.AuthInfo, time.Time, error) {
+	if output.ServiceAccount != "" {
+		splitted := strings.Split(output.ServiceAccount, "/")
+		expirationSeconds := int64(output.expiry().Seconds())
+		tokenRequest, err := w.VirtualClient.CoreV1().ServiceAccounts(splitted[0]).CreateToken(ctx, splitted[1], &authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				ExpirationSeconds: &expirationSeconds,
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, time.Time{}, errors.Wrapf(err, "request token for service account %s", output.ServiceAccount)
+		}
+
+		return &api.AuthInfo{Token: tokenRequest.Status.Token}, tokenRequest.Status.ExpirationTimestamp.Time, nil
+	}
+
+	caCert, caKey, err := w.loadClientCA()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	notAfter := time.Now().Add(output.expiry())
+	clientCert, clientKey, err := certs.NewCertAndKey(caCert, caKey, &certs.CertConfig{
+		Config: certutil.Config{
+			CommonName:   output.User,
+			Organization: output.Groups,
+			Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		},
+		NotAfter: &notAfter,
+	})
+	if err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "create client certificate")
+	}
+
+	encodedKey, err := keyutil.MarshalPrivateKeyToPEM(clientKey)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return &api.AuthInfo{
+		ClientCertificateData: certs.EncodeCertPEM(clientCert),
+		ClientKeyData:         encodedKey,
+	}, clientCert.NotAfter, nil
+}
+
+func (w *OutputWriter) loadClientCA() (*x509.Certificate, crypto.Signer, error) {
+	// the first certificate is the one used for signing, the others are only kept for trust
+	caCerts, err := certutil.CertsFromFile(w.ClientCACert)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "load client ca certificate")
+	}
+
+	privateKey, err := keyutil.PrivateKeyFromFile(w.ClientCAKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "load client ca key")
+	}
+	caKey, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("client ca key is not a valid signer")
+	}
+
+	return caCerts[0], caKey, nil
+}
+
+// needsRenewal returns true if the secret was generated from a different output or its credentials
+// expire within renewBefore
+func needsRenewal(secret *corev1.Secret, hash string, renewBefore time.Duration) bool {
+	if secret.Annotations[HashAnnotation] != hash {
+		return true
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, secret.Annotations[ExpiresAtAnnotation])
+	if err != nil {
+		return true
+	}
+
+	return time.Now().Add(renewBefore).After(expiresAt)
+}
+
+// hashOutput hashes everything that would change the generated kube config, so that a changed output
+// or a rotated certificate authority results in a new kube config
+func hashOutput(cluster *api.Cluster, output Output) (string, error) {
+	out, err := json.Marshal(struct {
+		Output Output
+		Server string
+		CAData []byte
+	}{
+		Output: output,
+		Server: cluster.Server,
+		CAData: cluster.CertificateAuthorityData,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.Sum256(out)
+	return hex.EncodeToString(digest[:]), nil
+}
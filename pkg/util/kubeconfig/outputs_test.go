@@ -0,0 +1,179 @@
+package kubeconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/loft-sh/vcluster/pkg/certs"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+)
+
+func TestLoadOutputs(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config string
+		err    bool
+	}{
+		{
+			name: "valid",
+			config: `
+- name: ci
+  user: ci
+  groups: ["ci"]
+  expiry: 24h
+  secret: ci-kubeconfig
+- name: dashboard
+  serviceAccount: kube-system/dashboard
+  secret: dashboard-kubeconfig
+  secretNamespace: dashboard`,
+		},
+		{
+			name: "missing credentials",
+			config: `
+- name: ci
+  secret: ci-kubeconfig`,
+			err: true,
+		},
+		{
+			name: "user and service account",
+			config: `
+- name: ci
+  user: ci
+  serviceAccount: default/ci
+  secret: ci-kubeconfig`,
+			err: true,
+		},
+		{
+			name: "renew before longer than expiry",
+			config: `
+- name: ci
+  user: ci
+  expiry: 1h
+  renewBefore: 2h
+  secret: ci-kubeconfig`,
+			err: true,
+		},
+		{
+			name: "duplicate secret",
+			config: `
+- name: ci
+  user: ci
+  secret: kubeconfig
+- name: dashboard
+  user: dashboard
+  secret: kubeconfig`,
+			err: true,
+		},
+		{
+			name: "duplicate secret in default namespace",
+			config: `
+- name: ci
+  user: ci
+  secret: kubeconfig
+- name: dashboard
+  user: dashboard
+  secret: kubeconfig
+  secretNamespace: test`,
+			err: true,
+		},
+	}
+
+	dir := t.TempDir()
+	for _, testCase := range testCases {
+		path := filepath.Join(dir, "outputs.yaml")
+		err := os.WriteFile(path, []byte(testCase.config), 0600)
+		assert.NilError(t, err)
+
+		_, err = LoadOutputs(path, "test")
+		assert.Equal(t, err != nil, testCase.err, "unexpected error in test case %s: %v", testCase.name, err)
+	}
+}
+
+func TestWriteOutputs(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey, err := certs.NewCertificateAuthority(&certs.CertConfig{Config: certutil.Config{CommonName: "client-ca"}})
+	assert.NilError(t, err)
+	assert.NilError(t, certutil.WriteCert(filepath.Join(dir, "client-ca.crt"), certs.EncodeCertPEM(caCert)))
+	encodedKey, err := keyutil.MarshalPrivateKeyToPEM(caKey)
+	assert.NilError(t, err)
+	assert.NilError(t, keyutil.WriteKey(filepath.Join(dir, "client-ca.key"), encodedKey))
+
+	hostClient := fake.NewSimpleClientset()
+	writer := &OutputWriter{
+		HostClient:   hostClient,
+		ClientCACert: filepath.Join(dir, "client-ca.crt"),
+		ClientCAKey:  filepath.Join(dir, "client-ca.key"),
+		Namespace:    "test",
+	}
+	cluster := &api.Cluster{Server: "https://localhost:8443", CertificateAuthorityData: certs.EncodeCertPEM(caCert)}
+	outputs := []Output{
+		{
+			Name:            "ci",
+			User:            "ci",
+			Groups:          []string{"ci"},
+			Expiry:          &metav1.Duration{Duration: time.Hour},
+			Secret:          "ci-kubeconfig",
+			SecretNamespace: "other",
+			Server:          "https://vcluster.example.com",
+		},
+	}
+	assert.NilError(t, writer.Write(context.TODO(), cluster, outputs))
+
+	secret, err := hostClient.CoreV1().Secrets("other").Get(context.TODO(), "ci-kubeconfig", metav1.GetOptions{})
+	assert.NilError(t, err)
+	config, err := clientcmd.Load(secret.Data[KubeconfigSecretKey])
+	assert.NilError(t, err)
+	assert.Equal(t, config.CurrentContext, "ci")
+	assert.Equal(t, config.Clusters["ci"].Server, "https://vcluster.example.com")
+
+	clientCerts, err := certutil.ParseCertsPEM(config.AuthInfos["ci"].ClientCertificateData)
+	assert.NilError(t, err)
+	assert.Equal(t, clientCerts[0].Subject.CommonName, "ci")
+	assert.DeepEqual(t, clientCerts[0].Subject.Organization, []string{"ci"})
+	assert.Assert(t, clientCerts[0].NotAfter.Before(time.Now().Add(time.Hour+time.Minute)))
+	assert.NilError(t, clientCerts[0].CheckSignatureFrom(caCert))
+
+	// an unchanged output that is not about to expire is not renewed
+	assert.NilError(t, writer.Write(context.TODO(), cluster, outputs))
+	unchanged, err := hostClient.CoreV1().Secrets("other").Get(context.TODO(), "ci-kubeconfig", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, unchanged.Data, secret.Data)
+
+	// a changed output is renewed
+	outputs[0].Groups = []string{"ci", "readers"}
+	assert.NilError(t, writer.Write(context.TODO(), cluster, outputs))
+	changed, err := hostClient.CoreV1().Secrets("other").Get(context.TODO(), "ci-kubeconfig", metav1.GetOptions{})
+	assert.NilError(t, err)
+	clientCerts, err = certutil.ParseCertsPEM(changed.Data[CertificateSecretKey])
+	assert.NilError(t, err)
+	assert.DeepEqual(t, clientCerts[0].Subject.Organization, []string{"ci", "readers"})
+
+	// secrets that were not created by vcluster are not overwritten
+	_, err = hostClient.CoreV1().Secrets("test").Create(context.TODO(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "test"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}, metav1.CreateOptions{})
+	assert.NilError(t, err)
+	err = writer.Write(context.TODO(), cluster, []Output{
+		{Name: "existing", User: "existing", Secret: "existing"},
+		{Name: "after", User: "after", Secret: "after-kubeconfig"},
+	})
+	assert.ErrorContains(t, err, "refusing to overwrite")
+	existing, err := hostClient.CoreV1().Secrets("test").Get(context.TODO(), "existing", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, existing.Data, map[string][]byte{"password": []byte("secret")})
+
+	// a failing output doesn't stop the other outputs from being written
+	_, err = hostClient.CoreV1().Secrets("test").Get(context.TODO(), "after-kubeconfig", metav1.GetOptions{})
+	assert.NilError(t, err)
+}
@@ -2,70 +2,219 @@ package portforward
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
-	"github.com/loft-sh/vcluster/cmd/vclusterctl/log"
 	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/loft-sh/vcluster/cmd/vclusterctl/log"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/transport/spdy"
-	"net/http"
-	"time"
 )
 
+// PodResolver returns the pod that should be forwarded to. It is called again every time the port
+// forwarding has to be restarted, as the pod might have been replaced in the meantime.
+type PodResolver func(ctx context.Context) (string, error)
+
+// RestartOptions configures StartPortForwardingWithRecovery
+type RestartOptions struct {
+	Address    string
+	Namespace  string
+	LocalPort  string
+	RemotePort string
+
+	// ResolvePod returns the pod to forward to
+	ResolvePod PodResolver
+
+	// KeepAlive probes the forwarded port in this interval and restarts the port forwarding if the
+	// probe fails multiple times in a row. Zero disables the probe.
+	KeepAlive time.Duration
+	// Probe checks if the forwarded port is still reachable, defaults to ProbeTLS
+	Probe func(address string) error
+
+	// Backoff is used between reconnection attempts
+	Backoff wait.Backoff
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// DefaultBackoff is the default backoff between reconnection attempts
+var DefaultBackoff = wait.Backoff{Duration: time.Second, Factor: 2, Jitter: 0.1, Cap: time.Second * 30, Steps: 1 << 30}
+
+// keepAliveFailureThreshold is the amount of failed probes after which the port forwarding is restarted
+const keepAliveFailureThreshold = 3
+
+// forwardPorts starts a single port forwarding, it is replaced in tests
+var forwardPorts = startPortForwarding
+
+// StartPortForwardingWithRestart forwards to the given pod and restarts the port forwarding to the
+// same pod once it is ready again.
 func StartPortForwardingWithRestart(config *rest.Config, address, pod, namespace string, localPort, remotePort string, interrupt chan struct{}, stdout io.Writer, stderr io.Writer, log log.Logger) error {
 	kubeClient, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return err
 	}
 
-	// restart port forwarding
-	stopChan, err := StartPortForwarding(config, kubeClient, address, pod, namespace, localPort, remotePort, stdout, stderr, log)
+	return StartPortForwardingWithRecovery(config, RestartOptions{
+		Address:    address,
+		Namespace:  namespace,
+		LocalPort:  localPort,
+		RemotePort: remotePort,
+		ResolvePod: func(ctx context.Context) (string, error) {
+			return pod, WaitForReadyPod(ctx, kubeClient, namespace, pod)
+		},
+		Stdout: stdout,
+		Stderr: stderr,
+	}, interrupt, log)
+}
+
+// StartPortForwardingWithRecovery forwards the local port to the pod returned by options.ResolvePod. If the
+// connection breaks or the keep alive probe fails, the pod is resolved again and the port forwarding is
+// restarted on the same local port with exponential backoff until interrupt is closed.
+func StartPortForwardingWithRecovery(config *rest.Config, options RestartOptions, interrupt chan struct{}, log log.Logger) error {
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	if options.Backoff.Steps == 0 {
+		options.Backoff = DefaultBackoff
+	}
+	if options.Probe == nil {
+		options.Probe = ProbeTLS
+	}
+	probeAddress := options.Address
+	if probeAddress == "" {
+		probeAddress = "localhost"
+	}
+	probeAddress = net.JoinHostPort(probeAddress, options.LocalPort)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	pod, err := options.ResolvePod(ctx)
+	if err != nil {
+		return fmt.Errorf("error finding pod: %v", err)
+	}
+	stopChan, stop, err := forwardPorts(config, kubeClient, options.Address, pod, options.Namespace, options.LocalPort, options.RemotePort, options.Stdout, options.Stderr, log)
 	if err != nil {
 		return fmt.Errorf("error starting port forwarding: %v", err)
 	}
 
+	var keepAlive <-chan time.Time
+	if options.KeepAlive > 0 {
+		ticker := time.NewTicker(options.KeepAlive)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+
+	failedProbes := 0
 	for {
 		select {
 		case <-interrupt:
-			close(stopChan)
+			stop()
 			return nil
+		case <-keepAlive:
+			err := options.Probe(probeAddress)
+			if err == nil {
+				failedProbes = 0
+				continue
+			}
+
+			failedProbes++
+			log.Debugf("Keep alive probe %d/%d failed: %v", failedProbes, keepAliveFailureThreshold, err)
+			if failedProbes >= keepAliveFailureThreshold {
+				log.Warnf("Port forwarding to pod %s is not responding, reconnecting", pod)
+				failedProbes = 0
+				stop()
+			}
 		case <-stopChan:
-			log.Info("Restarting port forwarding")
+			log.Infof("Port forwarding to pod %s lost, reconnecting...", pod)
 
-			// wait for loft pod to start
-			err := wait.PollImmediate(time.Second, time.Minute*10, func() (done bool, err error) {
-				pod, err := kubeClient.CoreV1().Pods(namespace).Get(context.Background(), pod, metav1.GetOptions{})
-				if err != nil {
-					return false, nil
+			backoff := options.Backoff
+			for {
+				pod, stopChan, stop, err = reconnect(ctx, config, kubeClient, options, log)
+				if err == nil {
+					break
 				}
-				for _, c := range pod.Status.Conditions {
-					if c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue {
-						return true, nil
-					}
-				}
-				return false, nil
-			})
-			if err != nil {
-				log.Warnf("error waiting for ready vcluster pod: %v", err)
-				continue
-			}
 
-			// restart port forwarding
-			stopChan, err = StartPortForwarding(config, kubeClient, address, pod, namespace, localPort, remotePort, stdout, stderr, log)
-			if err != nil {
-				log.Warnf("error starting port forwarding: %v", err)
-				continue
+				delay := backoff.Step()
+				log.Warnf("Reconnecting failed, retrying in %s: %v", delay.Round(time.Second).String(), err)
+				select {
+				case <-interrupt:
+					return nil
+				case <-time.After(delay):
+				}
 			}
 
-			log.Donef("Successfully restarted port forwarding")
+			failedProbes = 0
+			log.Donef("Port forwarding to pod %s reconnected on local port %s", pod, options.LocalPort)
 		}
 	}
 }
 
+func reconnect(ctx context.Context, config *rest.Config, kubeClient kubernetes.Interface, options RestartOptions, log log.Logger) (string, chan struct{}, func(), error) {
+	pod, err := options.ResolvePod(ctx)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("error finding pod: %v", err)
+	}
+
+	stopChan, stop, err := forwardPorts(config, kubeClient, options.Address, pod, options.Namespace, options.LocalPort, options.RemotePort, options.Stdout, options.Stderr, log)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("error starting port forwarding to pod %s: %v", pod, err)
+	}
+
+	return pod, stopChan, stop, nil
+}
+
+// ProbeTLS checks that a tls handshake through the forwarded port succeeds, which requires the
+// whole path up to the server within the pod to work
+func ProbeTLS(address string) error {
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Second * 5}, "tcp", address, &tls.Config{
+		InsecureSkipVerify: true, // nolint:gosec
+	})
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// WaitForReadyPod waits until the given pod is ready or the context is canceled
+func WaitForReadyPod(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) error {
+	return wait.PollImmediateUntil(time.Second, func() (done bool, err error) {
+		pod, err := kubeClient.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		for _, c := range pod.Status.Conditions {
+			if c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue {
+				return true, nil
+			}
+		}
+		return false, nil
+	}, ctx.Done())
+}
+
 func StartPortForwarding(config *rest.Config, client kubernetes.Interface, address, pod, namespace, localPort, remotePort string, stdout io.Writer, stderr io.Writer, log log.Logger) (chan struct{}, error) {
+	stopChan, _, err := startPortForwarding(config, client, address, pod, namespace, localPort, remotePort, stdout, stderr, log)
+	return stopChan, err
+}
+
+// startPortForwarding starts the port forwarding and returns a channel that is closed once it stopped
+// as well as a function to stop it, which can safely be called multiple times
+func startPortForwarding(config *rest.Config, client kubernetes.Interface, address, pod, namespace, localPort, remotePort string, stdout io.Writer, stderr io.Writer, log log.Logger) (chan struct{}, func(), error) {
 	execRequest := client.CoreV1().RESTClient().Post().
 		Resource("pods").
 		Name(pod).
@@ -74,7 +223,7 @@ func StartPortForwarding(config *rest.Config, client kubernetes.Interface, addre
 
 	t, upgrader, err := spdy.RoundTripperFor(config)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if address == "" {
@@ -85,9 +234,15 @@ func StartPortForwarding(config *rest.Config, client kubernetes.Interface, addre
 	errChan := make(chan error)
 	readyChan := make(chan struct{})
 	stopChan := make(chan struct{})
+	stopOnce := sync.Once{}
+	stop := func() {
+		stopOnce.Do(func() {
+			close(stopChan)
+		})
+	}
 	forwarder, err := NewOnAddresses(dialer, []string{address}, []string{localPort + ":" + remotePort}, stopChan, readyChan, errChan, stdout, stderr)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	go func() {
@@ -100,10 +255,11 @@ func StartPortForwarding(config *rest.Config, client kubernetes.Interface, addre
 	// wait till ready
 	select {
 	case err = <-errChan:
-		return nil, err
+		stop()
+		return nil, nil, err
 	case <-readyChan:
 	case <-stopChan:
-		return nil, fmt.Errorf("stopped before ready")
+		return nil, nil, fmt.Errorf("stopped before ready")
 	}
 
 	// start watcher
@@ -114,11 +270,11 @@ func StartPortForwarding(config *rest.Config, client kubernetes.Interface, addre
 				return
 			case err = <-errChan:
 				log.Infof("error during port forwarder: %v", err)
-				close(stopChan)
+				stop()
 				return
 			}
 		}
 	}()
 
-	return stopChan, nil
+	return stopChan, stop, nil
 }
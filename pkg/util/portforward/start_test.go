@@ -0,0 +1,117 @@
+package portforward
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/loft-sh/vcluster/cmd/vclusterctl/log"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// fakeForwarder records the started port forwardings and lets the test break them
+type fakeForwarder struct {
+	m       sync.Mutex
+	pods    []string
+	stops   []func()
+	failing int
+	started chan string
+}
+
+func (f *fakeForwarder) forward(config *rest.Config, client kubernetes.Interface, address, pod, namespace, localPort, remotePort string, stdout io.Writer, stderr io.Writer, log log.Logger) (chan struct{}, func(), error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if f.failing > 0 {
+		f.failing--
+		return nil, nil, fmt.Errorf("pod %s not ready", pod)
+	}
+
+	stopChan := make(chan struct{})
+	once := sync.Once{}
+	stop := func() { once.Do(func() { close(stopChan) }) }
+	f.pods = append(f.pods, pod)
+	f.stops = append(f.stops, stop)
+	f.started <- pod
+	return stopChan, stop, nil
+}
+
+func (f *fakeForwarder) breakConnection(i int) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.stops[i]()
+}
+
+func startRecovery(t *testing.T, forwarder *fakeForwarder, options RestartOptions) (chan struct{}, chan error) {
+	forwardPorts = forwarder.forward
+	t.Cleanup(func() { forwardPorts = startPortForwarding })
+
+	pods := 0
+	options.ResolvePod = func(ctx context.Context) (string, error) {
+		pods++
+		return fmt.Sprintf("vcluster-%d", pods), nil
+	}
+	options.Backoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 1 << 30}
+	options.LocalPort = "8443"
+	options.RemotePort = "8443"
+
+	interrupt := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		result <- StartPortForwardingWithRecovery(&rest.Config{Host: "https://localhost:6443"}, options, interrupt, &log.DiscardLogger{})
+	}()
+
+	return interrupt, result
+}
+
+func waitForStart(t *testing.T, forwarder *fakeForwarder) string {
+	select {
+	case pod := <-forwarder.started:
+		return pod
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for port forwarding")
+		return ""
+	}
+}
+
+func TestPortForwardingReconnects(t *testing.T) {
+	forwarder := &fakeForwarder{started: make(chan string, 10)}
+	interrupt, result := startRecovery(t, forwarder, RestartOptions{})
+	assert.Equal(t, waitForStart(t, forwarder), "vcluster-1")
+
+	// a lost connection resolves the pod again, failed attempts are retried
+	forwarder.m.Lock()
+	forwarder.failing = 2
+	forwarder.m.Unlock()
+	forwarder.breakConnection(0)
+	assert.Equal(t, waitForStart(t, forwarder), "vcluster-4")
+
+	close(interrupt)
+	assert.NilError(t, <-result)
+}
+
+func TestPortForwardingKeepAlive(t *testing.T) {
+	forwarder := &fakeForwarder{started: make(chan string, 10)}
+	probes := make(chan string, 100)
+	interrupt, result := startRecovery(t, forwarder, RestartOptions{
+		KeepAlive: time.Millisecond * 10,
+		Probe: func(address string) error {
+			probes <- address
+			return fmt.Errorf("connection refused")
+		},
+	})
+	assert.Equal(t, waitForStart(t, forwarder), "vcluster-1")
+
+	// the port forwarding is restarted after the probe failed multiple times
+	assert.Equal(t, waitForStart(t, forwarder), "vcluster-2")
+	assert.Assert(t, len(probes) >= keepAliveFailureThreshold)
+	assert.Equal(t, <-probes, "localhost:8443")
+
+	close(interrupt)
+	assert.NilError(t, <-result)
+}
@@ -8,6 +8,7 @@ import (
 
 	volumesnapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -75,6 +76,8 @@ func (fc *FakeIndexClient) updateIndices(ctx context.Context, obj runtime.Object
 	list, err := fc.scheme.New(listGvk)
 	if err != nil {
 		return err
+	} else if unstructuredList, ok := list.(*unstructured.UnstructuredList); ok {
+		unstructuredList.SetGroupVersionKind(listGvk)
 	}
 
 	err = fc.Client.List(ctx, list.(client.ObjectList))